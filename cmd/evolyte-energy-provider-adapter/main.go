@@ -8,44 +8,132 @@ import (
 
 	"github.com/entl/evolyte-energy-provider-adapter/internal/config"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/logging"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/metrics"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/migrations"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/secrets"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/server"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/tracing"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
 	"github.com/go-playground/validator/v10"
-	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
 func main() {
 	ctx := context.Background()
+	logLevel := new(slog.LevelVar)
 	opts := &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: logLevel,
 	}
 
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	handler := logging.NewContextHandler(logging.NewRedactingHandler(slog.NewJSONHandler(os.Stdout, opts)))
 	slog.SetDefault(slog.New(handler))
 
+	// Commands that need neither configuration nor a database, such as generating the first key.
+	if len(os.Args) > 1 && os.Args[1] == "secrets" && !secrets.NeedsKeyring(os.Args[2:]) {
+		if err := secrets.RunCLI(ctx, nil, nil, os.Args[2:], os.Stdout); err != nil {
+			slog.Error("Secrets command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	slog.Info("Starting Evolyte Energy Provider Adapter")
 	cfg, err := config.LoadConfig(".env.docker")
 	if err != nil {
 		slog.Error("Failed to load config", "error", err)
 		panic(err)
 	}
+	level, err := logging.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		slog.Error("Invalid log level", "level", cfg.Logging.Level, "error", err)
+		panic(err)
+	}
+	logLevel.Set(level)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		panic(err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
 
-	conn, err := pgx.Connect(ctx, fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s",
-		cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DB))
+	pool, err := db.NewPool(ctx, cfg.Postgres)
 	if err != nil {
 		slog.Error("Failed to connect to Postgres", "error", err)
 		panic(err)
 	}
-	defer conn.Close(ctx)
+	defer pool.Close()
+	prometheus.MustRegister(metrics.NewPgxPoolCollector(pool.Stat))
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.RunCLI(ctx, pool, os.Args[2:], os.Stdout); err != nil {
+			slog.Error("Migration command failed", "error", err)
+			pool.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importer := inverters.NewImporter(inverters.NewPostgresInverterStore(pool), utils.NewCustomValidator(validator.New()), cfg.Import.MaxRows)
+		if err := inverters.RunImportCLI(ctx, importer, os.Args[2:], os.Stdout); err != nil {
+			slog.Error("Import command failed", "error", err)
+			pool.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Only the server and token rotation read or write encrypted tokens.
+	keyring, err := secrets.LoadLocalKeyring(cfg.Secrets.KeyFile)
+	if err != nil {
+		slog.Error("Failed to load secrets keyring", "error", err)
+		panic(err)
+	}
+	envelope := secrets.NewEnvelope(keyring)
+
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		if err := secrets.RunCLI(ctx, envelope, db.New(pool), os.Args[2:], os.Stdout); err != nil {
+			slog.Error("Secrets command failed", "error", err)
+			pool.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.Postgres.MigrateOnStart {
+		migrator, err := migrations.NewMigrator(pool)
+		if err != nil {
+			slog.Error("Failed to load migrations", "error", err)
+			panic(err)
+		}
+		if _, err := migrator.Up(ctx, migrations.UpOptions{}); err != nil {
+			slog.Error("Failed to apply migrations", "error", err)
+			panic(err)
+		}
+	}
 
-	inverterQueries := db.New(conn)
+	inverterQueries := db.New(pool)
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
 		Password: cfg.Redis.Password,
 		DB:       cfg.Redis.DB,
 	})
+	redisClient.AddHook(metrics.RedisHook{})
+	if err := redisotel.InstrumentTracing(redisClient); err != nil {
+		slog.Error("Failed to instrument Redis tracing", "error", err)
+		panic(err)
+	}
 
 	if redisClient.Ping(ctx).Err() != nil {
 		slog.Error("Failed to connect to Redis", "error", err)
@@ -58,5 +146,9 @@ func main() {
 	slog.Info("Initializing Validator")
 	val := utils.NewCustomValidator(validator.New())
 
-	server.NewEchoServer(cfg, redisClient, inverterQueries, val).Start()
+	relayCtx, stopRelay := context.WithCancel(ctx)
+	defer stopRelay()
+	go outbox.NewRelay(outbox.NewPostgresRelayStore(pool), redisClient, cfg.Outbox.Stream, cfg.Outbox.StreamMaxLen, cfg.Outbox.BatchSize, cfg.Outbox.MaxAttempts, cfg.Outbox.PollInterval).Run(relayCtx)
+
+	server.NewEchoServer(cfg, redisClient, pool, inverterQueries, val, envelope, logLevel).Start()
 }
@@ -0,0 +1,70 @@
+-- Tables owned by the Evolyte core service and managed there with Alembic.
+-- They are listed here only so sqlc can type queries against them; the adapter
+-- never creates or alters them. Tables owned by the adapter live in
+-- internal/migrations/sql.
+
+CREATE TYPE roles AS ENUM ('ADMIN', 'USER');
+
+CREATE TYPE panelstatus AS ENUM ('OPERATIONAL', 'MAINTENANCE', 'OFFLINE', 'UNKNOWN');
+
+CREATE TABLE alembic_version (
+    version_num VARCHAR(32) NOT NULL PRIMARY KEY
+);
+
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR NOT NULL UNIQUE,
+    full_name VARCHAR NOT NULL,
+    password VARCHAR,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    role roles NOT NULL
+);
+
+CREATE TABLE solar_panels (
+    id SERIAL PRIMARY KEY,
+    serial_number VARCHAR NOT NULL,
+    name VARCHAR NOT NULL,
+    manufacturer VARCHAR,
+    model VARCHAR,
+    installation_date TIMESTAMP,
+    capacity_kw DOUBLE PRECISION NOT NULL,
+    efficiency DOUBLE PRECISION,
+    voltage_rating DOUBLE PRECISION,
+    current_rating DOUBLE PRECISION,
+    width DOUBLE PRECISION,
+    length DOUBLE PRECISION,
+    height DOUBLE PRECISION,
+    weight DOUBLE PRECISION,
+    orientation DOUBLE PRECISION,
+    tilt DOUBLE PRECISION,
+    status panelstatus,
+    location geography(POINT, 4326),
+    user_id INTEGER REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    inverter_id INTEGER
+);
+
+CREATE TABLE solar_panel_hourly_records (
+    id SERIAL PRIMARY KEY,
+    timestamp TIMESTAMP,
+    power_output_kw DOUBLE PRECISION NOT NULL,
+    energy_generated_kwh DOUBLE PRECISION NOT NULL,
+    predicted_power_output_kw DOUBLE PRECISION,
+    efficiency_percent DOUBLE PRECISION,
+    cell_temperature_celsius DOUBLE PRECISION,
+    temperature_celsius DOUBLE PRECISION,
+    irradiance DOUBLE PRECISION,
+    poa_irradiance DOUBLE PRECISION,
+    cloud_cover_percent DOUBLE PRECISION,
+    wind_speed_kmh DOUBLE PRECISION,
+    wind_direction_degrees DOUBLE PRECISION,
+    humidity_percent DOUBLE PRECISION,
+    precipitation_mm DOUBLE PRECISION,
+    pressure_msl_hpa DOUBLE PRECISION,
+    clear_sky_index DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    inverter_id INTEGER NOT NULL
+);
@@ -0,0 +1,196 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const evaluationLockKey = "alerts:evaluation-lock"
+
+// InverterSource walks every inverter visible to the adapter.
+type InverterSource interface {
+	IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error]
+}
+
+// EvaluationResult counts the alerts changed by one evaluation pass.
+type EvaluationResult struct {
+	Opened   int
+	Resolved int
+}
+
+// Engine periodically evaluates rules against every inverter, opening an alert when a rule starts
+// firing and resolving it once the rule stops firing.
+type Engine struct {
+	store    AlertStore
+	source   InverterSource
+	redis    *redis.Client
+	rules    []Rule
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewEngine(store AlertStore, source InverterSource, redisClient *redis.Client, interval time.Duration, rules ...Rule) *Engine {
+	return &Engine{
+		store:    store,
+		source:   source,
+		redis:    redisClient,
+		rules:    rules,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run evaluates rules every interval until ctx is cancelled. Only one replica evaluates per interval.
+func (e *Engine) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting alert engine", "interval", e.interval, "rules", len(e.rules))
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping alert engine")
+			return
+		case <-ticker.C:
+			claimed, err := e.redis.SetNX(ctx, evaluationLockKey, 1, e.interval*9/10).Result()
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to claim alert evaluation", "error", err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			result, err := e.EvaluateOnce(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Alert evaluation failed", "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Evaluated alert rules", "opened", result.Opened, "resolved", result.Resolved)
+		}
+	}
+}
+
+// EvaluateOnce runs every rule against every inverter once. Active alerts that no evaluation
+// visited, because their inverter was unlinked or their rule is no longer configured, are
+// resolved after a complete pass.
+func (e *Engine) EvaluateOnce(ctx context.Context) (EvaluationResult, error) {
+	var result EvaluationResult
+
+	activeAlerts, err := e.store.ListActiveAlerts(ctx)
+	if err != nil {
+		return result, fmt.Errorf("listing active alerts: %w", err)
+	}
+	active := make(map[string]db.Alert, len(activeAlerts))
+	for _, alert := range activeAlerts {
+		active[alertKey(alert.InverterID, alert.Rule)] = alert
+	}
+
+	now := e.now()
+	for inverter, err := range e.source.IterateInverters(ctx, 0) {
+		if err != nil {
+			return result, fmt.Errorf("listing inverters: %w", err)
+		}
+		for _, rule := range e.rules {
+			firing, message := rule.Evaluate(inverter, now)
+			key := alertKey(inverter.ID, rule.Name())
+			alert, isActive := active[key]
+			delete(active, key)
+
+			switch {
+			case firing && !isActive:
+				opened, err := e.open(ctx, db.OpenAlertParams{
+					InverterID: inverter.ID,
+					UserID:     inverter.UserID,
+					Rule:       rule.Name(),
+					Message:    message,
+				})
+				if err != nil {
+					return result, err
+				}
+				if opened {
+					result.Opened++
+				}
+			case !firing && isActive:
+				resolved, err := e.resolve(ctx, alert.ID)
+				if err != nil {
+					return result, err
+				}
+				if resolved {
+					result.Resolved++
+				}
+			}
+		}
+	}
+
+	for _, alert := range active {
+		slog.InfoContext(ctx, "Resolving orphaned alert", "alertID", alert.ID, "inverterID", alert.InverterID, "rule", alert.Rule)
+		resolved, err := e.resolve(ctx, alert.ID)
+		if err != nil {
+			return result, err
+		}
+		if resolved {
+			result.Resolved++
+		}
+	}
+	return result, nil
+}
+
+func (e *Engine) open(ctx context.Context, params db.OpenAlertParams) (bool, error) {
+	opened := false
+	err := e.store.InTx(ctx, func(store AlertStore) error {
+		alert, err := store.OpenAlert(ctx, params)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already opened, e.g. by an API call racing this evaluation.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("opening %s alert for inverter %s: %w", params.Rule, params.InverterID, err)
+		}
+		opened = true
+		slog.WarnContext(ctx, "Alert opened", "alertID", alert.ID, "inverterID", alert.InverterID, "rule", alert.Rule, "message", alert.Message)
+		return recordAlertEvent(ctx, store, outbox.EventAlertOpened, alert)
+	})
+	return opened, err
+}
+
+func (e *Engine) resolve(ctx context.Context, id int64) (bool, error) {
+	resolved := false
+	err := e.store.InTx(ctx, func(store AlertStore) error {
+		alert, err := store.ResolveAlert(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("resolving alert %d: %w", id, err)
+		}
+		resolved = true
+		slog.InfoContext(ctx, "Alert resolved", "alertID", alert.ID, "inverterID", alert.InverterID, "rule", alert.Rule)
+		return recordAlertEvent(ctx, store, outbox.EventAlertResolved, alert)
+	})
+	return resolved, err
+}
+
+func recordAlertEvent(ctx context.Context, store AlertStore, eventType string, alert db.Alert) error {
+	event, err := outbox.NewEvent(outbox.AggregateAlert, fmt.Sprint(alert.ID), eventType, AlertEventPayload{Alert: newAlertResponse(alert)})
+	if err != nil {
+		return err
+	}
+	if _, err := store.InsertOutboxEvent(ctx, event); err != nil {
+		return fmt.Errorf("recording %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+func alertKey(inverterID string, rule string) string {
+	return inverterID + "/" + rule
+}
@@ -0,0 +1,231 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeAlertStore is an in-memory AlertStore. InTx stages writes on a copy and applies them only
+// when fn succeeds.
+type fakeAlertStore struct {
+	alerts map[int64]db.Alert
+	nextID int64
+	events []db.InsertOutboxEventParams
+	err    error
+}
+
+func newFakeAlertStore(alerts ...db.Alert) *fakeAlertStore {
+	store := &fakeAlertStore{alerts: make(map[int64]db.Alert)}
+	for _, alert := range alerts {
+		store.alerts[alert.ID] = alert
+		store.nextID = max(store.nextID, alert.ID)
+	}
+	return store
+}
+
+func (f *fakeAlertStore) OpenAlert(ctx context.Context, arg db.OpenAlertParams) (db.Alert, error) {
+	if f.err != nil {
+		return db.Alert{}, f.err
+	}
+	for _, alert := range f.alerts {
+		if alert.InverterID == arg.InverterID && alert.Rule == arg.Rule && alert.Status != StatusResolved {
+			return db.Alert{}, pgx.ErrNoRows
+		}
+	}
+	f.nextID++
+	alert := db.Alert{ID: f.nextID, InverterID: arg.InverterID, UserID: arg.UserID, Rule: arg.Rule, Message: arg.Message, Status: StatusOpen}
+	f.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (f *fakeAlertStore) GetAlert(ctx context.Context, id int64) (db.Alert, error) {
+	alert, ok := f.alerts[id]
+	if !ok {
+		return db.Alert{}, pgx.ErrNoRows
+	}
+	return alert, nil
+}
+
+func (f *fakeAlertStore) ListActiveAlerts(ctx context.Context) ([]db.Alert, error) {
+	var active []db.Alert
+	for _, alert := range f.alerts {
+		if alert.Status != StatusResolved {
+			active = append(active, alert)
+		}
+	}
+	return active, nil
+}
+
+func (f *fakeAlertStore) ListAlerts(ctx context.Context, arg db.ListAlertsParams) ([]db.Alert, error) {
+	var alerts []db.Alert
+	for _, alert := range f.alerts {
+		if arg.Status.Valid && alert.Status != arg.Status.String {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (f *fakeAlertStore) AcknowledgeAlert(ctx context.Context, arg db.AcknowledgeAlertParams) (db.Alert, error) {
+	alert, ok := f.alerts[arg.ID]
+	if !ok || alert.Status != StatusOpen {
+		return db.Alert{}, pgx.ErrNoRows
+	}
+	alert.Status = StatusAcknowledged
+	alert.AcknowledgedBy = arg.AcknowledgedBy
+	f.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (f *fakeAlertStore) ResolveAlert(ctx context.Context, id int64) (db.Alert, error) {
+	alert, ok := f.alerts[id]
+	if !ok || alert.Status == StatusResolved {
+		return db.Alert{}, pgx.ErrNoRows
+	}
+	alert.Status = StatusResolved
+	f.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (f *fakeAlertStore) InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error) {
+	f.events = append(f.events, arg)
+	return db.OutboxEvent{ID: int64(len(f.events))}, nil
+}
+
+func (f *fakeAlertStore) InTx(ctx context.Context, fn func(store AlertStore) error) error {
+	staged := &fakeAlertStore{alerts: maps.Clone(f.alerts), nextID: f.nextID, events: f.events, err: f.err}
+	if err := fn(staged); err != nil {
+		return err
+	}
+	f.alerts, f.nextID, f.events = staged.alerts, staged.nextID, staged.events
+	return nil
+}
+
+type fakeInverterSource struct {
+	inverters []inverters.SolarInverter
+	err       error
+}
+
+func (f *fakeInverterSource) IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error] {
+	return func(yield func(inverters.SolarInverter, error) bool) {
+		for _, inverter := range f.inverters {
+			if !yield(inverter, nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(inverters.SolarInverter{}, f.err)
+		}
+	}
+}
+
+func TestEngine_EvaluateOnce(t *testing.T) {
+	now := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	offline := inverters.SolarInverter{ID: "inv-offline", UserID: "u1", LastSeen: now.Add(-3 * time.Hour)}
+	online := inverters.SolarInverter{ID: "inv-online", UserID: "u1", IsReachable: true, LastSeen: now}
+
+	tests := []struct {
+		name         string
+		existing     []db.Alert
+		inverters    []inverters.SolarInverter
+		sourceErr    error
+		want         EvaluationResult
+		wantErr      bool
+		wantEvents   []string
+		wantStatuses map[int64]string
+	}{
+		{
+			name:       "opens alert for offline inverter",
+			inverters:  []inverters.SolarInverter{offline, online},
+			want:       EvaluationResult{Opened: 1},
+			wantEvents: []string{outbox.EventAlertOpened},
+		},
+		{
+			name:         "keeps existing acknowledged alert",
+			existing:     []db.Alert{{ID: 7, InverterID: "inv-offline", Rule: RuleUnreachable, Status: StatusAcknowledged}},
+			inverters:    []inverters.SolarInverter{offline},
+			wantStatuses: map[int64]string{7: StatusAcknowledged},
+		},
+		{
+			name:         "resolves alert once inverter is back",
+			existing:     []db.Alert{{ID: 7, InverterID: "inv-online", Rule: RuleUnreachable, Status: StatusOpen}},
+			inverters:    []inverters.SolarInverter{online},
+			want:         EvaluationResult{Resolved: 1},
+			wantEvents:   []string{outbox.EventAlertResolved},
+			wantStatuses: map[int64]string{7: StatusResolved},
+		},
+		{
+			name:         "resolves alert of unlinked inverter",
+			existing:     []db.Alert{{ID: 7, InverterID: "inv-gone", Rule: RuleUnreachable, Status: StatusAcknowledged}},
+			inverters:    []inverters.SolarInverter{online},
+			want:         EvaluationResult{Resolved: 1},
+			wantEvents:   []string{outbox.EventAlertResolved},
+			wantStatuses: map[int64]string{7: StatusResolved},
+		},
+		{
+			name:         "resolves alert of rule no longer evaluated",
+			existing:     []db.Alert{{ID: 7, InverterID: "inv-online", Rule: RuleNotProducingDaylight, Status: StatusOpen}},
+			inverters:    []inverters.SolarInverter{online},
+			want:         EvaluationResult{Resolved: 1},
+			wantEvents:   []string{outbox.EventAlertResolved},
+			wantStatuses: map[int64]string{7: StatusResolved},
+		},
+		{
+			name:         "keeps unvisited alerts on listing failure",
+			existing:     []db.Alert{{ID: 7, InverterID: "inv-gone", Rule: RuleUnreachable, Status: StatusOpen}},
+			sourceErr:    errors.New("enode down"),
+			wantErr:      true,
+			wantStatuses: map[int64]string{7: StatusOpen},
+		},
+		{
+			name:      "stops on listing failure",
+			inverters: []inverters.SolarInverter{offline},
+			sourceErr: errors.New("enode down"),
+			want:      EvaluationResult{Opened: 1},
+			wantErr:   true,
+			wantEvents: []string{
+				outbox.EventAlertOpened,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeAlertStore(tt.existing...)
+			source := &fakeInverterSource{inverters: tt.inverters, err: tt.sourceErr}
+			engine := NewEngine(store, source, nil, time.Minute, UnreachableRule{After: 2 * time.Hour})
+			engine.now = func() time.Time { return now }
+
+			got, err := engine.EvaluateOnce(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("result = %+v, want %+v", got, tt.want)
+			}
+			if len(store.events) != len(tt.wantEvents) {
+				t.Fatalf("events = %+v, want %v", store.events, tt.wantEvents)
+			}
+			for i, event := range store.events {
+				if event.EventType != tt.wantEvents[i] || event.AggregateType != outbox.AggregateAlert {
+					t.Fatalf("event %d = %+v, want %s", i, event, tt.wantEvents[i])
+				}
+			}
+			for id, status := range tt.wantStatuses {
+				if store.alerts[id].Status != status {
+					t.Fatalf("alert %d status = %s, want %s", id, store.alerts[id].Status, status)
+				}
+			}
+		})
+	}
+}
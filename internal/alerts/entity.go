@@ -0,0 +1,64 @@
+package alerts
+
+import (
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+const (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+)
+
+type AlertResponse struct {
+	ID             int64      `json:"id"`
+	InverterID     string     `json:"inverterId"`
+	UserID         string     `json:"userId"`
+	Rule           string     `json:"rule"`
+	Status         string     `json:"status"`
+	Message        string     `json:"message"`
+	OpenedAt       time.Time  `json:"openedAt"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+type ListAlertsResponse struct {
+	Data []AlertResponse `json:"data"`
+}
+
+type ListAlertsFilter struct {
+	Status     string
+	InverterID string
+	UserID     string
+	Limit      int
+	Offset     int
+}
+
+type AcknowledgeAlertRequest struct {
+	AcknowledgedBy string `json:"acknowledgedBy" validate:"required"`
+}
+
+// AlertEventPayload is the outbox payload for alert lifecycle events.
+type AlertEventPayload struct {
+	Alert AlertResponse `json:"alert"`
+}
+
+func newAlertResponse(alert db.Alert) AlertResponse {
+	return AlertResponse{
+		ID:             alert.ID,
+		InverterID:     alert.InverterID,
+		UserID:         alert.UserID,
+		Rule:           alert.Rule,
+		Status:         alert.Status,
+		Message:        alert.Message,
+		OpenedAt:       alert.OpenedAt,
+		AcknowledgedAt: alert.AcknowledgedAt,
+		AcknowledgedBy: alert.AcknowledgedBy.String,
+		ResolvedAt:     alert.ResolvedAt,
+		UpdatedAt:      alert.UpdatedAt,
+	}
+}
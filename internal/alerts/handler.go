@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AlertHandler struct {
+	alertUseCase *AlertUseCase
+}
+
+func NewAlertHandler(alertUseCase *AlertUseCase) *AlertHandler {
+	return &AlertHandler{
+		alertUseCase: alertUseCase,
+	}
+}
+
+func (h *AlertHandler) ListAlerts(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	filter := ListAlertsFilter{
+		Status:     c.QueryParam("status"),
+		InverterID: c.QueryParam("inverterId"),
+		UserID:     c.QueryParam("userId"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+
+	alerts, err := h.alertUseCase.ListAlerts(c.Request().Context(), filter)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list alerts", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list alerts")
+	}
+
+	return c.JSON(http.StatusOK, alerts)
+}
+
+func (h *AlertHandler) GetAlert(c echo.Context) error {
+	alertID := c.Param("alertID")
+	alert, err := h.alertUseCase.GetAlert(c.Request().Context(), alertID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get alert", "alertID", alertID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get alert")
+	}
+
+	return c.JSON(http.StatusOK, alert)
+}
+
+func (h *AlertHandler) AcknowledgeAlert(c echo.Context) error {
+	alertID := c.Param("alertID")
+	var request AcknowledgeAlertRequest
+	if err := c.Bind(&request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Validation failed for AcknowledgeAlertRequest", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed")
+	}
+
+	alert, err := h.alertUseCase.AcknowledgeAlert(c.Request().Context(), alertID, request)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to acknowledge alert", "alertID", alertID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to acknowledge alert")
+	}
+
+	return c.JSON(http.StatusOK, alert)
+}
+
+func (h *AlertHandler) ResolveAlert(c echo.Context) error {
+	alertID := c.Param("alertID")
+	alert, err := h.alertUseCase.ResolveAlert(c.Request().Context(), alertID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to resolve alert", "alertID", alertID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to resolve alert")
+	}
+
+	return c.JSON(http.StatusOK, alert)
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidAlertID), errors.Is(err, ErrInvalidAlertStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAlertNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidAlertTransition):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
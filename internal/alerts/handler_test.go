@@ -0,0 +1,102 @@
+package alerts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+func serve(t *testing.T, method, route, target, body string, h echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.Validator = utils.NewCustomValidator(validator.New())
+	e.Add(method, route, h)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAlertHandler_Transitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		route      string
+		target     string
+		body       string
+		status     string
+		storeErr   error
+		wantStatus int
+		wantAlert  string
+		wantEvents int
+	}{
+		{name: "acknowledge open", route: "/alerts/:alertID/acknowledge", target: "/alerts/1/acknowledge", body: `{"acknowledgedBy":"support"}`, status: StatusOpen, wantStatus: http.StatusOK, wantAlert: StatusAcknowledged, wantEvents: 1},
+		{name: "acknowledge without actor", route: "/alerts/:alertID/acknowledge", target: "/alerts/1/acknowledge", body: `{}`, status: StatusOpen, wantStatus: http.StatusBadRequest, wantAlert: StatusOpen},
+		{name: "acknowledge resolved", route: "/alerts/:alertID/acknowledge", target: "/alerts/1/acknowledge", body: `{"acknowledgedBy":"support"}`, status: StatusResolved, wantStatus: http.StatusConflict, wantAlert: StatusResolved},
+		{name: "acknowledge missing", route: "/alerts/:alertID/acknowledge", target: "/alerts/9/acknowledge", body: `{"acknowledgedBy":"support"}`, status: StatusOpen, wantStatus: http.StatusNotFound, wantAlert: StatusOpen},
+		{name: "resolve acknowledged", route: "/alerts/:alertID/resolve", target: "/alerts/1/resolve", status: StatusAcknowledged, wantStatus: http.StatusOK, wantAlert: StatusResolved, wantEvents: 1},
+		{name: "resolve invalid id", route: "/alerts/:alertID/resolve", target: "/alerts/abc/resolve", status: StatusOpen, wantStatus: http.StatusBadRequest, wantAlert: StatusOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeAlertStore(db.Alert{ID: 1, InverterID: "inv-1", Rule: RuleUnreachable, Status: tt.status})
+			h := NewAlertHandler(NewAlertUseCase(store))
+
+			handler := h.ResolveAlert
+			if strings.HasSuffix(tt.route, "acknowledge") {
+				handler = h.AcknowledgeAlert
+			}
+			rec := serve(t, http.MethodPost, tt.route, tt.target, tt.body, handler)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if store.alerts[1].Status != tt.wantAlert {
+				t.Fatalf("alert status = %s, want %s", store.alerts[1].Status, tt.wantAlert)
+			}
+			if len(store.events) != tt.wantEvents {
+				t.Fatalf("events = %d, want %d", len(store.events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestAlertHandler_ListAlerts(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "all", target: "/alerts", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "by status", target: "/alerts?status=open", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "invalid status", target: "/alerts?status=closed", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeAlertStore(
+				db.Alert{ID: 1, InverterID: "inv-1", Rule: RuleUnreachable, Status: StatusOpen},
+				db.Alert{ID: 2, InverterID: "inv-2", Rule: RuleUnreachable, Status: StatusResolved},
+			)
+			h := NewAlertHandler(NewAlertUseCase(store))
+
+			rec := serve(t, http.MethodGet, "/alerts", tt.target, "", h.ListAlerts)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && strings.Count(rec.Body.String(), `"rule"`) != tt.wantCount {
+				t.Fatalf("body = %s, want %d alerts", rec.Body.String(), tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/solar"
+)
+
+const (
+	RuleUnreachable          = "unreachable"
+	RuleNotProducingDaylight = "not_producing_in_daylight"
+)
+
+// Rule decides whether an inverter is in an alerting condition. Evaluate reports whether the
+// rule fires and, if so, a message describing why.
+type Rule interface {
+	Name() string
+	Evaluate(inverter inverters.SolarInverter, now time.Time) (bool, string)
+}
+
+// UnreachableRule fires when Enode has not been able to reach an inverter for longer than After.
+// When the inverter's location is known only daylight counts, since many inverters power down
+// at night.
+type UnreachableRule struct {
+	After time.Duration
+}
+
+func (r UnreachableRule) Name() string {
+	return RuleUnreachable
+}
+
+func (r UnreachableRule) Evaluate(inverter inverters.SolarInverter, now time.Time) (bool, string) {
+	if inverter.IsReachable || inverter.LastSeen.IsZero() {
+		return false, ""
+	}
+	if !hasLocation(inverter.Location) {
+		offline := now.Sub(inverter.LastSeen)
+		if offline <= r.After {
+			return false, ""
+		}
+		return true, fmt.Sprintf("Inverter unreachable for %s (last seen %s)", offline.Truncate(time.Minute), inverter.LastSeen.Format(time.RFC3339))
+	}
+
+	offline := solar.DaylightDuration(inverter.Location.Latitude, inverter.Location.Longitude, inverter.LastSeen, now)
+	if offline <= r.After {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Inverter unreachable for %s of daylight (last seen %s)", offline.Truncate(time.Minute), inverter.LastSeen.Format(time.RFC3339))
+}
+
+// NotProducingInDaylightRule fires when a reachable inverter reports no production while the sun
+// at its location is at least MinElevation degrees above the horizon.
+type NotProducingInDaylightRule struct {
+	MinElevation float64
+}
+
+func (r NotProducingInDaylightRule) Name() string {
+	return RuleNotProducingDaylight
+}
+
+func (r NotProducingInDaylightRule) Evaluate(inverter inverters.SolarInverter, now time.Time) (bool, string) {
+	if !inverter.IsReachable || inverter.ProductionState.IsProducing || !hasLocation(inverter.Location) {
+		return false, ""
+	}
+	sun := solar.SunPosition(inverter.Location.Latitude, inverter.Location.Longitude, now)
+	if !sun.IsDaylight() || sun.Elevation < r.MinElevation {
+		return false, ""
+	}
+	return true, fmt.Sprintf("Inverter not producing with sun %.0f° above the horizon", sun.Elevation)
+}
+
+// hasLocation reports whether Enode has provided coordinates for the inverter.
+func hasLocation(location inverters.Location) bool {
+	return location.Latitude != 0 || location.Longitude != 0
+}
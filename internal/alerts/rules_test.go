@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+)
+
+func TestUnreachableRule(t *testing.T) {
+	now := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	earlyMorning := time.Date(2024, time.June, 22, 5, 0, 0, 0, time.UTC)
+	london := inverters.Location{Latitude: 51.5, Longitude: -0.13}
+	rule := UnreachableRule{After: 2 * time.Hour}
+
+	tests := []struct {
+		name     string
+		inverter inverters.SolarInverter
+		now      time.Time
+		want     bool
+	}{
+		{name: "reachable", inverter: inverters.SolarInverter{IsReachable: true, LastSeen: now.Add(-5 * time.Hour)}, now: now, want: false},
+		{name: "recently seen", inverter: inverters.SolarInverter{LastSeen: now.Add(-time.Hour)}, now: now, want: false},
+		{name: "offline too long", inverter: inverters.SolarInverter{LastSeen: now.Add(-3 * time.Hour)}, now: now, want: true},
+		{name: "never seen", inverter: inverters.SolarInverter{}, now: now, want: false},
+		{name: "offline since sunset", inverter: inverters.SolarInverter{LastSeen: now.Add(8 * time.Hour), Location: london}, now: earlyMorning, want: false},
+		{name: "offline through daylight", inverter: inverters.SolarInverter{LastSeen: now.Add(-3 * time.Hour), Location: london}, now: now, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, message := rule.Evaluate(tt.inverter, tt.now)
+			if got != tt.want {
+				t.Fatalf("firing = %v, want %v", got, tt.want)
+			}
+			if got && message == "" {
+				t.Fatal("expected a message when firing")
+			}
+		})
+	}
+}
+
+func TestNotProducingInDaylightRule(t *testing.T) {
+	london := inverters.Location{Latitude: 51.5, Longitude: -0.13}
+	noon := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	midnight := time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC)
+	rule := NotProducingInDaylightRule{MinElevation: 15}
+
+	idle := inverters.SolarInverter{IsReachable: true, Location: london}
+	producing := idle
+	producing.ProductionState.IsProducing = true
+	unreachable := idle
+	unreachable.IsReachable = false
+
+	tests := []struct {
+		name     string
+		inverter inverters.SolarInverter
+		now      time.Time
+		want     bool
+	}{
+		{name: "idle at noon", inverter: idle, now: noon, want: true},
+		{name: "idle at night", inverter: idle, now: midnight, want: false},
+		{name: "producing at noon", inverter: producing, now: noon, want: false},
+		{name: "unreachable at noon", inverter: unreachable, now: noon, want: false},
+		{name: "unknown location", inverter: inverters.SolarInverter{IsReachable: true}, now: noon, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := rule.Evaluate(tt.inverter, tt.now); got != tt.want {
+				t.Fatalf("firing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
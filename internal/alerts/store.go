@@ -0,0 +1,40 @@
+package alerts
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AlertStore persists alerts and the outbox events raised by their transitions.
+type AlertStore interface {
+	OpenAlert(ctx context.Context, arg db.OpenAlertParams) (db.Alert, error)
+	GetAlert(ctx context.Context, id int64) (db.Alert, error)
+	ListActiveAlerts(ctx context.Context) ([]db.Alert, error)
+	ListAlerts(ctx context.Context, arg db.ListAlertsParams) ([]db.Alert, error)
+	AcknowledgeAlert(ctx context.Context, arg db.AcknowledgeAlertParams) (db.Alert, error)
+	ResolveAlert(ctx context.Context, id int64) (db.Alert, error)
+	InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error)
+	InTx(ctx context.Context, fn func(store AlertStore) error) error
+}
+
+// PostgresAlertStore is the AlertStore backed by sqlc queries on a pgx pool.
+type PostgresAlertStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAlertStore(pool *pgxpool.Pool) *PostgresAlertStore {
+	return &PostgresAlertStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresAlertStore) InTx(ctx context.Context, fn func(store AlertStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresAlertStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
@@ -0,0 +1,165 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+var (
+	ErrAlertNotFound          = errors.New("alert not found")
+	ErrInvalidAlertID         = errors.New("invalid alert id")
+	ErrInvalidAlertStatus     = errors.New("invalid alert status")
+	ErrInvalidAlertTransition = errors.New("alert cannot move to the requested status")
+)
+
+type AlertUseCase struct {
+	store AlertStore
+}
+
+func NewAlertUseCase(store AlertStore) *AlertUseCase {
+	return &AlertUseCase{store: store}
+}
+
+func (uc *AlertUseCase) ListAlerts(ctx context.Context, filter ListAlertsFilter) (*ListAlertsResponse, error) {
+	switch filter.Status {
+	case "", StatusOpen, StatusAcknowledged, StatusResolved:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAlertStatus, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	filter.Limit = min(filter.Limit, maxListLimit)
+	filter.Offset = max(filter.Offset, 0)
+
+	alerts, err := uc.store.ListAlerts(ctx, db.ListAlertsParams{
+		Status:     optionalText(filter.Status),
+		InverterID: optionalText(filter.InverterID),
+		UserID:     optionalText(filter.UserID),
+		Limit:      int32(filter.Limit),
+		Offset:     int32(filter.Offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing alerts: %w", err)
+	}
+
+	response := &ListAlertsResponse{Data: make([]AlertResponse, 0, len(alerts))}
+	for _, alert := range alerts {
+		response.Data = append(response.Data, newAlertResponse(alert))
+	}
+	return response, nil
+}
+
+func (uc *AlertUseCase) GetAlert(ctx context.Context, alertID string) (*AlertResponse, error) {
+	id, err := parseAlertID(alertID)
+	if err != nil {
+		return nil, err
+	}
+	alert, err := getAlert(ctx, uc.store, id)
+	if err != nil {
+		return nil, err
+	}
+	response := newAlertResponse(alert)
+	return &response, nil
+}
+
+// AcknowledgeAlert marks an open alert as being handled. The alert stays acknowledged until its
+// rule stops firing or it is resolved manually.
+func (uc *AlertUseCase) AcknowledgeAlert(ctx context.Context, alertID string, request AcknowledgeAlertRequest) (*AlertResponse, error) {
+	id, err := parseAlertID(alertID)
+	if err != nil {
+		return nil, err
+	}
+
+	var acknowledged db.Alert
+	err = uc.store.InTx(ctx, func(store AlertStore) error {
+		alert, err := store.AcknowledgeAlert(ctx, db.AcknowledgeAlertParams{
+			ID:             id,
+			AcknowledgedBy: pgtype.Text{String: request.AcknowledgedBy, Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return transitionError(ctx, store, id)
+		}
+		if err != nil {
+			return fmt.Errorf("acknowledging alert: %w", err)
+		}
+		acknowledged = alert
+		return recordAlertEvent(ctx, store, outbox.EventAlertAcknowledged, alert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := newAlertResponse(acknowledged)
+	return &response, nil
+}
+
+// ResolveAlert closes an alert manually. If its rule is still firing, the next evaluation opens
+// a new alert.
+func (uc *AlertUseCase) ResolveAlert(ctx context.Context, alertID string) (*AlertResponse, error) {
+	id, err := parseAlertID(alertID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved db.Alert
+	err = uc.store.InTx(ctx, func(store AlertStore) error {
+		alert, err := store.ResolveAlert(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return transitionError(ctx, store, id)
+		}
+		if err != nil {
+			return fmt.Errorf("resolving alert: %w", err)
+		}
+		resolved = alert
+		return recordAlertEvent(ctx, store, outbox.EventAlertResolved, alert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := newAlertResponse(resolved)
+	return &response, nil
+}
+
+// transitionError explains why a conditional status update matched no rows.
+func transitionError(ctx context.Context, store AlertStore, id int64) error {
+	alert, err := getAlert(ctx, store, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: alert %d is %s", ErrInvalidAlertTransition, id, alert.Status)
+}
+
+func getAlert(ctx context.Context, store AlertStore, id int64) (db.Alert, error) {
+	alert, err := store.GetAlert(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Alert{}, ErrAlertNotFound
+	}
+	if err != nil {
+		return db.Alert{}, fmt.Errorf("getting alert: %w", err)
+	}
+	return alert, nil
+}
+
+func parseAlertID(alertID string) (int64, error) {
+	id, err := strconv.ParseInt(alertID, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAlertID
+	}
+	return id, nil
+}
+
+func optionalText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
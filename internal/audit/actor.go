@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ActorHeader carries the authenticated caller, set by the gateway in front of the adapter.
+	ActorHeader = "X-Actor-ID"
+	// AnonymousActor is recorded for requests that arrive without ActorHeader.
+	AnonymousActor = "anonymous"
+	// SystemActor is recorded for changes made outside an HTTP request, such as background jobs.
+	SystemActor = "system"
+)
+
+// Actor identifies who made a change and where the request came from.
+type Actor struct {
+	ID        string
+	RequestID string
+	SourceIP  string
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor attached by Middleware or WithActor, or SystemActor.
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{ID: SystemActor}
+}
+
+// Middleware attaches the calling actor, request ID and client IP to each request's context so use
+// cases can record them without depending on echo.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			actor := Actor{
+				ID:        req.Header.Get(ActorHeader),
+				RequestID: req.Header.Get(echo.HeaderXRequestID),
+				SourceIP:  c.RealIP(),
+			}
+			if actor.ID == "" {
+				actor.ID = AnonymousActor
+			}
+			if actor.RequestID == "" {
+				actor.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+			}
+			c.SetRequest(req.WithContext(WithActor(req.Context(), actor)))
+			return next(c)
+		}
+	}
+}
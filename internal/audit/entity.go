@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+type EntryResponse struct {
+	ID           string          `json:"id"`
+	OccurredAt   time.Time       `json:"occurredAt"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resourceType"`
+	ResourceID   string          `json:"resourceId"`
+	UserID       string          `json:"userId,omitempty"`
+	RequestID    string          `json:"requestId,omitempty"`
+	SourceIP     string          `json:"sourceIp,omitempty"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	Changes      json.RawMessage `json:"changes"`
+}
+
+type ListEntriesResponse struct {
+	Data []EntryResponse `json:"data"`
+}
+
+// ListEntriesFilter narrows the audit log. From and To are RFC 3339 timestamps; To is exclusive.
+type ListEntriesFilter struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	UserID       string
+	RequestID    string
+	From         string
+	To           string
+	Limit        int
+	Offset       int
+}
+
+func newEntryResponse(entry db.AuditLog) EntryResponse {
+	response := EntryResponse{
+		ID:           strconv.FormatInt(entry.ID, 10),
+		OccurredAt:   entry.OccurredAt,
+		Actor:        entry.Actor,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		UserID:       entry.UserID.String,
+		RequestID:    entry.RequestID.String,
+		SourceIP:     entry.SourceIp.String,
+		Changes:      entry.Changes,
+	}
+	if len(entry.Before) > 0 {
+		response.Before = entry.Before
+	}
+	if len(entry.After) > 0 {
+		response.After = entry.After
+	}
+	if len(response.Changes) == 0 {
+		response.Changes = json.RawMessage("{}")
+	}
+	return response
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	auditUseCase *AuditUseCase
+}
+
+func NewAuditHandler(auditUseCase *AuditUseCase) *AuditHandler {
+	return &AuditHandler{
+		auditUseCase: auditUseCase,
+	}
+}
+
+func (h *AuditHandler) ListEntries(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	filter := ListEntriesFilter{
+		Actor:        c.QueryParam("actor"),
+		Action:       c.QueryParam("action"),
+		ResourceType: c.QueryParam("resourceType"),
+		ResourceID:   c.QueryParam("resourceId"),
+		UserID:       c.QueryParam("userId"),
+		RequestID:    c.QueryParam("requestId"),
+		From:         c.QueryParam("from"),
+		To:           c.QueryParam("to"),
+		Limit:        limit,
+		Offset:       offset,
+	}
+
+	entries, err := h.auditUseCase.ListEntries(c.Request().Context(), filter)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list audit log", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list audit log")
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidAuditFilter):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeAuditStore struct {
+	entries []db.AuditLog
+	lastArg db.ListAuditLogParams
+}
+
+func (f *fakeAuditStore) ListAuditLog(ctx context.Context, arg db.ListAuditLogParams) ([]db.AuditLog, error) {
+	f.lastArg = arg
+	return f.entries, nil
+}
+
+func TestAuditHandler_ListEntries(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		check      func(t *testing.T, arg db.ListAuditLogParams)
+	}{
+		{
+			name:       "defaults",
+			target:     "/audit",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, arg db.ListAuditLogParams) {
+				if arg.Limit != defaultListLimit || arg.Actor.Valid || arg.From != nil {
+					t.Fatalf("arg = %+v", arg)
+				}
+			},
+		},
+		{
+			name:       "filters",
+			target:     "/audit?actor=ops&action=inverter.linked&userId=42&from=2024-06-01T00:00:00Z&to=2024-07-01T00:00:00Z&limit=1000",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, arg db.ListAuditLogParams) {
+				if arg.Actor.String != "ops" || arg.Action.String != "inverter.linked" || arg.UserID.String != "42" {
+					t.Fatalf("arg = %+v", arg)
+				}
+				if !arg.From.Equal(time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)) || arg.Limit != maxListLimit {
+					t.Fatalf("arg = %+v", arg)
+				}
+			},
+		},
+		{name: "invalid from", target: "/audit?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "empty range", target: "/audit?from=2024-07-01T00:00:00Z&to=2024-06-01T00:00:00Z", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeAuditStore{entries: []db.AuditLog{{ID: 1, Actor: "ops", Action: ActionInverterLinked, After: []byte(`{"id":"inv-1"}`)}}}
+			e := echo.New()
+			e.GET("/audit", NewAuditHandler(NewAuditUseCase(store)).ListEntries)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var response ListEntriesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Data) != 1 || string(response.Data[0].Changes) != "{}" || response.Data[0].Before != nil {
+				t.Fatalf("response = %+v", response)
+			}
+			tt.check(t, store.lastArg)
+		})
+	}
+}
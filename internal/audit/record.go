@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	ActionInverterCreated       = "inverter.created"
+	ActionInverterUpdated       = "inverter.updated"
+	ActionInverterDeleted       = "inverter.deleted"
+	ActionInverterSynced        = "inverter.synced"
+	ActionInverterMerged        = "inverter.merged"
+	ActionInverterLinkRequested = "inverter.link_requested"
+	ActionInverterLinked        = "inverter.linked"
+	ActionInverterUnlinked      = "inverter.unlinked"
+
+	ActionPrivacyExportRequested  = "privacy.export_requested"
+	ActionPrivacyErasureRequested = "privacy.erasure_requested"
+	ActionUserErased              = "user.erased"
+
+	ActionIdentityLinked  = "identity.linked"
+	ActionIdentityRevoked = "identity.revoked"
+
+	ActionLogLevelChanged = "log_level.changed"
+)
+
+const (
+	ResourceInverter      = "inverter"
+	ResourceEnodeInverter = "enode_inverter"
+	ResourceUser          = "user"
+	ResourceIdentity      = "identity"
+	ResourceLogLevel      = "log_level"
+)
+
+// Recorder stores audit log entries, usually inside the transaction making the change.
+type Recorder interface {
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+}
+
+// Entry describes one change. Before is nil for creations and After is nil for deletions.
+type Entry struct {
+	Action       string
+	ResourceType string
+	ResourceID   string
+	UserID       string
+	Before       any
+	After        any
+}
+
+// Change is the value of one top-level field before and after a change.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Record writes entry with the actor found in ctx.
+func Record(ctx context.Context, recorder Recorder, entry Entry) error {
+	before, err := encode(entry.Before)
+	if err != nil {
+		return fmt.Errorf("encoding %s audit state: %w", entry.Action, err)
+	}
+	after, err := encode(entry.After)
+	if err != nil {
+		return fmt.Errorf("encoding %s audit state: %w", entry.Action, err)
+	}
+	changes, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return fmt.Errorf("encoding %s audit changes: %w", entry.Action, err)
+	}
+
+	actor := ActorFromContext(ctx)
+	_, err = recorder.InsertAuditLogEntry(ctx, db.InsertAuditLogEntryParams{
+		Actor:        actor.ID,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		UserID:       optionalText(entry.UserID),
+		RequestID:    optionalText(actor.RequestID),
+		SourceIp:     optionalText(actor.SourceIP),
+		Before:       before,
+		After:        after,
+		Changes:      changes,
+	})
+	if err != nil {
+		return fmt.Errorf("recording %s audit entry: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// Diff compares two JSON objects field by field and returns the fields whose values differ.
+// Missing or non-object documents count as having no fields.
+func Diff(before []byte, after []byte) map[string]Change {
+	var beforeFields, afterFields map[string]json.RawMessage
+	_ = json.Unmarshal(before, &beforeFields)
+	_ = json.Unmarshal(after, &afterFields)
+
+	changes := make(map[string]Change)
+	for name, value := range beforeFields {
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = Change{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+	return changes
+}
+
+func encode(state any) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+	body, err := json.Marshal(state)
+	if err != nil || bytes.Equal(body, []byte("null")) {
+		return nil, err
+	}
+	return body, nil
+}
+
+func optionalText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeRecorder struct {
+	entries []db.InsertAuditLogEntryParams
+}
+
+func (f *fakeRecorder) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.entries = append(f.entries, arg)
+	return db.AuditLog{ID: int64(len(f.entries))}, nil
+}
+
+type state struct {
+	Vendor string  `json:"vendor"`
+	Model  string  `json:"model,omitempty"`
+	Total  float64 `json:"total"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before any
+		after  any
+		want   map[string]Change
+	}{
+		{
+			name:   "changed field",
+			before: state{Vendor: "SMA", Total: 1},
+			after:  state{Vendor: "SMA", Total: 2},
+			want:   map[string]Change{"total": {Before: json.RawMessage(`1`), After: json.RawMessage(`2`)}},
+		},
+		{
+			name:   "added and removed fields",
+			before: state{Vendor: "SMA", Model: "X"},
+			after:  map[string]any{"vendor": "SMA", "total": 0, "serial": "SN-1"},
+			want: map[string]Change{
+				"model":  {Before: json.RawMessage(`"X"`)},
+				"serial": {After: json.RawMessage(`"SN-1"`)},
+			},
+		},
+		{
+			name:  "creation",
+			after: state{Vendor: "SMA"},
+			want: map[string]Change{
+				"vendor": {After: json.RawMessage(`"SMA"`)},
+				"total":  {After: json.RawMessage(`0`)},
+			},
+		},
+		{
+			name:   "no change",
+			before: state{Vendor: "SMA"},
+			after:  state{Vendor: "SMA"},
+			want:   map[string]Change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := encode(tt.before)
+			after, _ := encode(tt.after)
+			got, _ := json.Marshal(Diff(before, after))
+			want, _ := json.Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Fatalf("diff = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRecordUsesRequestActor(t *testing.T) {
+	recorder := &fakeRecorder{}
+	e := echo.New()
+	e.Use(Middleware())
+	e.POST("/inverters", func(c echo.Context) error {
+		var before *state
+		err := Record(c.Request().Context(), recorder, Entry{
+			Action:       ActionInverterCreated,
+			ResourceType: ResourceInverter,
+			ResourceID:   "7",
+			UserID:       "42",
+			Before:       before,
+			After:        state{Vendor: "SMA"},
+		})
+		if err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/inverters", nil)
+	req.Header.Set(ActorHeader, "ops@evolyte.eu")
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated || len(recorder.entries) != 1 {
+		t.Fatalf("status = %d, entries = %d", rec.Code, len(recorder.entries))
+	}
+	entry := recorder.entries[0]
+	if entry.Actor != "ops@evolyte.eu" || entry.RequestID.String != "req-1" || entry.SourceIp.String != "203.0.113.7" {
+		t.Fatalf("unexpected actor fields %+v", entry)
+	}
+	if entry.Before != nil || string(entry.After) != `{"vendor":"SMA","total":0}` || entry.UserID.String != "42" {
+		t.Fatalf("unexpected state %+v", entry)
+	}
+}
+
+func TestActorFromContextDefaultsToSystem(t *testing.T) {
+	if actor := ActorFromContext(context.Background()); actor.ID != SystemActor {
+		t.Fatalf("actor = %+v", actor)
+	}
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+var ErrInvalidAuditFilter = errors.New("invalid audit log filter")
+
+// AuditStore reads the audit log.
+type AuditStore interface {
+	ListAuditLog(ctx context.Context, arg db.ListAuditLogParams) ([]db.AuditLog, error)
+}
+
+type AuditUseCase struct {
+	store AuditStore
+}
+
+func NewAuditUseCase(store AuditStore) *AuditUseCase {
+	return &AuditUseCase{store: store}
+}
+
+func (uc *AuditUseCase) ListEntries(ctx context.Context, filter ListEntriesFilter) (*ListEntriesResponse, error) {
+	from, err := parseTime("from", filter.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTime("to", filter.To)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAuditFilter)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	filter.Limit = min(filter.Limit, maxListLimit)
+	filter.Offset = max(filter.Offset, 0)
+
+	entries, err := uc.store.ListAuditLog(ctx, db.ListAuditLogParams{
+		Actor:        optionalText(filter.Actor),
+		Action:       optionalText(filter.Action),
+		ResourceType: optionalText(filter.ResourceType),
+		ResourceID:   optionalText(filter.ResourceID),
+		UserID:       optionalText(filter.UserID),
+		RequestID:    optionalText(filter.RequestID),
+		From:         from,
+		To:           to,
+		Limit:        int32(filter.Limit),
+		Offset:       int32(filter.Offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing audit log: %w", err)
+	}
+
+	response := &ListEntriesResponse{Data: make([]EntryResponse, 0, len(entries))}
+	for _, entry := range entries {
+		response.Data = append(response.Data, newEntryResponse(entry))
+	}
+	return response, nil
+}
+
+func parseTime(name string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %q is not an RFC 3339 timestamp", ErrInvalidAuditFilter, name, value)
+	}
+	return &parsed, nil
+}
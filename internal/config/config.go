@@ -2,16 +2,32 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   Server
-	Enode    Enode
-	Redis    Redis
-	Postgres Postgres
+	Server      Server
+	Enode       Enode
+	Redis       Redis
+	Postgres    Postgres
+	Tracing     Tracing
+	Health      Health
+	Outbox      Outbox
+	Live        Live
+	Webhooks    Webhooks
+	Alerts      Alerts
+	Performance Performance
+	Rollups     Rollups
+	Export      Export
+	Import      Import
+	Privacy     Privacy
+	Secrets     Secrets
+	OAuth       OAuth
+	Upstream    Upstream
+	Logging     Logging
 }
 
 type Server struct {
@@ -19,10 +35,21 @@ type Server struct {
 }
 
 type Enode struct {
-	ClientID     string `env:"ENODE_CLIENT_ID,required"`
-	ClientSecret string `env:"ENODE_CLIENT_SECRET,required"`
-	OAuthBaseURL string `env:"ENODE_OAUTH_URL,required"`
-	ApiURL       string `env:"ENODE_API_URL,required"`
+	ClientID      string `env:"ENODE_CLIENT_ID,required"`
+	ClientSecret  string `env:"ENODE_CLIENT_SECRET,required"`
+	OAuthBaseURL  string `env:"ENODE_OAUTH_URL,required"`
+	ApiURL        string `env:"ENODE_API_URL,required"`
+	WebhookSecret string `env:"ENODE_WEBHOOK_SECRET"`
+	Timeouts      EnodeTimeouts
+}
+
+// EnodeTimeouts bounds each category of upstream Enode call. Zero disables the deadline.
+type EnodeTimeouts struct {
+	Token      time.Duration `env:"ENODE_TOKEN_TIMEOUT" envDefault:"5s"`
+	List       time.Duration `env:"ENODE_LIST_TIMEOUT" envDefault:"10s"`
+	Get        time.Duration `env:"ENODE_GET_TIMEOUT" envDefault:"10s"`
+	Statistics time.Duration `env:"ENODE_STATISTICS_TIMEOUT" envDefault:"15s"`
+	Link       time.Duration `env:"ENODE_LINK_TIMEOUT" envDefault:"3s"`
 }
 
 type Redis struct {
@@ -38,6 +65,159 @@ type Postgres struct {
 	User     string `env:"POSTGRES_USER,required"`
 	Password string `env:"POSTGRES_PASSWORD,required"`
 	DB       string `env:"POSTGRES_DB,required"`
+
+	MaxConns          int32         `env:"POSTGRES_MAX_CONNS" envDefault:"10"`
+	MinConns          int32         `env:"POSTGRES_MIN_CONNS" envDefault:"1"`
+	MaxConnLifetime   time.Duration `env:"POSTGRES_MAX_CONN_LIFETIME" envDefault:"1h"`
+	MaxConnIdleTime   time.Duration `env:"POSTGRES_MAX_CONN_IDLE_TIME" envDefault:"30m"`
+	HealthCheckPeriod time.Duration `env:"POSTGRES_HEALTH_CHECK_PERIOD" envDefault:"30s"`
+	ConnectTimeout    time.Duration `env:"POSTGRES_CONNECT_TIMEOUT" envDefault:"5s"`
+	ConnectRetries    int           `env:"POSTGRES_CONNECT_RETRIES" envDefault:"5"`
+	StatementTimeout  time.Duration `env:"POSTGRES_STATEMENT_TIMEOUT" envDefault:"10s"`
+	MigrateOnStart    bool          `env:"POSTGRES_MIGRATE_ON_START" envDefault:"false"`
+}
+
+type Tracing struct {
+	Exporter     string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	OTLPEndpoint string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string  `env:"OTEL_SERVICE_NAME" envDefault:"evolyte-energy-provider-adapter"`
+	SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
+
+// Health controls readiness probing of Postgres, Redis and Enode.
+type Health struct {
+	CacheTTL     time.Duration `env:"HEALTH_CACHE_TTL" envDefault:"5s"`
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+}
+
+// Outbox configures publication of inverter domain events to Redis Streams.
+type Outbox struct {
+	Stream       string `env:"OUTBOX_STREAM" envDefault:"evolyte:inverter-events"`
+	StreamMaxLen int64  `env:"OUTBOX_STREAM_MAX_LEN" envDefault:"100000"`
+	BatchSize    int32  `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	// Failed publishes after which an event is given up on and skipped.
+	MaxAttempts            int32         `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10"`
+	PollInterval           time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	ProductionThresholdKwh float64       `env:"INVERTER_PRODUCTION_THRESHOLD_KWH" envDefault:"1000"`
+}
+
+// Live configures streaming of inverter production state to clients.
+type Live struct {
+	PollInterval      time.Duration `env:"LIVE_POLL_INTERVAL" envDefault:"30s"`
+	WatchTTL          time.Duration `env:"LIVE_WATCH_TTL" envDefault:"60s"`
+	HeartbeatInterval time.Duration `env:"LIVE_HEARTBEAT_INTERVAL" envDefault:"15s"`
+	WriteTimeout      time.Duration `env:"LIVE_WRITE_TIMEOUT" envDefault:"10s"`
+	MaxSubscriptions  int           `env:"LIVE_MAX_SUBSCRIPTIONS" envDefault:"500"`
+	// Last known states expire after this long without an update, so inverters that are no
+	// longer reported do not keep keys in Redis.
+	LastStateTTL time.Duration `env:"LIVE_LAST_STATE_TTL" envDefault:"24h"`
+	// TokenSecret verifies the WebSocket access tokens issued by the core service. The WebSocket
+	// gateway is disabled when it is empty.
+	TokenSecret string `env:"LIVE_TOKEN_SECRET"`
+}
+
+// Webhooks configures retries of Enode webhook events that could not be processed on receipt.
+type Webhooks struct {
+	RetryInterval  time.Duration `env:"WEBHOOK_RETRY_INTERVAL" envDefault:"1m"`
+	RetryAfter     time.Duration `env:"WEBHOOK_RETRY_AFTER" envDefault:"1m"`
+	RetryBatchSize int32         `env:"WEBHOOK_RETRY_BATCH_SIZE" envDefault:"100"`
+}
+
+// Alerts configures the rules that flag offline or idle inverters.
+type Alerts struct {
+	EvaluationInterval   time.Duration `env:"ALERT_EVALUATION_INTERVAL" envDefault:"5m"`
+	UnreachableAfter     time.Duration `env:"ALERT_UNREACHABLE_AFTER" envDefault:"2h"`
+	DaylightMinElevation float64       `env:"ALERT_DAYLIGHT_MIN_ELEVATION" envDefault:"15"`
+}
+
+// Performance configures detection of inverters producing less than their irradiance predicts.
+type Performance struct {
+	Window           time.Duration `env:"PERFORMANCE_WINDOW" envDefault:"168h"`
+	RatioThreshold   float64       `env:"PERFORMANCE_RATIO_THRESHOLD" envDefault:"0.75"`
+	MinClearSkyIndex float64       `env:"PERFORMANCE_MIN_CLEAR_SKY_INDEX" envDefault:"0.3"`
+	MinHours         int           `env:"PERFORMANCE_MIN_HOURS" envDefault:"12"`
+	// Hourly records missing a clear-sky index are backfilled in batches on this interval.
+	ClearSkyBackfillInterval  time.Duration `env:"CLEAR_SKY_BACKFILL_INTERVAL" envDefault:"15m"`
+	ClearSkyBackfillBatchSize int32         `env:"CLEAR_SKY_BACKFILL_BATCH_SIZE" envDefault:"500"`
+}
+
+// Rollups configures the daily, monthly and yearly production tables.
+type Rollups struct {
+	RefreshInterval time.Duration `env:"ROLLUP_REFRESH_INTERVAL" envDefault:"15m"`
+	// Hourly records are folded in once they are this old, so rows with lower ids committed by
+	// slow transactions are not skipped.
+	LateDataLag time.Duration `env:"ROLLUP_LATE_DATA_LAG" envDefault:"10m"`
+	// Each refresh transaction folds at most this many hourly records.
+	BatchSize           int32         `env:"ROLLUP_BATCH_SIZE" envDefault:"10000"`
+	EnodeImportInterval time.Duration `env:"ROLLUP_ENODE_IMPORT_INTERVAL" envDefault:"6h"`
+}
+
+// Export configures production data exports.
+type Export struct {
+	// Rows are read from Postgres in pages of this size while streaming.
+	PageSize int32 `env:"EXPORT_PAGE_SIZE" envDefault:"5000"`
+	// Exports that would need more Enode statistics calls than this are rejected.
+	MaxEnodeRequests int `env:"EXPORT_MAX_ENODE_REQUESTS" envDefault:"62"`
+}
+
+// Import configures bulk inverter imports.
+type Import struct {
+	// Files with more data rows than this are rejected before validation.
+	MaxRows int `env:"IMPORT_MAX_ROWS" envDefault:"200000"`
+}
+
+// Privacy configures the runner for data export and erasure jobs.
+type Privacy struct {
+	PollInterval time.Duration `env:"PRIVACY_JOB_POLL_INTERVAL" envDefault:"10s"`
+	// A claimed job is handed to another replica if it has not finished within the lease.
+	Lease time.Duration `env:"PRIVACY_JOB_LEASE" envDefault:"5m"`
+	// Jobs failing this many times in a row stop retrying until retried through the API.
+	MaxAttempts int `env:"PRIVACY_JOB_MAX_ATTEMPTS" envDefault:"5"`
+}
+
+// Secrets configures encryption of provider tokens at rest.
+type Secrets struct {
+	// JSON keyfile with the active key ID and base64-encoded 32-byte keys. Keys that are no
+	// longer active stay in the file until every value sealed with them has been rotated.
+	// Required by the server and `secrets rotate` only.
+	KeyFile string `env:"SECRETS_KEY_FILE"`
+}
+
+// OAuth configures per-user authorization with providers other than Enode.
+type OAuth struct {
+	// JSON array of providers with their client credentials and endpoints. Unset configures none.
+	ProvidersFile  string        `env:"OAUTH_PROVIDERS_FILE"`
+	StateTTL       time.Duration `env:"OAUTH_STATE_TTL" envDefault:"10m"`
+	RequestTimeout time.Duration `env:"OAUTH_REQUEST_TIMEOUT" envDefault:"10s"`
+	// Access tokens expiring within the leeway are refreshed every interval.
+	RefreshInterval time.Duration `env:"OAUTH_REFRESH_INTERVAL" envDefault:"1m"`
+	RefreshLeeway   time.Duration `env:"OAUTH_REFRESH_LEEWAY" envDefault:"10m"`
+}
+
+// Upstream tunes the HTTP clients that call Enode and other vendor APIs.
+type Upstream struct {
+	ConnectTimeout time.Duration `env:"UPSTREAM_CONNECT_TIMEOUT" envDefault:"5s"`
+	// Maximum wait for response headers after the request has been sent.
+	ReadTimeout         time.Duration `env:"UPSTREAM_READ_TIMEOUT" envDefault:"30s"`
+	IdleConnTimeout     time.Duration `env:"UPSTREAM_IDLE_CONN_TIMEOUT" envDefault:"90s"`
+	MaxIdleConns        int           `env:"UPSTREAM_MAX_IDLE_CONNS" envDefault:"100"`
+	MaxIdleConnsPerHost int           `env:"UPSTREAM_MAX_IDLE_CONNS_PER_HOST" envDefault:"20"`
+	// Zero leaves connections per host unlimited.
+	MaxConnsPerHost int `env:"UPSTREAM_MAX_CONNS_PER_HOST" envDefault:"0"`
+	// Idempotent requests are retried this many times on transport errors, 429, 502, 503 and 504.
+	MaxRetries     int           `env:"UPSTREAM_MAX_RETRIES" envDefault:"2"`
+	RetryBaseDelay time.Duration `env:"UPSTREAM_RETRY_BASE_DELAY" envDefault:"200ms"`
+	RetryMaxDelay  time.Duration `env:"UPSTREAM_RETRY_MAX_DELAY" envDefault:"2s"`
+	// A host failing this many times in a row is skipped for the cooldown, then probed once.
+	BreakerFailures int           `env:"UPSTREAM_BREAKER_FAILURES" envDefault:"5"`
+	BreakerCooldown time.Duration `env:"UPSTREAM_BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+// Logging configures the default logger.
+type Logging struct {
+	// Minimum level at startup: debug, info, warn or error. It can be changed at runtime through
+	// /api/v1/admin/log-level.
+	Level string `env:"LOG_LEVEL" envDefault:"info"`
 }
 
 func LoadConfig(envFile string) (*Config, error) {
@@ -172,6 +172,57 @@ func (q *Queries) GetInvertersByUserId(ctx context.Context, userID int32) ([]Inv
 	return items, nil
 }
 
+const listEnodeInverterMatches = `-- name: ListEnodeInverterMatches :many
+SELECT i.id, i.user_id, i.vendor, i.model, i.serial_number, i.total_lifetime_production_kwh, i.installation_date, i.created_at, i.updated_at FROM inverters i
+WHERE i.id IN (SELECT inverter_id FROM inverter_enode_links WHERE enode_inverter_id = $1)
+   OR (
+       i.serial_number = $2
+       AND i.user_id = $3
+       AND NOT EXISTS (
+           SELECT 1 FROM inverter_enode_links l
+           WHERE l.enode_inverter_id = $1 OR l.inverter_id = i.id
+       )
+   )
+ORDER BY i.id
+LIMIT 2
+`
+
+type ListEnodeInverterMatchesParams struct {
+	EnodeInverterID string
+	SerialNumber    string
+	UserID          int32
+}
+
+func (q *Queries) ListEnodeInverterMatches(ctx context.Context, arg ListEnodeInverterMatchesParams) ([]Inverter, error) {
+	rows, err := q.db.Query(ctx, listEnodeInverterMatches, arg.EnodeInverterID, arg.SerialNumber, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Inverter
+	for rows.Next() {
+		var i Inverter
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Vendor,
+			&i.Model,
+			&i.SerialNumber,
+			&i.TotalLifetimeProductionKwh,
+			&i.InstallationDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateInverter = `-- name: UpdateInverter :exec
 UPDATE inverters
 SET
@@ -98,19 +98,53 @@ func (ns NullRoles) Value() (driver.Value, error) {
 	return string(ns.Roles), nil
 }
 
+type Alert struct {
+	ID             int64
+	InverterID     string
+	UserID         string
+	Rule           string
+	Status         string
+	Message        string
+	OpenedAt       time.Time
+	AcknowledgedAt *time.Time
+	AcknowledgedBy pgtype.Text
+	ResolvedAt     *time.Time
+	UpdatedAt      time.Time
+}
+
 type AlembicVersion struct {
 	VersionNum string
 }
 
+type AuditLog struct {
+	ID           int64
+	OccurredAt   time.Time
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	UserID       pgtype.Text
+	RequestID    pgtype.Text
+	SourceIp     pgtype.Text
+	Before       []byte
+	After        []byte
+	Changes      []byte
+}
+
 type Identity struct {
-	ID             int32
-	UserID         int32
-	Provider       string
-	ProviderUserID string
-	AccessToken    pgtype.Text
-	ExpiresAt      *time.Time
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                 int32
+	UserID             int32
+	Provider           string
+	ProviderUserID     string
+	AccessToken        pgtype.Text
+	ExpiresAt          *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	RefreshToken       pgtype.Text
+	Scope              pgtype.Text
+	RevokedAt          *time.Time
+	RefreshFailures    int32
+	RefreshLockedUntil *time.Time
 }
 
 type Inverter struct {
@@ -125,6 +159,107 @@ type Inverter struct {
 	UpdatedAt                  time.Time
 }
 
+type InverterEnodeLink struct {
+	InverterID      int32
+	EnodeInverterID string
+	LinkedAt        time.Time
+}
+
+type InverterMerge struct {
+	ID                int64
+	SerialNumber      string
+	EnodeInverterID   string
+	KeptInverterID    int32
+	MergedInverterID  pgtype.Int4
+	MergedInverter    []byte
+	PanelsReassigned  int32
+	RecordsReassigned int32
+	DiscardedRecords  []byte
+	MergedBy          string
+	MergedAt          time.Time
+}
+
+type LinkSession struct {
+	ID          int32
+	UserID      int32
+	Provider    string
+	LinkToken   string
+	LinkUrl     string
+	Status      string
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+type OutboxEvent struct {
+	ID            int64
+	AggregateType string
+	AggregateID   string
+	EventType     string
+	Payload       []byte
+	CreatedAt     time.Time
+	PublishedAt   *time.Time
+	Attempts      int32
+	LastError     pgtype.Text
+}
+
+type PrivacyJob struct {
+	ID          int64
+	UserID      int32
+	Kind        string
+	Status      string
+	RequestedBy string
+	Attempts    int32
+	LastError   pgtype.Text
+	RunAfter    time.Time
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+type PrivacyJobStep struct {
+	JobID        int64
+	Position     int32
+	Name         string
+	Status       string
+	AffectedRows int64
+	Output       []byte
+	CompletedAt  *time.Time
+}
+
+type ProductionDaily struct {
+	InverterID  int32
+	UserID      int32
+	Day         pgtype.Date
+	Source      string
+	EnergyKwh   float64
+	Readings    int32
+	RefreshedAt time.Time
+}
+
+type ProductionMonthly struct {
+	InverterID  int32
+	UserID      int32
+	Month       pgtype.Date
+	EnergyKwh   float64
+	Days        int32
+	RefreshedAt time.Time
+}
+
+type ProductionRollupCursor struct {
+	Name   string
+	LastID int32
+}
+
+type ProductionYearly struct {
+	InverterID  int32
+	UserID      int32
+	Year        int32
+	EnergyKwh   float64
+	Months      int32
+	RefreshedAt time.Time
+}
+
 type SolarPanel struct {
 	ID               int32
 	SerialNumber     string
@@ -182,3 +317,12 @@ type User struct {
 	UpdatedAt time.Time
 	Role      Roles
 }
+
+type WebhookEvent struct {
+	ID          int64
+	Provider    string
+	EventType   string
+	Payload     []byte
+	ReceivedAt  time.Time
+	ProcessedAt *time.Time
+}
@@ -11,7 +11,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/entl/evolyte-energy-provider-adapter/internal/metrics"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type enodeOAuthResponse struct {
@@ -21,21 +25,32 @@ type enodeOAuthResponse struct {
 	Scope       string `json:"scope" validate:"required"`
 }
 
+// TokenSealer encrypts access tokens before they are cached. The associated data names the
+// cache key, so a value copied under another key fails to open.
+type TokenSealer interface {
+	Seal(ctx context.Context, plaintext string, associatedData string) (string, error)
+	Open(ctx context.Context, sealed string, associatedData string) (string, error)
+}
+
 type EnodeAuthClient struct {
 	clientID     string
 	clientSecret string
 	baseURL      string
 	oauthBaseURL string
 	redisClient  *redis.Client
+	sealer       TokenSealer
+	httpClient   *http.Client
 }
 
-func NewEnodeAuthClient(clientID, clientSecret, oauthBaseURL, baseURL string, redisClient *redis.Client) *EnodeAuthClient {
+func NewEnodeAuthClient(clientID, clientSecret, oauthBaseURL, baseURL string, redisClient *redis.Client, sealer TokenSealer, httpClient *http.Client) *EnodeAuthClient {
 	return &EnodeAuthClient{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		oauthBaseURL: oauthBaseURL,
 		baseURL:      baseURL,
 		redisClient:  redisClient,
+		sealer:       sealer,
+		httpClient:   httpClient,
 	}
 }
 
@@ -43,76 +58,123 @@ const (
 	enodeAccessTokenKey = "enode_access_token"
 )
 
-func (client *EnodeAuthClient) GetAccessToken() (string, error) {
-	token, err := client.redisClient.Get(context.Background(), enodeAccessTokenKey).Result()
-	if err != redis.Nil {
-		slog.Debug("Access token found in Redis")
+var tracer = otel.Tracer("github.com/entl/evolyte-energy-provider-adapter/internal/enode")
+
+func (client *EnodeAuthClient) GetAccessToken(ctx context.Context) (string, error) {
+	ctx, span := tracer.Start(ctx, "EnodeAuthClient.GetAccessToken")
+	defer span.End()
+
+	token, err := client.cachedAccessToken(ctx, enodeAccessTokenKey)
+	if err == nil {
+		slog.DebugContext(ctx, "Access token found in Redis")
+		metrics.TokenCacheHit()
+		span.SetAttributes(attribute.Bool("enode.token.cache_hit", true))
 		return token, nil
 	}
+	metrics.TokenCacheMiss()
+	span.SetAttributes(attribute.Bool("enode.token.cache_hit", false))
+	if err != redis.Nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("reading cached access token: %w", ctxErr)
+		}
+		slog.WarnContext(ctx, "Failed to read access token from Redis, authenticating with Enode", "error", err)
+	}
 
-	slog.Debug("Access token not found in Redis, authenticating with Enode")
-	tokenInfo, err := client.authenticate()
+	slog.DebugContext(ctx, "Access token not found in Redis, authenticating with Enode")
+	tokenInfo, err := client.authenticate(ctx)
+	metrics.TokenRefreshed(err)
 	if err != nil {
-		slog.Error("Failed to authenticate with Enode", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "authentication failed")
+		slog.ErrorContext(ctx, "Failed to authenticate with Enode", "error", err)
 		return "", err
 	}
 
-	if err := client.saveAccessToken(tokenInfo, enodeAccessTokenKey); err != nil {
-		slog.Error("Failed to save access token", "error", err)
+	if err := client.saveAccessToken(ctx, tokenInfo, enodeAccessTokenKey); err != nil {
+		slog.ErrorContext(ctx, "Failed to save access token", "error", err)
 		return "", err
 	}
 	return tokenInfo.AccessToken, nil
 }
 
-func (client *EnodeAuthClient) authenticate() (*enodeOAuthResponse, error) {
+func (client *EnodeAuthClient) authenticate(ctx context.Context) (*enodeOAuthResponse, error) {
 	form := url.Values{}
 	form.Set("grant_type", "client_credentials")
 
 	url := fmt.Sprintf("%s/oauth2/token", client.oauthBaseURL)
 
 	// Make request
-	req, err := http.NewRequest("POST", url, strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(form.Encode()))
 	if err != nil {
-		slog.Error("Failed to create request", "error", err)
+		slog.ErrorContext(ctx, "Failed to create request", "error", err)
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.SetBasicAuth(client.clientID, client.clientSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	httpClient := &http.Client{Timeout: 5 * time.Second}
-	resp, err := httpClient.Do(req)
+	start := time.Now()
+	resp, err := client.httpClient.Do(req)
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	metrics.ObserveEnodeCall("oauth_token", time.Since(start), status)
 	if err != nil {
-		slog.Error("Failed to make request", "error", err)
+		slog.ErrorContext(ctx, "Failed to make request", "error", err)
 		return nil, fmt.Errorf("authentication request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for a successful response
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		slog.Error("Authentication failed", "status_code", resp.StatusCode, "response_body", string(bodyBytes))
-		return nil, fmt.Errorf("authentication failed: %s", string(bodyBytes))
+		// Error bodies can echo credentials, so only the OAuth error code is kept.
+		var oauthErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&oauthErr)
+		slog.ErrorContext(ctx, "Authentication failed", "status_code", resp.StatusCode, "oauth_error", oauthErr.Error)
+		return nil, fmt.Errorf("authentication failed with status %d: %s", resp.StatusCode, oauthErr.Error)
 	}
 
 	// Parse the response body
 	var tokenInfo enodeOAuthResponse
 	err = json.NewDecoder(resp.Body).Decode(&tokenInfo)
 	if err != nil {
-		slog.Error("Failed to decode response", "error", err)
+		slog.ErrorContext(ctx, "Failed to decode response", "error", err)
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
 	return &tokenInfo, nil
 }
 
-func (client *EnodeAuthClient) saveAccessToken(tokenInfo *enodeOAuthResponse, key string) error {
-	err := client.redisClient.Set(context.Background(), key, tokenInfo.AccessToken, time.Duration(tokenInfo.ExpiresIn)*time.Second-10*time.Second).Err()
+// cachedAccessToken reads and decrypts the token cached under key. Tokens that fail to decrypt,
+// including plaintext tokens cached before encryption was enabled, are reported as errors so
+// the caller fetches a new one.
+func (client *EnodeAuthClient) cachedAccessToken(ctx context.Context, key string) (string, error) {
+	sealed, err := client.redisClient.Get(ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+	token, err := client.sealer.Open(ctx, sealed, key)
+	if err != nil {
+		return "", fmt.Errorf("decrypting cached access token: %w", err)
+	}
+	return token, nil
+}
+
+func (client *EnodeAuthClient) saveAccessToken(ctx context.Context, tokenInfo *enodeOAuthResponse, key string) error {
+	sealed, err := client.sealer.Seal(ctx, tokenInfo.AccessToken, key)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encrypt access token", "error", err)
+		return err
+	}
+	err = client.redisClient.Set(ctx, key, sealed, time.Duration(tokenInfo.ExpiresIn)*time.Second-10*time.Second).Err()
 	if err != nil {
-		slog.Error("Failed to save access token", "error", err)
+		slog.ErrorContext(ctx, "Failed to save access token", "error", err)
 		return err
 	}
 
-	slog.Debug("Saving access token")
+	slog.DebugContext(ctx, "Saving access token")
 	return nil
 }
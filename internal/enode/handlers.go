@@ -18,11 +18,11 @@ func NewEnodeAuthHandler(authClient *EnodeAuthClient) *enodeAuthHandler {
 }
 
 func (h *enodeAuthHandler) Authenticate(c echo.Context) error {
-	res, err := h.AuthClient.authenticate()
+	res, err := h.AuthClient.authenticate(c.Request().Context())
 	if err != nil {
-		slog.Error("Failed to authenticate with Enode", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Failed to authenticate with Enode", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to authenticate with Enode")
 	}
-	slog.Debug("Successfully authenticated with Enode")
+	slog.DebugContext(c.Request().Context(), "Successfully authenticated with Enode")
 	return c.JSON(200, res)
 }
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SourceLocal = "local"
+	SourceEnode = "enode"
+)
+
+// Row is the production of one inverter over one interval. Measurements other than energy are
+// averaged over the interval and are nil when the source does not provide them.
+type Row struct {
+	Timestamp              time.Time
+	InverterID             int32
+	Source                 string
+	EnergyKwh              float64
+	Readings               int32
+	PowerOutputKw          *float64
+	PredictedPowerOutputKw *float64
+	Irradiance             *float64
+	PoaIrradiance          *float64
+	TemperatureCelsius     *float64
+	CellTemperatureCelsius *float64
+	CloudCoverPercent      *float64
+	ClearSkyIndex          *float64
+}
+
+type fieldKind int
+
+const (
+	kindTime fieldKind = iota
+	kindInt
+	kindFloat
+	kindString
+)
+
+// Field is one selectable export column.
+type Field struct {
+	Name     string
+	kind     fieldKind
+	nullable bool
+	value    func(Row) any
+}
+
+var availableFields = []Field{
+	{Name: "timestamp", kind: kindTime, value: func(r Row) any { return r.Timestamp }},
+	{Name: "inverter_id", kind: kindInt, value: func(r Row) any { return r.InverterID }},
+	{Name: "source", kind: kindString, value: func(r Row) any { return r.Source }},
+	{Name: "energy_kwh", kind: kindFloat, value: func(r Row) any { return r.EnergyKwh }},
+	{Name: "readings", kind: kindInt, value: func(r Row) any { return r.Readings }},
+	optionalFloat("power_output_kw", func(r Row) *float64 { return r.PowerOutputKw }),
+	optionalFloat("predicted_power_output_kw", func(r Row) *float64 { return r.PredictedPowerOutputKw }),
+	optionalFloat("irradiance", func(r Row) *float64 { return r.Irradiance }),
+	optionalFloat("poa_irradiance", func(r Row) *float64 { return r.PoaIrradiance }),
+	optionalFloat("temperature_celsius", func(r Row) *float64 { return r.TemperatureCelsius }),
+	optionalFloat("cell_temperature_celsius", func(r Row) *float64 { return r.CellTemperatureCelsius }),
+	optionalFloat("cloud_cover_percent", func(r Row) *float64 { return r.CloudCoverPercent }),
+	optionalFloat("clear_sky_index", func(r Row) *float64 { return r.ClearSkyIndex }),
+}
+
+// DefaultFields are exported when the request does not select any.
+var DefaultFields = []string{"timestamp", "inverter_id", "energy_kwh"}
+
+func optionalFloat(name string, get func(Row) *float64) Field {
+	return Field{Name: name, kind: kindFloat, nullable: true, value: func(r Row) any {
+		if v := get(r); v != nil {
+			return *v
+		}
+		return nil
+	}}
+}
+
+// FieldNames lists every selectable field in its default column order.
+func FieldNames() []string {
+	names := make([]string, 0, len(availableFields))
+	for _, field := range availableFields {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+// ParseFields resolves field names in the order given. An empty list selects DefaultFields.
+func ParseFields(names []string) ([]Field, error) {
+	if len(names) == 0 {
+		names = DefaultFields
+	}
+	fields := make([]Field, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %q selected twice", ErrInvalidField, name)
+		}
+		field, ok := lookupField(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidField, name)
+		}
+		seen[name] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func lookupField(name string) (Field, bool) {
+	for _, field := range availableFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
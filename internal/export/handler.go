@@ -0,0 +1,109 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/labstack/echo/v4"
+)
+
+type ExportHandler struct {
+	exportUseCase *ExportUseCase
+}
+
+func NewExportHandler(exportUseCase *ExportUseCase) *ExportHandler {
+	return &ExportHandler{
+		exportUseCase: exportUseCase,
+	}
+}
+
+func (h *ExportHandler) ExportInverter(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	params, err := exportParams(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date range")
+	}
+
+	export, err := h.exportUseCase.PrepareInverterExport(c.Request().Context(), inverterID, params)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to prepare inverter export", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to export inverter production")
+	}
+	return stream(c, export)
+}
+
+func (h *ExportHandler) ExportUser(c echo.Context) error {
+	userID := c.Param("userID")
+	params, err := exportParams(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date range")
+	}
+
+	export, err := h.exportUseCase.PrepareUserExport(c.Request().Context(), userID, params)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to prepare user export", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to export user production")
+	}
+	return stream(c, export)
+}
+
+// stream writes the export as an attachment. Once the first byte is sent the status can no
+// longer change, so failures midway are only logged and the truncated response is aborted.
+func stream(c echo.Context, export *Export) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, export.ContentType)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", export.Filename))
+	res.WriteHeader(http.StatusOK)
+
+	if err := export.WriteTo(c.Request().Context(), res); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Export aborted", "filename", export.Filename, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// exportParams reads format, resolution, fields (comma separated), tz, and the optional from and
+// to dates, e.g. 2024-06-01.
+func exportParams(c echo.Context) (Params, error) {
+	params := Params{
+		Format:     c.QueryParam("format"),
+		Resolution: c.QueryParam("resolution"),
+		TimeZone:   c.QueryParam("tz"),
+	}
+	if fields := c.QueryParam("fields"); fields != "" {
+		params.Fields = strings.Split(fields, ",")
+	}
+	for name, target := range map[string]*time.Time{"from": &params.From, "to": &params.To} {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return Params{}, fmt.Errorf("%w: %s: %v", ErrInvalidRange, name, err)
+		}
+		*target = parsed
+	}
+	return params, nil
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInverterID), errors.Is(err, ErrInvalidUserID), errors.Is(err, ErrInvalidFormat),
+		errors.Is(err, ErrInvalidResolution), errors.Is(err, ErrInvalidField), errors.Is(err, ErrInvalidTimeZone),
+		errors.Is(err, ErrInvalidRange):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInverterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, inverters.ErrUpstreamTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,193 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeExportStore struct {
+	inverters map[int32]db.Inverter
+	rows      []db.ListProductionExportRowsRow
+	pages     int
+}
+
+func (f *fakeExportStore) GetInverterById(ctx context.Context, id int32) (db.Inverter, error) {
+	inverter, ok := f.inverters[id]
+	if !ok {
+		return db.Inverter{}, pgx.ErrNoRows
+	}
+	return inverter, nil
+}
+
+func (f *fakeExportStore) GetInvertersByUserId(ctx context.Context, userID int32) ([]db.Inverter, error) {
+	var owned []db.Inverter
+	for _, inverter := range f.inverters {
+		if inverter.UserID == userID {
+			owned = append(owned, inverter)
+		}
+	}
+	return owned, nil
+}
+
+func (f *fakeExportStore) ListInvertersWithHourlyRecords(ctx context.Context, arg db.ListInvertersWithHourlyRecordsParams) ([]int32, error) {
+	var ids []int32
+	for _, row := range f.rows {
+		if !slices.Contains(ids, row.InverterID) {
+			ids = append(ids, row.InverterID)
+		}
+	}
+	return ids, nil
+}
+
+// ListProductionExportRows applies the keyset cursor to rows, which are already sorted.
+func (f *fakeExportStore) ListProductionExportRows(ctx context.Context, arg db.ListProductionExportRowsParams) ([]db.ListProductionExportRowsRow, error) {
+	f.pages++
+	var page []db.ListProductionExportRowsRow
+	for _, row := range f.rows {
+		after := row.Bucket.After(arg.AfterBucket) || (row.Bucket.Equal(arg.AfterBucket) && row.InverterID > arg.AfterInverterID)
+		if after && len(page) < int(arg.PageSize) {
+			page = append(page, row)
+		}
+	}
+	return page, nil
+}
+
+type fakeStatisticsSource struct {
+	inverters []inverters.SolarInverter
+	stats     *inverters.InverterStatistic
+	calls     int
+}
+
+func (f *fakeStatisticsSource) IterateUserInverters(ctx context.Context, userID string, pageSize int) iter.Seq2[inverters.SolarInverter, error] {
+	return func(yield func(inverters.SolarInverter, error) bool) {
+		for _, inverter := range f.inverters {
+			if !yield(inverter, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeStatisticsSource) GetInverterProductionStatistics(ctx context.Context, inverterID string, year int, month int, day int) (*inverters.InverterStatistic, error) {
+	f.calls++
+	return f.stats, nil
+}
+
+func newExportRequest(t *testing.T, handler echo.HandlerFunc, path string, param string, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames(param)
+	c.SetParamValues(id)
+	if err := handler(c); err != nil {
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec.Code = httpErr.Code
+	}
+	return rec
+}
+
+func TestExportHandler_LocalRecordsArePaged(t *testing.T) {
+	hour := func(h int, inverterID int32) db.ListProductionExportRowsRow {
+		return db.ListProductionExportRowsRow{Bucket: time.Date(2024, time.June, 21, h, 0, 0, 0, time.UTC), InverterID: inverterID, EnergyKwh: float64(h), Readings: 1}
+	}
+	store := &fakeExportStore{
+		inverters: map[int32]db.Inverter{7: {ID: 7, UserID: 3}, 8: {ID: 8, UserID: 3}},
+		rows:      []db.ListProductionExportRowsRow{hour(10, 7), hour(10, 8), hour(11, 7), hour(11, 8), hour(12, 7)},
+	}
+	handler := NewExportHandler(NewExportUseCase(store, &fakeStatisticsSource{}, 2, 10))
+
+	rec := newExportRequest(t, handler.ExportUser, "/api/v1/users/3/export?from=2024-06-21&to=2024-06-21&fields=inverter_id,energy_kwh", "userID", "3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get(echo.HeaderContentDisposition); got != `attachment; filename="user-3-production.csv"` {
+		t.Fatalf("content disposition = %s", got)
+	}
+	want := "inverter_id,energy_kwh\n7,10\n8,10\n7,11\n8,11\n7,12\n"
+	if rec.Body.String() != want {
+		t.Fatalf("body =\n%s\nwant\n%s", rec.Body, want)
+	}
+	if store.pages != 3 {
+		t.Fatalf("pages = %d, want 3", store.pages)
+	}
+}
+
+func TestExportHandler_FallsBackToEnode(t *testing.T) {
+	serial := "SN-1"
+	store := &fakeExportStore{inverters: map[int32]db.Inverter{7: {ID: 7, UserID: 3, SerialNumber: serial}}}
+	quarter := func(h int, m int, wh float64) inverters.DataPoint {
+		return inverters.DataPoint{Date: time.Date(2024, time.June, 21, h, m, 0, 0, time.UTC), Value: wh}
+	}
+	source := &fakeStatisticsSource{
+		inverters: []inverters.SolarInverter{{ID: "enode-1", Information: inverters.Information{SerialNumber: &serial}}},
+		stats: &inverters.InverterStatistic{Resolutions: map[string]inverters.Resolution{
+			"QUARTER_HOUR": {Unit: "Wh", Data: []inverters.DataPoint{quarter(10, 0, 250), quarter(10, 15, 250), quarter(10, 30, 500), quarter(11, 0, 1000)}},
+		}},
+	}
+	handler := NewExportHandler(NewExportUseCase(store, source, 100, 10))
+
+	rec := newExportRequest(t, handler.ExportInverter, "/api/v1/inverters/7/export?format=ndjson&from=2024-06-21&to=2024-06-21&fields=timestamp,source,energy_kwh,readings", "inverterID", "7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	want := `{"timestamp":"2024-06-21T10:00:00Z","source":"enode","energy_kwh":1,"readings":3}` + "\n" +
+		`{"timestamp":"2024-06-21T11:00:00Z","source":"enode","energy_kwh":1,"readings":1}` + "\n"
+	if rec.Body.String() != want {
+		t.Fatalf("body =\n%s\nwant\n%s", rec.Body, want)
+	}
+	if source.calls != 1 {
+		t.Fatalf("statistics calls = %d, want one per day", source.calls)
+	}
+}
+
+func TestExportHandler_Errors(t *testing.T) {
+	serial := "SN-1"
+	store := &fakeExportStore{inverters: map[int32]db.Inverter{7: {ID: 7, UserID: 3, SerialNumber: serial}}}
+	source := &fakeStatisticsSource{
+		inverters: []inverters.SolarInverter{{ID: "enode-1", Information: inverters.Information{SerialNumber: &serial}}},
+	}
+	handler := NewExportHandler(NewExportUseCase(store, source, 100, 10))
+
+	tests := []struct {
+		name  string
+		query string
+		id    string
+		want  int
+	}{
+		{name: "unknown format", query: "format=xml", id: "7", want: http.StatusBadRequest},
+		{name: "unknown field", query: "fields=voltage", id: "7", want: http.StatusBadRequest},
+		{name: "unknown time zone", query: "tz=Mars/Olympus", id: "7", want: http.StatusBadRequest},
+		{name: "bad date", query: "from=21-06-2024", id: "7", want: http.StatusBadRequest},
+		{name: "too many Enode requests", query: "resolution=hour&from=2024-06-01&to=2024-06-30", id: "7", want: http.StatusBadRequest},
+		{name: "unknown inverter", query: "", id: "9", want: http.StatusNotFound},
+		{name: "invalid inverter", query: "", id: "abc", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := newExportRequest(t, handler.ExportInverter, "/api/v1/inverters/"+tt.id+"/export?"+tt.query, "inverterID", tt.id)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if strings.Contains(rec.Header().Get(echo.HeaderContentDisposition), "attachment") {
+				t.Fatal("export started despite error")
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInverterNotFound  = errors.New("inverter not found")
+	ErrInvalidInverterID = errors.New("invalid inverter id")
+	ErrInvalidUserID     = errors.New("invalid user id")
+	ErrInvalidFormat     = errors.New("invalid export format")
+	ErrInvalidResolution = errors.New("invalid resolution")
+	ErrInvalidField      = errors.New("invalid field")
+	ErrInvalidTimeZone   = errors.New("invalid time zone")
+	ErrInvalidRange      = errors.New("invalid date range")
+)
+
+const (
+	ResolutionHour  = "hour"
+	ResolutionDay   = "day"
+	ResolutionMonth = "month"
+)
+
+type ExportStore interface {
+	GetInverterById(ctx context.Context, id int32) (db.Inverter, error)
+	GetInvertersByUserId(ctx context.Context, userID int32) ([]db.Inverter, error)
+	ListInvertersWithHourlyRecords(ctx context.Context, arg db.ListInvertersWithHourlyRecordsParams) ([]int32, error)
+	ListProductionExportRows(ctx context.Context, arg db.ListProductionExportRowsParams) ([]db.ListProductionExportRowsRow, error)
+}
+
+// StatisticsSource provides Enode production statistics for inverters without local records.
+// Enode user IDs are the local user IDs.
+type StatisticsSource interface {
+	IterateUserInverters(ctx context.Context, userID string, pageSize int) iter.Seq2[inverters.SolarInverter, error]
+	GetInverterProductionStatistics(ctx context.Context, inverterID string, year int, month int, day int) (*inverters.InverterStatistic, error)
+}
+
+// Params selects what an export contains. From and To are inclusive dates in TimeZone; zero
+// values select the last 30 days.
+type Params struct {
+	Format     string
+	Resolution string
+	Fields     []string
+	TimeZone   string
+	From       time.Time
+	To         time.Time
+}
+
+type ExportUseCase struct {
+	store            ExportStore
+	source           StatisticsSource
+	pageSize         int32
+	maxEnodeRequests int
+	now              func() time.Time
+}
+
+func NewExportUseCase(store ExportStore, source StatisticsSource, pageSize int32, maxEnodeRequests int) *ExportUseCase {
+	return &ExportUseCase{
+		store:            store,
+		source:           source,
+		pageSize:         pageSize,
+		maxEnodeRequests: maxEnodeRequests,
+		now:              time.Now,
+	}
+}
+
+// Export is a validated export ready to be streamed.
+type Export struct {
+	Filename    string
+	ContentType string
+
+	uc         *ExportUseCase
+	format     string
+	resolution string
+	fields     []Field
+	loc        *time.Location
+	start      time.Time
+	end        time.Time
+	// local inverters are read from hourly records, enode ones from Enode statistics.
+	local []int32
+	enode []enodeInverter
+}
+
+type enodeInverter struct {
+	localID int32
+	enodeID string
+}
+
+// PrepareInverterExport validates params for an export of one local inverter. Enode statistics
+// are used when the inverter has no hourly records in the range.
+func (uc *ExportUseCase) PrepareInverterExport(ctx context.Context, inverterID string, params Params) (*Export, error) {
+	id, err := strconv.ParseInt(inverterID, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidInverterID
+	}
+	export, err := uc.newExport(fmt.Sprintf("inverter-%d-production", id), params)
+	if err != nil {
+		return nil, err
+	}
+	inverter, err := uc.store.GetInverterById(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInverterNotFound
+		}
+		return nil, fmt.Errorf("getting inverter: %w", err)
+	}
+	if err := uc.assignSources(ctx, export, []db.Inverter{inverter}); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// PrepareUserExport validates params for an export of every local inverter owned by userID.
+// Each inverter without hourly records in the range falls back to Enode statistics.
+func (uc *ExportUseCase) PrepareUserExport(ctx context.Context, userID string, params Params) (*Export, error) {
+	id, err := strconv.ParseInt(userID, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+	export, err := uc.newExport(fmt.Sprintf("user-%d-production", id), params)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := uc.store.GetInvertersByUserId(ctx, int32(id))
+	if err != nil {
+		return nil, fmt.Errorf("listing user inverters: %w", err)
+	}
+	if err := uc.assignSources(ctx, export, owned); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (uc *ExportUseCase) newExport(name string, params Params) (*Export, error) {
+	format := params.Format
+	if format == "" {
+		format = FormatCSV
+	}
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+
+	resolution := params.Resolution
+	if resolution == "" {
+		resolution = ResolutionHour
+	}
+	switch resolution {
+	case ResolutionHour, ResolutionDay, ResolutionMonth:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidResolution, resolution)
+	}
+
+	fields, err := ParseFields(params.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	timeZone := params.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeZone, timeZone)
+	}
+
+	to := params.To
+	if to.IsZero() {
+		to = uc.now().In(loc)
+	}
+	from := params.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -29)
+	}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: from is after to", ErrInvalidRange)
+	}
+
+	return &Export{
+		Filename:    name + "." + format,
+		ContentType: contentType(format),
+		uc:          uc,
+		format:      format,
+		resolution:  resolution,
+		fields:      fields,
+		loc:         loc,
+		start:       start,
+		end:         end,
+	}, nil
+}
+
+// assignSources splits owned inverters into those with hourly records in the export range and
+// those to be read from Enode. Inverters with neither are left out.
+func (uc *ExportUseCase) assignSources(ctx context.Context, export *Export, owned []db.Inverter) error {
+	if len(owned) == 0 {
+		return nil
+	}
+	ids := make([]int32, 0, len(owned))
+	for _, inverter := range owned {
+		ids = append(ids, inverter.ID)
+	}
+	local, err := uc.store.ListInvertersWithHourlyRecords(ctx, db.ListInvertersWithHourlyRecordsParams{
+		InverterIds: ids,
+		StartTime:   utcTimestamp(export.start),
+		EndTime:     utcTimestamp(export.end),
+	})
+	if err != nil {
+		return fmt.Errorf("checking hourly records: %w", err)
+	}
+	export.local = local
+
+	var missing []db.Inverter
+	for _, inverter := range owned {
+		if !slices.Contains(local, inverter.ID) {
+			missing = append(missing, inverter)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	enodeIDs, err := uc.enodeInvertersBySerial(ctx, missing[0].UserID)
+	if err != nil {
+		return err
+	}
+	for _, inverter := range missing {
+		if enodeID, ok := enodeIDs[inverter.SerialNumber]; ok {
+			export.enode = append(export.enode, enodeInverter{localID: inverter.ID, enodeID: enodeID})
+		}
+	}
+	if requests := len(export.enode) * len(export.statisticsRequests()); requests > uc.maxEnodeRequests {
+		return fmt.Errorf("%w: exporting from Enode would take %d requests, at most %d allowed", ErrInvalidRange, requests, uc.maxEnodeRequests)
+	}
+	return nil
+}
+
+// enodeInvertersBySerial maps serial numbers of the user's Enode inverters to their Enode IDs.
+func (uc *ExportUseCase) enodeInvertersBySerial(ctx context.Context, userID int32) (map[string]string, error) {
+	ids := make(map[string]string)
+	for inverter, err := range uc.source.IterateUserInverters(ctx, strconv.Itoa(int(userID)), 0) {
+		if err != nil {
+			return nil, fmt.Errorf("listing Enode inverters: %w", err)
+		}
+		if inverter.Information.SerialNumber != nil {
+			ids[*inverter.Information.SerialNumber] = inverter.ID
+		}
+	}
+	return ids, nil
+}
+
+// WriteTo streams the export to w. Local rows are written first, ordered by interval and
+// inverter, followed by Enode rows for each remaining inverter.
+func (e *Export) WriteTo(ctx context.Context, w io.Writer) error {
+	writer, err := NewRowWriter(e.format, w, e.fields, e.loc)
+	if err != nil {
+		return err
+	}
+	if err := e.writeLocal(ctx, writer); err != nil {
+		return err
+	}
+	for _, inverter := range e.enode {
+		if err := e.writeEnode(ctx, writer, inverter); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// writeLocal pages through aggregated hourly records with a keyset cursor so large exports are
+// never held in memory.
+func (e *Export) writeLocal(ctx context.Context, writer RowWriter) error {
+	if len(e.local) == 0 {
+		return nil
+	}
+	params := db.ListProductionExportRowsParams{
+		TimeZone:    e.loc.String(),
+		Resolution:  e.resolution,
+		InverterIds: e.local,
+		StartTime:   utcTimestamp(e.start),
+		EndTime:     utcTimestamp(e.end),
+		AfterBucket: time.Unix(0, 0),
+		PageSize:    e.uc.pageSize,
+	}
+	for {
+		rows, err := e.uc.store.ListProductionExportRows(ctx, params)
+		if err != nil {
+			return fmt.Errorf("listing production records: %w", err)
+		}
+		for _, row := range rows {
+			if err := writer.WriteRow(newLocalRow(row)); err != nil {
+				return fmt.Errorf("writing row: %w", err)
+			}
+		}
+		if len(rows) < int(params.PageSize) {
+			return nil
+		}
+		last := rows[len(rows)-1]
+		params.AfterBucket = last.Bucket
+		params.AfterInverterID = last.InverterID
+	}
+}
+
+func (e *Export) writeEnode(ctx context.Context, writer RowWriter, inverter enodeInverter) error {
+	totals := make(map[time.Time]*Row)
+	for _, day := range e.statisticsRequests() {
+		stats, err := e.uc.source.GetInverterProductionStatistics(ctx, inverter.enodeID, day.Year(), int(day.Month()), statisticsDay(e.resolution, day))
+		if err != nil {
+			return fmt.Errorf("fetching Enode statistics for inverter %d: %w", inverter.localID, err)
+		}
+		resolution, ok := enodeResolution(e.resolution, stats)
+		if !ok {
+			continue
+		}
+		for _, point := range resolution.Data {
+			if point.Date.Before(e.start) || !point.Date.Before(e.end) {
+				continue
+			}
+			bucket := truncate(point.Date.In(e.loc), e.resolution)
+			total, ok := totals[bucket]
+			if !ok {
+				total = &Row{Timestamp: bucket, InverterID: inverter.localID, Source: SourceEnode}
+				totals[bucket] = total
+			}
+			total.EnergyKwh += resolution.EnergyKwh(point.Value)
+			total.Readings++
+		}
+	}
+
+	buckets := make([]time.Time, 0, len(totals))
+	for bucket := range totals {
+		buckets = append(buckets, bucket)
+	}
+	slices.SortFunc(buckets, func(a, b time.Time) int { return a.Compare(b) })
+	for _, bucket := range buckets {
+		if err := writer.WriteRow(*totals[bucket]); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+	return nil
+}
+
+// statisticsRequests lists the periods to request from Enode: every day for hourly exports,
+// every month otherwise.
+func (e *Export) statisticsRequests() []time.Time {
+	var periods []time.Time
+	if e.resolution == ResolutionHour {
+		for day := e.start; day.Before(e.end); day = day.AddDate(0, 0, 1) {
+			periods = append(periods, day)
+		}
+		return periods
+	}
+	for month := truncate(e.start, ResolutionMonth); month.Before(e.end); month = month.AddDate(0, 1, 0) {
+		periods = append(periods, month)
+	}
+	return periods
+}
+
+func statisticsDay(resolution string, period time.Time) int {
+	if resolution == ResolutionHour {
+		return period.Day()
+	}
+	return 0
+}
+
+// enodeResolution picks the finest Enode resolution that is at least as coarse as needed.
+func enodeResolution(resolution string, stats *inverters.InverterStatistic) (inverters.Resolution, bool) {
+	candidates := []string{"DAY"}
+	if resolution == ResolutionHour {
+		candidates = []string{"HOUR", "QUARTER_HOUR"}
+	}
+	for _, name := range candidates {
+		if r, ok := stats.Resolutions[name]; ok {
+			return r, true
+		}
+	}
+	return inverters.Resolution{}, false
+}
+
+func truncate(t time.Time, resolution string) time.Time {
+	switch resolution {
+	case ResolutionHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case ResolutionDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func newLocalRow(row db.ListProductionExportRowsRow) Row {
+	return Row{
+		Timestamp:              row.Bucket,
+		InverterID:             row.InverterID,
+		Source:                 SourceLocal,
+		EnergyKwh:              row.EnergyKwh,
+		Readings:               row.Readings,
+		PowerOutputKw:          &row.PowerOutputKw,
+		PredictedPowerOutputKw: nullableFloat(row.PredictedPowerOutputKw),
+		Irradiance:             nullableFloat(row.Irradiance),
+		PoaIrradiance:          nullableFloat(row.PoaIrradiance),
+		TemperatureCelsius:     nullableFloat(row.TemperatureCelsius),
+		CellTemperatureCelsius: nullableFloat(row.CellTemperatureCelsius),
+		CloudCoverPercent:      nullableFloat(row.CloudCoverPercent),
+		ClearSkyIndex:          nullableFloat(row.ClearSkyIndex),
+	}
+}
+
+func nullableFloat(v pgtype.Float8) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// utcTimestamp converts t for comparison with the zone-less UTC timestamps of hourly records.
+func utcTimestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// RowWriter encodes rows in one export format. Close must be called to flush buffered output;
+// for Parquet it also writes the file footer.
+type RowWriter interface {
+	WriteRow(row Row) error
+	Close() error
+}
+
+// NewRowWriter returns a writer for format that encodes fields of each row to w. CSV and NDJSON
+// timestamps are rendered in loc; Parquet stores them as UTC instants.
+func NewRowWriter(format string, w io.Writer, fields []Field, loc *time.Location) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, fields, loc)
+	case FormatNDJSON:
+		return &ndjsonWriter{out: bufio.NewWriter(w), fields: fields, loc: loc}, nil
+	case FormatParquet:
+		return newParquetWriter(w, fields), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+}
+
+func contentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+type csvWriter struct {
+	out    *csv.Writer
+	fields []Field
+	loc    *time.Location
+	record []string
+}
+
+func newCSVWriter(w io.Writer, fields []Field, loc *time.Location) (*csvWriter, error) {
+	out := csv.NewWriter(w)
+	header := make([]string, 0, len(fields))
+	for _, field := range fields {
+		header = append(header, field.Name)
+	}
+	if err := out.Write(header); err != nil {
+		return nil, fmt.Errorf("writing csv header: %w", err)
+	}
+	return &csvWriter{out: out, fields: fields, loc: loc, record: make([]string, len(fields))}, nil
+}
+
+func (w *csvWriter) WriteRow(row Row) error {
+	for i, field := range w.fields {
+		w.record[i] = formatText(field.value(row), w.loc)
+	}
+	return w.out.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+// formatText renders a field value for CSV. Missing values are empty.
+func formatText(value any, loc *time.Location) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.In(loc).Format(time.RFC3339)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type ndjsonWriter struct {
+	out    *bufio.Writer
+	fields []Field
+	loc    *time.Location
+}
+
+// WriteRow writes one JSON object per line with keys in the selected field order.
+func (w *ndjsonWriter) WriteRow(row Row) error {
+	w.out.WriteByte('{')
+	for i, field := range w.fields {
+		if i > 0 {
+			w.out.WriteByte(',')
+		}
+		value := field.value(row)
+		if t, ok := value.(time.Time); ok {
+			value = t.In(w.loc).Format(time.RFC3339)
+		}
+		key, _ := json.Marshal(field.Name)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", field.Name, err)
+		}
+		w.out.Write(key)
+		w.out.WriteByte(':')
+		w.out.Write(encoded)
+	}
+	w.out.WriteByte('}')
+	return w.out.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.out.Flush()
+}
+
+type parquetWriter struct {
+	out *parquet.Writer
+	// columns maps each parquet column, which parquet orders by name, to the selected field.
+	columns []Field
+}
+
+func newParquetWriter(w io.Writer, fields []Field) *parquetWriter {
+	group := make(parquet.Group, len(fields))
+	for _, field := range fields {
+		var node parquet.Node
+		switch field.kind {
+		case kindTime:
+			node = parquet.Timestamp(parquet.Millisecond)
+		case kindInt:
+			node = parquet.Int(32)
+		case kindFloat:
+			node = parquet.Leaf(parquet.DoubleType)
+		default:
+			node = parquet.String()
+		}
+		if field.nullable {
+			node = parquet.Optional(node)
+		}
+		group[field.Name] = node
+	}
+	schema := parquet.NewSchema("production", group)
+
+	columns := make([]Field, 0, len(fields))
+	for _, column := range schema.Fields() {
+		field, _ := lookupField(column.Name())
+		columns = append(columns, field)
+	}
+	return &parquetWriter{out: parquet.NewWriter(w, schema), columns: columns}
+}
+
+func (w *parquetWriter) WriteRow(row Row) error {
+	values := make(parquet.Row, len(w.columns))
+	for i, field := range w.columns {
+		definitionLevel := 0
+		if field.nullable {
+			definitionLevel = 1
+		}
+		switch v := field.value(row).(type) {
+		case nil:
+			values[i] = parquet.NullValue().Level(0, 0, i)
+		case time.Time:
+			values[i] = parquet.Int64Value(v.UnixMilli()).Level(0, definitionLevel, i)
+		case int32:
+			values[i] = parquet.Int32Value(v).Level(0, definitionLevel, i)
+		case float64:
+			values[i] = parquet.DoubleValue(v).Level(0, definitionLevel, i)
+		case string:
+			values[i] = parquet.ByteArrayValue([]byte(v)).Level(0, definitionLevel, i)
+		}
+	}
+	_, err := w.out.WriteRows([]parquet.Row{values})
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.out.Close()
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testRows() []Row {
+	power := 1.5
+	return []Row{
+		{Timestamp: time.Date(2024, time.June, 21, 10, 0, 0, 0, time.UTC), InverterID: 7, Source: SourceLocal, EnergyKwh: 1.25, Readings: 1, PowerOutputKw: &power},
+		{Timestamp: time.Date(2024, time.June, 21, 11, 0, 0, 0, time.UTC), InverterID: 7, Source: SourceEnode, EnergyKwh: 2, Readings: 4},
+	}
+}
+
+func writeAll(t *testing.T, format string, fieldNames []string, loc *time.Location) []byte {
+	t.Helper()
+	fields, err := ParseFields(fieldNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writer, err := NewRowWriter(format, &buf, fields, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range testRows() {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(writeAll(t, FormatCSV, []string{"timestamp", "energy_kwh", "power_output_kw"}, paris))
+	want := "timestamp,energy_kwh,power_output_kw\n" +
+		"2024-06-21T12:00:00+02:00,1.25,1.5\n" +
+		"2024-06-21T13:00:00+02:00,2,\n"
+	if got != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	got := string(writeAll(t, FormatNDJSON, []string{"source", "timestamp", "power_output_kw"}, time.UTC))
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	// Keys keep the selected order rather than JSON map order.
+	if want := `{"source":"local","timestamp":"2024-06-21T10:00:00Z","power_output_kw":1.5}`; lines[0] != want {
+		t.Fatalf("line 0 = %s, want %s", lines[0], want)
+	}
+	if want := `{"source":"enode","timestamp":"2024-06-21T11:00:00Z","power_output_kw":null}`; lines[1] != want {
+		t.Fatalf("line 1 = %s, want %s", lines[1], want)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	data := writeAll(t, FormatParquet, []string{"timestamp", "inverter_id", "energy_kwh", "power_output_kw"}, time.UTC)
+
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.NumRows() != 2 {
+		t.Fatalf("rows = %d, want 2", file.NumRows())
+	}
+
+	reader := parquet.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	rows := make([]parquet.Row, 2)
+	if n, _ := reader.ReadRows(rows); n != 2 {
+		t.Fatalf("read %d rows, want 2", n)
+	}
+	values := make(map[string]parquet.Value)
+	for i, field := range reader.Schema().Fields() {
+		values[field.Name()] = rows[1][i]
+	}
+	if v := values["energy_kwh"].Double(); v != 2 {
+		t.Fatalf("energy_kwh = %v, want 2", v)
+	}
+	if v := values["inverter_id"].Int32(); v != 7 {
+		t.Fatalf("inverter_id = %v, want 7", v)
+	}
+	if v := values["timestamp"].Int64(); v != time.Date(2024, time.June, 21, 11, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Fatalf("timestamp = %v", v)
+	}
+	if !values["power_output_kw"].IsNull() {
+		t.Fatalf("power_output_kw = %v, want null", values["power_output_kw"])
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "defaults", want: DefaultFields},
+		{name: "selected order", names: []string{"energy_kwh", " timestamp"}, want: []string{"energy_kwh", "timestamp"}},
+		{name: "unknown", names: []string{"voltage"}, wantErr: true},
+		{name: "duplicate", names: []string{"timestamp", "timestamp"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseFields(tt.names)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, field := range fields {
+				got = append(got, field.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
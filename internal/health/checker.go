@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Check is a named dependency probe.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checkedAt"`
+	Cached    bool                   `json:"cached"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Checker runs dependency checks concurrently and caches the report for ttl so probes
+// from many replicas or kubelets do not hammer Postgres, Redis or Enode.
+type Checker struct {
+	checks  []Check
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu   sync.Mutex
+	last *Report
+}
+
+func NewChecker(ttl time.Duration, timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Report returns the cached report if it is younger than the ttl, otherwise runs every check.
+// Checks are detached from ctx cancellation and bounded by the check timeout only, so a probe
+// that disconnects early does not cache an unavailable report for everyone else.
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && c.now().Sub(c.last.CheckedAt) < c.ttl {
+		cached := *c.last
+		cached.Cached = true
+		return cached
+	}
+
+	report := c.run(context.WithoutCancel(ctx))
+	c.last = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	results := make(map[string]CheckResult, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := c.now()
+			err := check.Fn(checkCtx)
+			result := CheckResult{Status: StatusOK, DurationMs: c.now().Sub(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusUnavailable
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: c.now(), Checks: results}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+			break
+		}
+	}
+	return report
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestChecker_Report(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		wantFailed []string
+	}{
+		{
+			name: "all healthy",
+			checks: []Check{
+				{Name: "postgres", Fn: func(ctx context.Context) error { return nil }},
+				{Name: "redis", Fn: func(ctx context.Context) error { return nil }},
+			},
+			wantStatus: StatusOK,
+		},
+		{
+			name: "one failing",
+			checks: []Check{
+				{Name: "postgres", Fn: func(ctx context.Context) error { return nil }},
+				{Name: "redis", Fn: func(ctx context.Context) error { return errors.New("connection refused") }},
+			},
+			wantStatus: StatusUnavailable,
+			wantFailed: []string{"redis"},
+		},
+		{
+			name: "check exceeding timeout",
+			checks: []Check{
+				{Name: "enode_token", Fn: func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}},
+			},
+			wantStatus: StatusUnavailable,
+			wantFailed: []string{"enode_token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewChecker(0, 20*time.Millisecond, tt.checks...).Report(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("checks = %d, want %d", len(report.Checks), len(tt.checks))
+			}
+			for _, name := range tt.wantFailed {
+				if result := report.Checks[name]; result.Status != StatusUnavailable || result.Error == "" {
+					t.Fatalf("check %s = %+v, want unavailable with error", name, result)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_IgnoresCallerCancellation(t *testing.T) {
+	checker := NewChecker(time.Minute, time.Second, Check{Name: "postgres", Fn: func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return nil
+		}
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if report := checker.Report(ctx); report.Status != StatusOK {
+		t.Fatalf("report = %+v, want ok", report)
+	}
+}
+
+func TestChecker_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	checker := NewChecker(time.Minute, time.Second, Check{Name: "redis", Fn: func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return now }
+
+	first := checker.Report(context.Background())
+	second := checker.Report(context.Background())
+	if first.Cached || !second.Cached {
+		t.Fatalf("cached flags = %v, %v", first.Cached, second.Cached)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if checker.Report(context.Background()).Cached {
+		t.Fatal("expected fresh report after ttl")
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestHandler_Readyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "ready", wantStatus: http.StatusOK},
+		{name: "not ready", err: errors.New("down"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(NewChecker(0, time.Second, Check{Name: "postgres", Fn: func(ctx context.Context) error { return tt.err }}))
+			e := echo.New()
+			e.GET("/readyz", h.Readyz)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var report Report
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if _, ok := report.Checks["postgres"]; !ok {
+				t.Fatalf("missing postgres check in %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	checker *Checker
+}
+
+func NewHandler(checker *Checker) *Handler {
+	return &Handler{checker: checker}
+}
+
+// Livez reports that the process is up. It deliberately checks no dependencies so a
+// Postgres or Enode outage does not make Kubernetes restart healthy pods.
+func (h *Handler) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// Readyz reports whether every dependency needed to serve traffic is reachable.
+func (h *Handler) Readyz(c echo.Context) error {
+	report := h.checker.Report(c.Request().Context())
+	if report.Status != StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,56 @@
+package identities
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+	StatusRevoked = "revoked"
+)
+
+type AuthorizeResponse struct {
+	AuthorizationURL string    `json:"authorizationUrl"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+// IdentityResponse describes a user's connection to a provider. Tokens are never returned.
+type IdentityResponse struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"userId"`
+	Provider        string     `json:"provider"`
+	ProviderUserID  string     `json:"providerUserId"`
+	Status          string     `json:"status"`
+	Scope           string     `json:"scope,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt       *time.Time `json:"revokedAt,omitempty"`
+	RefreshFailures int32      `json:"refreshFailures"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+func newIdentityResponse(identity db.Identity, now time.Time) IdentityResponse {
+	status := StatusActive
+	switch {
+	case identity.RevokedAt != nil || !identity.AccessToken.Valid:
+		status = StatusRevoked
+	case identity.ExpiresAt != nil && !identity.ExpiresAt.After(now) && !identity.RefreshToken.Valid:
+		status = StatusExpired
+	}
+	return IdentityResponse{
+		ID:              strconv.Itoa(int(identity.ID)),
+		UserID:          strconv.Itoa(int(identity.UserID)),
+		Provider:        identity.Provider,
+		ProviderUserID:  identity.ProviderUserID,
+		Status:          status,
+		Scope:           identity.Scope.String,
+		ExpiresAt:       identity.ExpiresAt,
+		RevokedAt:       identity.RevokedAt,
+		RefreshFailures: identity.RefreshFailures,
+		UpdatedAt:       identity.UpdatedAt,
+	}
+}
@@ -0,0 +1,88 @@
+package identities
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type IdentityHandler struct {
+	identityUseCase *IdentityUseCase
+}
+
+func NewIdentityHandler(identityUseCase *IdentityUseCase) *IdentityHandler {
+	return &IdentityHandler{
+		identityUseCase: identityUseCase,
+	}
+}
+
+func (h *IdentityHandler) Authorize(c echo.Context) error {
+	userID, provider := c.Param("userID"), c.Param("provider")
+	response, err := h.identityUseCase.Authorize(c.Request().Context(), userID, provider)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to start authorization", "userID", userID, "provider", provider, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to start authorization")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Callback receives the provider's redirect after the user granted or denied access.
+func (h *IdentityHandler) Callback(c echo.Context) error {
+	provider := c.Param("provider")
+	if providerErr := c.QueryParam("error"); providerErr != "" {
+		slog.WarnContext(c.Request().Context(), "Provider denied authorization", "provider", provider, "error", providerErr, "description", c.QueryParam("error_description"))
+		return echo.NewHTTPError(http.StatusBadRequest, "Authorization was denied")
+	}
+
+	identity, err := h.identityUseCase.Callback(c.Request().Context(), provider, c.QueryParam("state"), c.QueryParam("code"))
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to complete authorization", "provider", provider, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to complete authorization")
+	}
+
+	slog.InfoContext(c.Request().Context(), "Linked identity", "userID", identity.UserID, "provider", provider, "identityID", identity.ID)
+	return c.JSON(http.StatusOK, identity)
+}
+
+func (h *IdentityHandler) GetIdentity(c echo.Context) error {
+	userID, provider := c.Param("userID"), c.Param("provider")
+	identity, err := h.identityUseCase.GetIdentity(c.Request().Context(), userID, provider)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get identity", "userID", userID, "provider", provider, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get identity")
+	}
+
+	return c.JSON(http.StatusOK, identity)
+}
+
+func (h *IdentityHandler) Revoke(c echo.Context) error {
+	userID, provider := c.Param("userID"), c.Param("provider")
+	if err := h.identityUseCase.Revoke(c.Request().Context(), userID, provider); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to revoke identity", "userID", userID, "provider", provider, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to revoke identity")
+	}
+
+	slog.InfoContext(c.Request().Context(), "Revoked identity", "userID", userID, "provider", provider)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidUserID), errors.Is(err, ErrInvalidState):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrIdentityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrIdentityConflict), errors.Is(err, ErrRefreshInProgress):
+		return http.StatusConflict
+	case errors.Is(err, ErrIdentityRevoked):
+		return http.StatusGone
+	case errors.Is(err, ErrInvalidGrant):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
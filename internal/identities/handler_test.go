@@ -0,0 +1,70 @@
+package identities
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/labstack/echo/v4"
+)
+
+func TestIdentityHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		seed       bool
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "authorize", method: http.MethodGet, target: "/users/42/identities/acme/authorize", wantStatus: http.StatusOK},
+		{name: "authorize unknown provider", method: http.MethodGet, target: "/users/42/identities/other/authorize", wantStatus: http.StatusNotFound},
+		{name: "authorize invalid user", method: http.MethodGet, target: "/users/abc/identities/acme/authorize", wantStatus: http.StatusBadRequest},
+		{name: "callback unknown state", method: http.MethodGet, target: "/oauth/acme/callback?code=c&state=s", wantStatus: http.StatusBadRequest},
+		{name: "callback denied", method: http.MethodGet, target: "/oauth/acme/callback?error=access_denied&state=s", wantStatus: http.StatusBadRequest},
+		{name: "get identity", seed: true, method: http.MethodGet, target: "/users/42/identities/acme", wantStatus: http.StatusOK},
+		{name: "get missing identity", method: http.MethodGet, target: "/users/42/identities/acme", wantStatus: http.StatusNotFound},
+		{name: "revoke", seed: true, method: http.MethodDelete, target: "/users/42/identities/acme", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newFakeProvider(t)
+			store := &fakeIdentityStore{}
+			uc, _ := newTestUseCase(t, provider, store)
+			if tt.seed {
+				seedIdentity(t, uc, store, time.Now().Add(time.Hour))
+			}
+			handler := NewIdentityHandler(uc)
+			e := echo.New()
+			e.Use(audit.Middleware())
+			e.GET("/users/:userID/identities/:provider/authorize", handler.Authorize)
+			e.GET("/users/:userID/identities/:provider", handler.GetIdentity)
+			e.DELETE("/users/:userID/identities/:provider", handler.Revoke)
+			e.GET("/oauth/:provider/callback", handler.Callback)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), "access-1") || strings.Contains(rec.Body.String(), "refresh-1") {
+				t.Fatalf("response leaks tokens: %s", rec.Body.String())
+			}
+			if tt.name == "get identity" {
+				var identity IdentityResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &identity); err != nil {
+					t.Fatal(err)
+				}
+				if identity.Status != StatusActive || identity.ProviderUserID != "9001" {
+					t.Fatalf("identity = %+v", identity)
+				}
+			}
+		})
+	}
+}
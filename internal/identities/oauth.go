@@ -0,0 +1,188 @@
+package identities
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidGrant is returned when the provider rejects a code or refresh token for good, for
+// example because the user revoked access.
+var ErrInvalidGrant = errors.New("oauth grant rejected")
+
+// Token is a provider's token response.
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Scope        string
+	ExpiresIn    int
+	// ProviderUserID is read from the provider's UserIDField, if any.
+	ProviderUserID string
+}
+
+// OAuthClient talks to provider authorization servers. Clients authenticate with HTTP Basic
+// credentials and authorization codes are protected with PKCE.
+type OAuthClient struct {
+	httpClient *http.Client
+}
+
+func NewOAuthClient(httpClient *http.Client) *OAuthClient {
+	return &OAuthClient{httpClient: httpClient}
+}
+
+// AuthorizationURL is where the user is sent to grant access.
+func (c *OAuthClient) AuthorizationURL(provider Provider, state string, codeVerifier string) (string, error) {
+	authURL, err := url.Parse(provider.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s authorization URL: %w", provider.Name, err)
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURL)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if len(provider.Scopes) > 0 {
+		query.Set("scope", strings.Join(provider.Scopes, " "))
+	}
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange trades an authorization code for tokens.
+func (c *OAuthClient) Exchange(ctx context.Context, provider Provider, code string, codeVerifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	return c.requestToken(ctx, provider, form)
+}
+
+// Refresh obtains a new access token. Providers that rotate refresh tokens return a new one.
+func (c *OAuthClient) Refresh(ctx context.Context, provider Provider, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return c.requestToken(ctx, provider, form)
+}
+
+// Revoke asks the provider to invalidate token (RFC 7009). Providers without a revocation
+// endpoint are skipped.
+func (c *OAuthClient) Revoke(ctx context.Context, provider Provider, token string, tokenTypeHint string) error {
+	if provider.RevokeURL == "" {
+		return nil
+	}
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", tokenTypeHint)
+	resp, err := c.post(ctx, provider, provider.RevokeURL, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return parseOAuthError(resp)
+	}
+	return nil
+}
+
+func (c *OAuthClient) requestToken(ctx context.Context, provider Provider, form url.Values) (*Token, error) {
+	resp, err := c.post(ctx, provider, provider.TokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseOAuthError(resp)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding %s token response: %w", provider.Name, err)
+	}
+	var token Token
+	fields := []struct {
+		name   string
+		target any
+	}{
+		{"access_token", &token.AccessToken},
+		{"refresh_token", &token.RefreshToken},
+		{"scope", &token.Scope},
+		{"expires_in", &token.ExpiresIn},
+	}
+	for _, field := range fields {
+		if raw, ok := body[field.name]; ok {
+			if err := json.Unmarshal(raw, field.target); err != nil {
+				return nil, fmt.Errorf("decoding %s token field %s: %w", provider.Name, field.name, err)
+			}
+		}
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s token response has no access token", provider.Name)
+	}
+	if raw, ok := body[provider.UserIDField]; ok && provider.UserIDField != "" {
+		// Vendors use both string and numeric user IDs.
+		var id any
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&id); err != nil {
+			return nil, fmt.Errorf("decoding %s token field %s: %w", provider.Name, provider.UserIDField, err)
+		}
+		token.ProviderUserID = fmt.Sprint(id)
+	}
+	return &token, nil
+}
+
+func (c *OAuthClient) post(ctx context.Context, provider Provider, endpoint string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", provider.Name, err)
+	}
+	return resp, nil
+}
+
+// parseOAuthError turns an RFC 6749 error response into an error, wrapping ErrInvalidGrant
+// when the grant itself was rejected.
+func parseOAuthError(resp *http.Response) error {
+	var oauthErr struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err := json.Unmarshal(body, &oauthErr); err != nil || oauthErr.Error == "" {
+		return fmt.Errorf("request failed with status %s", resp.Status)
+	}
+	if oauthErr.Error == "invalid_grant" {
+		return fmt.Errorf("%w: %s", ErrInvalidGrant, oauthErr.Description)
+	}
+	return fmt.Errorf("%s - %s", oauthErr.Error, oauthErr.Description)
+}
+
+// randomToken returns n random bytes, base64url-encoded, for states and PKCE verifiers.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,50 @@
+package identities
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Provider describes a vendor API that authorizes each user with the OAuth 2.0 authorization
+// code grant and refresh tokens.
+type Provider struct {
+	Name         string   `json:"name"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	AuthURL      string   `json:"authUrl"`
+	TokenURL     string   `json:"tokenUrl"`
+	RevokeURL    string   `json:"revokeUrl,omitempty"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes,omitempty"`
+	// UserIDField names the token response field holding the vendor's ID for the user. When it
+	// is empty or missing from the response, the local user ID is used, as with Enode.
+	UserIDField string `json:"userIdField,omitempty"`
+}
+
+// LoadProviders reads the JSON array of providers at path. An empty path configures none.
+func LoadProviders(path string) (map[string]Provider, error) {
+	providers := make(map[string]Provider)
+	if path == "" {
+		return providers, nil
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading OAuth providers: %w", err)
+	}
+	var list []Provider
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("decoding OAuth providers: %w", err)
+	}
+	for _, provider := range list {
+		if provider.Name == "" || provider.ClientID == "" || provider.AuthURL == "" || provider.TokenURL == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("OAuth provider %q needs name, clientId, authUrl, tokenUrl and redirectUrl", provider.Name)
+		}
+		if _, ok := providers[provider.Name]; ok {
+			return nil, fmt.Errorf("OAuth provider %q is configured twice", provider.Name)
+		}
+		providers[provider.Name] = provider
+	}
+	return providers, nil
+}
@@ -0,0 +1,50 @@
+package identities
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// refreshBatchSize bounds how many identities one tick refreshes.
+const refreshBatchSize = 100
+
+// Refresher refreshes access tokens shortly before they expire, so provider calls rarely have
+// to refresh inline. Identities are locked while refreshing, so several replicas can run it.
+type Refresher struct {
+	identityUseCase *IdentityUseCase
+	interval        time.Duration
+	leeway          time.Duration
+}
+
+func NewRefresher(identityUseCase *IdentityUseCase, interval time.Duration, leeway time.Duration) *Refresher {
+	return &Refresher{
+		identityUseCase: identityUseCase,
+		interval:        interval,
+		leeway:          leeway,
+	}
+}
+
+// Run refreshes identities expiring within leeway every interval until ctx is cancelled.
+func (r *Refresher) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting identity token refresher", "interval", r.interval, "leeway", r.leeway)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping identity token refresher")
+			return
+		case <-ticker.C:
+			refreshed, err := r.identityUseCase.RefreshExpiring(ctx, r.leeway, refreshBatchSize)
+			if err != nil {
+				slog.ErrorContext(ctx, "Identity token refresh failed", "error", err)
+				continue
+			}
+			if refreshed > 0 {
+				slog.InfoContext(ctx, "Refreshed identity tokens", "count", refreshed)
+			}
+		}
+	}
+}
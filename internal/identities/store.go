@@ -0,0 +1,98 @@
+package identities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const statePrefix = "oauth:state:"
+
+// IdentityStore persists per-user provider tokens.
+type IdentityStore interface {
+	GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (db.Identity, error)
+	UpsertIdentityTokens(ctx context.Context, arg db.UpsertIdentityTokensParams) (db.Identity, error)
+	ListIdentitiesDueForRefresh(ctx context.Context, arg db.ListIdentitiesDueForRefreshParams) ([]db.Identity, error)
+	LockIdentityRefresh(ctx context.Context, arg db.LockIdentityRefreshParams) (db.Identity, error)
+	UpdateIdentityTokens(ctx context.Context, arg db.UpdateIdentityTokensParams) (db.Identity, error)
+	RecordIdentityRefreshFailure(ctx context.Context, id int32) error
+	RevokeIdentity(ctx context.Context, id int32) (db.Identity, error)
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+	InTx(ctx context.Context, fn func(store IdentityStore) error) error
+}
+
+// PostgresIdentityStore is the IdentityStore backed by sqlc queries on a pgx pool.
+type PostgresIdentityStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresIdentityStore(pool *pgxpool.Pool) *PostgresIdentityStore {
+	return &PostgresIdentityStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresIdentityStore) InTx(ctx context.Context, fn func(store IdentityStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresIdentityStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
+
+// PendingAuthorization is what an authorization request remembers until its callback.
+type PendingAuthorization struct {
+	UserID       int32  `json:"userId"`
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"codeVerifier"`
+	// RequestedBy is the actor who started the authorization; the callback arrives from the
+	// user's browser without one.
+	RequestedBy string `json:"requestedBy"`
+}
+
+// StateStore holds pending authorizations by their OAuth state. Each state can be taken once.
+type StateStore interface {
+	Save(ctx context.Context, state string, pending PendingAuthorization, ttl time.Duration) error
+	Take(ctx context.Context, state string) (*PendingAuthorization, error)
+}
+
+// RedisStateStore is the StateStore backed by Redis keys that expire with the state.
+type RedisStateStore struct {
+	client *redis.Client
+}
+
+func NewRedisStateStore(client *redis.Client) *RedisStateStore {
+	return &RedisStateStore{client: client}
+}
+
+func (s *RedisStateStore) Save(ctx context.Context, state string, pending PendingAuthorization, ttl time.Duration) error {
+	body, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, statePrefix+state, body, ttl).Err()
+}
+
+// Take returns and deletes the authorization pending under state, or ErrInvalidState if there
+// is none.
+func (s *RedisStateStore) Take(ctx context.Context, state string) (*PendingAuthorization, error) {
+	body, err := s.client.GetDel(ctx, statePrefix+state).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading OAuth state: %w", err)
+	}
+	var pending PendingAuthorization
+	if err := json.Unmarshal(body, &pending); err != nil {
+		return nil, fmt.Errorf("decoding OAuth state: %w", err)
+	}
+	return &pending, nil
+}
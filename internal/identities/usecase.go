@@ -0,0 +1,421 @@
+package identities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/secrets"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidUserID     = errors.New("invalid user id")
+	ErrUnknownProvider   = errors.New("unknown OAuth provider")
+	ErrInvalidState      = errors.New("invalid or expired OAuth state")
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrIdentityConflict  = errors.New("provider account is linked to another user")
+	ErrIdentityRevoked   = errors.New("identity revoked")
+	ErrRefreshInProgress = errors.New("identity refresh already in progress")
+)
+
+// refreshLease is how long a refresh may hold an identity before another replica can retry it.
+const refreshLease = time.Minute
+
+// TokenSealer encrypts tokens before they are stored. The associated data names the identity
+// and column, so a value copied elsewhere fails to open.
+type TokenSealer interface {
+	Seal(ctx context.Context, plaintext string, associatedData string) (string, error)
+	Open(ctx context.Context, sealed string, associatedData string) (string, error)
+}
+
+type IdentityUseCase struct {
+	store     IdentityStore
+	states    StateStore
+	client    *OAuthClient
+	providers map[string]Provider
+	sealer    TokenSealer
+	stateTTL  time.Duration
+	now       func() time.Time
+}
+
+func NewIdentityUseCase(store IdentityStore, states StateStore, client *OAuthClient, providers map[string]Provider, sealer TokenSealer, stateTTL time.Duration) *IdentityUseCase {
+	return &IdentityUseCase{
+		store:     store,
+		states:    states,
+		client:    client,
+		providers: providers,
+		sealer:    sealer,
+		stateTTL:  stateTTL,
+		now:       time.Now,
+	}
+}
+
+// Authorize starts the authorization code flow for userID and returns where to send the user.
+func (uc *IdentityUseCase) Authorize(ctx context.Context, userID string, providerName string) (*AuthorizeResponse, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := uc.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("generating OAuth state: %w", err)
+	}
+	verifier, err := randomToken(48)
+	if err != nil {
+		return nil, fmt.Errorf("generating PKCE verifier: %w", err)
+	}
+	authURL, err := uc.client.AuthorizationURL(provider, state, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := PendingAuthorization{
+		UserID:       id,
+		Provider:     provider.Name,
+		CodeVerifier: verifier,
+		RequestedBy:  audit.ActorFromContext(ctx).ID,
+	}
+	if err := uc.states.Save(ctx, state, pending, uc.stateTTL); err != nil {
+		return nil, fmt.Errorf("saving OAuth state: %w", err)
+	}
+	return &AuthorizeResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresAt:        uc.now().Add(uc.stateTTL),
+	}, nil
+}
+
+// Callback completes the flow started by Authorize, exchanging code for tokens and storing them
+// encrypted on the user's identity.
+func (uc *IdentityUseCase) Callback(ctx context.Context, providerName string, state string, code string) (*IdentityResponse, error) {
+	provider, err := uc.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+	pending, err := uc.states.Take(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if pending.Provider != provider.Name {
+		return nil, ErrInvalidState
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	actor.ID = pending.RequestedBy
+	ctx = audit.WithActor(ctx, actor)
+
+	token, err := uc.client.Exchange(ctx, provider, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging %s authorization code: %w", provider.Name, err)
+	}
+	userID := strconv.Itoa(int(pending.UserID))
+	providerUserID := token.ProviderUserID
+	if providerUserID == "" {
+		providerUserID = userID
+	}
+
+	accessToken, err := uc.seal(ctx, secrets.ColumnAccessToken, provider.Name, providerUserID, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := uc.seal(ctx, secrets.ColumnRefreshToken, provider.Name, providerUserID, token.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IdentityResponse
+	err = uc.store.InTx(ctx, func(store IdentityStore) error {
+		identity, err := store.UpsertIdentityTokens(ctx, db.UpsertIdentityTokensParams{
+			UserID:         pending.UserID,
+			Provider:       provider.Name,
+			ProviderUserID: providerUserID,
+			AccessToken:    accessToken,
+			RefreshToken:   refreshToken,
+			Scope:          optionalText(token.Scope),
+			ExpiresAt:      uc.expiresAt(token),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrIdentityConflict
+		}
+		if err != nil {
+			return fmt.Errorf("storing %s identity: %w", provider.Name, err)
+		}
+
+		response = newIdentityResponse(identity, uc.now())
+		return audit.Record(ctx, store, audit.Entry{
+			Action:       audit.ActionIdentityLinked,
+			ResourceType: audit.ResourceIdentity,
+			ResourceID:   response.ID,
+			UserID:       userID,
+			After:        response,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (uc *IdentityUseCase) GetIdentity(ctx context.Context, userID string, providerName string) (*IdentityResponse, error) {
+	identity, err := uc.getIdentity(ctx, userID, providerName)
+	if err != nil {
+		return nil, err
+	}
+	response := newIdentityResponse(identity, uc.now())
+	return &response, nil
+}
+
+// AccessToken returns a usable access token for userID at the provider, refreshing it first if
+// it expires within leeway. Provider clients call this instead of reading identities directly.
+func (uc *IdentityUseCase) AccessToken(ctx context.Context, userID string, providerName string, leeway time.Duration) (string, error) {
+	identity, err := uc.getIdentity(ctx, userID, providerName)
+	if err != nil {
+		return "", err
+	}
+	if identity.RevokedAt != nil || !identity.AccessToken.Valid {
+		return "", ErrIdentityRevoked
+	}
+
+	if identity.ExpiresAt != nil && identity.ExpiresAt.Before(uc.now().Add(leeway)) && identity.RefreshToken.Valid {
+		refreshed, err := uc.refresh(ctx, identity)
+		switch {
+		case err == nil:
+			identity = refreshed
+		case errors.Is(err, ErrRefreshInProgress) && identity.ExpiresAt.After(uc.now()):
+			// Another replica is refreshing; the current token is still valid meanwhile.
+		default:
+			return "", err
+		}
+	}
+	return uc.open(ctx, secrets.ColumnAccessToken, identity, identity.AccessToken.String)
+}
+
+// RefreshExpiring refreshes up to limit identities whose access tokens expire within leeway and
+// returns how many were refreshed. Failures are recorded on each identity and do not stop the
+// batch.
+func (uc *IdentityUseCase) RefreshExpiring(ctx context.Context, leeway time.Duration, limit int32) (int, error) {
+	expiresBefore := uc.now().Add(leeway)
+	due, err := uc.store.ListIdentitiesDueForRefresh(ctx, db.ListIdentitiesDueForRefreshParams{
+		ExpiresBefore: &expiresBefore,
+		Limit:         limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("listing identities due for refresh: %w", err)
+	}
+
+	refreshed := 0
+	for _, identity := range due {
+		_, err := uc.refresh(ctx, identity)
+		switch {
+		case err == nil:
+			refreshed++
+		case errors.Is(err, ErrRefreshInProgress):
+		default:
+			slog.WarnContext(ctx, "Failed to refresh identity", "identityID", identity.ID, "provider", identity.Provider, "error", err)
+		}
+	}
+	return refreshed, nil
+}
+
+// Revoke disconnects userID from the provider. Tokens are revoked at the provider when it has a
+// revocation endpoint and are always removed locally, so revoking twice is harmless.
+func (uc *IdentityUseCase) Revoke(ctx context.Context, userID string, providerName string) error {
+	identity, err := uc.getIdentity(ctx, userID, providerName)
+	if err != nil {
+		return err
+	}
+	if identity.RevokedAt != nil {
+		return nil
+	}
+
+	if provider, ok := uc.providers[identity.Provider]; ok {
+		tokens := []struct {
+			column string
+			value  pgtype.Text
+		}{
+			{secrets.ColumnRefreshToken, identity.RefreshToken},
+			{secrets.ColumnAccessToken, identity.AccessToken},
+		}
+		for _, token := range tokens {
+			if !token.value.Valid {
+				continue
+			}
+			plaintext, err := uc.open(ctx, token.column, identity, token.value.String)
+			if err == nil {
+				err = uc.client.Revoke(ctx, provider, plaintext, token.column)
+			}
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to revoke token at provider", "identityID", identity.ID, "provider", identity.Provider, "tokenType", token.column, "error", err)
+			}
+		}
+	}
+
+	return uc.revokeLocally(ctx, identity)
+}
+
+// refresh exchanges the refresh token of the identity read as stale for new tokens. The tokens
+// are taken from the row returned by the lock, not from stale, because another replica may have
+// rotated them in between. A rejected grant means the user withdrew access at the provider, so
+// the identity is revoked.
+func (uc *IdentityUseCase) refresh(ctx context.Context, stale db.Identity) (db.Identity, error) {
+	provider, err := uc.provider(stale.Provider)
+	if err != nil {
+		return db.Identity{}, err
+	}
+	lockedUntil := uc.now().Add(refreshLease)
+	identity, err := uc.store.LockIdentityRefresh(ctx, db.LockIdentityRefreshParams{LockedUntil: &lockedUntil, ID: stale.ID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Identity{}, ErrRefreshInProgress
+	}
+	if err != nil {
+		return db.Identity{}, fmt.Errorf("locking identity %d for refresh: %w", stale.ID, err)
+	}
+	if !identity.RefreshToken.Valid {
+		return db.Identity{}, uc.recordFailure(ctx, identity, fmt.Errorf("identity %d has no refresh token", identity.ID))
+	}
+
+	refreshToken, err := uc.open(ctx, secrets.ColumnRefreshToken, identity, identity.RefreshToken.String)
+	if err != nil {
+		return db.Identity{}, uc.recordFailure(ctx, identity, err)
+	}
+	token, err := uc.client.Refresh(ctx, provider, refreshToken)
+	if errors.Is(err, ErrInvalidGrant) {
+		if err := uc.revokeLocally(ctx, identity); err != nil {
+			return db.Identity{}, err
+		}
+		return db.Identity{}, ErrIdentityRevoked
+	}
+	if err != nil {
+		return db.Identity{}, uc.recordFailure(ctx, identity, fmt.Errorf("refreshing %s token: %w", provider.Name, err))
+	}
+
+	accessToken, err := uc.seal(ctx, secrets.ColumnAccessToken, identity.Provider, identity.ProviderUserID, token.AccessToken)
+	if err != nil {
+		return db.Identity{}, uc.recordFailure(ctx, identity, err)
+	}
+	// Providers that do not rotate refresh tokens omit them from refresh responses.
+	newRefreshToken := identity.RefreshToken
+	if token.RefreshToken != "" {
+		newRefreshToken, err = uc.seal(ctx, secrets.ColumnRefreshToken, identity.Provider, identity.ProviderUserID, token.RefreshToken)
+		if err != nil {
+			return db.Identity{}, uc.recordFailure(ctx, identity, err)
+		}
+	}
+	scope := identity.Scope
+	if token.Scope != "" {
+		scope = optionalText(token.Scope)
+	}
+
+	updated, err := uc.store.UpdateIdentityTokens(ctx, db.UpdateIdentityTokensParams{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		Scope:        scope,
+		ExpiresAt:    uc.expiresAt(token),
+		ID:           identity.ID,
+	})
+	if err != nil {
+		return db.Identity{}, fmt.Errorf("storing refreshed identity %d: %w", identity.ID, err)
+	}
+	return updated, nil
+}
+
+func (uc *IdentityUseCase) recordFailure(ctx context.Context, identity db.Identity, cause error) error {
+	if err := uc.store.RecordIdentityRefreshFailure(ctx, identity.ID); err != nil {
+		return fmt.Errorf("recording refresh failure of identity %d: %w (after %w)", identity.ID, err, cause)
+	}
+	return cause
+}
+
+func (uc *IdentityUseCase) revokeLocally(ctx context.Context, identity db.Identity) error {
+	return uc.store.InTx(ctx, func(store IdentityStore) error {
+		revoked, err := store.RevokeIdentity(ctx, identity.ID)
+		if err != nil {
+			return fmt.Errorf("revoking identity %d: %w", identity.ID, err)
+		}
+		return audit.Record(ctx, store, audit.Entry{
+			Action:       audit.ActionIdentityRevoked,
+			ResourceType: audit.ResourceIdentity,
+			ResourceID:   strconv.Itoa(int(identity.ID)),
+			UserID:       strconv.Itoa(int(identity.UserID)),
+			Before:       newIdentityResponse(identity, uc.now()),
+			After:        newIdentityResponse(revoked, uc.now()),
+		})
+	})
+}
+
+func (uc *IdentityUseCase) getIdentity(ctx context.Context, userID string, providerName string) (db.Identity, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return db.Identity{}, err
+	}
+	identity, err := uc.store.GetUserIdentity(ctx, db.GetUserIdentityParams{UserID: id, Provider: providerName})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Identity{}, ErrIdentityNotFound
+	}
+	if err != nil {
+		return db.Identity{}, fmt.Errorf("getting %s identity of user %d: %w", providerName, id, err)
+	}
+	return identity, nil
+}
+
+func (uc *IdentityUseCase) provider(name string) (Provider, error) {
+	provider, ok := uc.providers[name]
+	if !ok {
+		return Provider{}, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+func (uc *IdentityUseCase) expiresAt(token *Token) *time.Time {
+	if token.ExpiresIn <= 0 {
+		return nil
+	}
+	expiresAt := uc.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return &expiresAt
+}
+
+func (uc *IdentityUseCase) seal(ctx context.Context, column string, provider string, providerUserID string, token string) (pgtype.Text, error) {
+	if token == "" {
+		return pgtype.Text{}, nil
+	}
+	sealed, err := uc.sealer.Seal(ctx, token, secrets.IdentityTokenAAD(column, provider, providerUserID))
+	if err != nil {
+		return pgtype.Text{}, fmt.Errorf("encrypting %s %s: %w", provider, column, err)
+	}
+	return pgtype.Text{String: sealed, Valid: true}, nil
+}
+
+func (uc *IdentityUseCase) open(ctx context.Context, column string, identity db.Identity, sealed string) (string, error) {
+	token, err := uc.sealer.Open(ctx, sealed, secrets.IdentityTokenAAD(column, identity.Provider, identity.ProviderUserID))
+	if err != nil {
+		return "", fmt.Errorf("decrypting %s of identity %d: %w", column, identity.ID, err)
+	}
+	return token, nil
+}
+
+func optionalText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func parseUserID(userID string) (int32, error) {
+	id, err := strconv.ParseInt(userID, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUserID, userID)
+	}
+	return int32(id), nil
+}
@@ -0,0 +1,490 @@
+package identities
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/secrets"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeIdentityStore keeps identities in memory. Rows locked for refresh stay locked until their
+// tokens are updated or a failure is recorded. beforeLock, when set, runs before each lock is
+// taken, standing in for other replicas.
+type fakeIdentityStore struct {
+	IdentityStore
+	identities []db.Identity
+	audits     []db.InsertAuditLogEntryParams
+	beforeLock func()
+}
+
+func (f *fakeIdentityStore) InTx(ctx context.Context, fn func(store IdentityStore) error) error {
+	tx := &fakeIdentityStore{identities: slices.Clone(f.identities), audits: slices.Clone(f.audits)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	f.identities, f.audits = tx.identities, tx.audits
+	return nil
+}
+
+func (f *fakeIdentityStore) GetUserIdentity(ctx context.Context, arg db.GetUserIdentityParams) (db.Identity, error) {
+	for _, identity := range f.identities {
+		if identity.UserID == arg.UserID && identity.Provider == arg.Provider {
+			return identity, nil
+		}
+	}
+	return db.Identity{}, pgx.ErrNoRows
+}
+
+func (f *fakeIdentityStore) UpsertIdentityTokens(ctx context.Context, arg db.UpsertIdentityTokensParams) (db.Identity, error) {
+	identity := db.Identity{
+		ID:             int32(len(f.identities) + 1),
+		UserID:         arg.UserID,
+		Provider:       arg.Provider,
+		ProviderUserID: arg.ProviderUserID,
+		AccessToken:    arg.AccessToken,
+		RefreshToken:   arg.RefreshToken,
+		Scope:          arg.Scope,
+		ExpiresAt:      arg.ExpiresAt,
+	}
+	for i, existing := range f.identities {
+		if existing.Provider == arg.Provider && existing.ProviderUserID == arg.ProviderUserID {
+			if existing.UserID != arg.UserID {
+				return db.Identity{}, pgx.ErrNoRows
+			}
+			identity.ID = existing.ID
+			f.identities[i] = identity
+			return identity, nil
+		}
+	}
+	f.identities = append(f.identities, identity)
+	return identity, nil
+}
+
+func (f *fakeIdentityStore) ListIdentitiesDueForRefresh(ctx context.Context, arg db.ListIdentitiesDueForRefreshParams) ([]db.Identity, error) {
+	var due []db.Identity
+	for _, identity := range f.identities {
+		if identity.RefreshToken.Valid && identity.RevokedAt == nil && identity.ExpiresAt.Before(*arg.ExpiresBefore) {
+			due = append(due, identity)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeIdentityStore) LockIdentityRefresh(ctx context.Context, arg db.LockIdentityRefreshParams) (db.Identity, error) {
+	if f.beforeLock != nil {
+		f.beforeLock()
+	}
+	identity := f.find(arg.ID)
+	if identity.RevokedAt != nil || identity.RefreshLockedUntil != nil {
+		return db.Identity{}, pgx.ErrNoRows
+	}
+	identity.RefreshLockedUntil = arg.LockedUntil
+	return *identity, nil
+}
+
+func (f *fakeIdentityStore) UpdateIdentityTokens(ctx context.Context, arg db.UpdateIdentityTokensParams) (db.Identity, error) {
+	identity := f.find(arg.ID)
+	identity.AccessToken, identity.RefreshToken, identity.Scope, identity.ExpiresAt = arg.AccessToken, arg.RefreshToken, arg.Scope, arg.ExpiresAt
+	identity.RefreshFailures, identity.RefreshLockedUntil = 0, nil
+	return *identity, nil
+}
+
+func (f *fakeIdentityStore) RecordIdentityRefreshFailure(ctx context.Context, id int32) error {
+	identity := f.find(id)
+	identity.RefreshFailures++
+	identity.RefreshLockedUntil = nil
+	return nil
+}
+
+func (f *fakeIdentityStore) RevokeIdentity(ctx context.Context, id int32) (db.Identity, error) {
+	identity := f.find(id)
+	revokedAt := time.Now()
+	identity.AccessToken, identity.RefreshToken = pgtype.Text{}, pgtype.Text{}
+	identity.RevokedAt, identity.RefreshLockedUntil = &revokedAt, nil
+	return *identity, nil
+}
+
+func (f *fakeIdentityStore) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.audits = append(f.audits, arg)
+	return db.AuditLog{}, nil
+}
+
+func (f *fakeIdentityStore) find(id int32) *db.Identity {
+	for i := range f.identities {
+		if f.identities[i].ID == id {
+			return &f.identities[i]
+		}
+	}
+	panic("unknown identity")
+}
+
+type fakeStateStore map[string]PendingAuthorization
+
+func (f fakeStateStore) Save(ctx context.Context, state string, pending PendingAuthorization, ttl time.Duration) error {
+	f[state] = pending
+	return nil
+}
+
+func (f fakeStateStore) Take(ctx context.Context, state string) (*PendingAuthorization, error) {
+	pending, ok := f[state]
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	delete(f, state)
+	return &pending, nil
+}
+
+// fakeProvider is an authorization server that records the forms posted to it. The code
+// challenge of the last authorization is checked against the verifier on exchange.
+type fakeProvider struct {
+	*httptest.Server
+	forms     []url.Values
+	challenge string
+	respond   func(w http.ResponseWriter, form url.Values)
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	provider := &fakeProvider{}
+	provider.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "adapter" || pass != "secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		provider.forms = append(provider.forms, r.PostForm)
+		if r.URL.Path == "/revoke" {
+			return
+		}
+		if verifier := r.PostForm.Get("code_verifier"); verifier != "" {
+			if codeChallenge(verifier) != provider.challenge {
+				http.Error(w, `{"error":"invalid_grant","error_description":"bad verifier"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		provider.respond(w, r.PostForm)
+	}))
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+func (p *fakeProvider) config() Provider {
+	return Provider{
+		Name:         "acme",
+		ClientID:     "adapter",
+		ClientSecret: "secret",
+		AuthURL:      p.URL + "/authorize",
+		TokenURL:     p.URL + "/token",
+		RevokeURL:    p.URL + "/revoke",
+		RedirectURL:  "https://adapter.example/api/v1/oauth/acme/callback",
+		Scopes:       []string{"offline_access"},
+		UserIDField:  "user_id",
+	}
+}
+
+func testEnvelope(t *testing.T) *secrets.Envelope {
+	t.Helper()
+	keyring, err := secrets.NewLocalKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secrets.NewEnvelope(keyring)
+}
+
+func newTestUseCase(t *testing.T, provider *fakeProvider, store *fakeIdentityStore) (*IdentityUseCase, fakeStateStore) {
+	states := fakeStateStore{}
+	providers := map[string]Provider{"acme": provider.config()}
+	return NewIdentityUseCase(store, states, NewOAuthClient(provider.Client()), providers, testEnvelope(t), 10*time.Minute), states
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestIdentityUseCase_AuthorizeCallback(t *testing.T) {
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "app"})
+	provider := newFakeProvider(t)
+	provider.respond = func(w http.ResponseWriter, form url.Values) {
+		writeJSON(w, map[string]any{"access_token": "access-1", "refresh_token": "refresh-1", "expires_in": 3600, "scope": "offline_access", "user_id": 9001})
+	}
+	store := &fakeIdentityStore{}
+	uc, states := newTestUseCase(t, provider, store)
+
+	authorization, err := uc.Authorize(ctx, "42", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(authorization.AuthorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if query.Get("state") != authorization.State || query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "adapter" {
+		t.Fatalf("authorization URL = %s", authorization.AuthorizationURL)
+	}
+	provider.challenge = query.Get("code_challenge")
+
+	// The callback arrives from the user's browser, without the actor who started the flow.
+	identity, err := uc.Callback(context.Background(), "acme", authorization.State, "code-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.ProviderUserID != "9001" || identity.Status != StatusActive || identity.Scope != "offline_access" {
+		t.Fatalf("identity = %+v", identity)
+	}
+	if form := provider.forms[0]; form.Get("grant_type") != "authorization_code" || form.Get("code") != "code-1" {
+		t.Fatalf("token request = %v", form)
+	}
+
+	stored := store.identities[0]
+	if strings.Contains(stored.AccessToken.String, "access-1") || strings.Contains(stored.RefreshToken.String, "refresh-1") {
+		t.Fatal("tokens stored in plaintext")
+	}
+	if len(store.audits) != 1 || store.audits[0].Action != audit.ActionIdentityLinked || store.audits[0].Actor != "app" {
+		t.Fatalf("audits = %+v", store.audits)
+	}
+	if strings.Contains(string(store.audits[0].After), "access-1") {
+		t.Fatalf("audit entry leaks tokens: %s", store.audits[0].After)
+	}
+
+	token, err := uc.AccessToken(ctx, "42", "acme", time.Minute)
+	if err != nil || token != "access-1" {
+		t.Fatalf("AccessToken() = %q, %v", token, err)
+	}
+
+	if _, err := uc.Callback(ctx, "acme", authorization.State, "code-1"); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("replayed state: err = %v", err)
+	}
+
+	// The same provider account cannot be linked to a second user.
+	second, err := uc.Authorize(ctx, "43", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.challenge = codeChallenge(states[second.State].CodeVerifier)
+	if _, err := uc.Callback(ctx, "acme", second.State, "code-2"); !errors.Is(err, ErrIdentityConflict) {
+		t.Fatalf("second user: err = %v", err)
+	}
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestIdentityUseCase_RefreshExpiring(t *testing.T) {
+	tests := []struct {
+		name        string
+		respond     func(w http.ResponseWriter, form url.Values)
+		locked      bool
+		wantCount   int
+		wantRefresh string
+		wantAccess  string
+		wantFailure int32
+		wantRevoked bool
+	}{
+		{
+			name: "rotated refresh token",
+			respond: func(w http.ResponseWriter, form url.Values) {
+				writeJSON(w, map[string]any{"access_token": "access-2", "refresh_token": "refresh-2", "expires_in": 3600})
+			},
+			wantCount:   1,
+			wantAccess:  "access-2",
+			wantRefresh: "refresh-2",
+		},
+		{
+			name: "refresh token kept",
+			respond: func(w http.ResponseWriter, form url.Values) {
+				writeJSON(w, map[string]any{"access_token": "access-2", "expires_in": 3600})
+			},
+			wantCount:   1,
+			wantAccess:  "access-2",
+			wantRefresh: "refresh-1",
+		},
+		{
+			name: "grant rejected",
+			respond: func(w http.ResponseWriter, form url.Values) {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "provider unavailable",
+			respond: func(w http.ResponseWriter, form url.Values) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			wantAccess:  "access-1",
+			wantRefresh: "refresh-1",
+			wantFailure: 1,
+		},
+		{
+			name:        "locked by another replica",
+			respond:     func(w http.ResponseWriter, form url.Values) { t.Fatal("unexpected refresh") },
+			locked:      true,
+			wantAccess:  "access-1",
+			wantRefresh: "refresh-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			provider := newFakeProvider(t)
+			provider.respond = tt.respond
+			store := &fakeIdentityStore{}
+			uc, _ := newTestUseCase(t, provider, store)
+			identity := seedIdentity(t, uc, store, time.Now().Add(time.Minute))
+			if tt.locked {
+				lockedUntil := time.Now().Add(time.Minute)
+				store.identities[0].RefreshLockedUntil = &lockedUntil
+			}
+
+			count, err := uc.RefreshExpiring(ctx, 10*time.Minute, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount {
+				t.Fatalf("refreshed %d identities, want %d", count, tt.wantCount)
+			}
+			if !tt.locked && provider.forms[0].Get("refresh_token") != "refresh-1" {
+				t.Fatalf("refresh request = %v", provider.forms[0])
+			}
+
+			got := store.identities[0]
+			if tt.wantRevoked {
+				if got.RevokedAt == nil || got.AccessToken.Valid || got.RefreshToken.Valid {
+					t.Fatalf("identity not revoked: %+v", got)
+				}
+				if len(store.audits) != 1 || store.audits[0].Action != audit.ActionIdentityRevoked {
+					t.Fatalf("audits = %+v", store.audits)
+				}
+				return
+			}
+			if got.RefreshFailures != tt.wantFailure {
+				t.Fatalf("refresh failures = %d, want %d", got.RefreshFailures, tt.wantFailure)
+			}
+			if access, _ := uc.open(ctx, secrets.ColumnAccessToken, got, got.AccessToken.String); access != tt.wantAccess {
+				t.Fatalf("access token = %q, want %q", access, tt.wantAccess)
+			}
+			if refresh, _ := uc.open(ctx, secrets.ColumnRefreshToken, got, got.RefreshToken.String); refresh != tt.wantRefresh {
+				t.Fatalf("refresh token = %q, want %q", refresh, tt.wantRefresh)
+			}
+			if tt.wantCount > 0 && !got.ExpiresAt.After(identity.ExpiresAt.Add(time.Hour-time.Minute)) {
+				t.Fatalf("expiry not extended: %v", got.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestIdentityUseCase_RefreshAfterConcurrentRotation(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeProvider(t)
+	provider.respond = func(w http.ResponseWriter, form url.Values) {
+		if form.Get("refresh_token") != "refresh-2" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{"access_token": "access-3", "refresh_token": "refresh-3", "expires_in": 3600})
+	}
+	store := &fakeIdentityStore{}
+	uc, _ := newTestUseCase(t, provider, store)
+	identity := seedIdentity(t, uc, store, time.Now().Add(time.Minute))
+
+	// Another replica rotates the tokens after this one listed the identity but before it locks it.
+	store.beforeLock = func() {
+		store.beforeLock = nil
+		accessToken, _ := uc.seal(ctx, secrets.ColumnAccessToken, "acme", "9001", "access-2")
+		refreshToken, _ := uc.seal(ctx, secrets.ColumnRefreshToken, "acme", "9001", "refresh-2")
+		if _, err := store.UpdateIdentityTokens(ctx, db.UpdateIdentityTokensParams{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresAt:    identity.ExpiresAt,
+			ID:           identity.ID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := uc.RefreshExpiring(ctx, 10*time.Minute, 100)
+	if err != nil || count != 1 {
+		t.Fatalf("RefreshExpiring() = %d, %v", count, err)
+	}
+	got := store.identities[0]
+	if got.RevokedAt != nil {
+		t.Fatal("identity revoked after a concurrent rotation")
+	}
+	if refresh, _ := uc.open(ctx, secrets.ColumnRefreshToken, got, got.RefreshToken.String); refresh != "refresh-3" {
+		t.Fatalf("refresh token = %q, want refresh-3", refresh)
+	}
+}
+
+func TestIdentityUseCase_Revoke(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeProvider(t)
+	store := &fakeIdentityStore{}
+	uc, _ := newTestUseCase(t, provider, store)
+	seedIdentity(t, uc, store, time.Now().Add(time.Hour))
+
+	if err := uc.Revoke(ctx, "42", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.forms) != 2 || provider.forms[0].Get("token") != "refresh-1" || provider.forms[1].Get("token") != "access-1" {
+		t.Fatalf("revocation requests = %v", provider.forms)
+	}
+	if store.identities[0].RevokedAt == nil || store.identities[0].AccessToken.Valid {
+		t.Fatalf("identity not revoked: %+v", store.identities[0])
+	}
+	if _, err := uc.AccessToken(ctx, "42", "acme", time.Minute); !errors.Is(err, ErrIdentityRevoked) {
+		t.Fatalf("AccessToken() err = %v", err)
+	}
+
+	if err := uc.Revoke(ctx, "42", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.forms) != 2 || len(store.audits) != 1 {
+		t.Fatalf("second revoke contacted provider or audited: %d requests, %d audits", len(provider.forms), len(store.audits))
+	}
+}
+
+// seedIdentity stores an identity for user 42 with tokens access-1 and refresh-1.
+func seedIdentity(t *testing.T, uc *IdentityUseCase, store *fakeIdentityStore, expiresAt time.Time) db.Identity {
+	t.Helper()
+	ctx := context.Background()
+	accessToken, err := uc.seal(ctx, secrets.ColumnAccessToken, "acme", "9001", "access-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken, err := uc.seal(ctx, secrets.ColumnRefreshToken, "acme", "9001", "refresh-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := store.UpsertIdentityTokens(ctx, db.UpsertIdentityTokensParams{
+		UserID:         42,
+		Provider:       "acme",
+		ProviderUserID: "9001",
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		ExpiresAt:      &expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
@@ -8,41 +8,34 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
-	"github.com/entl/evolyte-energy-provider-adapter/internal/enode"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/metrics"
 )
 
 type SolarInverterClient interface {
-	ListInverters(ctx context.Context, bearbearerToken string, after string, before string, pageSize int) (*SolarInverterResponse, error)
+	ListInverters(ctx context.Context, bearerToken string, after string, before string, pageSize int) (*SolarInverterResponse, error)
 	ListUserInverters(ctx context.Context, bearerToken string, userID string, after string, before string, pageSize int) (*SolarInverterResponse, error)
 	GetInverter(ctx context.Context, bearerToken string, inverterID string) (*SolarInverter, error)
 	GetInverterProductionStatistics(ctx context.Context, bearerToken string, inverterID string, params InverterStatisticParams) (*InverterStatistic, error)
 	LinkInverter(ctx context.Context, bearerToken string, userId string, linkBody LinkInverterRequest) (*LinkInverterResponse, error)
+	DeleteUser(ctx context.Context, bearerToken string, userID string) error
 }
 
 type EnodeSolarInverterClient struct {
-	enodeAuthClient *enode.EnodeAuthClient
-	enodeBaseURL    string
-	inverterQueries *db.Queries
-	httpClient      *http.Client
+	enodeBaseURL string
+	httpClient   *http.Client
 }
 
-func NewEnodeSolarInverterClient(authClient *enode.EnodeAuthClient, baseURL string, httpClient *http.Client, inverterQueries *db.Queries) *EnodeSolarInverterClient {
+func NewEnodeSolarInverterClient(baseURL string, httpClient *http.Client) *EnodeSolarInverterClient {
 	return &EnodeSolarInverterClient{
-		enodeAuthClient: authClient,
-		enodeBaseURL:    baseURL,
-		inverterQueries: inverterQueries,
-		httpClient:      httpClient,
+		enodeBaseURL: baseURL,
+		httpClient:   httpClient,
 	}
 }
 
 func (client *EnodeSolarInverterClient) ListInverters(ctx context.Context, bearerToken string, after string, before string, pageSize int) (*SolarInverterResponse, error) {
 	invertersBaseURL := client.enodeBaseURL + "/inverters"
-	token, err := client.enodeAuthClient.GetAccessToken()
-	if err != nil {
-		return nil, err
-	}
 
 	params := url.Values{}
 	// Add pagination parameters
@@ -53,13 +46,13 @@ func (client *EnodeSolarInverterClient) ListInverters(ctx context.Context, beare
 		fullURL += "?" + params.Encode()
 	}
 
-	req, err := http.NewRequest("GET", fullURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Authorization", bearerToken)
 
-	response, err := client.httpClient.Do(req)
+	response, err := client.do(req, "list_inverters")
 	if err != nil {
 		return nil, err
 	}
@@ -79,10 +72,6 @@ func (client *EnodeSolarInverterClient) ListInverters(ctx context.Context, beare
 
 func (client *EnodeSolarInverterClient) ListUserInverters(ctx context.Context, bearerToken string, userID string, after string, before string, pageSize int) (*SolarInverterResponse, error) {
 	invertersBaseURL := fmt.Sprintf("%s/users/%s/inverters", client.enodeBaseURL, userID)
-	token, err := client.enodeAuthClient.GetAccessToken()
-	if err != nil {
-		return nil, err
-	}
 
 	params := url.Values{}
 	// Add pagination parameters
@@ -92,16 +81,21 @@ func (client *EnodeSolarInverterClient) ListUserInverters(ctx context.Context, b
 	if len(params) > 0 {
 		fullURL += "?" + params.Encode()
 	}
-	req, err := http.NewRequest("GET", fullURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	response, err := client.httpClient.Do(req)
+	req.Header.Set("Authorization", bearerToken)
+	response, err := client.do(req, "list_user_inverters")
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, parseEnodeError(response)
+	}
+
 	var solarInverterResponse SolarInverterResponse
 	if err := json.NewDecoder(response.Body).Decode(&solarInverterResponse); err != nil {
 		return nil, err
@@ -125,18 +119,14 @@ func (client *EnodeSolarInverterClient) addPaginationParams(params *url.Values,
 
 func (client *EnodeSolarInverterClient) GetInverter(ctx context.Context, bearerToken string, inverterID string) (*SolarInverter, error) {
 	inverterURL := fmt.Sprintf("%s/inverters/%s", client.enodeBaseURL, inverterID)
-	token, err := client.enodeAuthClient.GetAccessToken()
-	if err != nil {
-		return nil, err
-	}
 
-	req, err := http.NewRequest("GET", inverterURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", inverterURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Authorization", bearerToken)
 
-	response, err := client.httpClient.Do(req)
+	response, err := client.do(req, "get_inverter")
 	if err != nil {
 		return nil, err
 	}
@@ -187,12 +177,12 @@ func (client *EnodeSolarInverterClient) GetInverterProductionStatistics(ctx cont
 	}
 
 	fullURL := inverterURL + "?" + params.Encode()
-	req, err := http.NewRequest("GET", fullURL, nil)
-	req.Header.Set("Authorization", bearerToken)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	response, err := client.httpClient.Do(req)
+	req.Header.Set("Authorization", bearerToken)
+	response, err := client.do(req, "get_inverter_statistics")
 	if err != nil {
 		return nil, err
 	}
@@ -224,7 +214,7 @@ func (client *EnodeSolarInverterClient) LinkInverter(ctx context.Context, bearer
 	req.Header.Set("Authorization", bearerToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	response, err := client.httpClient.Do(req)
+	response, err := client.do(req, "link_inverter")
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -242,6 +232,43 @@ func (client *EnodeSolarInverterClient) LinkInverter(ctx context.Context, bearer
 	return &linkResponse, nil
 }
 
+// DeleteUser removes the user at Enode, revoking every vendor connection and the data Enode holds
+// for them. A user Enode does not know is treated as already deleted.
+func (client *EnodeSolarInverterClient) DeleteUser(ctx context.Context, bearerToken string, userID string) error {
+	userURL := fmt.Sprintf("%s/users/%s", client.enodeBaseURL, userID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", userURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", bearerToken)
+
+	response, err := client.do(req, "delete_user")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusNoContent, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return parseEnodeError(response)
+	}
+}
+
+// do sends req and records its latency and outcome under the given operation name.
+func (client *EnodeSolarInverterClient) do(req *http.Request, operation string) (*http.Response, error) {
+	start := time.Now()
+	response, err := client.httpClient.Do(req)
+	status := 0
+	if err == nil {
+		status = response.StatusCode
+	}
+	metrics.ObserveEnodeCall(operation, time.Since(start), status)
+	return response, err
+}
+
 func parseEnodeError(resp *http.Response) error {
 	defer resp.Body.Close()
 
@@ -0,0 +1,117 @@
+package inverters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnodeSolarInverterClient_ListInverters(t *testing.T) {
+	tests := []struct {
+		name      string
+		after     string
+		before    string
+		pageSize  int
+		status    int
+		body      string
+		wantQuery string
+		wantErr   string
+	}{
+		{
+			name:      "forwards pagination",
+			after:     "a1",
+			pageSize:  50,
+			status:    http.StatusOK,
+			body:      `{"data":[],"pagination":{"after":"a2","before":null}}`,
+			wantQuery: "after=a1&pageSize=50",
+		},
+		{
+			name:      "maps enode error body",
+			status:    http.StatusUnauthorized,
+			body:      `{"type":"https://developers.enode.com/problems/unauthorized","title":"Unauthorized","detail":"token expired"}`,
+			wantQuery: "",
+			wantErr:   "Unauthorized - token expired",
+		},
+		{
+			name:      "unreadable error body",
+			status:    http.StatusBadGateway,
+			body:      `<html>`,
+			wantQuery: "",
+			wantErr:   "unreadable error body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/inverters" {
+					t.Errorf("path = %q", r.URL.Path)
+				}
+				if r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("query = %q, want %q", r.URL.RawQuery, tt.wantQuery)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer tok" {
+					t.Errorf("authorization = %q", got)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := NewEnodeSolarInverterClient(srv.URL, srv.Client())
+			resp, err := client.ListInverters(context.Background(), "Bearer tok", tt.after, tt.before, tt.pageSize)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var want SolarInverterResponse
+			_ = json.Unmarshal([]byte(tt.body), &want)
+			if resp.Pagination != want.Pagination {
+				t.Fatalf("pagination = %+v, want %+v", resp.Pagination, want.Pagination)
+			}
+		})
+	}
+}
+
+func TestEnodeSolarInverterClient_DeleteUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "deleted", status: http.StatusNoContent},
+		{name: "already deleted", status: http.StatusNotFound, body: `{"title":"Not Found","detail":"no such user"}`},
+		{name: "upstream error", status: http.StatusInternalServerError, body: `{"title":"Server Error","detail":"try again"}`, wantErr: "Server Error - try again"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != "/users/42" {
+					t.Errorf("request = %s %s", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := NewEnodeSolarInverterClient(srv.URL, srv.Client())
+			err := client.DeleteUser(context.Background(), "Bearer tok", "42")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
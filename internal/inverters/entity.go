@@ -1,6 +1,9 @@
 package inverters
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Response structure for solar inverter API calls
 type SolarInverterResponse struct {
@@ -74,6 +77,14 @@ type Resolution struct {
 	Data []DataPoint `json:"data"`
 }
 
+// EnergyKwh converts a data point value in this resolution's unit to kWh.
+func (r Resolution) EnergyKwh(value float64) float64 {
+	if strings.EqualFold(r.Unit, "Wh") {
+		return value / 1000
+	}
+	return value
+}
+
 type DataPoint struct {
 	Date  time.Time `json:"date"`
 	Value float64   `json:"value"`
@@ -110,6 +121,24 @@ type AddInverterResponse struct {
 	InstallationDate        time.Time `json:"installationDate" validate:"required"`
 }
 
+// UpdateInverterRequest patches a locally registered inverter; omitted fields are left unchanged.
+type UpdateInverterRequest struct {
+	Vendor                  *string    `json:"vendor" validate:"omitempty,min=1"`
+	Model                   *string    `json:"model" validate:"omitempty,min=1"`
+	SerialNumber            *string    `json:"serialNumber" validate:"omitempty,min=1"`
+	TotalLifetimeProduction *float64   `json:"totalLifetimeProduction" validate:"omitempty,gte=0"`
+	InstallationDate        *time.Time `json:"installationDate"`
+}
+
+// InverterEventPayload is the JSON body of inverter domain events written to the outbox.
+type InverterEventPayload struct {
+	Inverter        *AddInverterResponse `json:"inverter"`
+	Previous        *AddInverterResponse `json:"previous,omitempty"`
+	EnodeInverterID string               `json:"enodeInverterId,omitempty"`
+	ProductionState *ProductionState     `json:"productionState,omitempty"`
+	ThresholdKwh    float64              `json:"thresholdKwh,omitempty"`
+}
+
 type EnodeErrorResponse struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
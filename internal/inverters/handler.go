@@ -1,10 +1,13 @@
 package inverters
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/entl/evolyte-energy-provider-adapter/internal/upstream"
 	"github.com/labstack/echo/v4"
 )
 
@@ -26,9 +29,15 @@ func (h *InverterHandler) ListInverters(c echo.Context) error {
 		pageSize = 0 // Default to 0 if parsing fails
 	}
 
-	inverters, err := h.inverterUseCase.ListInverters(c.Request().Context(), after, before, pageSize)
+	var inverters *SolarInverterResponse
+	if c.QueryParam("all") == "true" {
+		inverters, err = h.inverterUseCase.ListAllInverters(c.Request().Context(), pageSize, maxItemsParam(c))
+	} else {
+		inverters, err = h.inverterUseCase.ListInverters(c.Request().Context(), after, before, pageSize)
+	}
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list inverters")
+		slog.ErrorContext(c.Request().Context(), "Failed to list inverters", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list inverters")
 	}
 
 	return c.JSON(http.StatusOK, inverters)
@@ -43,10 +52,15 @@ func (h *InverterHandler) ListUserInverters(c echo.Context) error {
 		pageSize = 0 // Default to 0 if parsing fails
 	}
 
-	inverters, err := h.inverterUseCase.ListUserInverters(c.Request().Context(), userID, after, before, pageSize)
+	var inverters *SolarInverterResponse
+	if c.QueryParam("all") == "true" {
+		inverters, err = h.inverterUseCase.ListAllUserInverters(c.Request().Context(), userID, pageSize, maxItemsParam(c))
+	} else {
+		inverters, err = h.inverterUseCase.ListUserInverters(c.Request().Context(), userID, after, before, pageSize)
+	}
 	if err != nil {
-		slog.Error("Failed to list user inverters", "userID", userID, "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list user inverters")
+		slog.ErrorContext(c.Request().Context(), "Failed to list user inverters", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list user inverters")
 	}
 
 	return c.JSON(http.StatusOK, inverters)
@@ -56,35 +70,59 @@ func (h *InverterHandler) GetInverter(c echo.Context) error {
 	inverterID := c.Param("inverterID")
 	inverter, err := h.inverterUseCase.GetInverter(c.Request().Context(), inverterID)
 	if err != nil {
-		slog.Error("Failed to get inverter", "inverterID", inverterID, "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get inverter")
+		slog.ErrorContext(c.Request().Context(), "Failed to get inverter", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get inverter")
 	}
 
 	return c.JSON(http.StatusOK, inverter)
 }
 
+func (h *InverterHandler) GetSolarPosition(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	at := time.Now()
+	if atParam := c.QueryParam("at"); atParam != "" {
+		var err error
+		at, err = time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "Invalid at parameter", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid at parameter")
+		}
+	}
+
+	position, err := h.inverterUseCase.GetSolarPosition(c.Request().Context(), inverterID, at)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get solar position", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get solar position")
+	}
+
+	return c.JSON(http.StatusOK, position)
+}
+
 func (h *InverterHandler) GetInverterProductionStatistics(c echo.Context) error {
 	inverterID := c.Param("inverterID")
 	year, err := strconv.Atoi(c.QueryParam("year"))
 	if err != nil {
-		slog.Error("Invalid year parameter", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Invalid year parameter", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid year parameter")
 	}
 	month, err := strconv.Atoi(c.QueryParam("month"))
 	if err != nil {
-		slog.Error("Invalid month parameter", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Invalid month parameter", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid month parameter")
 	}
-	day, err := strconv.Atoi(c.QueryParam("day"))
-	if err != nil {
-		slog.Error("Invalid day parameter", "error", err)
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid day parameter")
+	day := 0 // Day is optional, 0 requests monthly statistics
+	if dayParam := c.QueryParam("day"); dayParam != "" {
+		day, err = strconv.Atoi(dayParam)
+		if err != nil {
+			slog.ErrorContext(c.Request().Context(), "Invalid day parameter", "error", err)
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid day parameter")
+		}
 	}
 
 	stats, err := h.inverterUseCase.GetInverterProductionStatistics(c.Request().Context(), inverterID, year, month, day)
 	if err != nil {
-		slog.Error("Failed to get inverter production statistics", "inverterID", inverterID, "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get inverter production statistics")
+		slog.ErrorContext(c.Request().Context(), "Failed to get inverter production statistics", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get inverter production statistics")
 	}
 
 	return c.JSON(http.StatusOK, stats)
@@ -93,40 +131,111 @@ func (h *InverterHandler) GetInverterProductionStatistics(c echo.Context) error
 func (h *InverterHandler) AddInverter(c echo.Context) error {
 	var request AddInverterRequest
 	if err := c.Bind(&request); err != nil {
-		slog.Error("Failed to bind request", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
 	}
 	if err := c.Validate(request); err != nil {
-		slog.Error("Validation failed for AddInverterRequest", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Validation failed for AddInverterRequest", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed")
 	}
 
 	response, err := h.inverterUseCase.AddInverter(c.Request().Context(), request)
 	if err != nil {
-		slog.Error("Failed to add inverter", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Failed to add inverter", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add inverter")
 	}
 
 	return c.JSON(http.StatusCreated, response)
 }
 
+func (h *InverterHandler) UpdateInverter(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	var request UpdateInverterRequest
+	if err := c.Bind(&request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Validation failed for UpdateInverterRequest", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed")
+	}
+
+	response, err := h.inverterUseCase.UpdateInverter(c.Request().Context(), inverterID, request)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to update inverter", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to update inverter")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *InverterHandler) DeleteInverter(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	if err := h.inverterUseCase.DeleteInverter(c.Request().Context(), inverterID); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to delete inverter", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to delete inverter")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *InverterHandler) SyncInverter(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	response, err := h.inverterUseCase.SyncInverter(c.Request().Context(), inverterID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to sync inverter", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to sync inverter")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
 func (h *InverterHandler) LinkInverter(c echo.Context) error {
 	userID := c.Param("userID")
 	var request LinkInverterRequest
 	if err := c.Bind(&request); err != nil {
-		slog.Error("Failed to bind request", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
 	}
 	if err := c.Validate(request); err != nil {
-		slog.Error("Validation failed for LinkInverterRequest", "error", err)
+		slog.ErrorContext(c.Request().Context(), "Validation failed for LinkInverterRequest", "error", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed")
 	}
 
 	response, err := h.inverterUseCase.LinkInverter(c.Request().Context(), userID, request)
 	if err != nil {
-		slog.Error("Failed to link inverter", "userID", userID, "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to link inverter")
+		slog.ErrorContext(c.Request().Context(), "Failed to link inverter", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to link inverter")
 	}
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// maxItemsParam reads the optional maxItems query parameter, which may only lower DefaultMaxListItems.
+func maxItemsParam(c echo.Context) int {
+	maxItems, err := strconv.Atoi(c.QueryParam("maxItems"))
+	if err != nil || maxItems <= 0 || maxItems > DefaultMaxListItems {
+		return DefaultMaxListItems
+	}
+	return maxItems
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidStatisticParams), errors.Is(err, ErrInvalidInverterID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInverterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAmbiguousInverter):
+		return http.StatusConflict
+	case errors.Is(err, ErrMaxItemsExceeded), errors.Is(err, ErrLocationUnknown):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUpstreamTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, upstream.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,241 @@
+package inverters
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// serve runs a single request through an echo instance with the given route registered.
+func serve(t *testing.T, method, route, target, body string, h echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	e.Validator = utils.NewCustomValidator(validator.New())
+	e.Add(method, route, h)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestInverterHandler_ListInverters(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		clientErr    error
+		wantStatus   int
+		wantCall     listCall
+		expectCalled bool
+	}{
+		{
+			name:         "passes pagination params",
+			target:       "/inverters?after=a1&before=b1&pageSize=20",
+			wantStatus:   http.StatusOK,
+			wantCall:     listCall{bearerToken: "Bearer tok", after: "a1", before: "b1", pageSize: 20},
+			expectCalled: true,
+		},
+		{
+			name:         "invalid page size defaults to zero",
+			target:       "/inverters?pageSize=abc",
+			wantStatus:   http.StatusOK,
+			wantCall:     listCall{bearerToken: "Bearer tok"},
+			expectCalled: true,
+		},
+		{
+			name:         "upstream error",
+			target:       "/inverters",
+			clientErr:    errors.New("boom"),
+			wantStatus:   http.StatusInternalServerError,
+			wantCall:     listCall{bearerToken: "Bearer tok"},
+			expectCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSolarInverterClient{listResponse: &SolarInverterResponse{}, err: tt.clientErr}
+			h := NewInverterHandler(newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{}))
+
+			rec := serve(t, http.MethodGet, "/inverters", tt.target, "", h.ListInverters)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.expectCalled && (len(client.listCalls) != 1 || client.listCalls[0] != tt.wantCall) {
+				t.Fatalf("calls = %+v, want %+v", client.listCalls, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestInverterHandler_GetInverterProductionStatistics(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		clientErr  error
+		wantStatus int
+	}{
+		{name: "monthly without day", target: "/inverters/inv-1/stats?year=2024&month=5", wantStatus: http.StatusOK},
+		{name: "daily", target: "/inverters/inv-1/stats?year=2024&month=5&day=3", wantStatus: http.StatusOK},
+		{name: "missing year", target: "/inverters/inv-1/stats?month=5", wantStatus: http.StatusBadRequest},
+		{name: "invalid month", target: "/inverters/inv-1/stats?year=2024&month=x", wantStatus: http.StatusBadRequest},
+		{name: "invalid day", target: "/inverters/inv-1/stats?year=2024&month=5&day=x", wantStatus: http.StatusBadRequest},
+		{name: "out of range month", target: "/inverters/inv-1/stats?year=2024&month=13", wantStatus: http.StatusBadRequest},
+		{name: "upstream failure", target: "/inverters/inv-1/stats?year=2024&month=5", clientErr: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSolarInverterClient{statistic: &InverterStatistic{Timezone: "UTC"}, err: tt.clientErr}
+			h := NewInverterHandler(newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{}))
+
+			rec := serve(t, http.MethodGet, "/inverters/:inverterID/stats", tt.target, "", h.GetInverterProductionStatistics)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && client.lastInverter != "inv-1" {
+				t.Fatalf("inverter id = %q", client.lastInverter)
+			}
+		})
+	}
+}
+
+func TestInverterHandler_LinkInverter(t *testing.T) {
+	validBody := `{"scopes":["inverter:read:data"],"language":"en-US","redirectUri":"https://example.com"}`
+	tests := []struct {
+		name       string
+		body       string
+		client     *fakeSolarInverterClient
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       validBody,
+			client:     &fakeSolarInverterClient{linkResponse: &LinkInverterResponse{LinkURL: "u", LinkToken: "t"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "malformed body",
+			body:       `{"scopes":`,
+			client:     &fakeSolarInverterClient{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "validation failure",
+			body:       `{"language":"en-US"}`,
+			client:     &fakeSolarInverterClient{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "upstream timeout",
+			body:       validBody,
+			client:     &fakeSolarInverterClient{block: true},
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "upstream error",
+			body:       validBody,
+			client:     &fakeSolarInverterClient{err: errors.New("boom")},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestUseCase(tt.client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{})
+			uc.timeouts.Link = 20 * time.Millisecond
+			h := NewInverterHandler(uc)
+
+			rec := serve(t, http.MethodPost, "/users/:userID/link", "/users/u1/link", tt.body, h.LinkInverter)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestInverterHandler_AddInverter(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		storeErr   error
+		wantStatus int
+	}{
+		{
+			name:       "created",
+			body:       `{"userId":"7","vendor":"SMA","model":"X","serialNumber":"SN","totalLifetimeProduction":1.5,"installationDate":"2023-01-01T00:00:00Z"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing fields",
+			body:       `{"userId":"7"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "store failure",
+			body:       `{"userId":"7","vendor":"SMA","model":"X","serialNumber":"SN","totalLifetimeProduction":1.5,"installationDate":"2023-01-01T00:00:00Z"}`,
+			storeErr:   errors.New("db down"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewInverterHandler(newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, &fakeInverterStore{err: tt.storeErr}))
+
+			rec := serve(t, http.MethodPost, "/inverters", "/inverters", tt.body, h.AddInverter)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestInverterHandler_GetSolarPosition(t *testing.T) {
+	london := &SolarInverter{ID: "inv-1", IsReachable: true, Timezone: "Europe/London", Location: Location{Latitude: 51.5, Longitude: -0.13}}
+	tests := []struct {
+		name          string
+		target        string
+		inverter      *SolarInverter
+		wantStatus    int
+		wantDaylight  bool
+		wantTimestamp string
+	}{
+		{name: "midday", target: "/inverters/inv-1/solar-position?at=2024-06-21T12:00:00Z", inverter: london, wantStatus: http.StatusOK, wantDaylight: true, wantTimestamp: `"time":"2024-06-21T13:00:00+01:00"`},
+		{name: "midnight", target: "/inverters/inv-1/solar-position?at=2024-06-21T00:00:00Z", inverter: london, wantStatus: http.StatusOK, wantDaylight: false, wantTimestamp: `"sunrise":"2024-06-21T04:4`},
+		{name: "invalid time", target: "/inverters/inv-1/solar-position?at=noon", inverter: london, wantStatus: http.StatusBadRequest},
+		{name: "unknown location", target: "/inverters/inv-1/solar-position", inverter: &SolarInverter{ID: "inv-1"}, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSolarInverterClient{inverter: tt.inverter}
+			h := NewInverterHandler(newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{}))
+
+			rec := serve(t, http.MethodGet, "/inverters/:inverterID/solar-position", tt.target, "", h.GetSolarPosition)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.wantTimestamp) {
+				t.Fatalf("body %s does not contain %s", body, tt.wantTimestamp)
+			}
+			if daylight := strings.Contains(body, `"isDaylight":true`); daylight != tt.wantDaylight {
+				t.Fatalf("daylight = %v, want %v", daylight, tt.wantDaylight)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrImportRejected is returned when any row fails validation. Nothing is written in that case.
+var ErrImportRejected = errors.New("import rejected")
+
+var (
+	inverterImportColumns = []string{"user_id", "vendor", "model", "serial_number", "total_lifetime_production_kwh", "installation_date"}
+	readingImportColumns  = []string{"serial_number", "timestamp", "energy_kwh"}
+)
+
+// importFieldColumns maps validated struct fields back to the file column they came from.
+var importFieldColumns = map[string]string{
+	"UserID":                  "user_id",
+	"Vendor":                  "vendor",
+	"Model":                   "model",
+	"SerialNumber":            "serial_number",
+	"TotalLifetimeProduction": "total_lifetime_production_kwh",
+	"InstallationDate":        "installation_date",
+	"Timestamp":               "timestamp",
+	"EnergyKwh":               "energy_kwh",
+	"PowerOutputKw":           "power_output_kw",
+}
+
+// ImportReading is one hourly production reading for an imported or existing inverter. UserID
+// is optional and only needed when the serial number belongs to inverters of several users.
+type ImportReading struct {
+	UserID        string
+	SerialNumber  string    `validate:"required"`
+	Timestamp     time.Time `validate:"required"`
+	EnergyKwh     float64   `validate:"gte=0"`
+	PowerOutputKw float64   `validate:"gte=0"`
+}
+
+type ImportRowError struct {
+	File    string `json:"file"`
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	DryRun    bool                  `json:"dryRun"`
+	Inverters int                   `json:"inverters"`
+	Readings  int                   `json:"readings"`
+	Created   []AddInverterResponse `json:"created,omitempty"`
+	Errors    []ImportRowError      `json:"errors"`
+}
+
+// Importer registers inverters, and optionally their production history, in bulk from files.
+type Importer struct {
+	store     InverterStore
+	validator *utils.CustomValidator
+	maxRows   int
+}
+
+func NewImporter(store InverterStore, validator *utils.CustomValidator, maxRows int) *Importer {
+	return &Importer{
+		store:     store,
+		validator: validator,
+		maxRows:   maxRows,
+	}
+}
+
+// inverterKey identifies an inverter in an import. Serial numbers are only unique per user.
+type inverterKey struct {
+	userID int32
+	serial string
+}
+
+// importInverter is a validated inverter row.
+type importInverter struct {
+	row     int
+	key     inverterKey
+	request AddInverterRequest
+}
+
+// importReading is a validated reading row. target is the inverter it resolved to.
+type importReading struct {
+	row     int
+	reading ImportReading
+	target  inverterKey
+}
+
+// Import validates every row of the inverters file and the optional readings file. If any row
+// is invalid, the result lists every error and ErrImportRejected is returned. Otherwise all
+// inverters and readings are written in one transaction, unless dryRun is set.
+func (im *Importer) Import(ctx context.Context, inverterFile ImportFile, readingFile *ImportFile, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+
+	inverterTable, err := readImportTable(inverterFile, im.maxRows, inverterImportColumns...)
+	if err != nil {
+		return nil, err
+	}
+	inverters := im.parseInverters(inverterTable, result)
+
+	// Inverters a user already registered are rejected as inverters but accepted for readings.
+	serials := make([]string, 0, len(inverters))
+	for _, inverter := range inverters {
+		serials = append(serials, inverter.request.SerialNumber)
+	}
+	var readingTable *importTable
+	var readings []importReading
+	if readingFile != nil {
+		if readingTable, err = readImportTable(*readingFile, im.maxRows, readingImportColumns...); err != nil {
+			return nil, err
+		}
+		readings = im.parseReadings(readingTable, result)
+		for _, reading := range readings {
+			serials = append(serials, reading.reading.SerialNumber)
+		}
+	}
+	existing, err := im.store.ListInvertersBySerialNumbers(ctx, serials)
+	if err != nil {
+		return nil, fmt.Errorf("looking up serial numbers: %w", err)
+	}
+	registered := make(map[inverterKey][]db.Inverter, len(existing))
+	for _, inverter := range existing {
+		key := inverterKey{userID: inverter.UserID, serial: inverter.SerialNumber}
+		registered[key] = append(registered[key], inverter)
+	}
+
+	imported := make(map[inverterKey]bool, len(inverters))
+	for _, inverter := range inverters {
+		switch {
+		case imported[inverter.key]:
+			result.addError(inverterTable, inverter.row, "serial_number", "serial number appears more than once for this user in the file")
+		case len(registered[inverter.key]) > 0:
+			result.addError(inverterTable, inverter.row, "serial_number", "the user already has an inverter with this serial number")
+		default:
+			imported[inverter.key] = true
+		}
+	}
+	if readingTable != nil {
+		if err := im.checkReadings(ctx, readingTable, readings, imported, registered, result); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Inverters = len(inverters)
+	result.Readings = len(readings)
+	if len(result.Errors) > 0 {
+		return result, ErrImportRejected
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	created, err := im.commit(ctx, inverters, readings, registered)
+	if err != nil {
+		return nil, err
+	}
+	result.Created = created
+	return result, nil
+}
+
+func (im *Importer) parseInverters(table *importTable, result *ImportResult) []importInverter {
+	var inverters []importInverter
+	for i := range table.rows {
+		if table.isBlank(i) {
+			continue
+		}
+		row := i + 2
+		request := AddInverterRequest{
+			UserID:       table.value(i, "user_id"),
+			Vendor:       table.value(i, "vendor"),
+			Model:        table.value(i, "model"),
+			SerialNumber: table.value(i, "serial_number"),
+		}
+		failed := make(map[string]bool)
+		var err error
+		if request.TotalLifetimeProduction, err = table.float(i, "total_lifetime_production_kwh"); err != nil {
+			failed["total_lifetime_production_kwh"] = true
+			result.addError(table, row, "total_lifetime_production_kwh", "must be a number")
+		}
+		if request.InstallationDate, err = table.time(i, "installation_date"); err != nil {
+			failed["installation_date"] = true
+			result.addError(table, row, "installation_date", err.Error())
+		}
+		userID, err := strconv.ParseInt(request.UserID, 10, 32)
+		if request.UserID != "" && err != nil {
+			failed["user_id"] = true
+			result.addError(table, row, "user_id", "must be a numeric user ID")
+		}
+		if !im.validate(table, row, request, failed, result) || len(failed) > 0 {
+			continue
+		}
+		key := inverterKey{userID: int32(userID), serial: request.SerialNumber}
+		inverters = append(inverters, importInverter{row: row, key: key, request: request})
+	}
+	return inverters
+}
+
+func (im *Importer) parseReadings(table *importTable, result *ImportResult) []importReading {
+	_, hasPower := table.columns["power_output_kw"]
+	var readings []importReading
+	for i := range table.rows {
+		if table.isBlank(i) {
+			continue
+		}
+		row := i + 2
+		reading := ImportReading{UserID: table.value(i, "user_id"), SerialNumber: table.value(i, "serial_number")}
+		failed := make(map[string]bool)
+		var err error
+		if _, err := strconv.ParseInt(reading.UserID, 10, 32); reading.UserID != "" && err != nil {
+			failed["user_id"] = true
+			result.addError(table, row, "user_id", "must be a numeric user ID")
+		}
+		if reading.Timestamp, err = table.time(i, "timestamp"); err != nil {
+			failed["timestamp"] = true
+			result.addError(table, row, "timestamp", err.Error())
+		} else if reading.Timestamp = reading.Timestamp.UTC(); !reading.Timestamp.Equal(reading.Timestamp.Truncate(time.Hour)) {
+			failed["timestamp"] = true
+			result.addError(table, row, "timestamp", "must be on the hour")
+		}
+		if reading.EnergyKwh, err = table.float(i, "energy_kwh"); err != nil {
+			failed["energy_kwh"] = true
+			result.addError(table, row, "energy_kwh", "must be a number")
+		}
+		hasPowerValue := hasPower && table.value(i, "power_output_kw") != ""
+		if hasPowerValue {
+			if reading.PowerOutputKw, err = table.float(i, "power_output_kw"); err != nil {
+				failed["power_output_kw"] = true
+				result.addError(table, row, "power_output_kw", "must be a number")
+			}
+		}
+		if !im.validate(table, row, reading, failed, result) || len(failed) > 0 {
+			continue
+		}
+		if !hasPowerValue {
+			// An hourly reading without power is assumed to have produced at a constant rate.
+			reading.PowerOutputKw = reading.EnergyKwh
+		}
+		readings = append(readings, importReading{row: row, reading: reading})
+	}
+	return readings
+}
+
+// checkReadings resolves each reading to its inverter and rejects readings for unknown or
+// ambiguous inverters, duplicates within the file, and hours already recorded for registered
+// inverters.
+func (im *Importer) checkReadings(ctx context.Context, table *importTable, readings []importReading, imported map[inverterKey]bool, registered map[inverterKey][]db.Inverter, result *ImportResult) error {
+	type key struct {
+		target inverterKey
+		hour   time.Time
+	}
+	// Inverters that are already registered are never imported, so the two sets do not overlap.
+	bySerial := make(map[string][]inverterKey, len(imported)+len(registered))
+	for k := range imported {
+		bySerial[k.serial] = append(bySerial[k.serial], k)
+	}
+	for k := range registered {
+		bySerial[k.serial] = append(bySerial[k.serial], k)
+	}
+
+	seen := make(map[key]bool, len(readings))
+	byInverter := make(map[int32][]importReading)
+	for i := range readings {
+		reading := &readings[i]
+		targets := bySerial[reading.reading.SerialNumber]
+		if reading.reading.UserID != "" {
+			userID, _ := strconv.ParseInt(reading.reading.UserID, 10, 32)
+			targets = slices.DeleteFunc(slices.Clone(targets), func(k inverterKey) bool { return k.userID != int32(userID) })
+		}
+		if len(targets) == 1 {
+			reading.target = targets[0]
+		}
+		k := key{target: reading.target, hour: reading.reading.Timestamp}
+		switch {
+		case len(targets) == 0:
+			result.addError(table, reading.row, "serial_number", "no inverter with this serial number in the file or registered")
+		case len(targets) > 1:
+			result.addError(table, reading.row, "serial_number", "serial number belongs to inverters of several users; set user_id")
+		case !imported[reading.target] && len(registered[reading.target]) > 1:
+			result.addError(table, reading.row, "serial_number", "the user has more than one inverter with this serial number")
+		case seen[k]:
+			result.addError(table, reading.row, "timestamp", "reading appears more than once in the file")
+		case !imported[reading.target]:
+			id := registered[reading.target][0].ID
+			byInverter[id] = append(byInverter[id], *reading)
+		}
+		seen[k] = true
+	}
+
+	for inverterID, inverterReadings := range byInverter {
+		timestamps := make([]pgtype.Timestamp, 0, len(inverterReadings))
+		for _, reading := range inverterReadings {
+			timestamps = append(timestamps, pgtype.Timestamp{Time: reading.reading.Timestamp, Valid: true})
+		}
+		recorded, err := im.store.ListHourlyRecordTimestamps(ctx, db.ListHourlyRecordTimestampsParams{InverterID: inverterID, Timestamps: timestamps})
+		if err != nil {
+			return fmt.Errorf("checking recorded hours: %w", err)
+		}
+		exists := make(map[time.Time]bool, len(recorded))
+		for _, ts := range recorded {
+			exists[ts.Time] = true
+		}
+		for _, reading := range inverterReadings {
+			if exists[reading.reading.Timestamp] {
+				result.addError(table, reading.row, "timestamp", "a reading for this hour is already recorded")
+			}
+		}
+	}
+	return nil
+}
+
+func (im *Importer) commit(ctx context.Context, inverters []importInverter, readings []importReading, registered map[inverterKey][]db.Inverter) ([]AddInverterResponse, error) {
+	created := make([]AddInverterResponse, 0, len(inverters))
+	err := im.store.InTx(ctx, func(store InverterStore) error {
+		ids := make(map[inverterKey]int32, len(inverters)+len(registered))
+		for key, matches := range registered {
+			ids[key] = matches[0].ID
+		}
+
+		now := time.Now()
+		for _, inverter := range inverters {
+			row, err := store.CreateInverter(ctx, db.CreateInverterParams{
+				UserID:                     inverter.key.userID,
+				Vendor:                     inverter.request.Vendor,
+				Model:                      inverter.request.Model,
+				SerialNumber:               inverter.request.SerialNumber,
+				InstallationDate:           inverter.request.InstallationDate,
+				TotalLifetimeProductionKwh: inverter.request.TotalLifetimeProduction,
+				CreatedAt:                  now,
+				UpdatedAt:                  now,
+			})
+			if err != nil {
+				return fmt.Errorf("creating inverter from row %d: %w", inverter.row, err)
+			}
+			if err := recordInverterAudit(ctx, store, audit.ActionInverterCreated, nil, newInverterResponse(row)); err != nil {
+				return err
+			}
+			if err := recordInverterEvent(ctx, store, outbox.EventInverterCreated, InverterEventPayload{Inverter: newInverterResponse(row)}); err != nil {
+				return err
+			}
+			ids[inverter.key] = row.ID
+			created = append(created, *newInverterResponse(row))
+		}
+
+		if len(readings) == 0 {
+			return nil
+		}
+		records := make([]db.InsertHourlyRecordsParams, 0, len(readings))
+		for _, reading := range readings {
+			records = append(records, db.InsertHourlyRecordsParams{
+				InverterID:         ids[reading.target],
+				Timestamp:          pgtype.Timestamp{Time: reading.reading.Timestamp, Valid: true},
+				PowerOutputKw:      reading.reading.PowerOutputKw,
+				EnergyGeneratedKwh: reading.reading.EnergyKwh,
+			})
+		}
+		if _, err := store.InsertHourlyRecords(ctx, records); err != nil {
+			return fmt.Errorf("inserting readings: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import inverters: %w", err)
+	}
+	return created, nil
+}
+
+// validate runs CustomValidator on v and records one error per failing field. Columns in
+// failed could not be parsed and were already reported.
+func (im *Importer) validate(table *importTable, row int, v any, failed map[string]bool, result *ImportResult) bool {
+	err := im.validator.Validate(v)
+	if err == nil {
+		return true
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		result.addError(table, row, "", err.Error())
+		return false
+	}
+	for _, fieldError := range fieldErrors {
+		column := importFieldColumns[fieldError.Field()]
+		if !failed[column] {
+			result.addError(table, row, column, validationMessage(fieldError))
+		}
+	}
+	return false
+}
+
+func validationMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "gte":
+		return "must be at least " + fieldError.Param()
+	default:
+		return "failed " + fieldError.Tag() + " validation"
+	}
+}
+
+func (r *ImportResult) addError(table *importTable, row int, column string, message string) {
+	r.Errors = append(r.Errors, ImportRowError{File: table.name, Row: row, Column: column, Message: message})
+}
@@ -0,0 +1,82 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+)
+
+const importUsage = `usage: evolyte-energy-provider-adapter import [flags] <inverters.csv|inverters.xlsx>
+
+flags:
+  -readings FILE   CSV or XLSX of hourly production readings to import with the inverters
+  -dry-run         validate every row without writing anything
+  -actor NAME      who is importing, recorded in the audit log (default $USER)
+`
+
+// RunImportCLI executes the import subcommand described by args, writing row errors and a
+// summary to out.
+func RunImportCLI(ctx context.Context, importer *Importer, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() { fmt.Fprint(out, importUsage) }
+	readingsPath := flags.String("readings", "", "CSV or XLSX of hourly production readings")
+	dryRun := flags.Bool("dry-run", false, "validate every row without writing anything")
+	actor := flags.String("actor", os.Getenv("USER"), "who is importing, recorded in the audit log")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(out, importUsage)
+		return fmt.Errorf("expected one inverters file, got %d", flags.NArg())
+	}
+
+	inverterFile, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer inverterFile.Close()
+
+	var readings *ImportFile
+	if *readingsPath != "" {
+		readingFile, err := os.Open(*readingsPath)
+		if err != nil {
+			return err
+		}
+		defer readingFile.Close()
+		readings = &ImportFile{Name: readingFile.Name(), Reader: readingFile}
+	}
+
+	if *actor != "" {
+		ctx = audit.WithActor(ctx, audit.Actor{ID: *actor})
+	}
+	result, err := importer.Import(ctx, ImportFile{Name: inverterFile.Name(), Reader: inverterFile}, readings, *dryRun)
+	if errors.Is(err, ErrImportRejected) {
+		for _, rowError := range result.Errors {
+			if rowError.Column == "" {
+				fmt.Fprintf(out, "%s:%d: %s\n", rowError.File, rowError.Row, rowError.Message)
+				continue
+			}
+			fmt.Fprintf(out, "%s:%d: %s %s\n", rowError.File, rowError.Row, rowError.Column, rowError.Message)
+		}
+		return fmt.Errorf("%w: %d invalid rows, nothing was imported", err, len(result.Errors))
+	}
+	if err != nil {
+		return err
+	}
+
+	if result.DryRun {
+		fmt.Fprintf(out, "validated %d inverters and %d readings, nothing was written (dry run)\n", result.Inverters, result.Readings)
+		return nil
+	}
+	for _, inverter := range result.Created {
+		fmt.Fprintf(out, "created inverter %s (serial %s)\n", inverter.ID, inverter.SerialNumber)
+	}
+	fmt.Fprintf(out, "imported %d inverters and %d readings\n", result.Inverters, result.Readings)
+	return nil
+}
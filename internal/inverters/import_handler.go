@@ -0,0 +1,76 @@
+package inverters
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ImportHandler struct {
+	importer *Importer
+}
+
+func NewImportHandler(importer *Importer) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+	}
+}
+
+// ImportInverters accepts a multipart form with an inverters file and an optional readings
+// file. With dryRun=true every row is validated but nothing is written.
+func (h *ImportHandler) ImportInverters(c echo.Context) error {
+	dryRun := false
+	if raw := c.QueryParam("dryRun"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid dryRun")
+		}
+		dryRun = parsed
+	}
+
+	inverterHeader, err := c.FormFile("inverters")
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Missing inverters file", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Missing inverters file")
+	}
+	inverterFile, err := inverterHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unreadable inverters file")
+	}
+	defer inverterFile.Close()
+
+	var readings *ImportFile
+	readingHeader, err := c.FormFile("readings")
+	switch {
+	case errors.Is(err, http.ErrMissingFile):
+	case err != nil:
+		return echo.NewHTTPError(http.StatusBadRequest, "Unreadable readings file")
+	default:
+		readingFile, err := readingHeader.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unreadable readings file")
+		}
+		defer readingFile.Close()
+		readings = &ImportFile{Name: readingHeader.Filename, Reader: readingFile}
+	}
+
+	result, err := h.importer.Import(c.Request().Context(), ImportFile{Name: inverterHeader.Filename, Reader: inverterFile}, readings, dryRun)
+	switch {
+	case errors.Is(err, ErrImportRejected):
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	case errors.Is(err, ErrInvalidImportFile):
+		// File-level problems such as a missing column are safe and necessary to report.
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		slog.ErrorContext(c.Request().Context(), "Failed to import inverters", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to import inverters")
+	}
+
+	if dryRun {
+		return c.JSON(http.StatusOK, result)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,137 @@
+package inverters
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+// ImportFile is an uploaded CSV or XLSX file. The format is taken from the name's extension.
+type ImportFile struct {
+	Name   string
+	Reader io.Reader
+}
+
+// importTable is the content of an import file with columns addressed by normalized header name.
+type importTable struct {
+	name    string
+	columns map[string]int
+	rows    [][]string
+	// excel is set for XLSX files, whose dates may arrive as serial day numbers.
+	excel bool
+}
+
+// readImportTable reads the first sheet of an XLSX file, or a CSV file, and checks that every
+// required column is present. Row numbers in errors count the header as row 1.
+func readImportTable(file ImportFile, maxRows int, required ...string) (*importTable, error) {
+	var records [][]string
+	var err error
+	excel := false
+	switch strings.ToLower(filepath.Ext(file.Name)) {
+	case ".csv":
+		reader := csv.NewReader(file.Reader)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err = reader.ReadAll()
+	case ".xlsx":
+		excel = true
+		records, err = readFirstSheet(file.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %s: expected a .csv or .xlsx file", ErrInvalidImportFile, file.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImportFile, file.Name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: %s: missing header row", ErrInvalidImportFile, file.Name)
+	}
+	if len(records)-1 > maxRows {
+		return nil, fmt.Errorf("%w: %s: %d rows exceeds the limit of %d", ErrInvalidImportFile, file.Name, len(records)-1, maxRows)
+	}
+
+	table := &importTable{name: file.Name, columns: make(map[string]int), rows: records[1:], excel: excel}
+	for i, header := range records[0] {
+		table.columns[normalizeHeader(header)] = i
+	}
+	for _, column := range required {
+		if _, ok := table.columns[column]; !ok {
+			return nil, fmt.Errorf("%w: %s: missing column %q", ErrInvalidImportFile, file.Name, column)
+		}
+	}
+	return table, nil
+}
+
+func readFirstSheet(r io.Reader) ([][]string, error) {
+	workbook, err := excelize.OpenReader(r, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	defer workbook.Close()
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	return workbook.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+// normalizeHeader maps headers such as "Serial Number" to serial_number.
+func normalizeHeader(header string) string {
+	header = strings.TrimPrefix(header, "\ufeff")
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.Join(strings.Fields(header), "_")
+}
+
+// isBlank reports whether every cell of row i is empty, so trailing spreadsheet rows can be skipped.
+func (t *importTable) isBlank(i int) bool {
+	for _, cell := range t.rows[i] {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *importTable) value(i int, column string) string {
+	index, ok := t.columns[column]
+	if !ok || index >= len(t.rows[i]) {
+		return ""
+	}
+	return strings.TrimSpace(t.rows[i][index])
+}
+
+func (t *importTable) float(i int, column string) (float64, error) {
+	raw := t.value(i, column)
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// time parses dates and timestamps without a zone as UTC. XLSX cells holding a serial day
+// number are converted from Excel's date system.
+func (t *importTable) time(i int, column string) (time.Time, error) {
+	raw := t.value(i, column)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", time.DateOnly} {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			return parsed, nil
+		}
+	}
+	if t.excel {
+		if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+			return excelize.ExcelDateToTime(serial, false)
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", raw)
+}
@@ -0,0 +1,321 @@
+package inverters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+)
+
+const importedInverters = `User ID,Vendor,Model,Serial Number,Total Lifetime Production kWh,Installation Date
+42,SMA,Sunny Boy,SN-10,1200.5,2023-04-01
+42,Fronius,Primo,SN-11,800,2023-05-15
+`
+
+const importedReadings = `serial_number,timestamp,energy_kwh,power_output_kw
+SN-10,2024-06-21T10:00:00Z,1.5,1.6
+SN-10,2024-06-21T11:00:00Z,2,
+SN-1,2024-06-21 12:00,0.5,
+`
+
+func newTestImporter(store *fakeInverterStore) *Importer {
+	return NewImporter(store, utils.NewCustomValidator(validator.New()), 1000)
+}
+
+func csvFile(name string, content string) ImportFile {
+	return ImportFile{Name: name, Reader: strings.NewReader(content)}
+}
+
+func TestImporter_Import(t *testing.T) {
+	store := seededStore(100)
+	readings := csvFile("readings.csv", importedReadings)
+
+	result, err := newTestImporter(store).Import(context.Background(), csvFile("inverters.csv", importedInverters), &readings, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (errors %+v)", err, result)
+	}
+	if result.Inverters != 2 || result.Readings != 3 || len(result.Created) != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(store.created) != 2 || len(store.events) != 2 {
+		t.Fatalf("created %d inverters and %d events, want 2 each", len(store.created), len(store.events))
+	}
+	if got := store.created[0].InstallationDate; !got.Equal(time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("installation date = %v", got)
+	}
+
+	if len(store.records) != 3 {
+		t.Fatalf("records = %+v", store.records)
+	}
+	// SN-10 is the first created inverter; SN-1 was already registered with ID 1.
+	if r := store.records[0]; r.InverterID != 2 || r.EnergyGeneratedKwh != 1.5 || r.PowerOutputKw != 1.6 {
+		t.Fatalf("first record = %+v", r)
+	}
+	if r := store.records[1]; r.PowerOutputKw != 2 {
+		t.Fatalf("power defaults to hourly energy, got %+v", r)
+	}
+	if r := store.records[2]; r.InverterID != 1 || !r.Timestamp.Time.Equal(time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("reading for registered inverter = %+v", r)
+	}
+}
+
+func TestImporter_DryRunWritesNothing(t *testing.T) {
+	store := seededStore(100)
+	readings := csvFile("readings.csv", importedReadings)
+
+	result, err := newTestImporter(store).Import(context.Background(), csvFile("inverters.csv", importedInverters), &readings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.DryRun || result.Inverters != 2 || result.Readings != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(store.created) != 0 || len(store.records) != 0 || len(store.events) != 0 {
+		t.Fatal("dry run wrote to the store")
+	}
+}
+
+func TestImporter_RowErrors(t *testing.T) {
+	store := seededStore(100)
+	store.records = []db.InsertHourlyRecordsParams{
+		{InverterID: 1, Timestamp: pgtype.Timestamp{Time: time.Date(2024, time.June, 21, 9, 0, 0, 0, time.UTC), Valid: true}},
+	}
+	inverters := csvFile("inverters.csv", `user_id,vendor,model,serial_number,total_lifetime_production_kwh,installation_date
+42,,X,SN-20,10,2023-01-01
+42,SMA,X,SN-21,ten,2023-01-01
+42,SMA,X,SN-22,10,2023-01-01
+42,SMA,X,SN-22,10,2023-01-01
+42,SMA,X,SN-1,10,2023-01-01
+abc,SMA,X,SN-23,10,yesterday
+`)
+	readings := csvFile("readings.csv", `serial_number,timestamp,energy_kwh
+SN-22,2024-06-21T10:30:00Z,1
+SN-22,2024-06-21T11:00:00Z,1
+SN-22,2024-06-21T11:00:00Z,1
+SN-99,2024-06-21T11:00:00Z,1
+SN-1,2024-06-21T09:00:00Z,1
+SN-22,2024-06-21T12:00:00Z,-1
+`)
+
+	result, err := newTestImporter(store).Import(context.Background(), inverters, &readings, false)
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("err = %v, want ErrImportRejected", err)
+	}
+
+	want := []ImportRowError{
+		{File: "inverters.csv", Row: 2, Column: "vendor", Message: "is required"},
+		{File: "inverters.csv", Row: 3, Column: "total_lifetime_production_kwh", Message: "must be a number"},
+		{File: "inverters.csv", Row: 7, Column: "installation_date", Message: `unrecognized date "yesterday"`},
+		{File: "inverters.csv", Row: 7, Column: "user_id", Message: "must be a numeric user ID"},
+		{File: "readings.csv", Row: 2, Column: "timestamp", Message: "must be on the hour"},
+		{File: "readings.csv", Row: 7, Column: "energy_kwh", Message: "must be at least 0"},
+		{File: "inverters.csv", Row: 5, Column: "serial_number", Message: "serial number appears more than once for this user in the file"},
+		{File: "inverters.csv", Row: 6, Column: "serial_number", Message: "the user already has an inverter with this serial number"},
+		{File: "readings.csv", Row: 4, Column: "timestamp", Message: "reading appears more than once in the file"},
+		{File: "readings.csv", Row: 5, Column: "serial_number", Message: "no inverter with this serial number in the file or registered"},
+		{File: "readings.csv", Row: 6, Column: "timestamp", Message: "a reading for this hour is already recorded"},
+	}
+	if len(result.Errors) != len(want) {
+		t.Fatalf("errors = %+v", result.Errors)
+	}
+	for i := range want {
+		if result.Errors[i] != want[i] {
+			t.Errorf("error %d = %+v, want %+v", i, result.Errors[i], want[i])
+		}
+	}
+	if len(store.created) != 0 || len(store.records) != 1 {
+		t.Fatal("rejected import wrote to the store")
+	}
+}
+
+func TestImporter_SerialNumberOfAnotherUser(t *testing.T) {
+	// User 42 already has SN-1; user 7 registers an inverter with the same serial number.
+	inverters := `user_id,vendor,model,serial_number,total_lifetime_production_kwh,installation_date
+7,SMA,X,SN-1,10,2023-01-01
+`
+	tests := []struct {
+		name        string
+		readings    string
+		wantErrors  []ImportRowError
+		wantRecords []int32
+	}{
+		{
+			name: "readings name the user",
+			readings: `user_id,serial_number,timestamp,energy_kwh
+42,SN-1,2024-06-21T10:00:00Z,1
+7,SN-1,2024-06-21T10:00:00Z,1
+`,
+			wantRecords: []int32{1, 2},
+		},
+		{
+			name: "readings without a user are ambiguous",
+			readings: `serial_number,timestamp,energy_kwh
+SN-1,2024-06-21T10:00:00Z,1
+`,
+			wantErrors: []ImportRowError{
+				{File: "readings.csv", Row: 2, Column: "serial_number", Message: "serial number belongs to inverters of several users; set user_id"},
+			},
+		},
+		{
+			name: "user without the serial number",
+			readings: `user_id,serial_number,timestamp,energy_kwh
+8,SN-1,2024-06-21T10:00:00Z,1
+`,
+			wantErrors: []ImportRowError{
+				{File: "readings.csv", Row: 2, Column: "serial_number", Message: "no inverter with this serial number in the file or registered"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := seededStore(100)
+			readings := csvFile("readings.csv", tt.readings)
+
+			result, err := newTestImporter(store).Import(context.Background(), csvFile("inverters.csv", inverters), &readings, false)
+			if (err != nil) != (tt.wantErrors != nil) {
+				t.Fatalf("err = %v, result %+v", err, result)
+			}
+			if tt.wantErrors != nil {
+				if !slices.Equal(result.Errors, tt.wantErrors) {
+					t.Fatalf("errors = %+v, want %+v", result.Errors, tt.wantErrors)
+				}
+				return
+			}
+			if len(store.created) != 1 || store.created[0].UserID != 7 {
+				t.Fatalf("created = %+v", store.created)
+			}
+			var got []int32
+			for _, record := range store.records {
+				got = append(got, record.InverterID)
+			}
+			if !slices.Equal(got, tt.wantRecords) {
+				t.Fatalf("records for inverters %v, want %v", got, tt.wantRecords)
+			}
+		})
+	}
+}
+
+func TestImporter_XLSX(t *testing.T) {
+	workbook := excelize.NewFile()
+	sheet := workbook.GetSheetName(0)
+	rows := [][]any{
+		{"user_id", "vendor", "model", "serial_number", "total_lifetime_production_kwh", "installation_date"},
+		// 45017 is 2023-04-01 in Excel's 1900 date system.
+		{42, "SMA", "Sunny Boy", "SN-30", 1200.5, 45017},
+		{},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := workbook.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := workbook.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	store := seededStore(100)
+	result, err := newTestImporter(store).Import(context.Background(), ImportFile{Name: "fleet.XLSX", Reader: &buf}, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (result %+v)", err, result)
+	}
+	if len(store.created) != 1 {
+		t.Fatalf("created = %+v", store.created)
+	}
+	created := store.created[0]
+	if created.TotalLifetimeProductionKwh != 1200.5 || !created.InstallationDate.Equal(time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("created = %+v", created)
+	}
+}
+
+func TestImporter_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		file ImportFile
+	}{
+		{name: "unsupported extension", file: csvFile("inverters.json", "[]")},
+		{name: "missing column", file: csvFile("inverters.csv", "user_id,vendor\n42,SMA\n")},
+		{name: "empty", file: csvFile("inverters.csv", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestImporter(seededStore(100)).Import(context.Background(), tt.file, nil, false)
+			if !errors.Is(err, ErrInvalidImportFile) {
+				t.Fatalf("err = %v, want ErrInvalidImportFile", err)
+			}
+		})
+	}
+}
+
+func TestImportHandler_ImportInverters(t *testing.T) {
+	upload := func(t *testing.T, target string, files map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		for field, content := range files {
+			part, err := form.CreateFormFile(field, field+".csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(content))
+		}
+		form.Close()
+
+		e := echo.New()
+		handler := NewImportHandler(newTestImporter(seededStore(100)))
+		e.POST("/api/v1/inverters/import", handler.ImportInverters)
+		req := httptest.NewRequest(http.MethodPost, target, &body)
+		req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		files      map[string]string
+		wantStatus int
+		wantErrors int
+	}{
+		{name: "created", target: "/api/v1/inverters/import", files: map[string]string{"inverters": importedInverters}, wantStatus: http.StatusCreated},
+		{name: "dry run", target: "/api/v1/inverters/import?dryRun=true", files: map[string]string{"inverters": importedInverters, "readings": importedReadings}, wantStatus: http.StatusOK},
+		{name: "row errors", target: "/api/v1/inverters/import", files: map[string]string{"inverters": strings.Replace(importedInverters, "SMA", "", 1)}, wantStatus: http.StatusUnprocessableEntity, wantErrors: 1},
+		{name: "missing file", target: "/api/v1/inverters/import", files: map[string]string{"readings": importedReadings}, wantStatus: http.StatusBadRequest},
+		{name: "missing column", target: "/api/v1/inverters/import", files: map[string]string{"inverters": "vendor\nSMA\n"}, wantStatus: http.StatusBadRequest},
+		{name: "invalid dry run flag", target: "/api/v1/inverters/import?dryRun=maybe", files: map[string]string{"inverters": importedInverters}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := upload(t, tt.target, tt.files)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus >= http.StatusBadRequest && tt.wantStatus != http.StatusUnprocessableEntity {
+				return
+			}
+			var result ImportResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Errors) != tt.wantErrors {
+				t.Fatalf("errors = %+v, want %d", result.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+// ErrAmbiguousInverter means an Enode inverter matches more than one local inverter, so none of
+// them can be updated safely until the duplicates are merged.
+var ErrAmbiguousInverter = errors.New("enode inverter matches several local inverters")
+
+// InverterMatcher looks up local inverters that may stand for an Enode inverter.
+type InverterMatcher interface {
+	ListEnodeInverterMatches(ctx context.Context, arg db.ListEnodeInverterMatchesParams) ([]db.Inverter, error)
+}
+
+// MatchEnodeInverter returns the local inverter that stands for remote. An inverter linked to
+// remote by an admin merge wins; otherwise it is the unlinked inverter of remote's Enode user with
+// remote's serial number. It fails with ErrInverterNotFound when there is none and with
+// ErrAmbiguousInverter when there are several.
+func MatchEnodeInverter(ctx context.Context, store InverterMatcher, remote SolarInverter) (db.Inverter, error) {
+	var serialNumber string
+	if remote.Information.SerialNumber != nil {
+		serialNumber = *remote.Information.SerialNumber
+	}
+	// Without a serial number or a numeric user only a link can match.
+	userID, err := strconv.ParseInt(remote.UserID, 10, 32)
+	if err != nil || serialNumber == "" {
+		userID, serialNumber = 0, ""
+	}
+
+	matches, err := store.ListEnodeInverterMatches(ctx, db.ListEnodeInverterMatchesParams{
+		EnodeInverterID: remote.ID,
+		SerialNumber:    serialNumber,
+		UserID:          int32(userID),
+	})
+	if err != nil {
+		return db.Inverter{}, fmt.Errorf("matching enode inverter %s: %w", remote.ID, err)
+	}
+	switch len(matches) {
+	case 0:
+		return db.Inverter{}, fmt.Errorf("%w: no local inverter linked to enode inverter %s or of user %s with its serial number", ErrInverterNotFound, remote.ID, remote.UserID)
+	case 1:
+		return matches[0], nil
+	default:
+		return db.Inverter{}, fmt.Errorf("%w: user %s has several inverters with serial number %s", ErrAmbiguousInverter, remote.UserID, serialNumber)
+	}
+}
@@ -0,0 +1,72 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// DefaultMaxListItems caps how many inverters a single all=true list request may collect.
+const DefaultMaxListItems = 1000
+
+var (
+	ErrMaxItemsExceeded = errors.New("maximum number of items exceeded")
+	ErrPaginationLoop   = errors.New("pagination cursor repeated")
+)
+
+// PageFetcher fetches the page of inverters that follows the given cursor. An empty cursor requests the first page.
+type PageFetcher func(ctx context.Context, after string) (*SolarInverterResponse, error)
+
+// AllInverters returns an iterator that follows Enode `after` cursors until the last page.
+// Iteration stops after the first error, which is yielded alongside a zero SolarInverter.
+func AllInverters(ctx context.Context, fetch PageFetcher) iter.Seq2[SolarInverter, error] {
+	return func(yield func(SolarInverter, error) bool) {
+		after := ""
+		seen := make(map[string]struct{})
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(SolarInverter{}, err)
+				return
+			}
+
+			page, err := fetch(ctx, after)
+			if err != nil {
+				yield(SolarInverter{}, err)
+				return
+			}
+
+			for _, inverter := range page.Data {
+				if !yield(inverter, nil) {
+					return
+				}
+			}
+
+			next := page.Pagination.After
+			if next == "" || len(page.Data) == 0 {
+				return
+			}
+			if _, ok := seen[next]; ok {
+				yield(SolarInverter{}, fmt.Errorf("%w: %s", ErrPaginationLoop, next))
+				return
+			}
+			seen[next] = struct{}{}
+			after = next
+		}
+	}
+}
+
+// collectInverters drains seq into a single response, failing once more than maxItems are seen.
+func collectInverters(seq iter.Seq2[SolarInverter, error], maxItems int) (*SolarInverterResponse, error) {
+	response := &SolarInverterResponse{Data: []SolarInverter{}}
+	for inverter, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		if len(response.Data) >= maxItems {
+			return nil, fmt.Errorf("%w: more than %d inverters", ErrMaxItemsExceeded, maxItems)
+		}
+		response.Data = append(response.Data, inverter)
+	}
+	return response, nil
+}
@@ -0,0 +1,158 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func pageOf(after string, ids ...string) *SolarInverterResponse {
+	page := &SolarInverterResponse{Pagination: Pagination{After: after}}
+	for _, id := range ids {
+		page.Data = append(page.Data, SolarInverter{ID: id})
+	}
+	return page
+}
+
+func TestAllInverters(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   map[string]*SolarInverterResponse
+		failAt  string
+		wantIDs []string
+		wantErr error
+	}{
+		{
+			name:    "single page",
+			pages:   map[string]*SolarInverterResponse{"": pageOf("", "a", "b")},
+			wantIDs: []string{"a", "b"},
+		},
+		{
+			name: "follows after cursors",
+			pages: map[string]*SolarInverterResponse{
+				"":   pageOf("c1", "a", "b"),
+				"c1": pageOf("c2", "c"),
+				"c2": pageOf("", "d"),
+			},
+			wantIDs: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "stops on empty page with cursor",
+			pages: map[string]*SolarInverterResponse{
+				"":   pageOf("c1", "a"),
+				"c1": pageOf("c2"),
+			},
+			wantIDs: []string{"a"},
+		},
+		{
+			name: "detects cursor loop",
+			pages: map[string]*SolarInverterResponse{
+				"":   pageOf("c1", "a"),
+				"c1": pageOf("c1", "b"),
+			},
+			wantIDs: []string{"a", "b"},
+			wantErr: ErrPaginationLoop,
+		},
+		{
+			name: "surfaces fetch error",
+			pages: map[string]*SolarInverterResponse{
+				"": pageOf("c1", "a"),
+			},
+			failAt:  "c1",
+			wantIDs: []string{"a"},
+			wantErr: ErrUpstreamTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch := func(ctx context.Context, after string) (*SolarInverterResponse, error) {
+				if after == tt.failAt && tt.failAt != "" {
+					return nil, fmt.Errorf("fetch %s: %w", after, ErrUpstreamTimeout)
+				}
+				return tt.pages[after], nil
+			}
+
+			var ids []string
+			var err error
+			for inverter, iterErr := range AllInverters(context.Background(), fetch) {
+				if iterErr != nil {
+					err = iterErr
+					break
+				}
+				ids = append(ids, inverter.ID)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestInverterHandler_ListAll(t *testing.T) {
+	pages := map[string]*SolarInverterResponse{
+		"":   pageOf("c1", "a", "b"),
+		"c1": pageOf("", "c"),
+	}
+	tests := []struct {
+		name       string
+		route      string
+		target     string
+		wantStatus int
+		wantBody   string
+		wantCalls  int
+	}{
+		{
+			name:       "all inverters",
+			route:      "/inverters",
+			target:     "/inverters?all=true&pageSize=2",
+			wantStatus: http.StatusOK,
+			wantBody:   `"id":"c"`,
+			wantCalls:  2,
+		},
+		{
+			name:       "all user inverters",
+			route:      "/users/:userID",
+			target:     "/users/u1?all=true",
+			wantStatus: http.StatusOK,
+			wantBody:   `"id":"c"`,
+			wantCalls:  2,
+		},
+		{
+			name:       "max items guard",
+			route:      "/inverters",
+			target:     "/inverters?all=true&maxItems=2",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSolarInverterClient{pages: pages}
+			h := NewInverterHandler(newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{}))
+
+			handler := h.ListInverters
+			if strings.HasPrefix(tt.route, "/users") {
+				handler = h.ListUserInverters
+			}
+			rec := serve(t, http.MethodGet, tt.route, tt.target, "", handler)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("body %s does not contain %s", rec.Body.String(), tt.wantBody)
+			}
+			if len(client.listCalls) != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", len(client.listCalls), tt.wantCalls)
+			}
+		})
+	}
+}
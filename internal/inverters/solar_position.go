@@ -0,0 +1,86 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/solar"
+)
+
+var ErrLocationUnknown = errors.New("inverter location unknown")
+
+// SolarPositionResponse describes the sun as seen from an inverter. Times are in the inverter's
+// timezone; Sunrise and Sunset are omitted on days the sun does not cross the horizon.
+type SolarPositionResponse struct {
+	InverterID        string     `json:"inverterId"`
+	Time              time.Time  `json:"time"`
+	Timezone          string     `json:"timezone"`
+	Latitude          float64    `json:"latitude"`
+	Longitude         float64    `json:"longitude"`
+	Elevation         float64    `json:"elevation"`
+	Azimuth           float64    `json:"azimuth"`
+	Sunrise           *time.Time `json:"sunrise,omitempty"`
+	SolarNoon         time.Time  `json:"solarNoon"`
+	Sunset            *time.Time `json:"sunset,omitempty"`
+	PolarDay          bool       `json:"polarDay,omitempty"`
+	PolarNight        bool       `json:"polarNight,omitempty"`
+	IsDaylight        bool       `json:"isDaylight"`
+	ClearSkyGHI       float64    `json:"clearSkyGhi"`
+	ExpectedProducing bool       `json:"expectedProducing"`
+}
+
+// GetSolarPosition computes the sun's position, the day's sun events and clear-sky irradiance
+// at an Enode inverter's location at time at.
+func (uc *InverterUseCase) GetSolarPosition(ctx context.Context, inverterID string, at time.Time) (*SolarPositionResponse, error) {
+	inverter, err := uc.GetInverter(ctx, inverterID)
+	if err != nil {
+		return nil, err
+	}
+	if inverter.Location.Latitude == 0 && inverter.Location.Longitude == 0 {
+		return nil, ErrLocationUnknown
+	}
+
+	location := inverterLocation(inverter.Timezone)
+	at = at.In(location)
+	latitude, longitude := inverter.Location.Latitude, inverter.Location.Longitude
+	sun := solar.SunPosition(latitude, longitude, at)
+	times := solar.SunTimesOn(latitude, longitude, at)
+
+	response := &SolarPositionResponse{
+		InverterID:  inverter.ID,
+		Time:        at,
+		Timezone:    location.String(),
+		Latitude:    latitude,
+		Longitude:   longitude,
+		Elevation:   sun.Elevation,
+		Azimuth:     sun.Azimuth,
+		SolarNoon:   times.SolarNoon,
+		PolarDay:    times.PolarDay,
+		PolarNight:  times.PolarNight,
+		IsDaylight:  sun.IsDaylight(),
+		ClearSkyGHI: solar.ClearSkyGHI(sun),
+		// Reachable inverters are expected to report production whenever the sun is up.
+		ExpectedProducing: inverter.IsReachable && sun.IsDaylight(),
+	}
+	if !times.Sunrise.IsZero() {
+		response.Sunrise = &times.Sunrise
+		response.Sunset = &times.Sunset
+	}
+	return response, nil
+}
+
+// inverterLocation loads the inverter's IANA timezone, falling back to UTC when Enode does not
+// report one.
+func inverterLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		slog.Warn("Unknown inverter timezone, using UTC", "timezone", timezone, "error", err)
+		return time.UTC
+	}
+	return location
+}
@@ -0,0 +1,28 @@
+package inverters
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresInverterStore is the InverterStore backed by sqlc queries on a pgx pool.
+type PostgresInverterStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresInverterStore(pool *pgxpool.Pool) *PostgresInverterStore {
+	return &PostgresInverterStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresInverterStore) InTx(ctx context.Context, fn func(store InverterStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresInverterStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
@@ -1,108 +1,239 @@
 package inverters
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
 	"strconv"
 	"time"
 
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
-	"github.com/entl/evolyte-energy-provider-adapter/internal/enode"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// TokenSource provides bearer tokens for upstream provider calls.
+type TokenSource interface {
+	GetAccessToken(ctx context.Context) (string, error)
+}
+
+// InverterStore persists locally registered inverters and the domain events they raise.
+type InverterStore interface {
+	CreateInverter(ctx context.Context, arg db.CreateInverterParams) (db.Inverter, error)
+	GetInverterById(ctx context.Context, id int32) (db.Inverter, error)
+	ListEnodeInverterMatches(ctx context.Context, arg db.ListEnodeInverterMatchesParams) ([]db.Inverter, error)
+	UpdateInverter(ctx context.Context, arg db.UpdateInverterParams) error
+	DeleteInverter(ctx context.Context, id int32) error
+	InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error)
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+	ListInvertersBySerialNumbers(ctx context.Context, serialNumbers []string) ([]db.Inverter, error)
+	ListHourlyRecordTimestamps(ctx context.Context, arg db.ListHourlyRecordTimestampsParams) ([]pgtype.Timestamp, error)
+	InsertHourlyRecords(ctx context.Context, arg []db.InsertHourlyRecordsParams) (int64, error)
+	// InTx runs fn against a store whose writes commit together, or not at all if fn fails.
+	InTx(ctx context.Context, fn func(store InverterStore) error) error
+}
+
+var (
+	ErrInvalidStatisticParams = errors.New("invalid inverter statistic parameters")
+	ErrUpstreamTimeout        = errors.New("upstream request timed out")
+	ErrInverterNotFound       = errors.New("inverter not found")
+	ErrInvalidInverterID      = errors.New("invalid inverter id")
+)
+
+// Timeouts bounds each upstream operation. A zero value disables the deadline.
+type Timeouts struct {
+	Token      time.Duration
+	List       time.Duration
+	Get        time.Duration
+	Statistics time.Duration
+	Link       time.Duration
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Token:      5 * time.Second,
+		List:       10 * time.Second,
+		Get:        10 * time.Second,
+		Statistics: 15 * time.Second,
+		Link:       3 * time.Second,
+	}
+}
+
 type InverterUseCase struct {
 	inverterClient  SolarInverterClient
-	authClient      *enode.EnodeAuthClient
-	inverterQueries *db.Queries
+	authClient      TokenSource
+	inverterQueries InverterStore
 	validator       *utils.CustomValidator
+	timeouts        Timeouts
+	// productionStepKwh is the lifetime production interval at which threshold events are raised.
+	productionStepKwh float64
 }
 
-func NewInverterUseCase(inverterClient SolarInverterClient, authClient *enode.EnodeAuthClient, inverterQueries *db.Queries, validator *utils.CustomValidator) *InverterUseCase {
+func NewInverterUseCase(inverterClient SolarInverterClient, authClient TokenSource, inverterQueries InverterStore, validator *utils.CustomValidator, timeouts Timeouts, productionStepKwh float64) *InverterUseCase {
 	return &InverterUseCase{
-		inverterClient:  inverterClient,
-		authClient:      authClient,
-		inverterQueries: inverterQueries,
-		validator:       validator,
+		inverterClient:    inverterClient,
+		authClient:        authClient,
+		inverterQueries:   inverterQueries,
+		validator:         validator,
+		timeouts:          timeouts,
+		productionStepKwh: productionStepKwh,
+	}
+}
+
+// withTimeout derives a context bounded by d, leaving ctx untouched when d is zero.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// upstreamError wraps err, tagging it with ErrUpstreamTimeout when the deadline on ctx expired.
+func upstreamError(ctx context.Context, op string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.ErrorContext(ctx, op+" timed out", "error", err)
+		return fmt.Errorf("%s timed out: %w: %w", op, ErrUpstreamTimeout, err)
 	}
+	return fmt.Errorf("failed to %s: %w", op, err)
+}
+
+func (uc *InverterUseCase) bearerToken(ctx context.Context) (string, error) {
+	tokenCtx, cancel := withTimeout(ctx, uc.timeouts.Token)
+	defer cancel()
+
+	token, err := uc.authClient.GetAccessToken(tokenCtx)
+	if err != nil {
+		return "", upstreamError(tokenCtx, "get access token", err)
+	}
+	return "Bearer " + token, nil
 }
 
 func (uc *InverterUseCase) ListInverters(ctx context.Context, after string, before string, pageSize int) (*SolarInverterResponse, error) {
-	// Create context with timeout for token acquisition
-	tokenCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := withTimeout(ctx, uc.timeouts.List)
 	defer cancel()
 
-	token, err := uc.authClient.GetAccessToken()
+	bearerToken, err := uc.bearerToken(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
+		return nil, err
 	}
-	bearerToken := "Bearer " + token
 
 	inverters, err := uc.inverterClient.ListInverters(ctx, bearerToken, after, before, pageSize)
-	// Check for timeout specifically
-	if errors.Is(tokenCtx.Err(), context.DeadlineExceeded) {
-		slog.Error("timeout while getting access token", "error", tokenCtx.Err())
-		return nil, fmt.Errorf("timeout while getting access token: %w", tokenCtx.Err())
-	}
 	if err != nil {
-		slog.Error("Failed to list inverters", "error", err)
-		return nil, fmt.Errorf("failed to list inverters: %w", err)
+		slog.ErrorContext(ctx, "Failed to list inverters", "error", err)
+		return nil, upstreamError(ctx, "list inverters", err)
 	}
 	return inverters, nil
 }
 
 func (uc *InverterUseCase) ListUserInverters(ctx context.Context, userID string, after string, before string, pageSize int) (*SolarInverterResponse, error) {
-	token, err := uc.authClient.GetAccessToken()
+	ctx, cancel := withTimeout(ctx, uc.timeouts.List)
+	defer cancel()
+
+	bearerToken, err := uc.bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inverters, err := uc.inverterClient.ListUserInverters(ctx, bearerToken, userID, after, before, pageSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
+		return nil, upstreamError(ctx, "list user inverters", err)
 	}
-	bearerToken := "Bearer " + token
+	return inverters, nil
+}
+
+// IterateInverters walks every inverter visible to the client, fetching pages of pageSize on demand.
+// Each page gets its own list deadline.
+func (uc *InverterUseCase) IterateInverters(ctx context.Context, pageSize int) iter.Seq2[SolarInverter, error] {
+	return AllInverters(ctx, func(ctx context.Context, after string) (*SolarInverterResponse, error) {
+		return uc.ListInverters(ctx, after, "", pageSize)
+	})
+}
 
-	return uc.inverterClient.ListUserInverters(ctx, bearerToken, userID, after, before, pageSize)
+// IterateUserInverters walks every inverter linked to userID.
+func (uc *InverterUseCase) IterateUserInverters(ctx context.Context, userID string, pageSize int) iter.Seq2[SolarInverter, error] {
+	return AllInverters(ctx, func(ctx context.Context, after string) (*SolarInverterResponse, error) {
+		return uc.ListUserInverters(ctx, userID, after, "", pageSize)
+	})
+}
+
+func (uc *InverterUseCase) ListAllInverters(ctx context.Context, pageSize int, maxItems int) (*SolarInverterResponse, error) {
+	return collectInverters(uc.IterateInverters(ctx, pageSize), maxItems)
+}
+
+func (uc *InverterUseCase) ListAllUserInverters(ctx context.Context, userID string, pageSize int, maxItems int) (*SolarInverterResponse, error) {
+	return collectInverters(uc.IterateUserInverters(ctx, userID, pageSize), maxItems)
+}
+
+// UserInverterIDs returns the Enode IDs of every inverter linked to userID.
+func (uc *InverterUseCase) UserInverterIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	for inverter, err := range uc.IterateUserInverters(ctx, userID, 0) {
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == DefaultMaxListItems {
+			return nil, ErrMaxItemsExceeded
+		}
+		ids = append(ids, inverter.ID)
+	}
+	return ids, nil
 }
 
 func (uc *InverterUseCase) GetInverter(ctx context.Context, inverterID string) (*SolarInverter, error) {
-	token, err := uc.authClient.GetAccessToken()
+	ctx, cancel := withTimeout(ctx, uc.timeouts.Get)
+	defer cancel()
+
+	bearerToken, err := uc.bearerToken(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
+		return nil, err
 	}
-	bearerToken := "Bearer " + token
 
 	inverter, err := uc.inverterClient.GetInverter(ctx, bearerToken, inverterID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get inverter: %w", err)
+		return nil, upstreamError(ctx, "get inverter", err)
 	}
 	return inverter, nil
 }
 
 func (uc *InverterUseCase) GetInverterProductionStatistics(ctx context.Context, inverterID string, year int, month int, day int) (*InverterStatistic, error) {
-	token, err := uc.authClient.GetAccessToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
-	}
-	bearerToken := "Bearer " + token
-
 	params := InverterStatisticParams{
 		Year:  year,
 		Month: month,
 		Day:   day,
 	}
 	if err := params.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid inverter statistic parameters: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStatisticParams, err)
 	}
 
-	return uc.inverterClient.GetInverterProductionStatistics(ctx, bearerToken, inverterID, params)
+	ctx, cancel := withTimeout(ctx, uc.timeouts.Statistics)
+	defer cancel()
+
+	bearerToken, err := uc.bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := uc.inverterClient.GetInverterProductionStatistics(ctx, bearerToken, inverterID, params)
+	if err != nil {
+		return nil, upstreamError(ctx, "get inverter production statistics", err)
+	}
+	return stats, nil
 }
 
 func (uc *InverterUseCase) AddInverter(ctx context.Context, request AddInverterRequest) (*AddInverterResponse, error) {
 	userID, err := strconv.Atoi(request.UserID)
 	if err != nil {
-		slog.Error("Invalid user ID", "userID", request.UserID, "error", err)
+		slog.ErrorContext(ctx, "Invalid user ID", "userID", request.UserID, "error", err)
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	now := time.Now()
 	inverterCreateParams := db.CreateInverterParams{
 		UserID:                     int32(userID),
 		Vendor:                     request.Vendor,
@@ -110,14 +241,195 @@ func (uc *InverterUseCase) AddInverter(ctx context.Context, request AddInverterR
 		SerialNumber:               request.SerialNumber,
 		InstallationDate:           request.InstallationDate,
 		TotalLifetimeProductionKwh: request.TotalLifetimeProduction,
+		CreatedAt:                  now,
+		UpdatedAt:                  now,
 	}
 
-	inverter, err := uc.inverterQueries.CreateInverter(ctx, inverterCreateParams)
+	var inverter db.Inverter
+	err = uc.inverterQueries.InTx(ctx, func(store InverterStore) error {
+		inverter, err = store.CreateInverter(ctx, inverterCreateParams)
+		if err != nil {
+			return err
+		}
+		if err := recordInverterAudit(ctx, store, audit.ActionInverterCreated, nil, newInverterResponse(inverter)); err != nil {
+			return err
+		}
+		return recordInverterEvent(ctx, store, outbox.EventInverterCreated, InverterEventPayload{Inverter: newInverterResponse(inverter)})
+	})
 	if err != nil {
-		slog.Error("Failed to create inverter in database", "error", err)
+		slog.ErrorContext(ctx, "Failed to create inverter in database", "error", err)
 		return nil, fmt.Errorf("failed to create inverter: %w", err)
 	}
 
+	return newInverterResponse(inverter), nil
+}
+
+func (uc *InverterUseCase) UpdateInverter(ctx context.Context, inverterID string, request UpdateInverterRequest) (*AddInverterResponse, error) {
+	id, err := parseInverterID(inverterID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := db.UpdateInverterParams{ID: id, InstallationDate: request.InstallationDate}
+	if request.Vendor != nil {
+		params.Vendor = pgtype.Text{String: *request.Vendor, Valid: true}
+	}
+	if request.Model != nil {
+		params.Model = pgtype.Text{String: *request.Model, Valid: true}
+	}
+	if request.SerialNumber != nil {
+		params.SerialNumber = pgtype.Text{String: *request.SerialNumber, Valid: true}
+	}
+	if request.TotalLifetimeProduction != nil {
+		params.TotalLifetimeProductionKwh = pgtype.Float8{Float64: *request.TotalLifetimeProduction, Valid: true}
+	}
+
+	var updated db.Inverter
+	err = uc.inverterQueries.InTx(ctx, func(store InverterStore) error {
+		before, err := getInverter(ctx, store, id)
+		if err != nil {
+			return err
+		}
+		if err := store.UpdateInverter(ctx, params); err != nil {
+			return err
+		}
+		if updated, err = store.GetInverterById(ctx, id); err != nil {
+			return err
+		}
+
+		if err := recordInverterAudit(ctx, store, audit.ActionInverterUpdated, newInverterResponse(before), newInverterResponse(updated)); err != nil {
+			return err
+		}
+		payload := InverterEventPayload{Inverter: newInverterResponse(updated), Previous: newInverterResponse(before)}
+		if err := recordInverterEvent(ctx, store, outbox.EventInverterUpdated, payload); err != nil {
+			return err
+		}
+		return uc.recordThresholds(ctx, store, before, updated)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update inverter: %w", err)
+	}
+	return newInverterResponse(updated), nil
+}
+
+func (uc *InverterUseCase) DeleteInverter(ctx context.Context, inverterID string) error {
+	id, err := parseInverterID(inverterID)
+	if err != nil {
+		return err
+	}
+
+	err = uc.inverterQueries.InTx(ctx, func(store InverterStore) error {
+		inverter, err := getInverter(ctx, store, id)
+		if err != nil {
+			return err
+		}
+		if err := store.DeleteInverter(ctx, id); err != nil {
+			return err
+		}
+		if err := recordInverterAudit(ctx, store, audit.ActionInverterDeleted, newInverterResponse(inverter), nil); err != nil {
+			return err
+		}
+		return recordInverterEvent(ctx, store, outbox.EventInverterDeleted, InverterEventPayload{Inverter: newInverterResponse(inverter)})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete inverter: %w", err)
+	}
+	return nil
+}
+
+// SyncInverter copies lifetime production reported by Enode onto the matching local inverter. See
+// MatchEnodeInverter.
+func (uc *InverterUseCase) SyncInverter(ctx context.Context, enodeInverterID string) (*AddInverterResponse, error) {
+	remote, err := uc.GetInverter(ctx, enodeInverterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var synced db.Inverter
+	err = uc.inverterQueries.InTx(ctx, func(store InverterStore) error {
+		local, err := MatchEnodeInverter(ctx, store, *remote)
+		if err != nil {
+			return err
+		}
+
+		err = store.UpdateInverter(ctx, db.UpdateInverterParams{
+			ID:                         local.ID,
+			TotalLifetimeProductionKwh: pgtype.Float8{Float64: remote.ProductionState.TotalLifetimeProduction, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if synced, err = store.GetInverterById(ctx, local.ID); err != nil {
+			return err
+		}
+
+		if err := recordInverterAudit(ctx, store, audit.ActionInverterSynced, newInverterResponse(local), newInverterResponse(synced)); err != nil {
+			return err
+		}
+		payload := InverterEventPayload{
+			Inverter:        newInverterResponse(synced),
+			Previous:        newInverterResponse(local),
+			EnodeInverterID: remote.ID,
+			ProductionState: &remote.ProductionState,
+		}
+		if err := recordInverterEvent(ctx, store, outbox.EventInverterSynced, payload); err != nil {
+			return err
+		}
+		return uc.recordThresholds(ctx, store, local, synced)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync inverter: %w", err)
+	}
+	return newInverterResponse(synced), nil
+}
+
+func (uc *InverterUseCase) recordThresholds(ctx context.Context, store InverterStore, before db.Inverter, after db.Inverter) error {
+	for _, threshold := range outbox.ThresholdsCrossed(before.TotalLifetimeProductionKwh, after.TotalLifetimeProductionKwh, uc.productionStepKwh) {
+		payload := InverterEventPayload{Inverter: newInverterResponse(after), ThresholdKwh: threshold}
+		if err := recordInverterEvent(ctx, store, outbox.EventInverterProductionThresholdCrossed, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordInverterEvent(ctx context.Context, store InverterStore, eventType string, payload InverterEventPayload) error {
+	event, err := outbox.NewEvent(outbox.AggregateInverter, payload.Inverter.ID, eventType, payload)
+	if err != nil {
+		return err
+	}
+	_, err = store.InsertOutboxEvent(ctx, event)
+	return err
+}
+
+// recordInverterAudit logs a change to a local inverter. before is nil for creations and after is
+// nil for deletions.
+func recordInverterAudit(ctx context.Context, store InverterStore, action string, before *AddInverterResponse, after *AddInverterResponse) error {
+	entry := audit.Entry{Action: action, ResourceType: audit.ResourceInverter, Before: before, After: after}
+	if state := cmp.Or(after, before); state != nil {
+		entry.ResourceID = state.ID
+		entry.UserID = state.UserID
+	}
+	return audit.Record(ctx, store, entry)
+}
+
+func getInverter(ctx context.Context, store InverterStore, id int32) (db.Inverter, error) {
+	inverter, err := store.GetInverterById(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.Inverter{}, fmt.Errorf("%w: %d", ErrInverterNotFound, id)
+	}
+	return inverter, err
+}
+
+func parseInverterID(inverterID string) (int32, error) {
+	id, err := strconv.ParseInt(inverterID, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidInverterID, inverterID)
+	}
+	return int32(id), nil
+}
+
+func newInverterResponse(inverter db.Inverter) *AddInverterResponse {
 	return &AddInverterResponse{
 		ID:                      strconv.FormatInt(int64(inverter.ID), 10),
 		UserID:                  strconv.FormatInt(int64(inverter.UserID), 10),
@@ -126,27 +438,50 @@ func (uc *InverterUseCase) AddInverter(ctx context.Context, request AddInverterR
 		SerialNumber:            inverter.SerialNumber,
 		TotalLifetimeProduction: inverter.TotalLifetimeProductionKwh,
 		InstallationDate:        inverter.InstallationDate,
-	}, nil
+	}
+}
+
+// DeauthorizeUser deletes the user upstream at Enode, unlinking all of their devices.
+func (uc *InverterUseCase) DeauthorizeUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, uc.timeouts.Link)
+	defer cancel()
+
+	bearerToken, err := uc.bearerToken(ctx)
+	if err != nil {
+		return err
+	}
+	if err := uc.inverterClient.DeleteUser(ctx, bearerToken, userID); err != nil {
+		return upstreamError(ctx, "delete enode user", err)
+	}
+	return nil
 }
 
 func (uc *InverterUseCase) LinkInverter(ctx context.Context, userId string, request LinkInverterRequest) (*LinkInverterResponse, error) {
-	// Create context with timeout
-	timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	ctx, cancel := withTimeout(ctx, uc.timeouts.Link)
 	defer cancel()
 
-	token, err := uc.authClient.GetAccessToken()
+	bearerToken, err := uc.bearerToken(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
+		return nil, err
 	}
-	bearerToken := "Bearer " + token
 
-	resp, err := uc.inverterClient.LinkInverter(timeoutCtx, bearerToken, userId, request)
+	// The request is recorded before Enode opens the session, so no session exists without an
+	// audit entry. The link token is a credential for the session and is left out of the log.
+	err = audit.Record(ctx, uc.inverterQueries, audit.Entry{
+		Action:       audit.ActionInverterLinkRequested,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   userId,
+		UserID:       userId,
+		After:        request,
+	})
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
-			slog.Error("link inverter request timed out", "userId", userId, "error", err)
-			return nil, fmt.Errorf("link inverter request timed out: %w", err)
-		}
-		return nil, fmt.Errorf("failed to link inverter: %w", err)
+		return nil, err
+	}
+
+	resp, err := uc.inverterClient.LinkInverter(ctx, bearerToken, userId, request)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to link inverter", "userId", userId, "error", err)
+		return nil, upstreamError(ctx, "link inverter", err)
 	}
 
 	return resp, nil
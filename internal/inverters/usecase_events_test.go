@@ -0,0 +1,263 @@
+package inverters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+)
+
+func eventTypes(store *fakeInverterStore) []string {
+	var types []string
+	for _, event := range store.events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+func seededStore(totalKwh float64) *fakeInverterStore {
+	return &fakeInverterStore{
+		nextID: 1,
+		inverters: map[int32]db.Inverter{
+			1: {ID: 1, UserID: 42, Vendor: "SMA", Model: "X", SerialNumber: "SN-1", TotalLifetimeProductionKwh: totalKwh},
+		},
+	}
+}
+
+func TestInverterUseCase_AddInverterWritesOutboxEvent(t *testing.T) {
+	request := AddInverterRequest{UserID: "42", Vendor: "SMA", Model: "X", SerialNumber: "SN-1", InstallationDate: time.Now()}
+
+	t.Run("commits inverter and event together", func(t *testing.T) {
+		store := &fakeInverterStore{}
+		uc := newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, store)
+
+		if _, err := uc.AddInverter(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := eventTypes(store); !slices.Equal(got, []string{outbox.EventInverterCreated}) {
+			t.Fatalf("events = %v", got)
+		}
+		var payload InverterEventPayload
+		if err := json.Unmarshal(store.events[0].Payload, &payload); err != nil {
+			t.Fatalf("payload: %v", err)
+		}
+		if payload.Inverter.SerialNumber != "SN-1" || store.events[0].AggregateID != payload.Inverter.ID {
+			t.Fatalf("unexpected event %+v", store.events[0])
+		}
+	})
+
+	t.Run("event failure rolls back inverter", func(t *testing.T) {
+		store := &fakeInverterStore{eventErr: errors.New("outbox insert failed")}
+		uc := newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, store)
+
+		if _, err := uc.AddInverter(context.Background(), request); err == nil {
+			t.Fatal("expected error")
+		}
+		if len(store.inverters) != 0 || len(store.events) != 0 {
+			t.Fatalf("writes not rolled back: %d inverters, %d events", len(store.inverters), len(store.events))
+		}
+	})
+}
+
+func TestInverterUseCase_UpdateAndDeleteEvents(t *testing.T) {
+	total := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string
+		inverterID string
+		run        func(uc *InverterUseCase, id string) error
+		wantEvents []string
+		wantErr    error
+	}{
+		{
+			name:       "update below threshold",
+			inverterID: "1",
+			run: func(uc *InverterUseCase, id string) error {
+				_, err := uc.UpdateInverter(context.Background(), id, UpdateInverterRequest{TotalLifetimeProduction: total(950)})
+				return err
+			},
+			wantEvents: []string{outbox.EventInverterUpdated},
+		},
+		{
+			name:       "update crossing threshold",
+			inverterID: "1",
+			run: func(uc *InverterUseCase, id string) error {
+				_, err := uc.UpdateInverter(context.Background(), id, UpdateInverterRequest{TotalLifetimeProduction: total(2100)})
+				return err
+			},
+			wantEvents: []string{
+				outbox.EventInverterUpdated,
+				outbox.EventInverterProductionThresholdCrossed,
+				outbox.EventInverterProductionThresholdCrossed,
+			},
+		},
+		{
+			name:       "update missing inverter",
+			inverterID: "99",
+			run: func(uc *InverterUseCase, id string) error {
+				_, err := uc.UpdateInverter(context.Background(), id, UpdateInverterRequest{})
+				return err
+			},
+			wantErr: ErrInverterNotFound,
+		},
+		{
+			name:       "update invalid id",
+			inverterID: "abc",
+			run: func(uc *InverterUseCase, id string) error {
+				_, err := uc.UpdateInverter(context.Background(), id, UpdateInverterRequest{})
+				return err
+			},
+			wantErr: ErrInvalidInverterID,
+		},
+		{
+			name:       "delete",
+			inverterID: "1",
+			run: func(uc *InverterUseCase, id string) error {
+				return uc.DeleteInverter(context.Background(), id)
+			},
+			wantEvents: []string{outbox.EventInverterDeleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := seededStore(900)
+			uc := newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, store)
+
+			err := tt.run(uc, tt.inverterID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := eventTypes(store); !slices.Equal(got, tt.wantEvents) {
+				t.Fatalf("events = %v, want %v", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_SyncInverter(t *testing.T) {
+	serial := "SN-1"
+	unknown := "SN-404"
+	tests := []struct {
+		name       string
+		remote     *SolarInverter
+		others     []db.Inverter
+		links      map[int32]string
+		wantSynced int32
+		wantEvents []string
+		wantErr    error
+	}{
+		{
+			name:       "copies lifetime production and raises threshold",
+			remote:     &SolarInverter{ID: "enode-1", UserID: "42", Information: Information{SerialNumber: &serial}, ProductionState: ProductionState{TotalLifetimeProduction: 1200}},
+			others:     []db.Inverter{{ID: 2, UserID: 7, SerialNumber: "SN-1", TotalLifetimeProductionKwh: 50}},
+			wantSynced: 1,
+			wantEvents: []string{outbox.EventInverterSynced, outbox.EventInverterProductionThresholdCrossed},
+		},
+		{
+			name:    "no local match",
+			remote:  &SolarInverter{ID: "enode-2", UserID: "42", Information: Information{SerialNumber: &unknown}},
+			wantErr: ErrInverterNotFound,
+		},
+		{
+			name:    "serial number of another user",
+			remote:  &SolarInverter{ID: "enode-1", UserID: "7", Information: Information{SerialNumber: &serial}, ProductionState: ProductionState{TotalLifetimeProduction: 1200}},
+			wantErr: ErrInverterNotFound,
+		},
+		{
+			name:    "several local matches",
+			remote:  &SolarInverter{ID: "enode-1", UserID: "42", Information: Information{SerialNumber: &serial}, ProductionState: ProductionState{TotalLifetimeProduction: 1200}},
+			others:  []db.Inverter{{ID: 2, UserID: 42, SerialNumber: "SN-1", TotalLifetimeProductionKwh: 50}},
+			wantErr: ErrAmbiguousInverter,
+		},
+		{
+			name:       "linked inverter wins over serial number",
+			remote:     &SolarInverter{ID: "enode-1", UserID: "42", Information: Information{SerialNumber: &serial}, ProductionState: ProductionState{TotalLifetimeProduction: 1200}},
+			others:     []db.Inverter{{ID: 2, UserID: 42, SerialNumber: "SN-1", TotalLifetimeProductionKwh: 1000}},
+			links:      map[int32]string{2: "enode-1"},
+			wantSynced: 2,
+			wantEvents: []string{outbox.EventInverterSynced},
+		},
+		{
+			name:    "inverter linked to another enode inverter",
+			remote:  &SolarInverter{ID: "enode-1", UserID: "42", Information: Information{SerialNumber: &serial}, ProductionState: ProductionState{TotalLifetimeProduction: 1200}},
+			links:   map[int32]string{1: "enode-9"},
+			wantErr: ErrInverterNotFound,
+		},
+		{
+			name:    "missing serial number",
+			remote:  &SolarInverter{ID: "enode-3", UserID: "42"},
+			wantErr: ErrInverterNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := seededStore(900)
+			for _, other := range tt.others {
+				store.inverters[other.ID] = other
+			}
+			store.links = tt.links
+			before := maps.Clone(store.inverters)
+			uc := newTestUseCase(&fakeSolarInverterClient{inverter: tt.remote}, &fakeTokenSource{token: "tok"}, store)
+
+			_, err := uc.SyncInverter(context.Background(), tt.remote.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			for id, inverter := range store.inverters {
+				want := before[id].TotalLifetimeProductionKwh
+				if id == tt.wantSynced {
+					want = tt.remote.ProductionState.TotalLifetimeProduction
+				}
+				if inverter.TotalLifetimeProductionKwh != want {
+					t.Fatalf("inverter %d total = %v, want %v", id, inverter.TotalLifetimeProductionKwh, want)
+				}
+			}
+			if got := eventTypes(store); !slices.Equal(got, tt.wantEvents) {
+				t.Fatalf("events = %v, want %v", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_RecordsAuditEntries(t *testing.T) {
+	store := seededStore(100)
+	uc := newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, store)
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "ops", RequestID: "req-1", SourceIP: "203.0.113.7"})
+
+	vendor := "Fronius"
+	if _, err := uc.UpdateInverter(ctx, "1", UpdateInverterRequest{Vendor: &vendor}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := uc.DeleteInverter(ctx, "1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	var actions []string
+	for _, entry := range store.audits {
+		if entry.Actor != "ops" || entry.RequestID.String != "req-1" || entry.ResourceID != "1" || entry.UserID.String != "42" {
+			t.Fatalf("unexpected audit entry %+v", entry)
+		}
+		actions = append(actions, entry.Action)
+	}
+	if !slices.Equal(actions, []string{audit.ActionInverterUpdated, audit.ActionInverterDeleted}) {
+		t.Fatalf("actions = %v", actions)
+	}
+	var changes map[string]audit.Change
+	if err := json.Unmarshal(store.audits[0].Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || string(changes["vendor"].After) != `"Fronius"` {
+		t.Fatalf("changes = %v", changes)
+	}
+	if store.audits[1].After != nil || store.audits[1].Before == nil {
+		t.Fatalf("delete entry = %+v", store.audits[1])
+	}
+}
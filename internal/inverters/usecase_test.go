@@ -0,0 +1,531 @@
+package inverters
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeTokenSource struct {
+	token string
+	err   error
+	block bool
+}
+
+func (f *fakeTokenSource) GetAccessToken(ctx context.Context) (string, error) {
+	if f.block {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return f.token, f.err
+}
+
+// fakeInverterStore is an in-memory InverterStore. InTx stages writes on a copy and only
+// keeps them when fn succeeds, mirroring transaction rollback.
+type fakeInverterStore struct {
+	inverters map[int32]db.Inverter
+	links     map[int32]string
+	events    []db.InsertOutboxEventParams
+	audits    []db.InsertAuditLogEntryParams
+	created   []db.CreateInverterParams
+	records   []db.InsertHourlyRecordsParams
+	nextID    int32
+	err       error
+	eventErr  error
+	auditErr  error
+}
+
+func (f *fakeInverterStore) CreateInverter(ctx context.Context, arg db.CreateInverterParams) (db.Inverter, error) {
+	if f.err != nil {
+		return db.Inverter{}, f.err
+	}
+	f.created = append(f.created, arg)
+	f.nextID++
+	inverter := db.Inverter{
+		ID:                         f.nextID,
+		UserID:                     arg.UserID,
+		Vendor:                     arg.Vendor,
+		Model:                      arg.Model,
+		SerialNumber:               arg.SerialNumber,
+		TotalLifetimeProductionKwh: arg.TotalLifetimeProductionKwh,
+		InstallationDate:           arg.InstallationDate,
+	}
+	if f.inverters == nil {
+		f.inverters = make(map[int32]db.Inverter)
+	}
+	f.inverters[inverter.ID] = inverter
+	return inverter, nil
+}
+
+func (f *fakeInverterStore) GetInverterById(ctx context.Context, id int32) (db.Inverter, error) {
+	inverter, ok := f.inverters[id]
+	if !ok {
+		return db.Inverter{}, pgx.ErrNoRows
+	}
+	return inverter, nil
+}
+
+func (f *fakeInverterStore) ListEnodeInverterMatches(ctx context.Context, arg db.ListEnodeInverterMatchesParams) ([]db.Inverter, error) {
+	var linked, matches []db.Inverter
+	for _, id := range slices.Sorted(maps.Keys(f.inverters)) {
+		inverter := f.inverters[id]
+		enodeID, isLinked := f.links[id]
+		switch {
+		case enodeID == arg.EnodeInverterID:
+			linked = append(linked, inverter)
+		case !isLinked && inverter.SerialNumber == arg.SerialNumber && inverter.UserID == arg.UserID:
+			matches = append(matches, inverter)
+		}
+	}
+	if len(linked) > 0 {
+		return linked, nil
+	}
+	return matches[:min(len(matches), 2)], nil
+}
+
+func (f *fakeInverterStore) ListInvertersBySerialNumbers(ctx context.Context, serialNumbers []string) ([]db.Inverter, error) {
+	var matches []db.Inverter
+	for _, inverter := range f.inverters {
+		if slices.Contains(serialNumbers, inverter.SerialNumber) {
+			matches = append(matches, inverter)
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeInverterStore) ListHourlyRecordTimestamps(ctx context.Context, arg db.ListHourlyRecordTimestampsParams) ([]pgtype.Timestamp, error) {
+	var recorded []pgtype.Timestamp
+	for _, record := range f.records {
+		if record.InverterID == arg.InverterID && slices.Contains(arg.Timestamps, record.Timestamp) {
+			recorded = append(recorded, record.Timestamp)
+		}
+	}
+	return recorded, nil
+}
+
+func (f *fakeInverterStore) InsertHourlyRecords(ctx context.Context, arg []db.InsertHourlyRecordsParams) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.records = append(f.records, arg...)
+	return int64(len(arg)), nil
+}
+
+func (f *fakeInverterStore) UpdateInverter(ctx context.Context, arg db.UpdateInverterParams) error {
+	if f.err != nil {
+		return f.err
+	}
+	inverter, ok := f.inverters[arg.ID]
+	if !ok {
+		return nil
+	}
+	if arg.Vendor.Valid {
+		inverter.Vendor = arg.Vendor.String
+	}
+	if arg.Model.Valid {
+		inverter.Model = arg.Model.String
+	}
+	if arg.SerialNumber.Valid {
+		inverter.SerialNumber = arg.SerialNumber.String
+	}
+	if arg.TotalLifetimeProductionKwh.Valid {
+		inverter.TotalLifetimeProductionKwh = arg.TotalLifetimeProductionKwh.Float64
+	}
+	if arg.InstallationDate != nil {
+		inverter.InstallationDate = *arg.InstallationDate
+	}
+	f.inverters[arg.ID] = inverter
+	return nil
+}
+
+func (f *fakeInverterStore) DeleteInverter(ctx context.Context, id int32) error {
+	if f.err != nil {
+		return f.err
+	}
+	delete(f.inverters, id)
+	return nil
+}
+
+func (f *fakeInverterStore) InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error) {
+	if f.eventErr != nil {
+		return db.OutboxEvent{}, f.eventErr
+	}
+	f.events = append(f.events, arg)
+	return db.OutboxEvent{ID: int64(len(f.events)), EventType: arg.EventType, Payload: arg.Payload}, nil
+}
+
+func (f *fakeInverterStore) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	if f.auditErr != nil {
+		return db.AuditLog{}, f.auditErr
+	}
+	f.audits = append(f.audits, arg)
+	return db.AuditLog{ID: int64(len(f.audits)), Actor: arg.Actor, Action: arg.Action}, nil
+}
+
+func (f *fakeInverterStore) InTx(ctx context.Context, fn func(store InverterStore) error) error {
+	staged := *f
+	staged.inverters = maps.Clone(f.inverters)
+	staged.events = slices.Clone(f.events)
+	staged.audits = slices.Clone(f.audits)
+	staged.created = slices.Clone(f.created)
+	staged.records = slices.Clone(f.records)
+	if err := fn(&staged); err != nil {
+		return err
+	}
+	*f = staged
+	return nil
+}
+
+type listCall struct {
+	bearerToken string
+	userID      string
+	after       string
+	before      string
+	pageSize    int
+}
+
+// fakeSolarInverterClient records calls and returns canned responses.
+type fakeSolarInverterClient struct {
+	listResponse  *SolarInverterResponse
+	pages         map[string]*SolarInverterResponse
+	inverter      *SolarInverter
+	statistic     *InverterStatistic
+	linkResponse  *LinkInverterResponse
+	deletedUsers  []string
+	err           error
+	block         bool
+	listCalls     []listCall
+	statsCalls    []InverterStatisticParams
+	lastInverter  string
+	lastLinkToken string
+}
+
+func (f *fakeSolarInverterClient) ListInverters(ctx context.Context, bearerToken string, after string, before string, pageSize int) (*SolarInverterResponse, error) {
+	f.listCalls = append(f.listCalls, listCall{bearerToken: bearerToken, after: after, before: before, pageSize: pageSize})
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.page(after)
+}
+
+func (f *fakeSolarInverterClient) ListUserInverters(ctx context.Context, bearerToken string, userID string, after string, before string, pageSize int) (*SolarInverterResponse, error) {
+	f.listCalls = append(f.listCalls, listCall{bearerToken: bearerToken, userID: userID, after: after, before: before, pageSize: pageSize})
+	return f.page(after)
+}
+
+func (f *fakeSolarInverterClient) page(after string) (*SolarInverterResponse, error) {
+	if f.err != nil || f.pages == nil {
+		return f.listResponse, f.err
+	}
+	return f.pages[after], nil
+}
+
+func (f *fakeSolarInverterClient) GetInverter(ctx context.Context, bearerToken string, inverterID string) (*SolarInverter, error) {
+	f.lastInverter = inverterID
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.inverter, f.err
+}
+
+func (f *fakeSolarInverterClient) GetInverterProductionStatistics(ctx context.Context, bearerToken string, inverterID string, params InverterStatisticParams) (*InverterStatistic, error) {
+	f.lastInverter = inverterID
+	f.statsCalls = append(f.statsCalls, params)
+	return f.statistic, f.err
+}
+
+func (f *fakeSolarInverterClient) DeleteUser(ctx context.Context, bearerToken string, userID string) error {
+	f.deletedUsers = append(f.deletedUsers, userID)
+	return f.err
+}
+
+func (f *fakeSolarInverterClient) LinkInverter(ctx context.Context, bearerToken string, userId string, linkBody LinkInverterRequest) (*LinkInverterResponse, error) {
+	f.lastLinkToken = bearerToken
+	if f.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.linkResponse, f.err
+}
+
+func newTestUseCase(client SolarInverterClient, tokens TokenSource, store InverterStore) *InverterUseCase {
+	return NewInverterUseCase(client, tokens, store, utils.NewCustomValidator(validator.New()), DefaultTimeouts(), 1000)
+}
+
+func TestInverterUseCase_ListPaginationPassthrough(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		after    string
+		before   string
+		pageSize int
+	}{
+		{name: "no cursors", pageSize: 0},
+		{name: "after cursor", after: "cursor-a", pageSize: 50},
+		{name: "before cursor", before: "cursor-b", pageSize: 10},
+		{name: "user inverters", userID: "user-1", after: "cursor-a", pageSize: 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := &SolarInverterResponse{Pagination: Pagination{After: "next", Before: "prev"}}
+			client := &fakeSolarInverterClient{listResponse: want}
+			uc := newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{})
+
+			var got *SolarInverterResponse
+			var err error
+			if tt.userID != "" {
+				got, err = uc.ListUserInverters(context.Background(), tt.userID, tt.after, tt.before, tt.pageSize)
+			} else {
+				got, err = uc.ListInverters(context.Background(), tt.after, tt.before, tt.pageSize)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != want {
+				t.Fatalf("response not passed through: got %+v", got)
+			}
+			if len(client.listCalls) != 1 {
+				t.Fatalf("expected 1 call, got %d", len(client.listCalls))
+			}
+			call := client.listCalls[0]
+			wantCall := listCall{bearerToken: "Bearer tok", userID: tt.userID, after: tt.after, before: tt.before, pageSize: tt.pageSize}
+			if call != wantCall {
+				t.Fatalf("call = %+v, want %+v", call, wantCall)
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_GetInverterProductionStatistics(t *testing.T) {
+	tests := []struct {
+		name       string
+		year       int
+		month      int
+		day        int
+		wantErr    error
+		wantCalled bool
+	}{
+		{name: "monthly", year: 2024, month: 6, wantCalled: true},
+		{name: "daily", year: 2024, month: 6, day: 15, wantCalled: true},
+		{name: "zero year", year: 0, month: 6, wantErr: ErrInvalidStatisticParams},
+		{name: "month too large", year: 2024, month: 13, wantErr: ErrInvalidStatisticParams},
+		{name: "month zero", year: 2024, month: 0, wantErr: ErrInvalidStatisticParams},
+		{name: "negative day", year: 2024, month: 6, day: -1, wantErr: ErrInvalidStatisticParams},
+		{name: "day too large", year: 2024, month: 6, day: 32, wantErr: ErrInvalidStatisticParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSolarInverterClient{statistic: &InverterStatistic{Timezone: "UTC"}}
+			uc := newTestUseCase(client, &fakeTokenSource{token: "tok"}, &fakeInverterStore{})
+
+			_, err := uc.GetInverterProductionStatistics(context.Background(), "inv-1", tt.year, tt.month, tt.day)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if called := len(client.statsCalls) > 0; called != tt.wantCalled {
+				t.Fatalf("client called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantCalled {
+				want := InverterStatisticParams{Year: tt.year, Month: tt.month, Day: tt.day}
+				if client.statsCalls[0] != want {
+					t.Fatalf("params = %+v, want %+v", client.statsCalls[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_LinkInverter(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *fakeSolarInverterClient
+		tokenErr    error
+		auditErr    error
+		wantErr     error
+		wantTimeout bool
+	}{
+		{
+			name:   "success",
+			client: &fakeSolarInverterClient{linkResponse: &LinkInverterResponse{LinkURL: "https://link", LinkToken: "lt"}},
+		},
+		{
+			name:        "upstream timeout",
+			client:      &fakeSolarInverterClient{block: true},
+			wantTimeout: true,
+		},
+		{
+			name:    "upstream error",
+			client:  &fakeSolarInverterClient{err: errors.New("boom")},
+			wantErr: errors.New("boom"),
+		},
+		{
+			name:     "token error",
+			client:   &fakeSolarInverterClient{},
+			tokenErr: errors.New("no token"),
+			wantErr:  errors.New("no token"),
+		},
+		{
+			name:     "audit failure does not open a session",
+			client:   &fakeSolarInverterClient{linkResponse: &LinkInverterResponse{LinkURL: "https://link", LinkToken: "lt"}},
+			auditErr: errors.New("audit insert failed"),
+			wantErr:  errors.New("audit insert failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeInverterStore{auditErr: tt.auditErr}
+			uc := newTestUseCase(tt.client, &fakeTokenSource{token: "tok", err: tt.tokenErr}, store)
+			uc.timeouts.Link = 20 * time.Millisecond
+
+			resp, err := uc.LinkInverter(context.Background(), "user-1", LinkInverterRequest{})
+			switch {
+			case tt.wantTimeout:
+				if !errors.Is(err, ErrUpstreamTimeout) {
+					t.Fatalf("error = %v, want ErrUpstreamTimeout", err)
+				}
+			case tt.wantErr != nil:
+				if err == nil {
+					t.Fatalf("expected error containing %q", tt.wantErr)
+				}
+				if errors.Is(err, ErrUpstreamTimeout) {
+					t.Fatalf("non-timeout error mapped to timeout: %v", err)
+				}
+				if tt.auditErr != nil && tt.client.lastLinkToken != "" {
+					t.Fatal("link session opened without an audit entry")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if resp != tt.client.linkResponse {
+					t.Fatalf("response not passed through")
+				}
+				if tt.client.lastLinkToken != "Bearer tok" {
+					t.Fatalf("bearer token = %q", tt.client.lastLinkToken)
+				}
+				if len(store.audits) != 1 || store.audits[0].Action != audit.ActionInverterLinkRequested || store.audits[0].ResourceID != "user-1" {
+					t.Fatalf("audits = %+v", store.audits)
+				}
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_AddInverter(t *testing.T) {
+	installed := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		request  AddInverterRequest
+		storeErr error
+		wantErr  bool
+	}{
+		{
+			name:    "valid",
+			request: AddInverterRequest{UserID: "42", Vendor: "SMA", Model: "X", SerialNumber: "SN1", TotalLifetimeProduction: 10, InstallationDate: installed},
+		},
+		{
+			name:    "non numeric user id",
+			request: AddInverterRequest{UserID: "abc", Vendor: "SMA", Model: "X", SerialNumber: "SN1"},
+			wantErr: true,
+		},
+		{
+			name:     "store failure",
+			request:  AddInverterRequest{UserID: "42", Vendor: "SMA", Model: "X", SerialNumber: "SN1"},
+			storeErr: errors.New("db down"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeInverterStore{err: tt.storeErr}
+			uc := newTestUseCase(&fakeSolarInverterClient{}, &fakeTokenSource{}, store)
+
+			resp, err := uc.AddInverter(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resp.UserID != tt.request.UserID || resp.SerialNumber != tt.request.SerialNumber {
+				t.Fatalf("unexpected response %+v", resp)
+			}
+			if len(store.created) != 1 || store.created[0].UserID != 42 {
+				t.Fatalf("unexpected store writes %+v", store.created)
+			}
+		})
+	}
+}
+
+func TestInverterUseCase_Deadlines(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *fakeSolarInverterClient
+		tokens      *fakeTokenSource
+		timeouts    Timeouts
+		cancelFirst bool
+		wantTimeout bool
+	}{
+		{
+			name:        "list deadline enforced",
+			client:      &fakeSolarInverterClient{block: true},
+			tokens:      &fakeTokenSource{token: "tok"},
+			timeouts:    Timeouts{List: 20 * time.Millisecond},
+			wantTimeout: true,
+		},
+		{
+			name:        "token deadline enforced",
+			client:      &fakeSolarInverterClient{},
+			tokens:      &fakeTokenSource{block: true},
+			timeouts:    Timeouts{Token: 20 * time.Millisecond},
+			wantTimeout: true,
+		},
+		{
+			name:        "caller cancellation is not a timeout",
+			client:      &fakeSolarInverterClient{block: true},
+			tokens:      &fakeTokenSource{token: "tok"},
+			timeouts:    Timeouts{List: time.Minute},
+			cancelFirst: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestUseCase(tt.client, tt.tokens, &fakeInverterStore{})
+			uc.timeouts = tt.timeouts
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelFirst {
+				cancel()
+			}
+			defer cancel()
+
+			_, err := uc.ListInverters(ctx, "", "", 0)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := errors.Is(err, ErrUpstreamTimeout); got != tt.wantTimeout {
+				t.Fatalf("timeout = %v, want %v (err %v)", got, tt.wantTimeout, err)
+			}
+			if !tt.wantTimeout && !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	channelPrefix   = "inverter:production:"
+	lastStatePrefix = "inverter:production:last:"
+	pollLockPrefix  = "inverter:production:poll-lock:"
+	watchersKey     = "inverter:production:watchers"
+)
+
+// ProductionUpdate is the live production state of one inverter as seen by the poller or a webhook.
+type ProductionUpdate struct {
+	InverterID              string    `json:"inverterId"`
+	ProductionRate          float64   `json:"productionRate"`
+	IsProducing             bool      `json:"isProducing"`
+	TotalLifetimeProduction float64   `json:"totalLifetimeProduction"`
+	LastUpdated             time.Time `json:"lastUpdated"`
+	IsReachable             bool      `json:"isReachable"`
+}
+
+func NewProductionUpdate(inverter inverters.SolarInverter) ProductionUpdate {
+	return ProductionUpdate{
+		InverterID:              inverter.ID,
+		ProductionRate:          inverter.ProductionState.ProductionRate,
+		IsProducing:             inverter.ProductionState.IsProducing,
+		TotalLifetimeProduction: inverter.ProductionState.TotalLifetimeProduction,
+		LastUpdated:             inverter.ProductionState.LastUpdated,
+		IsReachable:             inverter.IsReachable,
+	}
+}
+
+// Broker fans production updates out through Redis pub/sub so subscribers on any replica
+// receive updates seen by any other replica.
+type Broker struct {
+	redis        *redis.Client
+	watchTTL     time.Duration
+	lastStateTTL time.Duration
+}
+
+func NewBroker(redisClient *redis.Client, watchTTL time.Duration, lastStateTTL time.Duration) *Broker {
+	return &Broker{redis: redisClient, watchTTL: watchTTL, lastStateTTL: lastStateTTL}
+}
+
+// Publish broadcasts update unless it is identical to the last state published for the inverter.
+// It reports whether the update was published. The last state expires after lastStateTTL without
+// an update.
+func (b *Broker) Publish(ctx context.Context, update ProductionUpdate) (bool, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return false, fmt.Errorf("encoding production update: %w", err)
+	}
+
+	previous, err := b.redis.SetArgs(ctx, lastStatePrefix+update.InverterID, body, redis.SetArgs{Get: true, TTL: b.lastStateTTL}).Result()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("storing last production state: %w", err)
+	}
+	if previous == string(body) {
+		return false, nil
+	}
+
+	if err := b.redis.Publish(ctx, channelPrefix+update.InverterID, body).Err(); err != nil {
+		return false, fmt.Errorf("publishing production update: %w", err)
+	}
+	return true, nil
+}
+
+// Last returns the most recently published state for inverterID, or nil if none is known.
+func (b *Broker) Last(ctx context.Context, inverterID string) (*ProductionUpdate, error) {
+	body, err := b.redis.Get(ctx, lastStatePrefix+inverterID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading last production state: %w", err)
+	}
+
+	var update ProductionUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		return nil, fmt.Errorf("decoding last production state: %w", err)
+	}
+	return &update, nil
+}
+
+// Watch marks inverterIDs as having live subscribers for the watch TTL so the poller refreshes them.
+// Subscribers call it again before the TTL lapses to keep the inverters watched.
+func (b *Broker) Watch(ctx context.Context, inverterIDs ...string) error {
+	if len(inverterIDs) == 0 {
+		return nil
+	}
+	expiry := float64(time.Now().Add(b.watchTTL).Unix())
+	members := make([]redis.Z, 0, len(inverterIDs))
+	for _, id := range inverterIDs {
+		members = append(members, redis.Z{Score: expiry, Member: id})
+	}
+	return b.redis.ZAdd(ctx, watchersKey, members...).Err()
+}
+
+// Watched returns every inverter with at least one live subscriber, pruning expired entries.
+func (b *Broker) Watched(ctx context.Context) ([]string, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := b.redis.ZRemRangeByScore(ctx, watchersKey, "-inf", "("+now).Err(); err != nil {
+		return nil, fmt.Errorf("pruning watchers: %w", err)
+	}
+	return b.redis.ZRangeByScore(ctx, watchersKey, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+}
+
+// ClaimPoll reports whether this replica won the right to poll inverterID for the next ttl.
+func (b *Broker) ClaimPoll(ctx context.Context, inverterID string, ttl time.Duration) (bool, error) {
+	return b.redis.SetNX(ctx, pollLockPrefix+inverterID, 1, ttl).Result()
+}
+
+// Subscribe starts receiving updates for inverterIDs. More inverters can be added or removed later.
+func (b *Broker) Subscribe(ctx context.Context, inverterIDs ...string) *Subscription {
+	sub := &Subscription{
+		pubsub:  b.redis.Subscribe(ctx, channels(inverterIDs)...),
+		updates: make(chan ProductionUpdate, 16),
+		done:    make(chan struct{}),
+	}
+	go sub.forward()
+	return sub
+}
+
+// Subscription delivers decoded production updates for a changing set of inverters.
+type Subscription struct {
+	pubsub    *redis.PubSub
+	updates   chan ProductionUpdate
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *Subscription) Updates() <-chan ProductionUpdate {
+	return s.updates
+}
+
+func (s *Subscription) Add(ctx context.Context, inverterIDs ...string) error {
+	return s.pubsub.Subscribe(ctx, channels(inverterIDs)...)
+}
+
+func (s *Subscription) Remove(ctx context.Context, inverterIDs ...string) error {
+	return s.pubsub.Unsubscribe(ctx, channels(inverterIDs)...)
+}
+
+// Close stops the subscription. Updates is closed once pending deliveries are abandoned, even if
+// nobody is reading it any more.
+func (s *Subscription) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+func (s *Subscription) forward() {
+	defer close(s.updates)
+	for msg := range s.pubsub.Channel() {
+		var update ProductionUpdate
+		if err := json.Unmarshal([]byte(msg.Payload), &update); err != nil {
+			slog.Error("Dropping malformed production update", "channel", msg.Channel, "error", err)
+			continue
+		}
+		select {
+		case s.updates <- update:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func channels(inverterIDs []string) []string {
+	names := make([]string, 0, len(inverterIDs))
+	for _, id := range inverterIDs {
+		names = append(names, channelPrefix+id)
+	}
+	return names
+}
@@ -0,0 +1,193 @@
+package live
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestBroker(t *testing.T) (*Broker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewBroker(client, time.Minute, time.Hour), mr
+}
+
+func TestBroker_PublishDeduplicates(t *testing.T) {
+	broker, mr := newTestBroker(t)
+	ctx := context.Background()
+	update := ProductionUpdate{InverterID: "inv-1", ProductionRate: 2.5, IsProducing: true}
+
+	tests := []struct {
+		name          string
+		update        ProductionUpdate
+		wantPublished bool
+	}{
+		{name: "first update", update: update, wantPublished: true},
+		{name: "identical update", update: update, wantPublished: false},
+		{name: "changed rate", update: ProductionUpdate{InverterID: "inv-1", ProductionRate: 3.1, IsProducing: true}, wantPublished: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published, err := broker.Publish(ctx, tt.update)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if published != tt.wantPublished {
+				t.Fatalf("published = %v, want %v", published, tt.wantPublished)
+			}
+			last, err := broker.Last(ctx, "inv-1")
+			if err != nil || last == nil || last.ProductionRate != tt.update.ProductionRate {
+				t.Fatalf("last = %+v, err %v", last, err)
+			}
+			if ttl := mr.TTL(lastStatePrefix + "inv-1"); ttl != time.Hour {
+				t.Fatalf("last state TTL = %v, want %v", ttl, time.Hour)
+			}
+		})
+	}
+}
+
+func TestBroker_SubscribeAddRemove(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	sub := broker.Subscribe(ctx, "inv-1")
+	defer sub.Close()
+	if err := sub.Add(ctx, "inv-2"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := sub.Remove(ctx, "inv-1"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	// Give the subscription time to register before publishing.
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := broker.Publish(ctx, ProductionUpdate{InverterID: "inv-1", ProductionRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Publish(ctx, ProductionUpdate{InverterID: "inv-2", ProductionRate: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case update := <-sub.Updates():
+		if update.InverterID != "inv-2" {
+			t.Fatalf("received update for %s after unsubscribing", update.InverterID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+	}
+}
+
+func TestBroker_CloseWithUnreadUpdates(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	ctx := context.Background()
+
+	sub := broker.Subscribe(ctx, "inv-1")
+	time.Sleep(50 * time.Millisecond)
+	// More updates than the buffer holds, none of them read.
+	for i := range 20 {
+		if _, err := broker.Publish(ctx, ProductionUpdate{InverterID: "inv-1", ProductionRate: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	sub.Close()
+
+	// The buffered updates are still delivered, then the channel is closed without anything
+	// further being forwarded.
+	for range cap(sub.updates) {
+		<-sub.Updates()
+	}
+	select {
+	case _, ok := <-sub.Updates():
+		if ok {
+			t.Fatal("subscription kept forwarding after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("updates not closed after Close")
+	}
+}
+
+func TestBroker_Watched(t *testing.T) {
+	broker, mr := newTestBroker(t)
+	ctx := context.Background()
+
+	if err := broker.Watch(ctx, "inv-1", "inv-2"); err != nil {
+		t.Fatal(err)
+	}
+	// An entry whose TTL already lapsed must be pruned.
+	mr.ZAdd(watchersKey, float64(time.Now().Add(-time.Minute).Unix()), "inv-stale")
+
+	watched, err := broker.Watched(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(watched, ",") != "inv-1,inv-2" {
+		t.Fatalf("watched = %v", watched)
+	}
+}
+
+func TestHandler_Stream(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	ctx := context.Background()
+	if _, err := broker.Publish(ctx, ProductionUpdate{InverterID: "inv-1", ProductionRate: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.GET("/inverters/:inverterID/live", NewHandler(broker, time.Hour).Stream)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/inverters/inv-1/live", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readData := func() string {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				return data
+			}
+		}
+	}
+
+	if data := readData(); !strings.Contains(data, `"productionRate":1.5`) {
+		t.Fatalf("snapshot = %s", data)
+	}
+
+	// Publish until the subscription is live; dedupe means each rate must differ.
+	go func() {
+		for i := 2; reqCtx.Err() == nil; i++ {
+			_, _ = broker.Publish(ctx, ProductionUpdate{InverterID: "inv-1", ProductionRate: float64(i)})
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	if data := readData(); !strings.Contains(data, `"inverterId":"inv-1"`) {
+		t.Fatalf("update = %s", data)
+	}
+}
@@ -0,0 +1,458 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/labstack/echo/v4"
+)
+
+// accessTokenParam carries the access token for clients that cannot set an Authorization header
+// on a WebSocket request, such as browsers.
+const accessTokenParam = "access_token"
+
+const (
+	messageSubscribe   = "subscribe"
+	messageUnsubscribe = "unsubscribe"
+	messageSubscribed  = "subscribed"
+	messageProduction  = "production"
+	messageError       = "error"
+)
+
+var (
+	ErrUserNotAuthorized     = errors.New("user not authorized for this connection")
+	ErrInverterNotAuthorized = errors.New("inverter does not belong to an authorized user")
+	ErrTooManySubscriptions  = errors.New("subscription limit reached")
+)
+
+// UserInverterSource lists the Enode inverter IDs that belong to a user.
+type UserInverterSource interface {
+	UserInverterIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// ClientMessage is sent by clients to change the set of inverters they receive updates for.
+// Subscribing to a user subscribes to every inverter the user owns.
+type ClientMessage struct {
+	Type      string   `json:"type"`
+	Users     []string `json:"users,omitempty"`
+	Inverters []string `json:"inverters,omitempty"`
+}
+
+// ServerMessage is sent to clients. Production messages carry Data; subscribed messages list the
+// inverters the connection currently receives.
+type ServerMessage struct {
+	Type      string            `json:"type"`
+	Data      *ProductionUpdate `json:"data,omitempty"`
+	Inverters []string          `json:"inverters,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type GatewayOptions struct {
+	Heartbeat        time.Duration
+	WriteTimeout     time.Duration
+	MaxSubscriptions int
+	// TokenSecret verifies the access tokens the core service issues to authenticated users.
+	TokenSecret []byte
+}
+
+// Gateway serves production updates for many inverters over one WebSocket per client.
+type Gateway struct {
+	broker *Broker
+	source UserInverterSource
+	opts   GatewayOptions
+	now    func() time.Time
+}
+
+func NewGateway(broker *Broker, source UserInverterSource, opts GatewayOptions) *Gateway {
+	return &Gateway{broker: broker, source: source, opts: opts, now: time.Now}
+}
+
+// Connect upgrades the request to a WebSocket. The users a client may observe come only from its
+// access token, which is signed with the token secret shared with the core service; nothing the
+// client sends unsigned widens them.
+func (g *Gateway) Connect(c echo.Context) error {
+	token := accessToken(c.Request())
+	if token == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Missing access token")
+	}
+	claimed, err := VerifyAccessToken(g.opts.TokenSecret, token, g.now())
+	if err != nil {
+		slog.WarnContext(c.Request().Context(), "Rejected live WebSocket access token", "error", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid access token")
+	}
+	users := authorizedUsers(claimed)
+	if len(users) == 0 {
+		return echo.NewHTTPError(http.StatusForbidden, "No authorized users")
+	}
+
+	conn, err := websocket.Accept(c.Response(), c.Request(), nil)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to accept WebSocket", "error", err)
+		return nil
+	}
+	defer conn.CloseNow()
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+
+	session := newSession(g, users)
+	sub := g.broker.Subscribe(ctx)
+	defer sub.Close()
+	session.sub = sub
+
+	go session.forward(ctx, sub)
+	go session.readLoop(ctx, conn, cancel)
+
+	err = session.writeLoop(ctx, conn)
+	switch {
+	case errors.Is(err, errSlowConsumer):
+		conn.Close(websocket.StatusPolicyViolation, "slow consumer")
+	case err != nil && ctx.Err() == nil:
+		slog.WarnContext(ctx, "WebSocket closed", "error", err)
+	default:
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+	return nil
+}
+
+// accessToken reads the bearer token from the Authorization header or the access_token query
+// parameter.
+func accessToken(req *http.Request) string {
+	if token, ok := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return req.URL.Query().Get(accessTokenParam)
+}
+
+func authorizedUsers(claimed []string) []string {
+	var users []string
+	for _, user := range claimed {
+		if user = strings.TrimSpace(user); user != "" {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+var errSlowConsumer = errors.New("client is not reading messages")
+
+// controlQueueSize bounds replies queued for a client that has stopped reading.
+const controlQueueSize = 16
+
+// session holds the state of one WebSocket connection. Production updates are coalesced per
+// inverter so a slow client receives the latest state rather than an ever-growing backlog.
+type session struct {
+	gateway *Gateway
+	users   []string
+	sub     *Subscription
+
+	// owned caches each authorized user's inverters for the lifetime of the connection.
+	owned map[string][]string
+
+	mu         sync.Mutex
+	subscribed map[string]bool
+	pending    map[string]ProductionUpdate
+	order      []string
+	notify     chan struct{}
+	control    chan ServerMessage
+	slow       atomic.Bool
+}
+
+func newSession(g *Gateway, users []string) *session {
+	return &session{
+		gateway:    g,
+		users:      users,
+		owned:      make(map[string][]string),
+		subscribed: make(map[string]bool),
+		pending:    make(map[string]ProductionUpdate),
+		notify:     make(chan struct{}, 1),
+		control:    make(chan ServerMessage, controlQueueSize),
+	}
+}
+
+func (s *session) readLoop(ctx context.Context, conn *websocket.Conn, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		var msg ClientMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			var closeErr websocket.CloseError
+			if ctx.Err() == nil && !errors.As(err, &closeErr) {
+				slog.WarnContext(ctx, "Failed to read WebSocket message", "error", err)
+			}
+			return
+		}
+		if !s.reply(s.handle(ctx, msg)) {
+			s.slow.Store(true)
+			return
+		}
+	}
+}
+
+func (s *session) handle(ctx context.Context, msg ClientMessage) ServerMessage {
+	inverterIDs, err := s.resolve(ctx, msg)
+	if err != nil {
+		return ServerMessage{Type: messageError, Error: err.Error()}
+	}
+
+	switch msg.Type {
+	case messageSubscribe:
+		added, err := s.subscribe(ctx, inverterIDs)
+		if err != nil {
+			return ServerMessage{Type: messageError, Error: err.Error()}
+		}
+		s.sendLast(ctx, added)
+	case messageUnsubscribe:
+		if err := s.unsubscribe(ctx, inverterIDs); err != nil {
+			return ServerMessage{Type: messageError, Error: err.Error()}
+		}
+	default:
+		return ServerMessage{Type: messageError, Error: fmt.Sprintf("unknown message type %q", msg.Type)}
+	}
+	return ServerMessage{Type: messageSubscribed, Inverters: s.subscriptions()}
+}
+
+// resolve expands the users and inverters in msg to inverter IDs, rejecting any the connection
+// is not authorized for.
+func (s *session) resolve(ctx context.Context, msg ClientMessage) ([]string, error) {
+	var inverterIDs []string
+	for _, user := range msg.Users {
+		owned, err := s.ownedBy(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		inverterIDs = append(inverterIDs, owned...)
+	}
+
+	for _, inverterID := range msg.Inverters {
+		authorized, err := s.authorized(ctx, inverterID)
+		if err != nil {
+			return nil, err
+		}
+		if !authorized {
+			return nil, fmt.Errorf("%w: %s", ErrInverterNotAuthorized, inverterID)
+		}
+		inverterIDs = append(inverterIDs, inverterID)
+	}
+	return inverterIDs, nil
+}
+
+func (s *session) ownedBy(ctx context.Context, user string) ([]string, error) {
+	if !slices.Contains(s.users, user) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotAuthorized, user)
+	}
+	if owned, ok := s.owned[user]; ok {
+		return owned, nil
+	}
+	owned, err := s.gateway.source.UserInverterIDs(ctx, user)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list user inverters", "userID", user, "error", err)
+		return nil, fmt.Errorf("listing inverters for user %s failed", user)
+	}
+	s.owned[user] = owned
+	return owned, nil
+}
+
+func (s *session) authorized(ctx context.Context, inverterID string) (bool, error) {
+	for _, user := range s.users {
+		owned, err := s.ownedBy(ctx, user)
+		if err != nil {
+			return false, err
+		}
+		if slices.Contains(owned, inverterID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *session) subscribe(ctx context.Context, inverterIDs []string) ([]string, error) {
+	s.mu.Lock()
+	var added []string
+	for _, id := range inverterIDs {
+		if !s.subscribed[id] && !slices.Contains(added, id) {
+			added = append(added, id)
+		}
+	}
+	if limit := s.gateway.opts.MaxSubscriptions; limit > 0 && len(s.subscribed)+len(added) > limit {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w (%d)", ErrTooManySubscriptions, limit)
+	}
+	for _, id := range added {
+		s.subscribed[id] = true
+	}
+	s.mu.Unlock()
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := s.gateway.broker.Watch(ctx, added...); err != nil {
+		slog.ErrorContext(ctx, "Failed to register live watchers", "error", err)
+	}
+	if err := s.sub.Add(ctx, added...); err != nil {
+		return nil, fmt.Errorf("subscribing to production updates failed")
+	}
+	return added, nil
+}
+
+func (s *session) unsubscribe(ctx context.Context, inverterIDs []string) error {
+	s.mu.Lock()
+	var removed []string
+	for _, id := range inverterIDs {
+		if s.subscribed[id] {
+			delete(s.subscribed, id)
+			delete(s.pending, id)
+			removed = append(removed, id)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := s.sub.Remove(ctx, removed...); err != nil {
+		return fmt.Errorf("unsubscribing from production updates failed")
+	}
+	return nil
+}
+
+func (s *session) subscriptions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.subscribed))
+	for id := range s.subscribed {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s *session) sendLast(ctx context.Context, inverterIDs []string) {
+	for _, id := range inverterIDs {
+		last, err := s.gateway.broker.Last(ctx, id)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to read last production state", "inverterID", id, "error", err)
+			continue
+		}
+		if last != nil {
+			s.enqueue(*last)
+		}
+	}
+}
+
+// reply queues a control message and reports false if the client has stopped reading.
+func (s *session) reply(msg ServerMessage) bool {
+	select {
+	case s.control <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *session) forward(ctx context.Context, sub *Subscription) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			s.enqueue(update)
+		}
+	}
+}
+
+// enqueue stores update as the next state to send for its inverter, replacing any state the
+// client has not received yet.
+func (s *session) enqueue(update ProductionUpdate) {
+	s.mu.Lock()
+	if !s.subscribed[update.InverterID] {
+		s.mu.Unlock()
+		return
+	}
+	if _, queued := s.pending[update.InverterID]; !queued {
+		s.order = append(s.order, update.InverterID)
+	}
+	s.pending[update.InverterID] = update
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *session) drain() []ProductionUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updates := make([]ProductionUpdate, 0, len(s.order))
+	for _, id := range s.order {
+		if update, ok := s.pending[id]; ok {
+			updates = append(updates, update)
+		}
+	}
+	s.order = s.order[:0]
+	clear(s.pending)
+	return updates
+}
+
+func (s *session) writeLoop(ctx context.Context, conn *websocket.Conn) error {
+	heartbeat := time.NewTicker(s.gateway.opts.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if s.slow.Load() {
+				return errSlowConsumer
+			}
+			return nil
+		case msg := <-s.control:
+			if err := s.write(ctx, conn, msg); err != nil {
+				return err
+			}
+		case <-s.notify:
+			for _, update := range s.drain() {
+				if err := s.write(ctx, conn, ServerMessage{Type: messageProduction, Data: &update}); err != nil {
+					return err
+				}
+			}
+		case <-heartbeat.C:
+			if ids := s.subscriptions(); len(ids) > 0 {
+				if err := s.gateway.broker.Watch(ctx, ids...); err != nil {
+					slog.ErrorContext(ctx, "Failed to refresh live watchers", "error", err)
+				}
+			}
+			pingCtx, cancel := context.WithTimeout(ctx, s.gateway.opts.WriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return errSlowConsumer
+			}
+		}
+	}
+}
+
+// write sends msg, treating a client that cannot accept it within the write timeout as too slow.
+func (s *session) write(ctx context.Context, conn *websocket.Conn, msg ServerMessage) error {
+	writeCtx, cancel := context.WithTimeout(ctx, s.gateway.opts.WriteTimeout)
+	defer cancel()
+	if err := wsjson.Write(writeCtx, conn, msg); err != nil {
+		if writeCtx.Err() != nil && ctx.Err() == nil {
+			return errSlowConsumer
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeUserInverters map[string][]string
+
+func (f fakeUserInverters) UserInverterIDs(ctx context.Context, userID string) ([]string, error) {
+	ids, ok := f[userID]
+	if !ok {
+		return nil, errors.New("unknown user")
+	}
+	return ids, nil
+}
+
+var testTokenSecret = []byte("live-secret")
+
+func signTestToken(t *testing.T, secret []byte, expiresAt time.Time, users ...string) string {
+	t.Helper()
+	token, err := SignAccessToken(secret, users, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// dialGateway connects to gateway with header, and with token as the access_token query
+// parameter when it is set.
+func dialGateway(t *testing.T, gateway *Gateway, token string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	e := echo.New()
+	e.GET("/live/ws", gateway.Connect)
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	target := "ws" + strings.TrimPrefix(srv.URL, "http") + "/live/ws"
+	if token != "" {
+		target += "?" + url.Values{accessTokenParam: {token}}.Encode()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return websocket.Dial(ctx, target, &websocket.DialOptions{HTTPHeader: header})
+}
+
+func TestGateway_RejectsUnauthenticatedConnections(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	source := fakeUserInverters{"u1": {"inv-1"}}
+	valid := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		token      string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "no token", wantStatus: http.StatusUnauthorized},
+		{name: "forged users header", header: http.Header{"X-Authorized-Enode-Users": {"u1"}}, wantStatus: http.StatusUnauthorized},
+		{name: "token signed with another secret", token: signTestToken(t, []byte("forged"), valid, "u1"), wantStatus: http.StatusUnauthorized},
+		{name: "tampered token", token: signTestToken(t, testTokenSecret, valid, "u1") + "x", wantStatus: http.StatusUnauthorized},
+		{name: "expired token", token: signTestToken(t, testTokenSecret, time.Now().Add(-time.Minute), "u1"), wantStatus: http.StatusUnauthorized},
+		{name: "token without users", token: signTestToken(t, testTokenSecret, valid), wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := NewGateway(broker, source, GatewayOptions{Heartbeat: time.Hour, WriteTimeout: time.Second, TokenSecret: testTokenSecret})
+			_, resp, err := dialGateway(t, gateway, tt.token, tt.header)
+			if err == nil {
+				t.Fatal("expected dial to fail")
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatus {
+				t.Fatalf("response = %+v, want status %d", resp, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGateway_AcceptsBearerToken(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	gateway := NewGateway(broker, fakeUserInverters{"u1": {"inv-1"}}, GatewayOptions{Heartbeat: time.Hour, WriteTimeout: time.Second, TokenSecret: testTokenSecret})
+	token := signTestToken(t, testTokenSecret, time.Now().Add(time.Hour), "u1")
+
+	conn, _, err := dialGateway(t, gateway, "", http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.CloseNow()
+}
+
+func TestGateway_Subscriptions(t *testing.T) {
+	broker, _ := newTestBroker(t)
+	source := fakeUserInverters{"u1": {"inv-1", "inv-2"}, "u2": {"inv-3"}}
+	gateway := NewGateway(broker, source, GatewayOptions{Heartbeat: time.Hour, WriteTimeout: time.Second, MaxSubscriptions: 2, TokenSecret: testTokenSecret})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := dialGateway(t, gateway, signTestToken(t, testTokenSecret, time.Now().Add(time.Hour), "u1", "u2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	roundTrip := func(msg ClientMessage) ServerMessage {
+		t.Helper()
+		if err := wsjson.Write(ctx, conn, msg); err != nil {
+			t.Fatal(err)
+		}
+		var reply ServerMessage
+		if err := wsjson.Read(ctx, conn, &reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	tests := []struct {
+		name      string
+		msg       ClientMessage
+		wantType  string
+		wantIDs   string
+		wantError string
+	}{
+		{name: "user not on connection", msg: ClientMessage{Type: "subscribe", Users: []string{"u9"}}, wantType: "error", wantError: "user not authorized"},
+		{name: "foreign inverter", msg: ClientMessage{Type: "subscribe", Inverters: []string{"inv-9"}}, wantType: "error", wantError: "does not belong"},
+		{name: "subscribe user", msg: ClientMessage{Type: "subscribe", Users: []string{"u1"}}, wantType: "subscribed", wantIDs: "inv-1,inv-2"},
+		{name: "over limit", msg: ClientMessage{Type: "subscribe", Inverters: []string{"inv-3"}}, wantType: "error", wantError: "subscription limit"},
+		{name: "unsubscribe", msg: ClientMessage{Type: "unsubscribe", Inverters: []string{"inv-1"}}, wantType: "subscribed", wantIDs: "inv-2"},
+		{name: "subscribe inverter", msg: ClientMessage{Type: "subscribe", Inverters: []string{"inv-3"}}, wantType: "subscribed", wantIDs: "inv-2,inv-3"},
+		{name: "unknown type", msg: ClientMessage{Type: "dance"}, wantType: "error", wantError: "unknown message type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := roundTrip(tt.msg)
+			if reply.Type != tt.wantType {
+				t.Fatalf("reply = %+v, want type %s", reply, tt.wantType)
+			}
+			if got := strings.Join(reply.Inverters, ","); got != tt.wantIDs {
+				t.Fatalf("inverters = %s, want %s", got, tt.wantIDs)
+			}
+			if !strings.Contains(reply.Error, tt.wantError) {
+				t.Fatalf("error = %q, want %q", reply.Error, tt.wantError)
+			}
+		})
+	}
+
+	// Only subscribed inverters are delivered; keep publishing until the subscription is live.
+	go func() {
+		for i := 1; ctx.Err() == nil; i++ {
+			_, _ = broker.Publish(ctx, ProductionUpdate{InverterID: "inv-1", ProductionRate: float64(i)})
+			_, _ = broker.Publish(ctx, ProductionUpdate{InverterID: "inv-3", ProductionRate: float64(i)})
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	var msg ServerMessage
+	if err := wsjson.Read(ctx, conn, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "production" || msg.Data == nil || msg.Data.InverterID != "inv-3" {
+		t.Fatalf("message = %+v", msg)
+	}
+}
+
+func TestSession_EnqueueCoalescesPerInverter(t *testing.T) {
+	s := newSession(&Gateway{}, []string{"u1"})
+	s.subscribed["inv-1"] = true
+	s.subscribed["inv-2"] = true
+
+	s.enqueue(ProductionUpdate{InverterID: "inv-1", ProductionRate: 1})
+	s.enqueue(ProductionUpdate{InverterID: "inv-2", ProductionRate: 5})
+	s.enqueue(ProductionUpdate{InverterID: "inv-1", ProductionRate: 2})
+	s.enqueue(ProductionUpdate{InverterID: "inv-9", ProductionRate: 7})
+
+	updates := s.drain()
+	if len(updates) != 2 {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if updates[0].InverterID != "inv-1" || updates[0].ProductionRate != 2 || updates[1].InverterID != "inv-2" {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if len(s.drain()) != 0 {
+		t.Fatal("expected drain to empty the queue")
+	}
+}
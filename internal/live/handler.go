@@ -0,0 +1,85 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	broker    *Broker
+	heartbeat time.Duration
+}
+
+func NewHandler(broker *Broker, heartbeat time.Duration) *Handler {
+	return &Handler{broker: broker, heartbeat: heartbeat}
+}
+
+// Stream serves production updates for one inverter as Server-Sent Events. The last known
+// state is sent immediately, followed by every change until the client disconnects.
+func (h *Handler) Stream(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	ctx := c.Request().Context()
+
+	if err := h.broker.Watch(ctx, inverterID); err != nil {
+		slog.ErrorContext(ctx, "Failed to register live watcher", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Live updates unavailable")
+	}
+	sub := h.broker.Subscribe(ctx, inverterID)
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if last, err := h.broker.Last(ctx, inverterID); err != nil {
+		slog.ErrorContext(ctx, "Failed to read last production state", "inverterID", inverterID, "error", err)
+	} else if last != nil {
+		if err := writeEvent(res, *last); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-sub.Updates():
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, update); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-heartbeat.C:
+			if err := h.broker.Watch(ctx, inverterID); err != nil {
+				slog.ErrorContext(ctx, "Failed to refresh live watcher", "inverterID", inverterID, "error", err)
+			}
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func writeEvent(res *echo.Response, update ProductionUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "event: production\ndata: %s\n\n", body)
+	return err
+}
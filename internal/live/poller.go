@@ -0,0 +1,73 @@
+package live
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+)
+
+// InverterSource fetches the current state of an inverter from the provider.
+type InverterSource interface {
+	GetInverter(ctx context.Context, inverterID string) (*inverters.SolarInverter, error)
+}
+
+// Poller refreshes inverters that have live subscribers. Each inverter is polled by at most one
+// replica per interval, so Enode load scales with watched inverters rather than connected clients.
+type Poller struct {
+	broker   *Broker
+	source   InverterSource
+	interval time.Duration
+}
+
+func NewPoller(broker *Broker, source InverterSource, interval time.Duration) *Poller {
+	return &Poller{broker: broker, source: source, interval: interval}
+}
+
+func (p *Poller) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting live production poller", "interval", p.interval)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping live production poller")
+			return
+		case <-ticker.C:
+			if err := p.PollOnce(ctx); err != nil {
+				slog.ErrorContext(ctx, "Live production poll failed", "error", err)
+			}
+		}
+	}
+}
+
+func (p *Poller) PollOnce(ctx context.Context) error {
+	watched, err := p.broker.Watched(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Release the claim slightly before the next tick so the same or another replica can take it.
+	claimTTL := p.interval * 9 / 10
+	for _, inverterID := range watched {
+		claimed, err := p.broker.ClaimPoll(ctx, inverterID, claimTTL)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		inverter, err := p.source.GetInverter(ctx, inverterID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to poll inverter", "inverterID", inverterID, "error", err)
+			continue
+		}
+		if _, err := p.broker.Publish(ctx, NewProductionUpdate(*inverter)); err != nil {
+			slog.ErrorContext(ctx, "Failed to publish production update", "inverterID", inverterID, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package live
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	ErrAccessTokenExpired = errors.New("access token expired")
+)
+
+// accessTokenClaims is the signed part of an access token: the Enode user IDs the holder may
+// observe and when the token stops being accepted, in Unix seconds.
+type accessTokenClaims struct {
+	Users     []string `json:"users"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// SignAccessToken returns a token granting access to users until expiresAt. Tokens have the form
+// base64url(claims JSON) "." base64url(HMAC-SHA256 of the first part under secret), so the core
+// service can mint them with any HMAC library.
+func SignAccessToken(secret []byte, users []string, expiresAt time.Time) (string, error) {
+	claims, err := json.Marshal(accessTokenClaims{Users: users, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, payload)), nil
+}
+
+// VerifyAccessToken checks token's signature and expiry and returns the users it grants.
+func VerifyAccessToken(secret []byte, token string, now time.Time) ([]string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidAccessToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, tokenSignature(secret, payload)) {
+		return nil, ErrInvalidAccessToken
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	var claims accessTokenClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrAccessTokenExpired
+	}
+	return claims.Users, nil
+}
+
+func tokenSignature(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,68 @@
+// Package logging ties slog records to the request they were written for.
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestFields identifies the request a context belongs to.
+type RequestFields struct {
+	RequestID string
+	UserID    string
+	Route     string
+}
+
+type fieldsKey struct{}
+
+func WithRequestFields(ctx context.Context, fields RequestFields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func RequestFieldsFromContext(ctx context.Context) (RequestFields, bool) {
+	fields, ok := ctx.Value(fieldsKey{}).(RequestFields)
+	return fields, ok
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	fields, _ := RequestFieldsFromContext(ctx)
+	return fields.RequestID
+}
+
+// ContextHandler adds the request ID, user ID, route and trace ID found in the context to each
+// record, so every line logged for a request can be joined with its access log line. Records
+// must be written with the slog *Context functions for the fields to be found.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: next}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields, ok := RequestFieldsFromContext(ctx); ok {
+		record.AddAttrs(slog.String("request_id", fields.RequestID))
+		if fields.UserID != "" {
+			record.AddAttrs(slog.String("user_id", fields.UserID))
+		}
+		if fields.Route != "" {
+			record.AddAttrs(slog.String("route", fields.Route))
+		}
+	}
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/labstack/echo/v4"
+)
+
+// ParseLevel reads a level name such as "debug", "INFO" or "warn+2".
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(name)))
+	return level, err
+}
+
+type LevelResponse struct {
+	Level    string `json:"level"`
+	Instance string `json:"instance"`
+}
+
+type SetLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler reads and changes the minimum level of the running process. Changes apply to the
+// replica that serves the request only and last until it restarts.
+type LevelHandler struct {
+	level    *slog.LevelVar
+	recorder audit.Recorder
+	instance string
+}
+
+func NewLevelHandler(level *slog.LevelVar, recorder audit.Recorder) *LevelHandler {
+	instance, _ := os.Hostname()
+	return &LevelHandler{level: level, recorder: recorder, instance: instance}
+}
+
+func (h *LevelHandler) GetLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, LevelResponse{Level: h.level.Level().String(), Instance: h.instance})
+}
+
+func (h *LevelHandler) SetLevel(c echo.Context) error {
+	ctx := c.Request().Context()
+	var request SetLevelRequest
+	if err := c.Bind(&request); err != nil {
+		slog.ErrorContext(ctx, "Failed to bind request", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	level, err := ParseLevel(request.Level)
+	if err != nil {
+		slog.WarnContext(ctx, "Rejected unknown log level", "level", request.Level)
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown log level")
+	}
+
+	before := LevelResponse{Level: h.level.Level().String(), Instance: h.instance}
+	after := LevelResponse{Level: level.String(), Instance: h.instance}
+	err = audit.Record(ctx, h.recorder, audit.Entry{
+		Action:       audit.ActionLogLevelChanged,
+		ResourceType: audit.ResourceLogLevel,
+		ResourceID:   h.instance,
+		Before:       before,
+		After:        after,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record log level change", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to change log level")
+	}
+
+	h.level.Set(level)
+	// Logged at Warn so the change is visible at any level.
+	slog.WarnContext(ctx, "Changed log level", "from", before.Level, "to", after.Level)
+	return c.JSON(http.StatusOK, after)
+}
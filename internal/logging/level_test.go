@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeRecorder struct {
+	entries []db.InsertAuditLogEntryParams
+}
+
+func (f *fakeRecorder) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.entries = append(f.entries, arg)
+	return db.AuditLog{}, nil
+}
+
+func TestLevelHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantLevel  slog.Level
+	}{
+		{name: "debug", body: `{"level":"debug"}`, wantStatus: http.StatusOK, wantLevel: slog.LevelDebug},
+		{name: "offset", body: `{"level":"WARN+2"}`, wantStatus: http.StatusOK, wantLevel: slog.LevelWarn + 2},
+		{name: "unknown", body: `{"level":"verbose"}`, wantStatus: http.StatusBadRequest, wantLevel: slog.LevelInfo},
+		{name: "missing", body: `{}`, wantStatus: http.StatusBadRequest, wantLevel: slog.LevelInfo},
+		{name: "malformed", body: `{`, wantStatus: http.StatusBadRequest, wantLevel: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLogs(t)
+			level := new(slog.LevelVar)
+			recorder := &fakeRecorder{}
+			handler := NewLevelHandler(level, recorder)
+			e := echo.New()
+			e.Use(audit.Middleware())
+			e.GET("/admin/log-level", handler.GetLevel)
+			e.PUT("/admin/log-level", handler.SetLevel)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(audit.ActorHeader, "ops")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || level.Level() != tt.wantLevel {
+				t.Fatalf("status = %d, level = %s; want %d, %s", rec.Code, level.Level(), tt.wantStatus, tt.wantLevel)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(recorder.entries) != 0 {
+					t.Fatalf("rejected change was audited: %+v", recorder.entries)
+				}
+				return
+			}
+			if len(recorder.entries) != 1 || recorder.entries[0].Action != audit.ActionLogLevelChanged || recorder.entries[0].Actor != "ops" {
+				t.Fatalf("audit entries = %+v", recorder.entries)
+			}
+
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+			if !strings.Contains(rec.Body.String(), `"level":"`+tt.wantLevel.String()+`"`) {
+				t.Fatalf("GET body = %s", rec.Body.String())
+			}
+		})
+	}
+}
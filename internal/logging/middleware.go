@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLength bounds request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// Middleware accepts the caller's X-Request-ID or generates one, echoes it on the response and
+// stores it in the request context with the user ID and matched route. One access log line is
+// written per request through the default logger, carrying the same fields.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			requestID := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+			c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+			ctx := WithRequestFields(req.Context(), RequestFields{
+				RequestID: requestID,
+				UserID:    c.Param("userID"),
+				Route:     c.Path(),
+			})
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// Write the error response now so its status and size are logged.
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			slog.LogAttrs(ctx, level, "Handled request",
+				slog.String("method", req.Method),
+				slog.String("uri", req.RequestURI),
+				slog.Int("status", status),
+				slog.Int64("bytes_out", c.Response().Size),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", c.RealIP()),
+			)
+			return err
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
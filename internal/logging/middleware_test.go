@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// captureLogs routes the default logger through a ContextHandler into a buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		target     string
+		wantStatus int
+		wantLevel  string
+		keepID     bool
+	}{
+		{name: "accepts request ID", requestID: "req-42.a:b_c", target: "/users/42/things", wantStatus: http.StatusOK, wantLevel: "INFO", keepID: true},
+		{name: "generates request ID", target: "/users/42/things", wantStatus: http.StatusOK, wantLevel: "INFO"},
+		{name: "replaces invalid request ID", requestID: "bad id\n", target: "/users/42/things", wantStatus: http.StatusOK, wantLevel: "INFO"},
+		{name: "replaces oversized request ID", requestID: strings.Repeat("a", maxRequestIDLength+1), target: "/users/42/things", wantStatus: http.StatusOK, wantLevel: "INFO"},
+		{name: "logs handler errors", target: "/users/7/fail", wantStatus: http.StatusConflict, wantLevel: "WARN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLogs(t)
+			var seenID string
+			e := echo.New()
+			e.Use(Middleware())
+			e.GET("/users/:userID/things", func(c echo.Context) error {
+				seenID = c.Request().Header.Get(echo.HeaderXRequestID)
+				slog.InfoContext(c.Request().Context(), "Listing things")
+				return c.String(http.StatusOK, "ok")
+			})
+			e.GET("/users/:userID/fail", func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusConflict, "conflict")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			requestID := rec.Header().Get(echo.HeaderXRequestID)
+			if !validRequestID(requestID) || (tt.keepID && requestID != tt.requestID) || (!tt.keepID && requestID == tt.requestID) {
+				t.Fatalf("response request ID = %q", requestID)
+			}
+			if seenID != "" && seenID != requestID {
+				t.Fatalf("handler saw request ID %q, response has %q", seenID, requestID)
+			}
+
+			records := decodeLines(t, buf)
+			access := records[len(records)-1]
+			if access["msg"] != "Handled request" || access["level"] != tt.wantLevel || access["status"] != float64(tt.wantStatus) {
+				t.Fatalf("access log = %v", access)
+			}
+			for _, record := range records {
+				if record["request_id"] != requestID || record["user_id"] == nil || !strings.HasPrefix(record["route"].(string), "/users/:userID/") {
+					t.Fatalf("record missing request fields: %v", record)
+				}
+			}
+		})
+	}
+}
+
+func TestContextHandler_OutsideRequest(t *testing.T) {
+	buf := captureLogs(t)
+	slog.Info("Background work")
+
+	record := decodeLines(t, buf)[0]
+	if _, ok := record["request_id"]; ok {
+		t.Fatalf("record outside a request has request fields: %v", record)
+	}
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute and field names whose values are never logged. Names are compared
+// lower-cased with '_' and '-' removed, so "access_token" also covers "accessToken".
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"idtoken":       true,
+	"linktoken":     true,
+	"clientsecret":  true,
+	"secret":        true,
+	"password":      true,
+	"authorization": true,
+	"codeverifier":  true,
+	"code":          true,
+	"email":         true,
+	"serial":        true,
+	"serialnumber":  true,
+}
+
+var (
+	bearerPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]{8,}=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	serialPattern = regexp.MustCompile(`(?i)(\bserial number\s+)([A-Za-z0-9][A-Za-z0-9\-._/]*)`)
+	// fieldPattern finds sensitive fields embedded in JSON, form or query strings, such as
+	// upstream error bodies.
+	fieldPattern = regexp.MustCompile(`(?i)("?\b(access_token|refresh_token|id_token|link_token|linkToken|client_secret|password|code_verifier|serial_number|serialNumber|email)"?\s*[:=]\s*"?)([^"&,\s}]+)`)
+)
+
+// RedactingHandler scrubs credentials and personal data from records before they are written:
+// values of sensitive attributes are replaced entirely, and tokens, emails and sensitive fields
+// are masked inside messages, strings and errors.
+type RedactingHandler struct {
+	slog.Handler
+}
+
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{Handler: next}
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	scrubbed := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		scrubbed.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, scrubbed)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		scrubbed[i] = redactAttr(attr)
+	}
+	return &RedactingHandler{Handler: h.Handler.WithAttrs(scrubbed)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Redact masks bearer and basic credentials, JWTs, email addresses, serial numbers and
+// sensitive fields in s.
+func Redact(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = fieldPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = serialPattern.ReplaceAllString(s, "${1}"+redacted)
+	return emailPattern.ReplaceAllString(s, redacted)
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	if isSensitiveKey(attr.Key) && value.Kind() != slog.KindGroup {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, member := range group {
+			scrubbed[i] = redactAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(scrubbed...)}
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+func isSensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[normalized]
+}
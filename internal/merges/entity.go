@@ -0,0 +1,132 @@
+package merges
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+// DuplicateInverterResponse is one local inverter sharing a serial number with an Enode inverter.
+type DuplicateInverterResponse struct {
+	ID                    string    `json:"id"`
+	UserID                string    `json:"userId"`
+	Vendor                string    `json:"vendor"`
+	Model                 string    `json:"model"`
+	CreatedAt             time.Time `json:"createdAt"`
+	LinkedEnodeInverterID string    `json:"linkedEnodeInverterId,omitempty"`
+}
+
+// DuplicateGroupResponse describes a physical inverter that is counted more than once: an Enode
+// inverter and every local row with its serial number.
+type DuplicateGroupResponse struct {
+	SerialNumber            string                      `json:"serialNumber"`
+	EnodeInverterID         string                      `json:"enodeInverterId"`
+	EnodeUserID             string                      `json:"enodeUserId"`
+	Inverters               []DuplicateInverterResponse `json:"inverters"`
+	SuggestedKeepInverterID string                      `json:"suggestedKeepInverterId"`
+}
+
+type ListDuplicatesResponse struct {
+	Data []DuplicateGroupResponse `json:"data"`
+}
+
+type MergeInvertersRequest struct {
+	EnodeInverterID string `json:"enodeInverterId" validate:"required"`
+	KeepInverterID  string `json:"keepInverterId" validate:"required"`
+}
+
+// MergeRecordResponse is one entry of the merge audit trail. MergedInverterID is empty when the
+// kept inverter was only linked to its Enode inverter.
+type MergeRecordResponse struct {
+	ID                string          `json:"id"`
+	SerialNumber      string          `json:"serialNumber"`
+	EnodeInverterID   string          `json:"enodeInverterId"`
+	KeptInverterID    string          `json:"keptInverterId"`
+	MergedInverterID  string          `json:"mergedInverterId,omitempty"`
+	MergedInverter    json.RawMessage `json:"mergedInverter,omitempty"`
+	PanelsReassigned  int32           `json:"panelsReassigned"`
+	RecordsReassigned int32           `json:"recordsReassigned"`
+	DiscardedRecords  json.RawMessage `json:"discardedRecords"`
+	MergedBy          string          `json:"mergedBy"`
+	MergedAt          time.Time       `json:"mergedAt"`
+}
+
+type MergeInvertersResponse struct {
+	KeptInverterID  string                `json:"keptInverterId"`
+	EnodeInverterID string                `json:"enodeInverterId"`
+	SerialNumber    string                `json:"serialNumber"`
+	Merges          []MergeRecordResponse `json:"merges"`
+}
+
+type ListMergesResponse struct {
+	Data []MergeRecordResponse `json:"data"`
+}
+
+type ListMergesFilter struct {
+	SerialNumber string
+	InverterID   string
+	Limit        int
+	Offset       int
+}
+
+// MergeEventPayload is the outbox payload for inverter.merged events.
+type MergeEventPayload struct {
+	Merge MergeRecordResponse `json:"merge"`
+}
+
+// inverterSnapshot is the copy of a merged inverter kept in the audit trail.
+type inverterSnapshot struct {
+	ID                      int32     `json:"id"`
+	UserID                  int32     `json:"userId"`
+	Vendor                  string    `json:"vendor"`
+	Model                   string    `json:"model"`
+	SerialNumber            string    `json:"serialNumber"`
+	TotalLifetimeProduction float64   `json:"totalLifetimeProduction"`
+	InstallationDate        time.Time `json:"installationDate"`
+	CreatedAt               time.Time `json:"createdAt"`
+}
+
+// discardedRecord is an hourly reading dropped because the kept inverter already had that hour.
+type discardedRecord struct {
+	ID                 int32     `json:"id"`
+	Timestamp          time.Time `json:"timestamp"`
+	PowerOutputKw      float64   `json:"powerOutputKw"`
+	EnergyGeneratedKwh float64   `json:"energyGeneratedKwh"`
+}
+
+func newMergeRecordResponse(merge db.InverterMerge) MergeRecordResponse {
+	response := MergeRecordResponse{
+		ID:                strconv.FormatInt(merge.ID, 10),
+		SerialNumber:      merge.SerialNumber,
+		EnodeInverterID:   merge.EnodeInverterID,
+		KeptInverterID:    strconv.FormatInt(int64(merge.KeptInverterID), 10),
+		PanelsReassigned:  merge.PanelsReassigned,
+		RecordsReassigned: merge.RecordsReassigned,
+		DiscardedRecords:  merge.DiscardedRecords,
+		MergedBy:          merge.MergedBy,
+		MergedAt:          merge.MergedAt,
+	}
+	if merge.MergedInverterID.Valid {
+		response.MergedInverterID = strconv.FormatInt(int64(merge.MergedInverterID.Int32), 10)
+	}
+	if len(merge.MergedInverter) > 0 {
+		response.MergedInverter = merge.MergedInverter
+	}
+	if len(response.DiscardedRecords) == 0 {
+		response.DiscardedRecords = json.RawMessage("[]")
+	}
+	return response
+}
+
+func newDuplicateInverterResponse(inverter db.Inverter, linkedEnodeInverterID string) DuplicateInverterResponse {
+	return DuplicateInverterResponse{
+		ID:                    strconv.FormatInt(int64(inverter.ID), 10),
+		UserID:                strconv.FormatInt(int64(inverter.UserID), 10),
+		Vendor:                inverter.Vendor,
+		Model:                 inverter.Model,
+		CreatedAt:             inverter.CreatedAt,
+		LinkedEnodeInverterID: linkedEnodeInverterID,
+	}
+}
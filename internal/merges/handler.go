@@ -0,0 +1,87 @@
+package merges
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/labstack/echo/v4"
+)
+
+type MergeHandler struct {
+	mergeUseCase *MergeUseCase
+}
+
+func NewMergeHandler(mergeUseCase *MergeUseCase) *MergeHandler {
+	return &MergeHandler{
+		mergeUseCase: mergeUseCase,
+	}
+}
+
+func (h *MergeHandler) ListDuplicates(c echo.Context) error {
+	duplicates, err := h.mergeUseCase.ListDuplicates(c.Request().Context())
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list duplicate inverters", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list duplicate inverters")
+	}
+
+	return c.JSON(http.StatusOK, duplicates)
+}
+
+func (h *MergeHandler) MergeInverters(c echo.Context) error {
+	var request MergeInvertersRequest
+	if err := c.Bind(&request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := c.Validate(request); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Validation failed for MergeInvertersRequest", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Validation failed")
+	}
+
+	merged, err := h.mergeUseCase.MergeInverters(c.Request().Context(), request)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to merge inverters", "enodeInverterID", request.EnodeInverterID, "keepInverterID", request.KeepInverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to merge inverters")
+	}
+
+	slog.InfoContext(c.Request().Context(), "Merged inverters", "enodeInverterID", merged.EnodeInverterID, "keepInverterID", merged.KeptInverterID, "merges", len(merged.Merges))
+	return c.JSON(http.StatusOK, merged)
+}
+
+func (h *MergeHandler) ListMerges(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	filter := ListMergesFilter{
+		SerialNumber: c.QueryParam("serialNumber"),
+		InverterID:   c.QueryParam("inverterId"),
+		Limit:        limit,
+		Offset:       offset,
+	}
+
+	merges, err := h.mergeUseCase.ListMerges(c.Request().Context(), filter)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list inverter merges", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list inverter merges")
+	}
+
+	return c.JSON(http.StatusOK, merges)
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInverterID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInverterNotFound), errors.Is(err, inverters.ErrInverterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrMergeConflict):
+		return http.StatusConflict
+	case errors.Is(err, inverters.ErrUpstreamTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,50 @@
+package merges
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MergeStore reads duplicate inverters and moves the rows that reference them.
+type MergeStore interface {
+	GetInverterById(ctx context.Context, id int32) (db.Inverter, error)
+	ListInvertersBySerialNumbers(ctx context.Context, serialNumbers []string) ([]db.Inverter, error)
+	ListInverterEnodeLinks(ctx context.Context, inverterIds []int32) ([]db.InverterEnodeLink, error)
+	LinkInverterToEnode(ctx context.Context, arg db.LinkInverterToEnodeParams) error
+	ReassignSolarPanels(ctx context.Context, arg db.ReassignSolarPanelsParams) (int64, error)
+	DeleteOverlappingHourlyRecords(ctx context.Context, arg db.DeleteOverlappingHourlyRecordsParams) ([]db.DeleteOverlappingHourlyRecordsRow, error)
+	ReassignHourlyRecords(ctx context.Context, arg db.ReassignHourlyRecordsParams) (int64, error)
+	LockProductionRollups(ctx context.Context) error
+	MoveFoldedHourlyProduction(ctx context.Context, arg db.MoveFoldedHourlyProductionParams) (int64, error)
+	DeleteInverterProductionRollups(ctx context.Context, inverterID int32) error
+	RefreshMonthlyProduction(ctx context.Context) (int64, error)
+	RefreshYearlyProduction(ctx context.Context) (int64, error)
+	DeleteInverter(ctx context.Context, id int32) error
+	InsertInverterMerge(ctx context.Context, arg db.InsertInverterMergeParams) (db.InverterMerge, error)
+	ListInverterMerges(ctx context.Context, arg db.ListInverterMergesParams) ([]db.InverterMerge, error)
+	InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error)
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+	InTx(ctx context.Context, fn func(store MergeStore) error) error
+}
+
+// PostgresMergeStore is the MergeStore backed by sqlc queries on a pgx pool.
+type PostgresMergeStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresMergeStore(pool *pgxpool.Pool) *PostgresMergeStore {
+	return &PostgresMergeStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresMergeStore) InTx(ctx context.Context, fn func(store MergeStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresMergeStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
@@ -0,0 +1,382 @@
+package merges
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+var (
+	ErrInverterNotFound  = errors.New("inverter not found")
+	ErrInvalidInverterID = errors.New("invalid inverter id")
+	ErrMergeConflict     = errors.New("inverters cannot be merged")
+)
+
+// InverterSource reads the inverters Enode reports for every linked user.
+type InverterSource interface {
+	IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error]
+	GetInverter(ctx context.Context, inverterID string) (*inverters.SolarInverter, error)
+}
+
+type MergeUseCase struct {
+	store  MergeStore
+	source InverterSource
+}
+
+func NewMergeUseCase(store MergeStore, source InverterSource) *MergeUseCase {
+	return &MergeUseCase{store: store, source: source}
+}
+
+// ListDuplicates returns every Enode inverter whose serial number is also registered locally by the
+// same user, unless a single local row already links to it. Such inverters are reported twice: once
+// from their local hourly records and once from Enode. Another user's inverter with the same serial
+// is not a duplicate and is never offered for merging.
+func (uc *MergeUseCase) ListDuplicates(ctx context.Context) (*ListDuplicatesResponse, error) {
+	var remotes []inverters.SolarInverter
+	var serialNumbers []string
+	for inverter, err := range uc.source.IterateInverters(ctx, 0) {
+		if err != nil {
+			return nil, fmt.Errorf("listing enode inverters: %w", err)
+		}
+		if inverter.Information.SerialNumber == nil || *inverter.Information.SerialNumber == "" {
+			continue
+		}
+		remotes = append(remotes, inverter)
+		serialNumbers = append(serialNumbers, *inverter.Information.SerialNumber)
+	}
+	response := &ListDuplicatesResponse{Data: []DuplicateGroupResponse{}}
+	if len(remotes) == 0 {
+		return response, nil
+	}
+
+	locals, err := uc.store.ListInvertersBySerialNumbers(ctx, serialNumbers)
+	if err != nil {
+		return nil, fmt.Errorf("listing local inverters: %w", err)
+	}
+	links, err := uc.enodeLinks(ctx, uc.store, locals)
+	if err != nil {
+		return nil, err
+	}
+	bySerial := make(map[userSerial][]db.Inverter)
+	for _, local := range locals {
+		key := userSerial{userID: strconv.FormatInt(int64(local.UserID), 10), serialNumber: local.SerialNumber}
+		bySerial[key] = append(bySerial[key], local)
+	}
+
+	slices.SortFunc(remotes, func(a, b inverters.SolarInverter) int {
+		return cmp.Or(cmp.Compare(*a.Information.SerialNumber, *b.Information.SerialNumber), cmp.Compare(a.ID, b.ID))
+	})
+	for _, remote := range remotes {
+		matches := bySerial[userSerial{userID: remote.UserID, serialNumber: *remote.Information.SerialNumber}]
+		if len(matches) == 0 || len(matches) == 1 && links[matches[0].ID] == remote.ID {
+			continue
+		}
+		slices.SortFunc(matches, func(a, b db.Inverter) int { return cmp.Compare(a.ID, b.ID) })
+
+		group := DuplicateGroupResponse{
+			SerialNumber:            *remote.Information.SerialNumber,
+			EnodeInverterID:         remote.ID,
+			EnodeUserID:             remote.UserID,
+			SuggestedKeepInverterID: strconv.FormatInt(int64(suggestKeep(matches, links, remote.ID)), 10),
+		}
+		for _, match := range matches {
+			group.Inverters = append(group.Inverters, newDuplicateInverterResponse(match, links[match.ID]))
+		}
+		response.Data = append(response.Data, group)
+	}
+	return response, nil
+}
+
+// MergeInverters folds every local inverter sharing the Enode inverter's serial number into the
+// kept one and links the kept inverter to Enode. Solar panels and hourly records move to the kept
+// inverter; readings for hours it already has are discarded so they are not counted twice. Each
+// merged inverter is deleted and recorded in the audit trail with an inverter.merged event, merged
+// by the calling actor.
+func (uc *MergeUseCase) MergeInverters(ctx context.Context, request MergeInvertersRequest) (*MergeInvertersResponse, error) {
+	mergedBy := audit.ActorFromContext(ctx).ID
+	keepID, err := parseInverterID(request.KeepInverterID)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := uc.source.GetInverter(ctx, request.EnodeInverterID)
+	if err != nil {
+		return nil, err
+	}
+	if remote.Information.SerialNumber == nil || *remote.Information.SerialNumber == "" {
+		return nil, fmt.Errorf("%w: enode inverter %s has no serial number", ErrMergeConflict, remote.ID)
+	}
+	serialNumber := *remote.Information.SerialNumber
+
+	response := &MergeInvertersResponse{
+		KeptInverterID:  strconv.FormatInt(int64(keepID), 10),
+		EnodeInverterID: remote.ID,
+		SerialNumber:    serialNumber,
+	}
+	err = uc.store.InTx(ctx, func(store MergeStore) error {
+		keep, err := store.GetInverterById(ctx, keepID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrInverterNotFound, keepID)
+		}
+		if err != nil {
+			return err
+		}
+		if keep.SerialNumber != serialNumber {
+			return fmt.Errorf("%w: inverter %d has serial number %s, enode inverter %s has serial number %s", ErrMergeConflict, keep.ID, keep.SerialNumber, remote.ID, serialNumber)
+		}
+		if strconv.FormatInt(int64(keep.UserID), 10) != remote.UserID {
+			return fmt.Errorf("%w: inverter %d belongs to user %d, enode inverter %s to user %s", ErrMergeConflict, keep.ID, keep.UserID, remote.ID, remote.UserID)
+		}
+
+		matches, err := store.ListInvertersBySerialNumbers(ctx, []string{serialNumber})
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(matches, func(a, b db.Inverter) int { return cmp.Compare(a.ID, b.ID) })
+		duplicates := slices.DeleteFunc(matches, func(match db.Inverter) bool { return match.ID == keep.ID })
+		for _, duplicate := range duplicates {
+			if duplicate.UserID != keep.UserID {
+				return fmt.Errorf("%w: inverter %d belongs to user %d, not %d", ErrMergeConflict, duplicate.ID, duplicate.UserID, keep.UserID)
+			}
+		}
+
+		links, err := uc.enodeLinks(ctx, store, []db.Inverter{keep})
+		if err != nil {
+			return err
+		}
+		if len(duplicates) == 0 && links[keep.ID] == remote.ID {
+			return fmt.Errorf("%w: inverter %d is already linked to enode inverter %s", ErrMergeConflict, keep.ID, remote.ID)
+		}
+		if err := store.LinkInverterToEnode(ctx, db.LinkInverterToEnodeParams{InverterID: keep.ID, EnodeInverterID: remote.ID}); err != nil {
+			return fmt.Errorf("linking inverter %d: %w", keep.ID, err)
+		}
+
+		if len(duplicates) == 0 {
+			merge, err := recordMerge(ctx, store, keep, db.InsertInverterMergeParams{
+				SerialNumber:     serialNumber,
+				EnodeInverterID:  remote.ID,
+				KeptInverterID:   keep.ID,
+				DiscardedRecords: []byte("[]"),
+				MergedBy:         mergedBy,
+			})
+			if err != nil {
+				return err
+			}
+			response.Merges = append(response.Merges, merge)
+			return nil
+		}
+		for _, duplicate := range duplicates {
+			arg, err := mergeInto(ctx, store, keep, duplicate)
+			if err != nil {
+				return fmt.Errorf("merging inverter %d into %d: %w", duplicate.ID, keep.ID, err)
+			}
+			arg.EnodeInverterID = remote.ID
+			arg.MergedBy = mergedBy
+			merge, err := recordMerge(ctx, store, keep, arg)
+			if err != nil {
+				return err
+			}
+			response.Merges = append(response.Merges, merge)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge inverters: %w", err)
+	}
+	return response, nil
+}
+
+func (uc *MergeUseCase) ListMerges(ctx context.Context, filter ListMergesFilter) (*ListMergesResponse, error) {
+	arg := db.ListInverterMergesParams{
+		SerialNumber: pgtype.Text{String: filter.SerialNumber, Valid: filter.SerialNumber != ""},
+		Limit:        int32(min(cmp.Or(max(filter.Limit, 0), defaultListLimit), maxListLimit)),
+		Offset:       int32(max(filter.Offset, 0)),
+	}
+	if filter.InverterID != "" {
+		id, err := parseInverterID(filter.InverterID)
+		if err != nil {
+			return nil, err
+		}
+		arg.InverterID = pgtype.Int4{Int32: id, Valid: true}
+	}
+
+	merges, err := uc.store.ListInverterMerges(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("listing inverter merges: %w", err)
+	}
+	response := &ListMergesResponse{Data: make([]MergeRecordResponse, 0, len(merges))}
+	for _, merge := range merges {
+		response.Data = append(response.Data, newMergeRecordResponse(merge))
+	}
+	return response, nil
+}
+
+func (uc *MergeUseCase) enodeLinks(ctx context.Context, store MergeStore, locals []db.Inverter) (map[int32]string, error) {
+	ids := make([]int32, 0, len(locals))
+	for _, local := range locals {
+		ids = append(ids, local.ID)
+	}
+	rows, err := store.ListInverterEnodeLinks(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("listing enode links: %w", err)
+	}
+	links := make(map[int32]string, len(rows))
+	for _, row := range rows {
+		links[row.InverterID] = row.EnodeInverterID
+	}
+	return links, nil
+}
+
+// mergeInto moves everything referencing duplicate onto keep and deletes duplicate. Production of
+// the duplicate's records already folded into the rollups is added to the kept inverter before its
+// rollups are dropped; records not folded yet are counted for the kept inverter by the next
+// refresh. The rollup lock keeps the refresher from folding while records move.
+func mergeInto(ctx context.Context, store MergeStore, keep db.Inverter, duplicate db.Inverter) (db.InsertInverterMergeParams, error) {
+	if err := store.LockProductionRollups(ctx); err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("locking production rollups: %w", err)
+	}
+	panels, err := store.ReassignSolarPanels(ctx, db.ReassignSolarPanelsParams{
+		ToInverterID:   pgtype.Int4{Int32: keep.ID, Valid: true},
+		FromInverterID: pgtype.Int4{Int32: duplicate.ID, Valid: true},
+	})
+	if err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("reassigning solar panels: %w", err)
+	}
+	overlapping, err := store.DeleteOverlappingHourlyRecords(ctx, db.DeleteOverlappingHourlyRecordsParams{
+		FromInverterID: duplicate.ID,
+		ToInverterID:   keep.ID,
+	})
+	if err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("discarding overlapping hourly records: %w", err)
+	}
+	_, err = store.MoveFoldedHourlyProduction(ctx, db.MoveFoldedHourlyProductionParams{
+		ToInverterID:   keep.ID,
+		FromInverterID: duplicate.ID,
+	})
+	if err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("moving production rollups: %w", err)
+	}
+	records, err := store.ReassignHourlyRecords(ctx, db.ReassignHourlyRecordsParams{
+		ToInverterID:   keep.ID,
+		FromInverterID: duplicate.ID,
+	})
+	if err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("reassigning hourly records: %w", err)
+	}
+	if err := store.DeleteInverterProductionRollups(ctx, duplicate.ID); err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("deleting production rollups: %w", err)
+	}
+	if _, err := store.RefreshMonthlyProduction(ctx); err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("refreshing monthly production: %w", err)
+	}
+	if _, err := store.RefreshYearlyProduction(ctx); err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("refreshing yearly production: %w", err)
+	}
+	if err := store.DeleteInverter(ctx, duplicate.ID); err != nil {
+		return db.InsertInverterMergeParams{}, fmt.Errorf("deleting inverter: %w", err)
+	}
+
+	snapshot, err := json.Marshal(inverterSnapshot{
+		ID:                      duplicate.ID,
+		UserID:                  duplicate.UserID,
+		Vendor:                  duplicate.Vendor,
+		Model:                   duplicate.Model,
+		SerialNumber:            duplicate.SerialNumber,
+		TotalLifetimeProduction: duplicate.TotalLifetimeProductionKwh,
+		InstallationDate:        duplicate.InstallationDate,
+		CreatedAt:               duplicate.CreatedAt,
+	})
+	if err != nil {
+		return db.InsertInverterMergeParams{}, err
+	}
+	discarded := make([]discardedRecord, 0, len(overlapping))
+	for _, record := range overlapping {
+		discarded = append(discarded, discardedRecord{
+			ID:                 record.ID,
+			Timestamp:          record.Timestamp.Time,
+			PowerOutputKw:      record.PowerOutputKw,
+			EnergyGeneratedKwh: record.EnergyGeneratedKwh,
+		})
+	}
+	discardedJSON, err := json.Marshal(discarded)
+	if err != nil {
+		return db.InsertInverterMergeParams{}, err
+	}
+
+	return db.InsertInverterMergeParams{
+		SerialNumber:      keep.SerialNumber,
+		KeptInverterID:    keep.ID,
+		MergedInverterID:  pgtype.Int4{Int32: duplicate.ID, Valid: true},
+		MergedInverter:    snapshot,
+		PanelsReassigned:  int32(panels),
+		RecordsReassigned: int32(records),
+		DiscardedRecords:  discardedJSON,
+	}, nil
+}
+
+func recordMerge(ctx context.Context, store MergeStore, keep db.Inverter, arg db.InsertInverterMergeParams) (MergeRecordResponse, error) {
+	merge, err := store.InsertInverterMerge(ctx, arg)
+	if err != nil {
+		return MergeRecordResponse{}, fmt.Errorf("recording merge: %w", err)
+	}
+	response := newMergeRecordResponse(merge)
+	err = audit.Record(ctx, store, audit.Entry{
+		Action:       audit.ActionInverterMerged,
+		ResourceType: audit.ResourceInverter,
+		ResourceID:   response.KeptInverterID,
+		UserID:       strconv.FormatInt(int64(keep.UserID), 10),
+		Before:       response.MergedInverter,
+		After:        response,
+	})
+	if err != nil {
+		return MergeRecordResponse{}, err
+	}
+	event, err := outbox.NewEvent(outbox.AggregateInverter, response.KeptInverterID, outbox.EventInverterMerged, MergeEventPayload{Merge: response})
+	if err != nil {
+		return MergeRecordResponse{}, err
+	}
+	if _, err := store.InsertOutboxEvent(ctx, event); err != nil {
+		return MergeRecordResponse{}, fmt.Errorf("recording %s event: %w", outbox.EventInverterMerged, err)
+	}
+	return response, nil
+}
+
+// userSerial groups local inverters by owner and serial number.
+type userSerial struct {
+	userID       string
+	serialNumber string
+}
+
+// suggestKeep prefers the inverter already linked to the Enode inverter, then the oldest one.
+func suggestKeep(matches []db.Inverter, links map[int32]string, enodeInverterID string) int32 {
+	for _, match := range matches {
+		if links[match.ID] == enodeInverterID {
+			return match.ID
+		}
+	}
+	return matches[0].ID
+}
+
+func parseInverterID(inverterID string) (int32, error) {
+	id, err := strconv.ParseInt(inverterID, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidInverterID, inverterID)
+	}
+	return int32(id), nil
+}
@@ -0,0 +1,419 @@
+package merges
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type hourlyRecord struct {
+	id         int32
+	inverterID int32
+	timestamp  time.Time
+	energyKwh  float64
+}
+
+// fakeMergeStore is an in-memory MergeStore. InTx stages writes on a copy and applies them only
+// when fn succeeds.
+type fakeMergeStore struct {
+	inverters map[int32]db.Inverter
+	links     map[int32]string
+	panels    map[int32]int32
+	records   []hourlyRecord
+	// rollups holds the folded hourly energy per inverter; records up to cursor are folded.
+	rollups map[int32]float64
+	cursor  int32
+	locked  bool
+	merges  []db.InverterMerge
+	events  []db.InsertOutboxEventParams
+	audits  []db.InsertAuditLogEntryParams
+}
+
+func (f *fakeMergeStore) GetInverterById(ctx context.Context, id int32) (db.Inverter, error) {
+	inverter, ok := f.inverters[id]
+	if !ok {
+		return db.Inverter{}, pgx.ErrNoRows
+	}
+	return inverter, nil
+}
+
+func (f *fakeMergeStore) ListInvertersBySerialNumbers(ctx context.Context, serialNumbers []string) ([]db.Inverter, error) {
+	var matches []db.Inverter
+	for _, inverter := range f.inverters {
+		if slices.Contains(serialNumbers, inverter.SerialNumber) {
+			matches = append(matches, inverter)
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeMergeStore) ListInverterEnodeLinks(ctx context.Context, inverterIds []int32) ([]db.InverterEnodeLink, error) {
+	var links []db.InverterEnodeLink
+	for id, enodeID := range f.links {
+		if slices.Contains(inverterIds, id) {
+			links = append(links, db.InverterEnodeLink{InverterID: id, EnodeInverterID: enodeID})
+		}
+	}
+	return links, nil
+}
+
+func (f *fakeMergeStore) LinkInverterToEnode(ctx context.Context, arg db.LinkInverterToEnodeParams) error {
+	f.links[arg.InverterID] = arg.EnodeInverterID
+	return nil
+}
+
+func (f *fakeMergeStore) ReassignSolarPanels(ctx context.Context, arg db.ReassignSolarPanelsParams) (int64, error) {
+	var moved int64
+	for panel, inverterID := range f.panels {
+		if inverterID == arg.FromInverterID.Int32 {
+			f.panels[panel] = arg.ToInverterID.Int32
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (f *fakeMergeStore) DeleteOverlappingHourlyRecords(ctx context.Context, arg db.DeleteOverlappingHourlyRecordsParams) ([]db.DeleteOverlappingHourlyRecordsRow, error) {
+	var kept []hourlyRecord
+	var deleted []db.DeleteOverlappingHourlyRecordsRow
+	for _, record := range f.records {
+		overlaps := record.inverterID == arg.FromInverterID && slices.ContainsFunc(f.records, func(other hourlyRecord) bool {
+			return other.inverterID == arg.ToInverterID && other.timestamp.Equal(record.timestamp)
+		})
+		if !overlaps {
+			kept = append(kept, record)
+			continue
+		}
+		deleted = append(deleted, db.DeleteOverlappingHourlyRecordsRow{
+			ID:                 record.id,
+			Timestamp:          pgtype.Timestamp{Time: record.timestamp, Valid: true},
+			EnergyGeneratedKwh: record.energyKwh,
+		})
+	}
+	f.records = kept
+	return deleted, nil
+}
+
+func (f *fakeMergeStore) ReassignHourlyRecords(ctx context.Context, arg db.ReassignHourlyRecordsParams) (int64, error) {
+	var moved int64
+	for i := range f.records {
+		if f.records[i].inverterID == arg.FromInverterID {
+			f.records[i].inverterID = arg.ToInverterID
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (f *fakeMergeStore) LockProductionRollups(ctx context.Context) error {
+	f.locked = true
+	return nil
+}
+
+func (f *fakeMergeStore) MoveFoldedHourlyProduction(ctx context.Context, arg db.MoveFoldedHourlyProductionParams) (int64, error) {
+	var moved int64
+	for _, record := range f.records {
+		if record.inverterID == arg.FromInverterID && record.id <= f.cursor {
+			f.rollups[arg.ToInverterID] += record.energyKwh
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (f *fakeMergeStore) RefreshMonthlyProduction(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMergeStore) RefreshYearlyProduction(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeMergeStore) DeleteInverterProductionRollups(ctx context.Context, inverterID int32) error {
+	delete(f.rollups, inverterID)
+	return nil
+}
+
+func (f *fakeMergeStore) DeleteInverter(ctx context.Context, id int32) error {
+	delete(f.inverters, id)
+	delete(f.links, id)
+	return nil
+}
+
+func (f *fakeMergeStore) InsertInverterMerge(ctx context.Context, arg db.InsertInverterMergeParams) (db.InverterMerge, error) {
+	merge := db.InverterMerge{
+		ID:                int64(len(f.merges) + 1),
+		SerialNumber:      arg.SerialNumber,
+		EnodeInverterID:   arg.EnodeInverterID,
+		KeptInverterID:    arg.KeptInverterID,
+		MergedInverterID:  arg.MergedInverterID,
+		MergedInverter:    arg.MergedInverter,
+		PanelsReassigned:  arg.PanelsReassigned,
+		RecordsReassigned: arg.RecordsReassigned,
+		DiscardedRecords:  arg.DiscardedRecords,
+		MergedBy:          arg.MergedBy,
+	}
+	f.merges = append(f.merges, merge)
+	return merge, nil
+}
+
+func (f *fakeMergeStore) ListInverterMerges(ctx context.Context, arg db.ListInverterMergesParams) ([]db.InverterMerge, error) {
+	return f.merges, nil
+}
+
+func (f *fakeMergeStore) InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error) {
+	f.events = append(f.events, arg)
+	return db.OutboxEvent{ID: int64(len(f.events))}, nil
+}
+
+func (f *fakeMergeStore) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.audits = append(f.audits, arg)
+	return db.AuditLog{ID: int64(len(f.audits))}, nil
+}
+
+func (f *fakeMergeStore) InTx(ctx context.Context, fn func(store MergeStore) error) error {
+	staged := *f
+	staged.inverters = maps.Clone(f.inverters)
+	staged.links = maps.Clone(f.links)
+	staged.panels = maps.Clone(f.panels)
+	staged.records = slices.Clone(f.records)
+	staged.rollups = maps.Clone(f.rollups)
+	staged.merges = slices.Clone(f.merges)
+	staged.events = slices.Clone(f.events)
+	staged.audits = slices.Clone(f.audits)
+	if err := fn(&staged); err != nil {
+		return err
+	}
+	*f = staged
+	return nil
+}
+
+type fakeInverterSource struct {
+	inverters []inverters.SolarInverter
+}
+
+func (f *fakeInverterSource) IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error] {
+	return func(yield func(inverters.SolarInverter, error) bool) {
+		for _, inverter := range f.inverters {
+			if !yield(inverter, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeInverterSource) GetInverter(ctx context.Context, inverterID string) (*inverters.SolarInverter, error) {
+	for _, inverter := range f.inverters {
+		if inverter.ID == inverterID {
+			return &inverter, nil
+		}
+	}
+	return nil, inverters.ErrInverterNotFound
+}
+
+func enodeInverter(id string, userID string, serialNumber string) inverters.SolarInverter {
+	inverter := inverters.SolarInverter{ID: id, UserID: userID}
+	if serialNumber != "" {
+		inverter.Information.SerialNumber = &serialNumber
+	}
+	return inverter
+}
+
+func newFakeMergeStore() *fakeMergeStore {
+	hour := time.Date(2024, time.June, 21, 10, 0, 0, 0, time.UTC)
+	return &fakeMergeStore{
+		inverters: map[int32]db.Inverter{
+			1: {ID: 1, UserID: 42, Vendor: "SMA", SerialNumber: "SN-1"},
+			2: {ID: 2, UserID: 42, Vendor: "SMA", SerialNumber: "SN-1"},
+			3: {ID: 3, UserID: 42, Vendor: "Fronius", SerialNumber: "SN-2"},
+			4: {ID: 4, UserID: 7, Vendor: "Huawei", SerialNumber: "SN-3"},
+			5: {ID: 5, UserID: 42, Vendor: "Huawei", SerialNumber: "SN-3"},
+		},
+		links:  map[int32]string{3: "enode-2"},
+		panels: map[int32]int32{10: 1, 11: 2, 12: 2},
+		records: []hourlyRecord{
+			{id: 100, inverterID: 1, timestamp: hour, energyKwh: 1.5},
+			{id: 101, inverterID: 2, timestamp: hour, energyKwh: 1.5},
+			{id: 102, inverterID: 2, timestamp: hour.Add(time.Hour), energyKwh: 2},
+		},
+		rollups: map[int32]float64{1: 1.5, 2: 3.5},
+		cursor:  102,
+	}
+}
+
+func newFakeInverterSource() *fakeInverterSource {
+	return &fakeInverterSource{inverters: []inverters.SolarInverter{
+		enodeInverter("enode-1", "42", "SN-1"),
+		enodeInverter("enode-2", "42", "SN-2"),
+		enodeInverter("enode-3", "42", "SN-3"),
+		enodeInverter("enode-4", "42", ""),
+		enodeInverter("enode-5", "42", "SN-9"),
+	}}
+}
+
+func TestMergeUseCase_ListDuplicates(t *testing.T) {
+	uc := NewMergeUseCase(newFakeMergeStore(), newFakeInverterSource())
+
+	duplicates, err := uc.ListDuplicates(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var serials []string
+	for _, group := range duplicates.Data {
+		serials = append(serials, group.SerialNumber)
+	}
+	// SN-2 has a single local row already linked to its Enode inverter.
+	if !slices.Equal(serials, []string{"SN-1", "SN-3"}) {
+		t.Fatalf("duplicate serials = %v", serials)
+	}
+	group := duplicates.Data[0]
+	if group.EnodeInverterID != "enode-1" || len(group.Inverters) != 2 || group.SuggestedKeepInverterID != "1" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	// Inverter 4 has serial SN-3 too but belongs to another user.
+	if group := duplicates.Data[1]; len(group.Inverters) != 1 || group.Inverters[0].ID != "5" || group.SuggestedKeepInverterID != "5" {
+		t.Fatalf("unexpected group %+v", group)
+	}
+}
+
+func TestMergeUseCase_ListDuplicatesIgnoresOtherUsers(t *testing.T) {
+	store := newFakeMergeStore()
+	source := &fakeInverterSource{inverters: []inverters.SolarInverter{enodeInverter("enode-7", "7", "SN-1")}}
+	uc := NewMergeUseCase(store, source)
+
+	duplicates, err := uc.ListDuplicates(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// SN-1 is registered twice, but by user 42, not by the Enode inverter's user.
+	if len(duplicates.Data) != 0 {
+		t.Fatalf("duplicates = %+v", duplicates.Data)
+	}
+}
+
+func TestMergeUseCase_MergeInverters(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    MergeInvertersRequest
+		wantStatus int
+		wantMerges int
+	}{
+		{name: "merges duplicate into kept inverter", request: MergeInvertersRequest{EnodeInverterID: "enode-1", KeepInverterID: "1"}, wantMerges: 1},
+		{name: "already linked", request: MergeInvertersRequest{EnodeInverterID: "enode-2", KeepInverterID: "3"}, wantStatus: http.StatusConflict},
+		{name: "serial number mismatch", request: MergeInvertersRequest{EnodeInverterID: "enode-1", KeepInverterID: "3"}, wantStatus: http.StatusConflict},
+		{name: "duplicate owned by another user", request: MergeInvertersRequest{EnodeInverterID: "enode-3", KeepInverterID: "5"}, wantStatus: http.StatusConflict},
+		{name: "enode inverter without serial", request: MergeInvertersRequest{EnodeInverterID: "enode-4", KeepInverterID: "1"}, wantStatus: http.StatusConflict},
+		{name: "unknown enode inverter", request: MergeInvertersRequest{EnodeInverterID: "enode-9", KeepInverterID: "1"}, wantStatus: http.StatusNotFound},
+		{name: "unknown kept inverter", request: MergeInvertersRequest{EnodeInverterID: "enode-1", KeepInverterID: "99"}, wantStatus: http.StatusNotFound},
+		{name: "invalid kept inverter id", request: MergeInvertersRequest{EnodeInverterID: "enode-1", KeepInverterID: "abc"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeMergeStore()
+			uc := NewMergeUseCase(store, newFakeInverterSource())
+
+			merged, err := uc.MergeInverters(context.Background(), tt.request)
+			if tt.wantStatus != 0 {
+				if err == nil || statusFromError(err) != tt.wantStatus {
+					t.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+				}
+				if len(store.merges) != 0 || len(store.events) != 0 || len(store.inverters) != 5 {
+					t.Fatalf("failed merge left changes: merges %v, events %v", store.merges, store.events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(merged.Merges) != tt.wantMerges || len(store.events) != tt.wantMerges {
+				t.Fatalf("merges = %+v, events = %d", merged.Merges, len(store.events))
+			}
+		})
+	}
+}
+
+func TestMergeUseCase_MergeInvertersMovesReferences(t *testing.T) {
+	store := newFakeMergeStore()
+	uc := NewMergeUseCase(store, newFakeInverterSource())
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "ops"})
+	merged, err := uc.MergeInverters(ctx, MergeInvertersRequest{EnodeInverterID: "enode-1", KeepInverterID: "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := store.inverters[2]; ok {
+		t.Fatal("merged inverter was not deleted")
+	}
+	if store.links[1] != "enode-1" {
+		t.Fatalf("links = %v", store.links)
+	}
+	for panel, inverterID := range store.panels {
+		if inverterID != 1 {
+			t.Fatalf("panel %d still on inverter %d", panel, inverterID)
+		}
+	}
+	// The overlapping reading of inverter 2 is dropped, the other one moves.
+	var ids []int32
+	for _, record := range store.records {
+		if record.inverterID != 1 {
+			t.Fatalf("record %d still on inverter %d", record.id, record.inverterID)
+		}
+		ids = append(ids, record.id)
+	}
+	if !slices.Equal(ids, []int32{100, 102}) {
+		t.Fatalf("records = %v", ids)
+	}
+	// The discarded reading was only counted for inverter 2, whose rollups are dropped.
+	if _, ok := store.rollups[2]; ok || store.rollups[1] != 3.5 || !store.locked {
+		t.Fatalf("rollups = %v, locked = %v", store.rollups, store.locked)
+	}
+
+	merge := merged.Merges[0]
+	if merge.MergedInverterID != "2" || merge.PanelsReassigned != 2 || merge.RecordsReassigned != 1 || merge.MergedBy != "ops" {
+		t.Fatalf("unexpected merge %+v", merge)
+	}
+	var discarded []discardedRecord
+	if err := json.Unmarshal(merge.DiscardedRecords, &discarded); err != nil {
+		t.Fatal(err)
+	}
+	if len(discarded) != 1 || discarded[0].ID != 101 {
+		t.Fatalf("discarded = %+v", discarded)
+	}
+	if event := store.events[0]; event.EventType != outbox.EventInverterMerged || event.AggregateID != "1" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if entry := store.audits[0]; entry.Action != audit.ActionInverterMerged || entry.ResourceID != "1" || entry.Actor != "ops" {
+		t.Fatalf("unexpected audit entry %+v", entry)
+	}
+}
+
+func TestMergeUseCase_LinkOnly(t *testing.T) {
+	store := newFakeMergeStore()
+	delete(store.links, 3)
+	uc := NewMergeUseCase(store, newFakeInverterSource())
+
+	merged, err := uc.MergeInverters(context.Background(), MergeInvertersRequest{EnodeInverterID: "enode-2", KeepInverterID: "3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.links[3] != "enode-2" || len(merged.Merges) != 1 || merged.Merges[0].MergedInverterID != "" {
+		t.Fatalf("links = %v, merges = %+v", store.links, merged.Merges)
+	}
+	if len(store.inverters) != 5 {
+		t.Fatalf("inverters = %v", store.inverters)
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "evolyte_adapter"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests served by the adapter.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	enodeRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "enode_request_duration_seconds",
+		Help:      "Latency of upstream Enode API calls by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	enodeRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enode_request_errors_total",
+		Help:      "Failed upstream Enode API calls by operation and status code (0 for transport errors).",
+	}, []string{"operation", "status"})
+
+	tokenCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enode_token_cache_lookups_total",
+		Help:      "Enode access token cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	tokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enode_token_refreshes_total",
+		Help:      "Enode access token refreshes by outcome (success or failure).",
+	}, []string{"outcome"})
+
+	redisCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Latency of Redis commands.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+
+	redisCommandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_command_errors_total",
+		Help:      "Failed Redis commands, excluding cache misses.",
+	}, []string{"command"})
+
+	upstreamCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_state",
+		Help:      "Circuit breaker state per upstream host (0 closed, 1 half-open, 2 open).",
+	}, []string{"host"})
+
+	upstreamRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Upstream requests retried after a transport error or retryable status.",
+	}, []string{"host"})
+)
+
+// ObserveEnodeCall records the latency of an Enode call. A status of 0 marks a transport error;
+// any non-2xx status is counted as an error.
+func ObserveEnodeCall(operation string, duration time.Duration, status int) {
+	enodeRequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if status < 200 || status > 299 {
+		enodeRequestErrors.WithLabelValues(operation, strconv.Itoa(status)).Inc()
+	}
+}
+
+func TokenCacheHit() {
+	tokenCacheLookups.WithLabelValues("hit").Inc()
+}
+
+func TokenCacheMiss() {
+	tokenCacheLookups.WithLabelValues("miss").Inc()
+}
+
+func TokenRefreshed(err error) {
+	if err != nil {
+		tokenRefreshes.WithLabelValues("failure").Inc()
+		return
+	}
+	tokenRefreshes.WithLabelValues("success").Inc()
+}
+
+// SetCircuitState records the circuit breaker state of an upstream host: 0 closed, 1 half-open,
+// 2 open.
+func SetCircuitState(host string, state int) {
+	upstreamCircuitState.WithLabelValues(host).Set(float64(state))
+}
+
+func UpstreamRetry(host string) {
+	upstreamRetries.WithLabelValues(host).Inc()
+}
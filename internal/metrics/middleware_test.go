@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		handler echo.HandlerFunc
+		route   string
+		status  string
+	}{
+		{
+			name:    "labels route template",
+			target:  "/items/42",
+			handler: func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+			route:   "/items/:id",
+			status:  "200",
+		},
+		{
+			name:    "uses http error code",
+			target:  "/items/7",
+			handler: func(c echo.Context) error { return echo.NewHTTPError(http.StatusGatewayTimeout) },
+			route:   "/items/:id",
+			status:  "504",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Middleware())
+			e.GET("/items/:id", tt.handler)
+
+			before := testutil.CollectAndCount(httpRequestDuration)
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			after := testutil.CollectAndCount(httpRequestDuration)
+			if after != before+1 {
+				t.Fatalf("series = %d, want %d", after, before+1)
+			}
+			// Looking up an existing series must not create a new one.
+			httpRequestDuration.WithLabelValues(http.MethodGet, tt.route, tt.status)
+			if got := testutil.CollectAndCount(httpRequestDuration); got != after {
+				t.Fatalf("no series recorded for route %q status %s", tt.route, tt.status)
+			}
+		})
+	}
+}
+
+func TestObserveEnodeCall(t *testing.T) {
+	ObserveEnodeCall("test_op", 0, http.StatusOK)
+	if got := testutil.ToFloat64(enodeRequestErrors.WithLabelValues("test_op", "200")); got != 0 {
+		t.Fatalf("success counted as error: %v", got)
+	}
+
+	ObserveEnodeCall("test_op", 0, http.StatusBadGateway)
+	ObserveEnodeCall("test_op", 0, 0)
+	if got := testutil.ToFloat64(enodeRequestErrors.WithLabelValues("test_op", "502")); got != 1 {
+		t.Fatalf("502 errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(enodeRequestErrors.WithLabelValues("test_op", "0")); got != 1 {
+		t.Fatalf("transport errors = %v, want 1", got)
+	}
+}
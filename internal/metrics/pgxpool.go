@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PgxPoolCollector exports pgxpool statistics on every scrape.
+type PgxPoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns      *prometheus.Desc
+	idleConns          *prometheus.Desc
+	totalConns         *prometheus.Desc
+	maxConns           *prometheus.Desc
+	acquireCount       *prometheus.Desc
+	acquireDuration    *prometheus.Desc
+	emptyAcquireCount  *prometheus.Desc
+	canceledAcquires   *prometheus.Desc
+	newConnsCount      *prometheus.Desc
+	maxLifetimeDestroy *prometheus.Desc
+	maxIdleDestroy     *prometheus.Desc
+}
+
+func NewPgxPoolCollector(stat func() *pgxpool.Stat) *PgxPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+	return &PgxPoolCollector{
+		stat:               stat,
+		acquiredConns:      desc("acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:          desc("idle_conns", "Idle connections in the pool."),
+		totalConns:         desc("total_conns", "Total connections in the pool."),
+		maxConns:           desc("max_conns", "Maximum size of the pool."),
+		acquireCount:       desc("acquire_total", "Successful connection acquisitions."),
+		acquireDuration:    desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquireCount:  desc("empty_acquire_total", "Acquisitions that had to wait for a connection."),
+		canceledAcquires:   desc("canceled_acquire_total", "Acquisitions canceled by their context."),
+		newConnsCount:      desc("new_conns_total", "Connections opened by the pool."),
+		maxLifetimeDestroy: desc("max_lifetime_destroy_total", "Connections closed for exceeding their max lifetime."),
+		maxIdleDestroy:     desc("max_idle_destroy_total", "Connections closed for exceeding their max idle time."),
+	}
+}
+
+func (c *PgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.newConnsCount, prometheus.CounterValue, float64(s.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeDestroy, prometheus.CounterValue, float64(s.MaxLifetimeDestroyCount()))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleDestroy, prometheus.CounterValue, float64(s.MaxIdleDestroyCount()))
+}
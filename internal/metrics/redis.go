@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook is a go-redis hook that records command latency and errors.
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeRedis(cmd.Name(), time.Since(start), err)
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observeRedis("pipeline", time.Since(start), err)
+		return err
+	}
+}
+
+func observeRedis(command string, duration time.Duration, err error) {
+	redisCommandDuration.WithLabelValues(command).Observe(duration.Seconds())
+	if err != nil && err != redis.Nil {
+		redisCommandErrors.WithLabelValues(command).Inc()
+	}
+}
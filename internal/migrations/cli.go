@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `usage: evolyte-energy-provider-adapter migrate <command> [flags]
+
+commands:
+  up       apply all pending migrations (-adopt-alembic to take over existing tables)
+  down     roll back migrations (-steps N, default 1)
+  status   list migrations and when they were applied
+`
+
+// RunCLI executes the migrate subcommand described by args, writing progress to out.
+func RunCLI(ctx context.Context, pool *pgxpool.Pool, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return fmt.Errorf("missing migrate command")
+	}
+
+	migrator, err := NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+
+	switch args[0] {
+	case "up":
+		adopt := flags.Bool("adopt-alembic", false, "record migrations for tables that already exist in an Alembic-managed database")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		applied, err := migrator.Up(ctx, UpOptions{AdoptAlembic: *adopt})
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		rolledBack, err := migrator.Down(ctx, *steps)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05Z07:00")
+			}
+			fmt.Fprintf(out, "%04d_%-20s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	default:
+		fmt.Fprint(out, usage)
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,323 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// advisoryLockID serialises migrations across replicas starting at the same time.
+const advisoryLockID = 7_311_204_551
+
+var (
+	// ErrAlembicManaged is returned when the database is owned by an Alembic setup and the
+	// adapter has not yet adopted its tables.
+	ErrAlembicManaged = errors.New("database schema is managed by Alembic")
+	ErrNoMigrations   = errors.New("no migrations to roll back")
+
+	fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+// alembicProbes report, per migration version, whether an Alembic-managed schema already holds
+// what the migration creates. Only the tables the core service created before the adapter took
+// them over have a probe; every other migration runs when adopting.
+var alembicProbes = map[int]string{
+	1: "SELECT to_regclass('inverters') IS NOT NULL",
+	2: "SELECT to_regclass('identities') IS NOT NULL",
+	3: "SELECT to_regclass('link_sessions') IS NOT NULL",
+	4: "SELECT to_regclass('webhook_events') IS NOT NULL",
+}
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// UpOptions controls how pending migrations are applied.
+type UpOptions struct {
+	// AdoptAlembic records migrations as applied without running them when the tables
+	// already exist under an Alembic-managed schema.
+	AdoptAlembic bool
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql pairs from the sql directory of fsys,
+// ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration, each in its own transaction.
+func (m *Migrator) Up(ctx context.Context, opts UpOptions) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		adopt := false
+		if len(done) == 0 {
+			alembicVersion, err := alembicVersion(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if alembicVersion != "" {
+				if !opts.AdoptAlembic {
+					return fmt.Errorf("%w (alembic version %s); rerun with -adopt-alembic to take over existing tables", ErrAlembicManaged, alembicVersion)
+				}
+				slog.WarnContext(ctx, "Adopting Alembic-managed schema", "alembic_version", alembicVersion)
+				adopt = true
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, migration, adopt); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recent steps migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			return ErrNoMigrations
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration with the time it was applied, if at all.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID); err != nil {
+			slog.ErrorContext(ctx, "Failed to release migration lock", "error", err)
+		}
+	}()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureVersionTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS adapter_schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`)
+	if err != nil {
+		return fmt.Errorf("creating migrations table: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM adapter_schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// alembicVersion returns the current Alembic revision, or "" when the database is not Alembic-managed.
+func alembicVersion(ctx context.Context, conn *pgxpool.Conn) (string, error) {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('alembic_version') IS NOT NULL").Scan(&exists); err != nil {
+		return "", fmt.Errorf("checking for alembic_version: %w", err)
+	}
+	if !exists {
+		return "", nil
+	}
+
+	var version string
+	err := conn.QueryRow(ctx, "SELECT version_num FROM alembic_version LIMIT 1").Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading alembic version: %w", err)
+	}
+	return version, nil
+}
+
+// apply runs migration inside a transaction. When adopting an Alembic schema, a migration whose
+// probe finds its objects already in place is only recorded, not executed.
+func apply(ctx context.Context, conn *pgxpool.Conn, migration Migration, adopt bool) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if adopt {
+			exists := false
+			if probe, ok := alembicProbes[migration.Version]; ok {
+				if err := tx.QueryRow(ctx, probe).Scan(&exists); err != nil {
+					return fmt.Errorf("probing schema for migration %d: %w", migration.Version, err)
+				}
+			}
+			adopt = exists
+		}
+		if adopt {
+			slog.InfoContext(ctx, "Recording existing table as migrated", "version", migration.Version, "name", migration.Name)
+		} else {
+			slog.InfoContext(ctx, "Applying migration", "version", migration.Version, "name", migration.Name)
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO adapter_schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+		return err
+	})
+}
+
+func revert(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		slog.InfoContext(ctx, "Reverting migration", "version", migration.Version, "name", migration.Name)
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM adapter_schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       fstest.MapFS
+		wantVersion []int
+		wantErr     string
+	}{
+		{
+			name: "orders by version",
+			files: fstest.MapFS{
+				"sql/0002_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+				"sql/0002_b.down.sql": {Data: []byte("DROP TABLE b;")},
+				"sql/0001_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"sql/0001_a.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			wantVersion: []int{1, 2},
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"sql/0001_a.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			},
+			wantErr: "must have both up and down",
+		},
+		{
+			name: "bad file name",
+			files: fstest.MapFS{
+				"sql/init.sql": {Data: []byte("")},
+			},
+			wantErr: "unexpected migration file name",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"sql/0001_a.up.sql":   {Data: []byte("")},
+				"sql/0001_b.down.sql": {Data: []byte("")},
+			},
+			wantErr: "conflicting names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(migrations) != len(tt.wantVersion) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.wantVersion))
+			}
+			for i, version := range tt.wantVersion {
+				if migrations[i].Version != version {
+					t.Fatalf("migration %d version = %d, want %d", i, migrations[i].Version, version)
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		t.Fatalf("embedded migrations invalid: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration versions must be contiguous, got %d at position %d", m.Version, i)
+		}
+	}
+}
+
+func TestAlembicProbes(t *testing.T) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		t.Fatalf("embedded migrations invalid: %v", err)
+	}
+	byVersion := make(map[int]Migration)
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	for version, probe := range alembicProbes {
+		m, ok := byVersion[version]
+		if !ok {
+			t.Fatalf("probe for unknown migration %d", version)
+		}
+		_, table, _ := strings.Cut(probe, "to_regclass('")
+		table, _, _ = strings.Cut(table, "')")
+		if !strings.Contains(m.Up, "CREATE TABLE "+table+" ") {
+			t.Fatalf("migration %d_%s does not create %q, which its probe checks", m.Version, m.Name, table)
+		}
+	}
+}
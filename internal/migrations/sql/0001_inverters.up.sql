@@ -0,0 +1,14 @@
+CREATE TABLE inverters (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    vendor VARCHAR NOT NULL,
+    model VARCHAR NOT NULL,
+    serial_number VARCHAR NOT NULL,
+    total_lifetime_production_kwh DOUBLE PRECISION NOT NULL,
+    installation_date TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ix_inverters_user_id ON inverters (user_id);
+CREATE INDEX ix_inverters_serial_number ON inverters (serial_number);
@@ -0,0 +1,13 @@
+CREATE TABLE identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR NOT NULL,
+    provider_user_id VARCHAR NOT NULL,
+    access_token TEXT,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_identities_provider_user UNIQUE (provider, provider_user_id)
+);
+
+CREATE INDEX ix_identities_user_id ON identities (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE link_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR NOT NULL,
+    link_token TEXT NOT NULL,
+    link_url TEXT NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX ix_link_sessions_user_id ON link_sessions (user_id);
@@ -0,0 +1,17 @@
+CREATE TABLE alerts (
+    id BIGSERIAL PRIMARY KEY,
+    inverter_id VARCHAR NOT NULL,
+    user_id VARCHAR NOT NULL,
+    rule VARCHAR NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'acknowledged', 'resolved')),
+    message TEXT NOT NULL,
+    opened_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by VARCHAR,
+    resolved_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- At most one unresolved alert per inverter and rule.
+CREATE UNIQUE INDEX ux_alerts_active ON alerts (inverter_id, rule) WHERE status <> 'resolved';
+CREATE INDEX ix_alerts_opened_at ON alerts (opened_at DESC);
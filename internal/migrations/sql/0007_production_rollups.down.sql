@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS production_rollup_cursors;
+DROP TABLE IF EXISTS production_yearly;
+DROP TABLE IF EXISTS production_monthly;
+DROP TABLE IF EXISTS production_daily;
@@ -0,0 +1,46 @@
+-- Daily energy per local inverter, kept separately per source. Hourly records take precedence
+-- over Enode DAY statistics when both exist for a day.
+CREATE TABLE production_daily (
+    inverter_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    day DATE NOT NULL,
+    source VARCHAR NOT NULL CHECK (source IN ('hourly', 'enode')),
+    energy_kwh DOUBLE PRECISION NOT NULL,
+    readings INTEGER NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (inverter_id, day, source)
+);
+
+CREATE INDEX ix_production_daily_user_day ON production_daily (user_id, day);
+CREATE INDEX ix_production_daily_refreshed_at ON production_daily (refreshed_at);
+
+CREATE TABLE production_monthly (
+    inverter_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    month DATE NOT NULL,
+    energy_kwh DOUBLE PRECISION NOT NULL,
+    days INTEGER NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (inverter_id, month)
+);
+
+CREATE INDEX ix_production_monthly_user_month ON production_monthly (user_id, month);
+
+CREATE TABLE production_yearly (
+    inverter_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    energy_kwh DOUBLE PRECISION NOT NULL,
+    months INTEGER NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (inverter_id, year)
+);
+
+CREATE INDEX ix_production_yearly_user_year ON production_yearly (user_id, year);
+
+-- Hourly records are folded into production_daily once each, in id order, so a refresh only
+-- reads records added since the last one. The cursor holds the last folded record id.
+CREATE TABLE production_rollup_cursors (
+    name VARCHAR PRIMARY KEY,
+    last_id INTEGER NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inverter_merges;
+DROP TABLE IF EXISTS inverter_enode_links;
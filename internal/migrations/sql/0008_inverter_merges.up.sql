@@ -0,0 +1,26 @@
+-- Local inverters confirmed to be the same device as an Enode inverter. Rows go away with the
+-- inverter they point at.
+CREATE TABLE inverter_enode_links (
+    inverter_id INTEGER PRIMARY KEY REFERENCES inverters (id) ON DELETE CASCADE,
+    enode_inverter_id VARCHAR NOT NULL UNIQUE,
+    linked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Audit trail of duplicate inverters folded into another. merged_inverter holds the deleted row
+-- and discarded_records the hourly readings dropped because the kept inverter already had that hour.
+CREATE TABLE inverter_merges (
+    id BIGSERIAL PRIMARY KEY,
+    serial_number VARCHAR NOT NULL,
+    enode_inverter_id VARCHAR NOT NULL,
+    kept_inverter_id INTEGER NOT NULL,
+    merged_inverter_id INTEGER,
+    merged_inverter JSONB,
+    panels_reassigned INTEGER NOT NULL DEFAULT 0,
+    records_reassigned INTEGER NOT NULL DEFAULT 0,
+    discarded_records JSONB NOT NULL DEFAULT '[]',
+    merged_by VARCHAR NOT NULL,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ix_inverter_merges_kept_inverter_id ON inverter_merges (kept_inverter_id);
+CREATE INDEX ix_inverter_merges_merged_at ON inverter_merges (merged_at DESC);
@@ -0,0 +1,31 @@
+-- Append-only record of every mutating operation. changes holds the top-level fields that differ
+-- between before and after.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor VARCHAR NOT NULL,
+    action VARCHAR NOT NULL,
+    resource_type VARCHAR NOT NULL,
+    resource_id VARCHAR NOT NULL,
+    user_id VARCHAR,
+    request_id VARCHAR,
+    source_ip VARCHAR,
+    before JSONB,
+    after JSONB,
+    changes JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX ix_audit_log_occurred_at ON audit_log (occurred_at DESC);
+CREATE INDEX ix_audit_log_resource ON audit_log (resource_type, resource_id);
+CREATE INDEX ix_audit_log_user_id ON audit_log (user_id);
+CREATE INDEX ix_audit_log_actor ON audit_log (actor);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS privacy_job_steps;
+DROP TABLE IF EXISTS privacy_jobs;
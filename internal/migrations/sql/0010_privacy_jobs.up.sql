@@ -0,0 +1,31 @@
+-- Data subject requests for a user. A job is split into ordered steps that each commit on their
+-- own, so a job interrupted by a crash or an upstream failure resumes at its first pending step.
+CREATE TABLE privacy_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    kind VARCHAR NOT NULL CHECK (kind IN ('export', 'erasure')),
+    status VARCHAR NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    requested_by VARCHAR NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_after TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+-- At most one unfinished job of each kind per user.
+CREATE UNIQUE INDEX ux_privacy_jobs_active ON privacy_jobs (user_id, kind) WHERE status IN ('pending', 'running');
+CREATE INDEX ix_privacy_jobs_runnable ON privacy_jobs (run_after) WHERE status IN ('pending', 'running');
+
+CREATE TABLE privacy_job_steps (
+    job_id BIGINT NOT NULL REFERENCES privacy_jobs (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name VARCHAR NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    affected_rows BIGINT NOT NULL DEFAULT 0,
+    output JSONB,
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (job_id, name)
+);
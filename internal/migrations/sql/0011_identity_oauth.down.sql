@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS ix_identities_refresh_due;
+
+ALTER TABLE identities
+    DROP COLUMN IF EXISTS refresh_locked_until,
+    DROP COLUMN IF EXISTS refresh_failures,
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS scope,
+    DROP COLUMN IF EXISTS refresh_token;
@@ -0,0 +1,11 @@
+-- Per-user OAuth for vendor APIs. Tokens are stored sealed (see internal/secrets).
+ALTER TABLE identities
+    ADD COLUMN refresh_token TEXT,
+    ADD COLUMN scope VARCHAR,
+    ADD COLUMN revoked_at TIMESTAMPTZ,
+    ADD COLUMN refresh_failures INTEGER NOT NULL DEFAULT 0,
+    -- Set while a replica refreshes the token, so a rotating refresh token is used only once.
+    ADD COLUMN refresh_locked_until TIMESTAMPTZ;
+
+CREATE INDEX ix_identities_refresh_due ON identities (expires_at)
+    WHERE refresh_token IS NOT NULL AND revoked_at IS NULL;
@@ -0,0 +1,9 @@
+DROP FUNCTION IF EXISTS redact_user_audit_log(VARCHAR);
+DROP TRIGGER IF EXISTS audit_log_redact_only ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_redact_only();
+DROP FUNCTION IF EXISTS audit_log_redacted_changes(JSONB);
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
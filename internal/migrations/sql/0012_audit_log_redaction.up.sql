@@ -0,0 +1,51 @@
+-- Erasure redacts the before/after snapshots of a user's audit entries, which can hold serial
+-- numbers and inverter locations. redact_user_audit_log is the only way to update audit_log: it
+-- sets a transaction-local flag the row trigger checks, and the trigger only accepts updates that
+-- clear the snapshots and reduce changes to the changed field names. Deletes stay forbidden.
+DROP TRIGGER audit_log_append_only ON audit_log;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE FUNCTION audit_log_redacted_changes(changes JSONB) RETURNS JSONB AS $$
+    SELECT COALESCE(jsonb_object_agg(field, '{}'::jsonb), '{}'::jsonb)
+    FROM jsonb_object_keys(changes) AS field;
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE FUNCTION audit_log_redact_only() RETURNS trigger AS $$
+BEGIN
+    IF current_setting('audit_log.redacting', true) IS DISTINCT FROM 'on'
+        OR NEW.before IS NOT NULL
+        OR NEW.after IS NOT NULL
+        OR NEW.changes IS DISTINCT FROM audit_log_redacted_changes(OLD.changes)
+        OR (NEW.id, NEW.occurred_at, NEW.actor, NEW.action, NEW.resource_type, NEW.resource_id,
+            NEW.user_id, NEW.request_id, NEW.source_ip)
+           IS DISTINCT FROM
+           (OLD.id, OLD.occurred_at, OLD.actor, OLD.action, OLD.resource_type, OLD.resource_id,
+            OLD.user_id, OLD.request_id, OLD.source_ip)
+    THEN
+        RAISE EXCEPTION 'audit_log is append-only';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_redact_only
+BEFORE UPDATE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_redact_only();
+
+CREATE FUNCTION redact_user_audit_log(target_user_id VARCHAR) RETURNS BIGINT AS $$
+DECLARE
+    redacted BIGINT;
+BEGIN
+    PERFORM set_config('audit_log.redacting', 'on', true);
+    UPDATE audit_log
+    SET before = NULL, after = NULL, changes = audit_log_redacted_changes(changes)
+    WHERE user_id = target_user_id
+      AND (before IS NOT NULL OR after IS NOT NULL OR changes IS DISTINCT FROM audit_log_redacted_changes(changes));
+    GET DIAGNOSTICS redacted = ROW_COUNT;
+    PERFORM set_config('audit_log.redacting', 'off', true);
+    RETURN redacted;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+const (
+	AggregateInverter = "inverter"
+	AggregateAlert    = "alert"
+	AggregateUser     = "user"
+)
+
+const (
+	EventInverterCreated                    = "inverter.created"
+	EventInverterSynced                     = "inverter.synced"
+	EventInverterUpdated                    = "inverter.updated"
+	EventInverterDeleted                    = "inverter.deleted"
+	EventInverterProductionThresholdCrossed = "inverter.production_threshold_crossed"
+	EventInverterMerged                     = "inverter.merged"
+
+	EventAlertOpened       = "alert.opened"
+	EventAlertAcknowledged = "alert.acknowledged"
+	EventAlertResolved     = "alert.resolved"
+
+	EventUserErased = "user.erased"
+)
+
+// NewEvent builds the insert parameters for an outbox row, encoding payload as JSON.
+func NewEvent(aggregateType string, aggregateID string, eventType string, payload any) (db.InsertOutboxEventParams, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return db.InsertOutboxEventParams{}, fmt.Errorf("encoding %s payload: %w", eventType, err)
+	}
+	return db.InsertOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       body,
+	}, nil
+}
+
+// ThresholdsCrossed returns each multiple of stepKwh passed when lifetime production moves
+// from previousKwh to currentKwh. It returns nil when stepKwh is not positive.
+func ThresholdsCrossed(previousKwh float64, currentKwh float64, stepKwh float64) []float64 {
+	if stepKwh <= 0 || currentKwh <= previousKwh {
+		return nil
+	}
+	var crossed []float64
+	for next := (float64(int64(previousKwh/stepKwh)) + 1) * stepKwh; next <= currentKwh; next += stepKwh {
+		crossed = append(crossed, next)
+	}
+	return crossed
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestThresholdsCrossed(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous float64
+		current  float64
+		step     float64
+		want     []float64
+	}{
+		{name: "no change", previous: 500, current: 500, step: 1000},
+		{name: "below next threshold", previous: 500, current: 999, step: 1000},
+		{name: "exactly on threshold", previous: 999, current: 1000, step: 1000, want: []float64{1000}},
+		{name: "crosses several", previous: 900, current: 3100, step: 1000, want: []float64{1000, 2000, 3000}},
+		{name: "already on threshold", previous: 1000, current: 1500, step: 1000},
+		{name: "decrease", previous: 2500, current: 1500, step: 1000},
+		{name: "disabled", previous: 0, current: 5000, step: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ThresholdsCrossed(tt.previous, tt.current, tt.step)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ThresholdsCrossed(%v, %v, %v) = %v, want %v", tt.previous, tt.current, tt.step, got, tt.want)
+			}
+		})
+	}
+}
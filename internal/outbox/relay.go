@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+)
+
+// Relay publishes pending outbox events to a Redis stream. Delivery is at-least-once:
+// an event may be published again if marking it fails, so consumers should dedupe on
+// the outbox_id field.
+type Relay struct {
+	store       RelayStore
+	redis       *redis.Client
+	stream      string
+	maxLen      int64
+	batchSize   int32
+	maxAttempts int32
+	interval    time.Duration
+}
+
+func NewRelay(store RelayStore, redisClient *redis.Client, stream string, maxLen int64, batchSize int32, maxAttempts int32, interval time.Duration) *Relay {
+	return &Relay{
+		store:       store,
+		redis:       redisClient,
+		stream:      stream,
+		maxLen:      maxLen,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		interval:    interval,
+	}
+}
+
+// Run relays events every interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting outbox relay", "stream", r.stream, "interval", r.interval)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping outbox relay")
+			return
+		case <-ticker.C:
+			for {
+				published, err := r.RelayOnce(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "Outbox relay failed", "error", err)
+					break
+				}
+				// Drain backlogs without waiting a full interval between batches.
+				if published < int(r.batchSize) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RelayOnce publishes one batch of pending events in id order and returns how many were published.
+// Only one replica relays at a time, so events reach the stream in id order. An event that fails
+// maxAttempts times is given up on and left in the table, and the events after it are published.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	published := 0
+	err := r.store.InTx(ctx, func(q RelayStore) error {
+		locked, err := q.TryLockOutboxRelay(ctx)
+		if err != nil {
+			return fmt.Errorf("locking outbox relay: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		events, err := q.ListPendingOutboxEvents(ctx, db.ListPendingOutboxEventsParams{
+			MaxAttempts: r.maxAttempts,
+			BatchSize:   r.batchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("listing pending outbox events: %w", err)
+		}
+
+		for _, event := range events {
+			if err := r.publish(ctx, event); err != nil {
+				slog.ErrorContext(ctx, "Failed to publish outbox event", "outbox_id", event.ID, "event_type", event.EventType, "attempt", event.Attempts+1, "error", err)
+				if event.Attempts+1 >= r.maxAttempts {
+					slog.ErrorContext(ctx, "Giving up on outbox event", "outbox_id", event.ID, "event_type", event.EventType, "attempts", event.Attempts+1)
+				}
+				// Stop at the first failure so later events are not published ahead of this one.
+				return q.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+					ID:        event.ID,
+					LastError: pgtype.Text{String: err.Error(), Valid: true},
+				})
+			}
+			if err := q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return fmt.Errorf("marking outbox event %d published: %w", event.ID, err)
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+func (r *Relay) publish(ctx context.Context, event db.OutboxEvent) error {
+	args := &redis.XAddArgs{
+		Stream: r.stream,
+		Values: map[string]any{
+			"outbox_id":      strconv.FormatInt(event.ID, 10),
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   event.AggregateID,
+			"event_type":     event.EventType,
+			"payload":        string(event.Payload),
+			"created_at":     event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}
+	if r.maxLen > 0 {
+		args.MaxLen = r.maxLen
+		args.Approx = true
+	}
+	return r.redis.XAdd(ctx, args).Err()
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/redis/go-redis/v9"
+)
+
+const testStream = "test:inverter-events"
+
+// fakeRelayStore keeps outbox events in memory. failAfterPublishing, when set, makes Redis
+// reject writes once that event is marked published.
+type fakeRelayStore struct {
+	locked              bool
+	events              []db.OutboxEvent
+	redis               *miniredis.Miniredis
+	failAfterPublishing int64
+}
+
+func (f *fakeRelayStore) TryLockOutboxRelay(ctx context.Context) (bool, error) {
+	return f.locked, nil
+}
+
+func (f *fakeRelayStore) ListPendingOutboxEvents(ctx context.Context, arg db.ListPendingOutboxEventsParams) ([]db.OutboxEvent, error) {
+	var pending []db.OutboxEvent
+	for _, event := range f.events {
+		if event.PublishedAt == nil && event.Attempts < arg.MaxAttempts && len(pending) < int(arg.BatchSize) {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeRelayStore) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	event := f.event(id)
+	now := time.Now()
+	event.PublishedAt = &now
+	event.Attempts++
+	if id == f.failAfterPublishing {
+		f.redis.SetError("ERR stream unavailable")
+	}
+	return nil
+}
+
+func (f *fakeRelayStore) MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error {
+	event := f.event(arg.ID)
+	event.Attempts++
+	event.LastError = arg.LastError
+	return nil
+}
+
+func (f *fakeRelayStore) InTx(ctx context.Context, fn func(store RelayStore) error) error {
+	return fn(f)
+}
+
+func (f *fakeRelayStore) event(id int64) *db.OutboxEvent {
+	for i := range f.events {
+		if f.events[i].ID == id {
+			return &f.events[i]
+		}
+	}
+	return nil
+}
+
+func pendingEvents(ids ...int64) []db.OutboxEvent {
+	events := make([]db.OutboxEvent, len(ids))
+	for i, id := range ids {
+		events[i] = db.OutboxEvent{ID: id, AggregateType: AggregateInverter, AggregateID: "1", EventType: EventInverterUpdated, Payload: []byte(`{}`)}
+	}
+	return events
+}
+
+// streamIDs returns the outbox ids in the stream, in stream order.
+func streamIDs(t *testing.T, mr *miniredis.Miniredis) []string {
+	t.Helper()
+	if !mr.Exists(testStream) {
+		return nil
+	}
+	entries, err := mr.Stream(testStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		for i := 0; i+1 < len(entry.Values); i += 2 {
+			if entry.Values[i] == "outbox_id" {
+				ids = append(ids, entry.Values[i+1])
+			}
+		}
+	}
+	return ids
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	tests := []struct {
+		name                string
+		locked              bool
+		events              []db.OutboxEvent
+		failAfterPublishing int64
+		wantPublished       int
+		wantStream          []string
+		wantAttempts        map[int64]int32
+	}{
+		{
+			name:          "publishes in id order",
+			locked:        true,
+			events:        pendingEvents(1, 2, 3),
+			wantPublished: 3,
+			wantStream:    []string{"1", "2", "3"},
+			wantAttempts:  map[int64]int32{1: 1, 2: 1, 3: 1},
+		},
+		{
+			name:          "skips when another replica holds the lock",
+			locked:        false,
+			events:        pendingEvents(1, 2),
+			wantPublished: 0,
+			wantAttempts:  map[int64]int32{1: 0, 2: 0},
+		},
+		{
+			name:                "stops at the first failure",
+			locked:              true,
+			events:              pendingEvents(1, 2, 3),
+			failAfterPublishing: 1,
+			wantPublished:       1,
+			wantStream:          []string{"1"},
+			wantAttempts:        map[int64]int32{1: 1, 2: 1, 3: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { client.Close() })
+			store := &fakeRelayStore{locked: tt.locked, events: tt.events, redis: mr, failAfterPublishing: tt.failAfterPublishing}
+
+			published, err := NewRelay(store, client, testStream, 0, 10, 3, time.Minute).RelayOnce(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			mr.SetError("")
+			if published != tt.wantPublished {
+				t.Fatalf("published = %d, want %d", published, tt.wantPublished)
+			}
+			if got := streamIDs(t, mr); !slices.Equal(got, tt.wantStream) {
+				t.Fatalf("stream = %v, want %v", got, tt.wantStream)
+			}
+			for id, want := range tt.wantAttempts {
+				if got := store.event(id).Attempts; got != want {
+					t.Fatalf("event %d attempts = %d, want %d", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRelay_RelayOnceGivesUpAfterMaxAttempts(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := &fakeRelayStore{locked: true, events: pendingEvents(1, 2), redis: mr}
+	store.events[0].Attempts = 2
+	relay := NewRelay(store, client, testStream, 0, 10, 3, time.Minute)
+
+	mr.SetError("ERR stream unavailable")
+	if published, err := relay.RelayOnce(context.Background()); err != nil || published != 0 {
+		t.Fatalf("published = %d, err = %v", published, err)
+	}
+	mr.SetError("")
+
+	published, err := relay.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 || !slices.Equal(streamIDs(t, mr), []string{"2"}) {
+		t.Fatalf("published = %d, stream = %v", published, streamIDs(t, mr))
+	}
+	if given := store.event(1); given.PublishedAt != nil || given.Attempts != 3 || !given.LastError.Valid {
+		t.Fatalf("given up event = %+v", given)
+	}
+}
@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RelayStore reads pending outbox events and records the outcome of publishing them.
+type RelayStore interface {
+	TryLockOutboxRelay(ctx context.Context) (bool, error)
+	ListPendingOutboxEvents(ctx context.Context, arg db.ListPendingOutboxEventsParams) ([]db.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error
+	// InTx runs fn against a store whose writes commit together.
+	InTx(ctx context.Context, fn func(store RelayStore) error) error
+}
+
+// PostgresRelayStore is the RelayStore backed by sqlc queries on a pgx pool.
+type PostgresRelayStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRelayStore(pool *pgxpool.Pool) *PostgresRelayStore {
+	return &PostgresRelayStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresRelayStore) InTx(ctx context.Context, fn func(store RelayStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresRelayStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
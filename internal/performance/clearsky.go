@@ -0,0 +1,79 @@
+package performance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/solar"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ClearSkyStore reads hourly records without a clear-sky index and stores computed ones.
+type ClearSkyStore interface {
+	ListHourlyRecordsMissingClearSkyIndex(ctx context.Context, limit int32) ([]db.ListHourlyRecordsMissingClearSkyIndexRow, error)
+	SetHourlyRecordClearSkyIndex(ctx context.Context, arg db.SetHourlyRecordClearSkyIndexParams) error
+}
+
+// ClearSkyBackfill fills in clear_sky_index on hourly records that have irradiance but no index,
+// using the location of a panel on the record's inverter.
+type ClearSkyBackfill struct {
+	store     ClearSkyStore
+	batchSize int32
+	interval  time.Duration
+}
+
+func NewClearSkyBackfill(store ClearSkyStore, batchSize int32, interval time.Duration) *ClearSkyBackfill {
+	return &ClearSkyBackfill{store: store, batchSize: batchSize, interval: interval}
+}
+
+func (b *ClearSkyBackfill) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting clear-sky index backfill", "interval", b.interval)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping clear-sky index backfill")
+			return
+		case <-ticker.C:
+			for {
+				updated, err := b.BackfillOnce(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "Clear-sky index backfill failed", "error", err)
+					break
+				}
+				// Drain backlogs without waiting a full interval between batches.
+				if updated < int(b.batchSize) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// BackfillOnce updates one batch of records and returns how many were updated.
+func (b *ClearSkyBackfill) BackfillOnce(ctx context.Context) (int, error) {
+	records, err := b.store.ListHourlyRecordsMissingClearSkyIndex(ctx, b.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("listing hourly records: %w", err)
+	}
+
+	for _, record := range records {
+		// Records cover the hour starting at their UTC timestamp, so the sun is placed at the half hour.
+		midpoint := record.Timestamp.Time.Add(30 * time.Minute)
+		sun := solar.SunPosition(record.Latitude, record.Longitude, midpoint)
+		index := solar.ClearSkyIndex(record.Irradiance.Float64, sun)
+
+		if err := b.store.SetHourlyRecordClearSkyIndex(ctx, db.SetHourlyRecordClearSkyIndexParams{
+			ID:            record.ID,
+			ClearSkyIndex: pgtype.Float8{Float64: index, Valid: true},
+		}); err != nil {
+			return 0, fmt.Errorf("storing clear-sky index for record %d: %w", record.ID, err)
+		}
+	}
+	return len(records), nil
+}
@@ -0,0 +1,54 @@
+package performance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeClearSkyStore struct {
+	records []db.ListHourlyRecordsMissingClearSkyIndexRow
+	updates map[int32]float64
+}
+
+func (f *fakeClearSkyStore) ListHourlyRecordsMissingClearSkyIndex(ctx context.Context, limit int32) ([]db.ListHourlyRecordsMissingClearSkyIndexRow, error) {
+	return f.records[:min(int(limit), len(f.records))], nil
+}
+
+func (f *fakeClearSkyStore) SetHourlyRecordClearSkyIndex(ctx context.Context, arg db.SetHourlyRecordClearSkyIndexParams) error {
+	f.updates[arg.ID] = arg.ClearSkyIndex.Float64
+	return nil
+}
+
+func TestClearSkyBackfill_BackfillOnce(t *testing.T) {
+	record := func(id int32, hour int, ghi float64) db.ListHourlyRecordsMissingClearSkyIndexRow {
+		return db.ListHourlyRecordsMissingClearSkyIndexRow{
+			ID:         id,
+			Timestamp:  pgtype.Timestamp{Time: time.Date(2024, time.June, 21, hour, 0, 0, 0, time.UTC), Valid: true},
+			Irradiance: pgtype.Float8{Float64: ghi, Valid: true},
+			Latitude:   51.5,
+			Longitude:  -0.13,
+		}
+	}
+	store := &fakeClearSkyStore{
+		records: []db.ListHourlyRecordsMissingClearSkyIndexRow{record(1, 11, 450), record(2, 0, 0), record(3, 12, 900)},
+		updates: make(map[int32]float64),
+	}
+
+	updated, err := NewClearSkyBackfill(store, 2, time.Minute).BackfillOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 2 || len(store.updates) != 2 {
+		t.Fatalf("updated = %d, updates = %v", updated, store.updates)
+	}
+	if index := store.updates[1]; index < 0.4 || index > 0.6 {
+		t.Fatalf("midday index = %v, want about half of clear sky", index)
+	}
+	if index := store.updates[2]; index != 0 {
+		t.Fatalf("night index = %v, want 0", index)
+	}
+}
@@ -0,0 +1,20 @@
+package performance
+
+import "time"
+
+type InverterPerformanceResponse struct {
+	InverterID       int32     `json:"inverterId"`
+	WindowStart      time.Time `json:"windowStart"`
+	WindowEnd        time.Time `json:"windowEnd"`
+	ActualKwh        float64   `json:"actualKwh"`
+	ExpectedKwh      float64   `json:"expectedKwh"`
+	PerformanceRatio float64   `json:"performanceRatio"`
+	Threshold        float64   `json:"threshold"`
+	HoursEvaluated   int       `json:"hoursEvaluated"`
+	HoursSkipped     int       `json:"hoursSkipped"`
+	Status           string    `json:"status"`
+}
+
+type ListPerformanceResponse struct {
+	Data []InverterPerformanceResponse `json:"data"`
+}
@@ -0,0 +1,77 @@
+package performance
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PerformanceHandler struct {
+	performanceUseCase *PerformanceUseCase
+}
+
+func NewPerformanceHandler(performanceUseCase *PerformanceUseCase) *PerformanceHandler {
+	return &PerformanceHandler{
+		performanceUseCase: performanceUseCase,
+	}
+}
+
+func (h *PerformanceHandler) GetInverterPerformance(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	window, err := windowParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid window")
+	}
+
+	response, err := h.performanceUseCase.GetInverterPerformance(c.Request().Context(), inverterID, window)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get inverter performance", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get inverter performance")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *PerformanceHandler) ListPerformance(c echo.Context) error {
+	window, err := windowParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid window")
+	}
+
+	response, err := h.performanceUseCase.ListPerformance(c.Request().Context(), c.QueryParam("status"), window)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list inverter performance", "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list inverter performance")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// windowParam reads the optional window query parameter as a Go duration, e.g. 72h.
+func windowParam(c echo.Context) (time.Duration, error) {
+	raw := c.QueryParam("window")
+	if raw == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidWindow, err)
+	}
+	return window, nil
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInverterID), errors.Is(err, ErrInvalidWindow), errors.Is(err, ErrInvalidStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInverterNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,132 @@
+package performance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+type fakePerformanceStore struct {
+	panels  []db.ListInverterPanelsRow
+	records []db.ListHourlyRecordsInRangeRow
+	lastArg db.ListHourlyRecordsInRangeParams
+}
+
+func (f *fakePerformanceStore) GetInverterById(ctx context.Context, id int32) (db.Inverter, error) {
+	for _, panel := range f.panels {
+		if panel.InverterID.Int32 == id {
+			return db.Inverter{ID: id}, nil
+		}
+	}
+	return db.Inverter{}, pgx.ErrNoRows
+}
+
+func (f *fakePerformanceStore) ListPanelInverterIds(ctx context.Context) ([]pgtype.Int4, error) {
+	var ids []pgtype.Int4
+	for _, panel := range f.panels {
+		ids = append(ids, panel.InverterID)
+	}
+	return ids, nil
+}
+
+func (f *fakePerformanceStore) ListInverterPanels(ctx context.Context, inverterIds []int32) ([]db.ListInverterPanelsRow, error) {
+	return f.panels, nil
+}
+
+func (f *fakePerformanceStore) ListHourlyRecordsInRange(ctx context.Context, arg db.ListHourlyRecordsInRangeParams) ([]db.ListHourlyRecordsInRangeRow, error) {
+	f.lastArg = arg
+	return f.records, nil
+}
+
+func newFakeStore(now time.Time) *fakePerformanceStore {
+	store := &fakePerformanceStore{
+		panels: []db.ListInverterPanelsRow{
+			{ID: 1, InverterID: pgtype.Int4{Int32: 1, Valid: true}, CapacityKw: 4},
+			{ID: 2, InverterID: pgtype.Int4{Int32: 2, Valid: true}, CapacityKw: 4},
+		},
+	}
+	for i := 1; i <= 20; i++ {
+		ts := pgtype.Timestamp{Time: now.Add(-time.Duration(i) * time.Hour), Valid: true}
+		poa := pgtype.Float8{Float64: 500, Valid: true}
+		// 4 kW at 500 W/m² should yield 2 kWh; inverter 2 delivers half of that.
+		store.records = append(store.records,
+			db.ListHourlyRecordsInRangeRow{InverterID: 1, Timestamp: ts, EnergyGeneratedKwh: 1.9, PoaIrradiance: poa},
+			db.ListHourlyRecordsInRangeRow{InverterID: 2, Timestamp: ts, EnergyGeneratedKwh: 1, PoaIrradiance: poa},
+		)
+	}
+	return store
+}
+
+func TestPerformanceHandler(t *testing.T) {
+	now := time.Date(2024, time.June, 21, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		route      string
+		target     string
+		list       bool
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "single inverter", route: "/inverters/:inverterID/performance", target: "/inverters/1/performance", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "unknown inverter", route: "/inverters/:inverterID/performance", target: "/inverters/9/performance", wantStatus: http.StatusNotFound},
+		{name: "invalid inverter id", route: "/inverters/:inverterID/performance", target: "/inverters/abc/performance", wantStatus: http.StatusBadRequest},
+		{name: "invalid window", route: "/inverters/:inverterID/performance", target: "/inverters/1/performance?window=30m", wantStatus: http.StatusBadRequest},
+		{name: "all inverters", route: "/performance", target: "/performance", list: true, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "underperforming only", route: "/performance", target: "/performance?status=underperforming", list: true, wantStatus: http.StatusOK, wantCount: 1},
+		{name: "invalid status", route: "/performance", target: "/performance?status=bad", list: true, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(now)
+			uc := NewPerformanceUseCase(store, 24*time.Hour, Options{Threshold: 0.75, MinHours: 12})
+			uc.now = func() time.Time { return now }
+			h := NewPerformanceHandler(uc)
+
+			handler := h.GetInverterPerformance
+			if tt.list {
+				handler = h.ListPerformance
+			}
+			e := echo.New()
+			e.GET(tt.route, handler)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var results []InverterPerformanceResponse
+			if tt.list {
+				var list ListPerformanceResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+					t.Fatal(err)
+				}
+				results = list.Data
+			} else {
+				var single InverterPerformanceResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &single); err != nil {
+					t.Fatal(err)
+				}
+				results = []InverterPerformanceResponse{single}
+			}
+			if len(results) != tt.wantCount {
+				t.Fatalf("results = %+v, want %d", results, tt.wantCount)
+			}
+			if !store.lastArg.EndTime.Time.Equal(now.Truncate(time.Hour)) {
+				t.Fatalf("window end = %v", store.lastArg.EndTime.Time)
+			}
+		})
+	}
+}
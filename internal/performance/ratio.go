@@ -0,0 +1,115 @@
+package performance
+
+import (
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/solar"
+)
+
+const (
+	StatusOK               = "ok"
+	StatusUnderperforming  = "underperforming"
+	StatusInsufficientData = "insufficient_data"
+)
+
+// minPlaneIrradiance excludes low-light hours, where small absolute errors dominate the ratio.
+const minPlaneIrradiance = 50.0
+
+// Panel is one solar panel attached to an inverter. Orientation is the panel azimuth in degrees
+// clockwise from north; Tilt is measured from horizontal.
+type Panel struct {
+	CapacityKw  float64
+	Tilt        float64
+	Orientation float64
+	Latitude    float64
+	Longitude   float64
+	HasLocation bool
+}
+
+// Reading is one hourly record for an inverter. Irradiance values are in W/m² and are nil when
+// they were not recorded.
+type Reading struct {
+	Time          time.Time
+	EnergyKwh     float64
+	Irradiance    *float64
+	PoaIrradiance *float64
+	ClearSkyIndex *float64
+}
+
+type Options struct {
+	Threshold        float64
+	MinClearSkyIndex float64
+	MinHours         int
+}
+
+type Result struct {
+	ActualKwh        float64
+	ExpectedKwh      float64
+	PerformanceRatio float64
+	HoursEvaluated   int
+	HoursSkipped     int
+	Status           string
+}
+
+// Ratio computes the performance ratio of an inverter: the energy it produced divided by the
+// energy its panels would produce at rated efficiency under the recorded irradiance.
+//
+// Stored plane-of-array irradiance is used when present; otherwise it is estimated per panel from
+// global horizontal irradiance using the panel's tilt, orientation and location. Hours without
+// usable irradiance, in low light, or below opts.MinClearSkyIndex are skipped, as heavy cloud
+// makes the irradiance estimate unreliable.
+func Ratio(panels []Panel, readings []Reading, opts Options) Result {
+	var result Result
+	for _, reading := range readings {
+		expected, ok := expectedEnergy(panels, reading, opts)
+		if !ok {
+			result.HoursSkipped++
+			continue
+		}
+		result.ActualKwh += reading.EnergyKwh
+		result.ExpectedKwh += expected
+		result.HoursEvaluated++
+	}
+
+	if result.HoursEvaluated < max(opts.MinHours, 1) || result.ExpectedKwh == 0 {
+		result.Status = StatusInsufficientData
+		return result
+	}
+	result.PerformanceRatio = result.ActualKwh / result.ExpectedKwh
+	result.Status = StatusOK
+	if result.PerformanceRatio < opts.Threshold {
+		result.Status = StatusUnderperforming
+	}
+	return result
+}
+
+// expectedEnergy returns the energy in kWh the panels should produce over the reading's hour.
+func expectedEnergy(panels []Panel, reading Reading, opts Options) (float64, bool) {
+	if reading.ClearSkyIndex != nil && *reading.ClearSkyIndex < opts.MinClearSkyIndex {
+		return 0, false
+	}
+
+	// Records cover the hour starting at Time, so the sun is placed at the half hour.
+	midpoint := reading.Time.Add(30 * time.Minute)
+	var expected, weightedIrradiance, capacity float64
+	for _, panel := range panels {
+		var irradiance float64
+		switch {
+		case reading.PoaIrradiance != nil:
+			irradiance = *reading.PoaIrradiance
+		case reading.Irradiance != nil && panel.HasLocation:
+			sun := solar.SunPosition(panel.Latitude, panel.Longitude, midpoint)
+			irradiance = solar.PlaneOfArray(*reading.Irradiance, sun, panel.Tilt, panel.Orientation, midpoint)
+		default:
+			return 0, false
+		}
+		expected += panel.CapacityKw * irradiance / solar.StandardIrradiance
+		weightedIrradiance += panel.CapacityKw * irradiance
+		capacity += panel.CapacityKw
+	}
+
+	if capacity == 0 || weightedIrradiance/capacity < minPlaneIrradiance {
+		return 0, false
+	}
+	return expected, true
+}
@@ -0,0 +1,83 @@
+package performance
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+// hours returns n hourly readings starting at midday UTC, each with the given energy and POA.
+func hours(n int, energyKwh float64, poa float64) []Reading {
+	start := time.Date(2024, time.June, 21, 10, 0, 0, 0, time.UTC)
+	readings := make([]Reading, n)
+	for i := range readings {
+		readings[i] = Reading{Time: start.Add(time.Duration(i) * time.Hour), EnergyKwh: energyKwh, PoaIrradiance: ptr(poa)}
+	}
+	return readings
+}
+
+func TestRatio(t *testing.T) {
+	panels := []Panel{{CapacityKw: 3}, {CapacityKw: 2}}
+	opts := Options{Threshold: 0.75, MinClearSkyIndex: 0.3, MinHours: 3}
+
+	cloudy := hours(4, 1, 800)
+	for i := range cloudy {
+		cloudy[i].ClearSkyIndex = ptr(0.1)
+	}
+
+	tests := []struct {
+		name          string
+		panels        []Panel
+		readings      []Reading
+		wantStatus    string
+		wantRatio     float64
+		wantEvaluated int
+	}{
+		// 5 kW at 800 W/m² is expected to yield 4 kWh per hour.
+		{name: "healthy", panels: panels, readings: hours(4, 3.6, 800), wantStatus: StatusOK, wantRatio: 0.9, wantEvaluated: 4},
+		{name: "underperforming", panels: panels, readings: hours(4, 2, 800), wantStatus: StatusUnderperforming, wantRatio: 0.5, wantEvaluated: 4},
+		{name: "too few hours", panels: panels, readings: hours(2, 2, 800), wantStatus: StatusInsufficientData, wantEvaluated: 2},
+		{name: "low light skipped", panels: panels, readings: hours(4, 0.1, 20), wantStatus: StatusInsufficientData},
+		{name: "overcast skipped", panels: panels, readings: cloudy, wantStatus: StatusInsufficientData},
+		{name: "no panels", readings: hours(4, 2, 800), wantStatus: StatusInsufficientData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Ratio(tt.panels, tt.readings, opts)
+			if got.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s (%+v)", got.Status, tt.wantStatus, got)
+			}
+			if math.Abs(got.PerformanceRatio-tt.wantRatio) > 1e-9 {
+				t.Fatalf("ratio = %v, want %v", got.PerformanceRatio, tt.wantRatio)
+			}
+			if got.HoursEvaluated != tt.wantEvaluated || got.HoursEvaluated+got.HoursSkipped != len(tt.readings) {
+				t.Fatalf("evaluated = %d, skipped = %d, want %d evaluated", got.HoursEvaluated, got.HoursSkipped, tt.wantEvaluated)
+			}
+		})
+	}
+}
+
+func TestRatio_TransposesHorizontalIrradiance(t *testing.T) {
+	readings := hours(4, 3, 0)
+	for i := range readings {
+		readings[i].PoaIrradiance = nil
+		readings[i].Irradiance = ptr(700)
+	}
+	opts := Options{Threshold: 0.75, MinHours: 1}
+
+	located := []Panel{{CapacityKw: 5, Tilt: 35, Orientation: 180, Latitude: 51.5, Longitude: -0.13, HasLocation: true}}
+	got := Ratio(located, readings, opts)
+	if got.HoursEvaluated != 4 || got.ExpectedKwh <= 0 {
+		t.Fatalf("result = %+v", got)
+	}
+
+	unlocated := []Panel{{CapacityKw: 5, Tilt: 35, Orientation: 180}}
+	if got := Ratio(unlocated, readings, opts); got.Status != StatusInsufficientData {
+		t.Fatalf("status = %s, want %s without a location", got.Status, StatusInsufficientData)
+	}
+}
@@ -0,0 +1,190 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInverterNotFound  = errors.New("inverter not found")
+	ErrInvalidInverterID = errors.New("invalid inverter id")
+	ErrInvalidWindow     = errors.New("invalid window")
+	ErrInvalidStatus     = errors.New("invalid performance status")
+)
+
+type PerformanceStore interface {
+	GetInverterById(ctx context.Context, id int32) (db.Inverter, error)
+	ListPanelInverterIds(ctx context.Context) ([]pgtype.Int4, error)
+	ListInverterPanels(ctx context.Context, inverterIds []int32) ([]db.ListInverterPanelsRow, error)
+	ListHourlyRecordsInRange(ctx context.Context, arg db.ListHourlyRecordsInRangeParams) ([]db.ListHourlyRecordsInRangeRow, error)
+}
+
+type PerformanceUseCase struct {
+	store   PerformanceStore
+	window  time.Duration
+	options Options
+	now     func() time.Time
+}
+
+func NewPerformanceUseCase(store PerformanceStore, window time.Duration, options Options) *PerformanceUseCase {
+	return &PerformanceUseCase{
+		store:   store,
+		window:  window,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// GetInverterPerformance computes the performance ratio of one local inverter over the window
+// ending now. A zero window uses the configured default.
+func (uc *PerformanceUseCase) GetInverterPerformance(ctx context.Context, inverterID string, window time.Duration) (*InverterPerformanceResponse, error) {
+	id, err := strconv.ParseInt(inverterID, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidInverterID
+	}
+	if _, err := uc.store.GetInverterById(ctx, int32(id)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInverterNotFound
+		}
+		return nil, fmt.Errorf("getting inverter: %w", err)
+	}
+
+	results, err := uc.evaluate(ctx, []int32{int32(id)}, window)
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// ListPerformance computes the performance ratio of every inverter with panels attached. If
+// status is set, only inverters with that status are returned.
+func (uc *PerformanceUseCase) ListPerformance(ctx context.Context, status string, window time.Duration) (*ListPerformanceResponse, error) {
+	switch status {
+	case "", StatusOK, StatusUnderperforming, StatusInsufficientData:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+
+	ids, err := uc.store.ListPanelInverterIds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing inverters with panels: %w", err)
+	}
+	inverterIDs := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		inverterIDs = append(inverterIDs, id.Int32)
+	}
+
+	response := &ListPerformanceResponse{Data: []InverterPerformanceResponse{}}
+	if len(inverterIDs) == 0 {
+		return response, nil
+	}
+	results, err := uc.evaluate(ctx, inverterIDs, window)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if status == "" || result.Status == status {
+			response.Data = append(response.Data, result)
+		}
+	}
+	return response, nil
+}
+
+func (uc *PerformanceUseCase) evaluate(ctx context.Context, inverterIDs []int32, window time.Duration) ([]InverterPerformanceResponse, error) {
+	if window == 0 {
+		window = uc.window
+	}
+	if window < time.Hour {
+		return nil, fmt.Errorf("%w: must be at least 1h", ErrInvalidWindow)
+	}
+	// Hourly records are stored as UTC timestamps without a zone.
+	end := uc.now().UTC().Truncate(time.Hour)
+	start := end.Add(-window)
+
+	panelRows, err := uc.store.ListInverterPanels(ctx, inverterIDs)
+	if err != nil {
+		return nil, fmt.Errorf("listing panels: %w", err)
+	}
+	panels := make(map[int32][]Panel)
+	for _, row := range panelRows {
+		panels[row.InverterID.Int32] = append(panels[row.InverterID.Int32], newPanel(row))
+	}
+
+	recordRows, err := uc.store.ListHourlyRecordsInRange(ctx, db.ListHourlyRecordsInRangeParams{
+		InverterIds: inverterIDs,
+		StartTime:   pgtype.Timestamp{Time: start, Valid: true},
+		EndTime:     pgtype.Timestamp{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing hourly records: %w", err)
+	}
+	readings := make(map[int32][]Reading)
+	for _, row := range recordRows {
+		readings[row.InverterID] = append(readings[row.InverterID], newReading(row))
+	}
+
+	results := make([]InverterPerformanceResponse, 0, len(inverterIDs))
+	for _, id := range inverterIDs {
+		result := Ratio(panels[id], readings[id], uc.options)
+		results = append(results, InverterPerformanceResponse{
+			InverterID:       id,
+			WindowStart:      start,
+			WindowEnd:        end,
+			ActualKwh:        result.ActualKwh,
+			ExpectedKwh:      result.ExpectedKwh,
+			PerformanceRatio: result.PerformanceRatio,
+			Threshold:        uc.options.Threshold,
+			HoursEvaluated:   result.HoursEvaluated,
+			HoursSkipped:     result.HoursSkipped,
+			Status:           result.Status,
+		})
+	}
+	return results, nil
+}
+
+func newPanel(row db.ListInverterPanelsRow) Panel {
+	return Panel{
+		CapacityKw:  row.CapacityKw,
+		Tilt:        row.Tilt.Float64,
+		Orientation: orientation(row.Orientation, row.Latitude.Float64),
+		Latitude:    row.Latitude.Float64,
+		Longitude:   row.Longitude.Float64,
+		HasLocation: row.Latitude.Valid && row.Longitude.Valid,
+	}
+}
+
+// orientation assumes panels without a recorded azimuth face the equator.
+func orientation(value pgtype.Float8, latitude float64) float64 {
+	switch {
+	case value.Valid:
+		return value.Float64
+	case latitude < 0:
+		return 0
+	default:
+		return 180
+	}
+}
+
+func newReading(row db.ListHourlyRecordsInRangeRow) Reading {
+	return Reading{
+		Time:          row.Timestamp.Time,
+		EnergyKwh:     row.EnergyGeneratedKwh,
+		Irradiance:    optionalFloat(row.Irradiance),
+		PoaIrradiance: optionalFloat(row.PoaIrradiance),
+		ClearSkyIndex: optionalFloat(row.ClearSkyIndex),
+	}
+}
+
+func optionalFloat(value pgtype.Float8) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
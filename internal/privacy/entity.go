@@ -0,0 +1,66 @@
+package privacy
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+type JobStepResponse struct {
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	AffectedRows int64      `json:"affectedRows"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+}
+
+type JobResponse struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"userId"`
+	Kind        string            `json:"kind"`
+	Status      string            `json:"status"`
+	RequestedBy string            `json:"requestedBy"`
+	Attempts    int32             `json:"attempts"`
+	Steps       []JobStepResponse `json:"steps"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+}
+
+type ListJobsResponse struct {
+	Data []JobResponse `json:"data"`
+}
+
+// ExportDocument is the downloadable result of a completed export job. Sections are keyed by
+// export step name and hold the rows as stored, without credentials.
+type ExportDocument struct {
+	JobID       string                     `json:"jobId"`
+	UserID      string                     `json:"userId"`
+	GeneratedAt *time.Time                 `json:"generatedAt"`
+	Data        map[string]json.RawMessage `json:"data"`
+}
+
+func newJobResponse(job db.PrivacyJob, steps []db.PrivacyJobStep) JobResponse {
+	response := JobResponse{
+		ID:          strconv.FormatInt(job.ID, 10),
+		UserID:      strconv.Itoa(int(job.UserID)),
+		Kind:        job.Kind,
+		Status:      job.Status,
+		RequestedBy: job.RequestedBy,
+		Attempts:    job.Attempts,
+		Steps:       make([]JobStepResponse, 0, len(steps)),
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	for _, step := range steps {
+		response.Steps = append(response.Steps, JobStepResponse{
+			Name:         step.Name,
+			Status:       step.Status,
+			AffectedRows: step.AffectedRows,
+			CompletedAt:  step.CompletedAt,
+		})
+	}
+	return response
+}
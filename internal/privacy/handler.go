@@ -0,0 +1,102 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PrivacyHandler struct {
+	privacyUseCase *PrivacyUseCase
+}
+
+func NewPrivacyHandler(privacyUseCase *PrivacyUseCase) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyUseCase: privacyUseCase,
+	}
+}
+
+func (h *PrivacyHandler) RequestExport(c echo.Context) error {
+	userID := c.Param("userID")
+	job, err := h.privacyUseCase.RequestExport(c.Request().Context(), userID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to request privacy export", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to request privacy export")
+	}
+
+	slog.InfoContext(c.Request().Context(), "Queued privacy export", "userID", userID, "jobID", job.ID, "requestedBy", job.RequestedBy)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (h *PrivacyHandler) RequestErasure(c echo.Context) error {
+	userID := c.Param("userID")
+	job, err := h.privacyUseCase.RequestErasure(c.Request().Context(), userID)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to request privacy erasure", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to request privacy erasure")
+	}
+
+	slog.InfoContext(c.Request().Context(), "Queued privacy erasure", "userID", userID, "jobID", job.ID, "requestedBy", job.RequestedBy)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (h *PrivacyHandler) ListUserJobs(c echo.Context) error {
+	jobs, err := h.privacyUseCase.ListUserJobs(c.Request().Context(), c.Param("userID"))
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to list privacy jobs", "userID", c.Param("userID"), "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to list privacy jobs")
+	}
+
+	return c.JSON(http.StatusOK, jobs)
+}
+
+func (h *PrivacyHandler) GetJob(c echo.Context) error {
+	job, err := h.privacyUseCase.GetJob(c.Request().Context(), c.Param("jobID"))
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get privacy job", "jobID", c.Param("jobID"), "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get privacy job")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func (h *PrivacyHandler) RetryJob(c echo.Context) error {
+	job, err := h.privacyUseCase.RetryJob(c.Request().Context(), c.Param("jobID"))
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to retry privacy job", "jobID", c.Param("jobID"), "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to retry privacy job")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (h *PrivacyHandler) DownloadExport(c echo.Context) error {
+	document, err := h.privacyUseCase.ExportDocument(c.Request().Context(), c.Param("jobID"))
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to download privacy export", "jobID", c.Param("jobID"), "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to download privacy export")
+	}
+
+	filename := fmt.Sprintf("user-%s-export-%s.json", document.UserID, document.JobID)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.JSON(http.StatusOK, document)
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidUserID), errors.Is(err, ErrInvalidJobID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobInProgress), errors.Is(err, ErrJobNotCompleted), errors.Is(err, ErrJobNotFailed):
+		return http.StatusConflict
+	case errors.Is(err, ErrExportUnavailable):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
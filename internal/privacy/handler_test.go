@@ -0,0 +1,107 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+func TestPrivacyHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(store *fakePrivacyStore)
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "request export", method: http.MethodPost, target: "/users/42/privacy/export", wantStatus: http.StatusAccepted},
+		{name: "request erasure", method: http.MethodPost, target: "/users/42/privacy/erasure", wantStatus: http.StatusAccepted},
+		{name: "invalid user", method: http.MethodPost, target: "/users/abc/privacy/export", wantStatus: http.StatusBadRequest},
+		{
+			name:       "export in progress",
+			setup:      func(store *fakePrivacyStore) { store.CreatePrivacyJob(context.Background(), dbJob(42, KindExport)) },
+			method:     http.MethodPost,
+			target:     "/users/42/privacy/export",
+			wantStatus: http.StatusConflict,
+		},
+		{name: "unknown job", method: http.MethodGet, target: "/privacy/jobs/7", wantStatus: http.StatusNotFound},
+		{
+			name:       "download pending export",
+			setup:      func(store *fakePrivacyStore) { store.CreatePrivacyJob(context.Background(), dbJob(42, KindExport)) },
+			method:     http.MethodGet,
+			target:     "/privacy/jobs/1/download",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "download erased export",
+			setup: func(store *fakePrivacyStore) {
+				store.CreatePrivacyJob(context.Background(), dbJob(42, KindExport))
+				store.InsertPrivacyJobStep(context.Background(), dbStep(1, "identities"))
+				store.CompletePrivacyJob(context.Background(), 1)
+			},
+			method:     http.MethodGet,
+			target:     "/privacy/jobs/1/download",
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "retry running job",
+			setup:      func(store *fakePrivacyStore) { store.CreatePrivacyJob(context.Background(), dbJob(42, KindErasure)) },
+			method:     http.MethodPost,
+			target:     "/privacy/jobs/1/retry",
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakePrivacyStore()
+			if tt.setup != nil {
+				tt.setup(store)
+			}
+			handler := NewPrivacyHandler(NewPrivacyUseCase(store))
+			e := echo.New()
+			e.Use(audit.Middleware())
+			e.POST("/users/:userID/privacy/export", handler.RequestExport)
+			e.POST("/users/:userID/privacy/erasure", handler.RequestErasure)
+			e.GET("/privacy/jobs/:jobID", handler.GetJob)
+			e.GET("/privacy/jobs/:jobID/download", handler.DownloadExport)
+			e.POST("/privacy/jobs/:jobID/retry", handler.RetryJob)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(audit.ActorHeader, "dpo")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusAccepted {
+				return
+			}
+			var job JobResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != StatusPending || job.RequestedBy != "dpo" || len(job.Steps) != len(stepsFor(job.Kind)) {
+				t.Fatalf("job = %+v", job)
+			}
+			if len(store.audits) != 1 || store.audits[0].Actor != "dpo" || store.audits[0].UserID.String != "42" {
+				t.Fatalf("audits = %+v", store.audits)
+			}
+		})
+	}
+}
+
+func dbJob(userID int32, kind string) db.CreatePrivacyJobParams {
+	return db.CreatePrivacyJobParams{UserID: userID, Kind: kind, RequestedBy: "dpo"}
+}
+
+func dbStep(jobID int64, name string) db.InsertPrivacyJobStepParams {
+	return db.InsertPrivacyJobStepParams{JobID: jobID, Name: name}
+}
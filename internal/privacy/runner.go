@@ -0,0 +1,145 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxRetryDelay caps the backoff between attempts of a failing job.
+const maxRetryDelay = time.Hour
+
+// Runner executes queued privacy jobs. Jobs are claimed with a lease, so several replicas can
+// run side by side and a job abandoned by a crashed replica is picked up once its lease expires.
+type Runner struct {
+	store       PrivacyStore
+	services    services
+	interval    time.Duration
+	lease       time.Duration
+	maxAttempts int32
+	now         func() time.Time
+}
+
+func NewRunner(store PrivacyStore, enode EnodeUsers, identities IdentityRevoker, interval time.Duration, lease time.Duration, maxAttempts int) *Runner {
+	return &Runner{
+		store:       store,
+		services:    services{enode: enode, identities: identities},
+		interval:    interval,
+		lease:       lease,
+		maxAttempts: int32(maxAttempts),
+		now:         time.Now,
+	}
+}
+
+// Run drains runnable jobs every interval until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting privacy job runner", "interval", r.interval, "lease", r.lease)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping privacy job runner")
+			return
+		case <-ticker.C:
+			for {
+				ran, err := r.RunOnce(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "Privacy job runner failed", "error", err)
+				}
+				if !ran || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce claims the next runnable job and runs its pending steps. It reports whether a job was
+// claimed; a failing job is rescheduled and does not make RunOnce return an error.
+func (r *Runner) RunOnce(ctx context.Context) (bool, error) {
+	lockedUntil := r.now().Add(r.lease)
+	job, err := r.store.ClaimPrivacyJob(ctx, &lockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claiming privacy job: %w", err)
+	}
+
+	// Steps are attributed to whoever requested the job.
+	ctx = audit.WithActor(ctx, audit.Actor{ID: job.RequestedBy})
+	if err := r.runJob(ctx, job); err != nil {
+		return true, r.fail(ctx, job, err)
+	}
+	if err := r.store.CompletePrivacyJob(ctx, job.ID); err != nil {
+		return true, fmt.Errorf("completing privacy job %d: %w", job.ID, err)
+	}
+	slog.InfoContext(ctx, "Completed privacy job", "jobID", job.ID, "kind", job.Kind, "userID", job.UserID)
+	return true, nil
+}
+
+func (r *Runner) runJob(ctx context.Context, job db.PrivacyJob) error {
+	stored, err := r.store.ListPrivacyJobSteps(ctx, job.ID)
+	if err != nil {
+		return fmt.Errorf("listing steps: %w", err)
+	}
+	completed := make(map[string]bool, len(stored))
+	for _, step := range stored {
+		completed[step.Name] = step.Status == StatusCompleted
+	}
+
+	for _, step := range stepsFor(job.Kind) {
+		if completed[step.name] {
+			continue
+		}
+		err := r.store.InTx(ctx, func(store PrivacyStore) error {
+			result, err := step.run(ctx, store, r.services, job.UserID)
+			if err != nil {
+				return err
+			}
+			return store.CompletePrivacyJobStep(ctx, db.CompletePrivacyJobStepParams{
+				JobID:        job.ID,
+				Name:         step.name,
+				AffectedRows: result.affectedRows,
+				Output:       result.output,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.name, err)
+		}
+		slog.InfoContext(ctx, "Completed privacy job step", "jobID", job.ID, "kind", job.Kind, "step", step.name)
+	}
+	return nil
+}
+
+// fail reschedules job with exponential backoff, or marks it failed once it has used all of
+// its attempts. Failed jobs resume at the failed step when retried through the API.
+func (r *Runner) fail(ctx context.Context, job db.PrivacyJob, jobErr error) error {
+	status := StatusPending
+	runAfter := r.now().Add(min(r.interval<<min(job.Attempts-1, 16), maxRetryDelay))
+	if job.Attempts >= r.maxAttempts {
+		status = StatusFailed
+		runAfter = r.now()
+	}
+	slog.ErrorContext(ctx, "Privacy job failed", "jobID", job.ID, "kind", job.Kind, "attempt", job.Attempts, "status", status, "error", jobErr)
+
+	err := r.store.FailPrivacyJob(ctx, db.FailPrivacyJobParams{
+		ID:        job.ID,
+		Status:    status,
+		LastError: pgtype.Text{String: jobErr.Error(), Valid: true},
+		RunAfter:  runAfter,
+	})
+	if err != nil {
+		return fmt.Errorf("rescheduling privacy job %d: %w", job.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,420 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakePrivacyStore keeps jobs and steps in memory. Export queries return one row each and erase
+// queries record the order they ran in; other queries are left to the embedded nil interface.
+type fakePrivacyStore struct {
+	PrivacyStore
+	jobs    map[int64]db.PrivacyJob
+	steps   map[int64][]db.PrivacyJobStep
+	erased  []string
+	outbox  []db.InsertOutboxEventParams
+	audits  []db.InsertAuditLogEntryParams
+	failing string
+	failed  *db.FailPrivacyJobParams
+}
+
+func newFakePrivacyStore() *fakePrivacyStore {
+	return &fakePrivacyStore{jobs: map[int64]db.PrivacyJob{}, steps: map[int64][]db.PrivacyJobStep{}}
+}
+
+func (f *fakePrivacyStore) InTx(ctx context.Context, fn func(store PrivacyStore) error) error {
+	steps := make(map[int64][]db.PrivacyJobStep, len(f.steps))
+	for id, jobSteps := range f.steps {
+		steps[id] = slices.Clone(jobSteps)
+	}
+	tx := &fakePrivacyStore{
+		jobs:    f.jobs,
+		steps:   steps,
+		erased:  slices.Clone(f.erased),
+		outbox:  slices.Clone(f.outbox),
+		audits:  slices.Clone(f.audits),
+		failing: f.failing,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	f.steps, f.erased, f.outbox, f.audits = tx.steps, tx.erased, tx.outbox, tx.audits
+	return nil
+}
+
+func (f *fakePrivacyStore) CreatePrivacyJob(ctx context.Context, arg db.CreatePrivacyJobParams) (db.PrivacyJob, error) {
+	for _, job := range f.jobs {
+		if job.UserID == arg.UserID && job.Kind == arg.Kind && (job.Status == StatusPending || job.Status == StatusRunning) {
+			return db.PrivacyJob{}, pgx.ErrNoRows
+		}
+	}
+	job := db.PrivacyJob{ID: int64(len(f.jobs) + 1), UserID: arg.UserID, Kind: arg.Kind, Status: StatusPending, RequestedBy: arg.RequestedBy}
+	f.jobs[job.ID] = job
+	return job, nil
+}
+
+func (f *fakePrivacyStore) InsertPrivacyJobStep(ctx context.Context, arg db.InsertPrivacyJobStepParams) error {
+	f.steps[arg.JobID] = append(f.steps[arg.JobID], db.PrivacyJobStep{JobID: arg.JobID, Position: arg.Position, Name: arg.Name, Status: StatusPending})
+	return nil
+}
+
+func (f *fakePrivacyStore) GetPrivacyJob(ctx context.Context, id int64) (db.PrivacyJob, error) {
+	job, ok := f.jobs[id]
+	if !ok {
+		return db.PrivacyJob{}, pgx.ErrNoRows
+	}
+	return job, nil
+}
+
+func (f *fakePrivacyStore) ListPrivacyJobSteps(ctx context.Context, jobID int64) ([]db.PrivacyJobStep, error) {
+	return f.steps[jobID], nil
+}
+
+func (f *fakePrivacyStore) ClaimPrivacyJob(ctx context.Context, lockedUntil *time.Time) (db.PrivacyJob, error) {
+	for id := int64(1); id <= int64(len(f.jobs)); id++ {
+		job := f.jobs[id]
+		if job.Status == StatusPending {
+			job.Status, job.Attempts, job.LockedUntil = StatusRunning, job.Attempts+1, lockedUntil
+			f.jobs[id] = job
+			return job, nil
+		}
+	}
+	return db.PrivacyJob{}, pgx.ErrNoRows
+}
+
+func (f *fakePrivacyStore) CompletePrivacyJobStep(ctx context.Context, arg db.CompletePrivacyJobStepParams) error {
+	for i, step := range f.steps[arg.JobID] {
+		if step.Name == arg.Name {
+			f.steps[arg.JobID][i].Status, f.steps[arg.JobID][i].AffectedRows, f.steps[arg.JobID][i].Output = StatusCompleted, arg.AffectedRows, arg.Output
+		}
+	}
+	return nil
+}
+
+func (f *fakePrivacyStore) CompletePrivacyJob(ctx context.Context, id int64) error {
+	job := f.jobs[id]
+	job.Status = StatusCompleted
+	f.jobs[id] = job
+	return nil
+}
+
+func (f *fakePrivacyStore) FailPrivacyJob(ctx context.Context, arg db.FailPrivacyJobParams) error {
+	f.failed = &arg
+	job := f.jobs[arg.ID]
+	job.Status, job.RunAfter = arg.Status, arg.RunAfter
+	f.jobs[arg.ID] = job
+	return nil
+}
+
+func (f *fakePrivacyStore) RetryPrivacyJob(ctx context.Context, id int64) (db.PrivacyJob, error) {
+	job := f.jobs[id]
+	if job.Status != StatusFailed {
+		return db.PrivacyJob{}, pgx.ErrNoRows
+	}
+	job.Status, job.Attempts = StatusPending, 0
+	f.jobs[id] = job
+	return job, nil
+}
+
+func (f *fakePrivacyStore) export(ctx context.Context, userID int32) ([]byte, error) {
+	return []byte(`[{"id":1}]`), nil
+}
+
+func (f *fakePrivacyStore) ExportUserIdentities(ctx context.Context, userID int32) ([]byte, error) {
+	return []byte(`[{"id":1,"provider":"enode"}]`), nil
+}
+
+func (f *fakePrivacyStore) ExportUserLinkSessions(ctx context.Context, userID int32) ([]byte, error) {
+	return f.export(ctx, userID)
+}
+
+func (f *fakePrivacyStore) ExportUserInverters(ctx context.Context, userID int32) ([]byte, error) {
+	return f.export(ctx, userID)
+}
+
+func (f *fakePrivacyStore) ExportUserSolarPanels(ctx context.Context, userID int32) ([]byte, error) {
+	return f.export(ctx, userID)
+}
+
+func (f *fakePrivacyStore) ExportUserHourlyRecords(ctx context.Context, userID int32) ([]byte, error) {
+	return []byte(`[{"id":1},{"id":2}]`), nil
+}
+
+func (f *fakePrivacyStore) ExportUserDailyProduction(ctx context.Context, userID int32) ([]byte, error) {
+	return f.export(ctx, userID)
+}
+
+func (f *fakePrivacyStore) ExportUserAlerts(ctx context.Context, userID int32) ([]byte, error) {
+	return []byte(`[]`), nil
+}
+
+func (f *fakePrivacyStore) ExportUserWebhookEvents(ctx context.Context, userID int32) ([]byte, error) {
+	return []byte(`[]`), nil
+}
+
+func (f *fakePrivacyStore) ExportUserAuditLog(ctx context.Context, userID int32) ([]byte, error) {
+	return f.export(ctx, userID)
+}
+
+func (f *fakePrivacyStore) erase(name string) (int64, error) {
+	if name == f.failing {
+		return 0, errors.New("connection reset")
+	}
+	f.erased = append(f.erased, name)
+	return 1, nil
+}
+
+func (f *fakePrivacyStore) ListUserIdentityProviders(ctx context.Context, userID int32) ([]string, error) {
+	return []string{"enode", "github"}, nil
+}
+
+func (f *fakePrivacyStore) EraseUserAlerts(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("alerts")
+}
+
+func (f *fakePrivacyStore) EraseUserProductionRollups(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("production_rollups")
+}
+
+func (f *fakePrivacyStore) EraseUserHourlyRecords(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("hourly_records")
+}
+
+func (f *fakePrivacyStore) EraseUserInverterMerges(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("inverter_merges")
+}
+
+func (f *fakePrivacyStore) DetachUserSolarPanels(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("solar_panels")
+}
+
+func (f *fakePrivacyStore) EraseUserInverters(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("inverters")
+}
+
+func (f *fakePrivacyStore) EraseUserLinkSessions(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("link_sessions")
+}
+
+func (f *fakePrivacyStore) EraseUserIdentities(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("identities")
+}
+
+func (f *fakePrivacyStore) EraseUserWebhookEvents(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("webhook_events")
+}
+
+func (f *fakePrivacyStore) EraseUserOutboxEvents(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("outbox_events")
+}
+
+func (f *fakePrivacyStore) ClearUserExportOutputs(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("export_documents")
+}
+
+func (f *fakePrivacyStore) RedactUserAuditLog(ctx context.Context, userID int32) (int64, error) {
+	return f.erase("audit_log")
+}
+
+func (f *fakePrivacyStore) InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error) {
+	f.outbox = append(f.outbox, arg)
+	return db.OutboxEvent{}, nil
+}
+
+func (f *fakePrivacyStore) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.audits = append(f.audits, arg)
+	return db.AuditLog{}, nil
+}
+
+type fakeEnodeUsers struct {
+	inverters    []inverters.SolarInverter
+	deauthorized []string
+	err          error
+}
+
+func (f *fakeEnodeUsers) IterateUserInverters(ctx context.Context, userID string, pageSize int) iter.Seq2[inverters.SolarInverter, error] {
+	return func(yield func(inverters.SolarInverter, error) bool) {
+		for _, inverter := range f.inverters {
+			if !yield(inverter, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeEnodeUsers) DeauthorizeUser(ctx context.Context, userID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deauthorized = append(f.deauthorized, userID)
+	return nil
+}
+
+type fakeIdentityRevoker struct {
+	revoked []string
+	err     error
+}
+
+func (f *fakeIdentityRevoker) Revoke(ctx context.Context, userID string, providerName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.revoked = append(f.revoked, userID+"/"+providerName)
+	return nil
+}
+
+func newTestRunner(store *fakePrivacyStore, enode *fakeEnodeUsers, identities *fakeIdentityRevoker) *Runner {
+	runner := NewRunner(store, enode, identities, time.Minute, 5*time.Minute, 3)
+	runner.now = func() time.Time { return time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC) }
+	return runner
+}
+
+func TestRunner_RunOnce_Export(t *testing.T) {
+	store := newFakePrivacyStore()
+	enode := &fakeEnodeUsers{inverters: []inverters.SolarInverter{{ID: "enode-1"}}}
+	if _, err := NewPrivacyUseCase(store).RequestExport(context.Background(), "42"); err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := newTestRunner(store, enode, &fakeIdentityRevoker{}).RunOnce(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("RunOnce() = %v, %v", ran, err)
+	}
+	if store.jobs[1].Status != StatusCompleted {
+		t.Fatalf("status = %s", store.jobs[1].Status)
+	}
+	for _, step := range store.steps[1] {
+		if step.Status != StatusCompleted || step.Output == nil {
+			t.Fatalf("step = %+v", step)
+		}
+		if step.Name == "hourly_records" && step.AffectedRows != 2 {
+			t.Fatalf("hourly_records affected = %d", step.AffectedRows)
+		}
+	}
+
+	document, err := NewPrivacyUseCase(store).ExportDocument(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Data) != len(exportSteps) || string(document.Data["identities"]) != `[{"id":1,"provider":"enode"}]` {
+		t.Fatalf("document = %+v", document)
+	}
+
+	ran, err = newTestRunner(store, enode, &fakeIdentityRevoker{}).RunOnce(context.Background())
+	if err != nil || ran {
+		t.Fatalf("second RunOnce() = %v, %v", ran, err)
+	}
+}
+
+func TestRunner_RunOnce_ErasureResumes(t *testing.T) {
+	store := newFakePrivacyStore()
+	enode := &fakeEnodeUsers{}
+	identities := &fakeIdentityRevoker{}
+	if _, err := NewPrivacyUseCase(store).RequestErasure(context.Background(), "42"); err != nil {
+		t.Fatal(err)
+	}
+	runner := newTestRunner(store, enode, identities)
+
+	store.failing = "inverters"
+	if _, err := runner.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.jobs[1].Status != StatusPending || store.failed == nil {
+		t.Fatalf("job = %+v", store.jobs[1])
+	}
+	if want := runner.now().Add(time.Minute); !store.failed.RunAfter.Equal(want) {
+		t.Fatalf("run after = %v, want %v", store.failed.RunAfter, want)
+	}
+	if want := []string{"alerts", "hourly_records", "production_rollups", "inverter_merges", "solar_panels"}; !slices.Equal(store.erased, want) {
+		t.Fatalf("erased = %v, want %v", store.erased, want)
+	}
+
+	store.failing = ""
+	if _, err := runner.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.jobs[1].Status != StatusCompleted {
+		t.Fatalf("status = %s", store.jobs[1].Status)
+	}
+	if len(enode.deauthorized) != 1 || enode.deauthorized[0] != "42" {
+		t.Fatalf("deauthorized = %v", enode.deauthorized)
+	}
+	if want := []string{"42/enode", "42/github"}; !slices.Equal(identities.revoked, want) {
+		t.Fatalf("revoked = %v, want %v", identities.revoked, want)
+	}
+	want := []string{"alerts", "hourly_records", "production_rollups", "inverter_merges", "solar_panels", "inverters", "link_sessions", "identities", "webhook_events", "outbox_events", "export_documents", "audit_log"}
+	if !slices.Equal(store.erased, want) {
+		t.Fatalf("erased = %v, want %v", store.erased, want)
+	}
+	if len(store.outbox) != 1 || store.outbox[0].EventType != "user.erased" || store.outbox[0].AggregateID != "42" {
+		t.Fatalf("outbox = %+v", store.outbox)
+	}
+	if last := store.audits[len(store.audits)-1]; last.Action != "user.erased" || last.Actor != "system" {
+		t.Fatalf("audit = %+v", last)
+	}
+}
+
+func TestRunner_RunOnce_ErasureKeepsIdentitiesUntilRevoked(t *testing.T) {
+	store := newFakePrivacyStore()
+	identities := &fakeIdentityRevoker{err: errors.New("revocation failed")}
+	if _, err := NewPrivacyUseCase(store).RequestErasure(context.Background(), "42"); err != nil {
+		t.Fatal(err)
+	}
+	runner := newTestRunner(store, &fakeEnodeUsers{}, identities)
+
+	if _, err := runner.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.jobs[1].Status != StatusPending || len(store.erased) != 0 {
+		t.Fatalf("job = %+v, erased = %v", store.jobs[1], store.erased)
+	}
+
+	identities.err = nil
+	if _, err := runner.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.jobs[1].Status != StatusCompleted || !slices.Contains(store.erased, "identities") {
+		t.Fatalf("job = %+v, erased = %v", store.jobs[1], store.erased)
+	}
+}
+
+func TestRunner_RunOnce_FailsAfterMaxAttempts(t *testing.T) {
+	store := newFakePrivacyStore()
+	enode := &fakeEnodeUsers{err: errors.New("enode unavailable")}
+	useCase := NewPrivacyUseCase(store)
+	if _, err := useCase.RequestErasure(context.Background(), "42"); err != nil {
+		t.Fatal(err)
+	}
+	runner := newTestRunner(store, enode, &fakeIdentityRevoker{})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if _, err := runner.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.jobs[1].Status != StatusFailed || len(store.erased) != 0 {
+		t.Fatalf("job = %+v, erased = %v", store.jobs[1], store.erased)
+	}
+	if ran, _ := runner.RunOnce(context.Background()); ran {
+		t.Fatal("failed job was claimed again")
+	}
+
+	enode.err = nil
+	if _, err := useCase.RetryJob(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.jobs[1].Status != StatusCompleted {
+		t.Fatalf("status = %s", store.jobs[1].Status)
+	}
+}
@@ -0,0 +1,188 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/outbox"
+)
+
+const (
+	KindExport  = "export"
+	KindErasure = "erasure"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// EnodeUsers reads and removes a user's account at Enode. Enode user IDs are local user IDs.
+type EnodeUsers interface {
+	IterateUserInverters(ctx context.Context, userID string, pageSize int) iter.Seq2[inverters.SolarInverter, error]
+	DeauthorizeUser(ctx context.Context, userID string) error
+}
+
+// IdentityRevoker revokes a user's identity at its provider and clears the stored tokens.
+type IdentityRevoker interface {
+	Revoke(ctx context.Context, userID string, providerName string) error
+}
+
+// services are the systems outside the database that steps talk to.
+type services struct {
+	enode      EnodeUsers
+	identities IdentityRevoker
+}
+
+// stepResult is what a step leaves behind: the rows it touched and, for exports, the JSON
+// document section it produced.
+type stepResult struct {
+	affectedRows int64
+	output       []byte
+}
+
+// step is one unit of a job. A step runs in its own transaction together with the write that
+// marks it completed, so it must be safe to run again if that transaction rolls back.
+type step struct {
+	name string
+	run  func(ctx context.Context, store PrivacyStore, services services, userID int32) (stepResult, error)
+}
+
+// exportSteps produce the sections of the export document, in document order.
+var exportSteps = []step{
+	{name: "identities", run: exportQuery(PrivacyStore.ExportUserIdentities)},
+	{name: "link_sessions", run: exportQuery(PrivacyStore.ExportUserLinkSessions)},
+	{name: "inverters", run: exportQuery(PrivacyStore.ExportUserInverters)},
+	{name: "enode_inverters", run: exportEnodeInverters},
+	{name: "solar_panels", run: exportQuery(PrivacyStore.ExportUserSolarPanels)},
+	{name: "hourly_records", run: exportQuery(PrivacyStore.ExportUserHourlyRecords)},
+	{name: "daily_production", run: exportQuery(PrivacyStore.ExportUserDailyProduction)},
+	{name: "alerts", run: exportQuery(PrivacyStore.ExportUserAlerts)},
+	{name: "webhook_events", run: exportQuery(PrivacyStore.ExportUserWebhookEvents)},
+	{name: "audit_log", run: exportQuery(PrivacyStore.ExportUserAuditLog)},
+}
+
+// erasureSteps remove the user's data. Enode is deauthorized and identities are revoked at their
+// providers first, so no new webhooks, statistics or tokens arrive for the user while local rows
+// are deleted, and rows are removed before the
+// rows they reference. Hourly records go before rollups so the refresher cannot fold them back
+// into rows that were already erased. The user's audit entries are kept for accountability, but
+// their snapshots are redacted once the steps that record entries have run.
+var erasureSteps = []step{
+	{name: "enode_deauthorize", run: deauthorizeEnodeUser},
+	{name: "identity_revocation", run: revokeIdentities},
+	{name: "alerts", run: eraseQuery(PrivacyStore.EraseUserAlerts)},
+	{name: "hourly_records", run: eraseQuery(PrivacyStore.EraseUserHourlyRecords)},
+	{name: "production_rollups", run: eraseQuery(PrivacyStore.EraseUserProductionRollups)},
+	{name: "inverter_merges", run: eraseQuery(PrivacyStore.EraseUserInverterMerges)},
+	{name: "solar_panels", run: eraseQuery(PrivacyStore.DetachUserSolarPanels)},
+	{name: "inverters", run: eraseQuery(PrivacyStore.EraseUserInverters)},
+	{name: "link_sessions", run: eraseQuery(PrivacyStore.EraseUserLinkSessions)},
+	{name: "identities", run: eraseQuery(PrivacyStore.EraseUserIdentities)},
+	{name: "webhook_events", run: eraseQuery(PrivacyStore.EraseUserWebhookEvents)},
+	{name: "outbox_events", run: eraseQuery(PrivacyStore.EraseUserOutboxEvents)},
+	{name: "export_documents", run: eraseQuery(PrivacyStore.ClearUserExportOutputs)},
+	{name: "audit_log", run: eraseQuery(PrivacyStore.RedactUserAuditLog)},
+	{name: "user_erased_event", run: publishUserErased},
+}
+
+func stepsFor(kind string) []step {
+	if kind == KindErasure {
+		return erasureSteps
+	}
+	return exportSteps
+}
+
+// UserErasedPayload is the outbox payload telling downstream services to erase their copies.
+type UserErasedPayload struct {
+	UserID string `json:"userId"`
+}
+
+func exportQuery(query func(PrivacyStore, context.Context, int32) ([]byte, error)) func(context.Context, PrivacyStore, services, int32) (stepResult, error) {
+	return func(ctx context.Context, store PrivacyStore, _ services, userID int32) (stepResult, error) {
+		output, err := query(store, ctx, userID)
+		if err != nil {
+			return stepResult{}, err
+		}
+		var rows []json.RawMessage
+		if err := json.Unmarshal(output, &rows); err != nil {
+			return stepResult{}, fmt.Errorf("decoding export section: %w", err)
+		}
+		return stepResult{affectedRows: int64(len(rows)), output: output}, nil
+	}
+}
+
+func eraseQuery(query func(PrivacyStore, context.Context, int32) (int64, error)) func(context.Context, PrivacyStore, services, int32) (stepResult, error) {
+	return func(ctx context.Context, store PrivacyStore, _ services, userID int32) (stepResult, error) {
+		affected, err := query(store, ctx, userID)
+		if err != nil {
+			return stepResult{}, err
+		}
+		return stepResult{affectedRows: affected}, nil
+	}
+}
+
+func exportEnodeInverters(ctx context.Context, _ PrivacyStore, services services, userID int32) (stepResult, error) {
+	devices := []inverters.SolarInverter{}
+	for inverter, err := range services.enode.IterateUserInverters(ctx, strconv.Itoa(int(userID)), 0) {
+		if err != nil {
+			return stepResult{}, fmt.Errorf("listing Enode inverters: %w", err)
+		}
+		devices = append(devices, inverter)
+	}
+	output, err := json.Marshal(devices)
+	if err != nil {
+		return stepResult{}, fmt.Errorf("encoding Enode inverters: %w", err)
+	}
+	return stepResult{affectedRows: int64(len(devices)), output: output}, nil
+}
+
+func deauthorizeEnodeUser(ctx context.Context, _ PrivacyStore, services services, userID int32) (stepResult, error) {
+	if err := services.enode.DeauthorizeUser(ctx, strconv.Itoa(int(userID))); err != nil {
+		return stepResult{}, err
+	}
+	return stepResult{affectedRows: 1}, nil
+}
+
+// revokeIdentities revokes every identity of the user, so providers stop honouring tokens that
+// were issued to us. Revoked identities are skipped, which makes the step safe to run again.
+func revokeIdentities(ctx context.Context, store PrivacyStore, services services, userID int32) (stepResult, error) {
+	providers, err := store.ListUserIdentityProviders(ctx, userID)
+	if err != nil {
+		return stepResult{}, fmt.Errorf("listing identities: %w", err)
+	}
+	for _, provider := range providers {
+		if err := services.identities.Revoke(ctx, strconv.Itoa(int(userID)), provider); err != nil {
+			return stepResult{}, fmt.Errorf("revoking %s identity: %w", provider, err)
+		}
+	}
+	return stepResult{affectedRows: int64(len(providers))}, nil
+}
+
+func publishUserErased(ctx context.Context, store PrivacyStore, _ services, userID int32) (stepResult, error) {
+	id := strconv.Itoa(int(userID))
+	event, err := outbox.NewEvent(outbox.AggregateUser, id, outbox.EventUserErased, UserErasedPayload{UserID: id})
+	if err != nil {
+		return stepResult{}, err
+	}
+	if _, err := store.InsertOutboxEvent(ctx, event); err != nil {
+		return stepResult{}, fmt.Errorf("storing outbox event: %w", err)
+	}
+	err = audit.Record(ctx, store, audit.Entry{
+		Action:       audit.ActionUserErased,
+		ResourceType: audit.ResourceUser,
+		ResourceID:   id,
+		UserID:       id,
+	})
+	if err != nil {
+		return stepResult{}, err
+	}
+	return stepResult{affectedRows: 1}, nil
+}
@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PrivacyStore persists privacy jobs and reads or erases the personal data they cover.
+type PrivacyStore interface {
+	CreatePrivacyJob(ctx context.Context, arg db.CreatePrivacyJobParams) (db.PrivacyJob, error)
+	InsertPrivacyJobStep(ctx context.Context, arg db.InsertPrivacyJobStepParams) error
+	GetPrivacyJob(ctx context.Context, id int64) (db.PrivacyJob, error)
+	ListUserPrivacyJobs(ctx context.Context, userID int32) ([]db.PrivacyJob, error)
+	ListPrivacyJobSteps(ctx context.Context, jobID int64) ([]db.PrivacyJobStep, error)
+	ClaimPrivacyJob(ctx context.Context, lockedUntil *time.Time) (db.PrivacyJob, error)
+	CompletePrivacyJobStep(ctx context.Context, arg db.CompletePrivacyJobStepParams) error
+	CompletePrivacyJob(ctx context.Context, id int64) error
+	FailPrivacyJob(ctx context.Context, arg db.FailPrivacyJobParams) error
+	RetryPrivacyJob(ctx context.Context, id int64) (db.PrivacyJob, error)
+
+	ExportUserIdentities(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserLinkSessions(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserInverters(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserSolarPanels(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserHourlyRecords(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserDailyProduction(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserAlerts(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserWebhookEvents(ctx context.Context, userID int32) ([]byte, error)
+	ExportUserAuditLog(ctx context.Context, userID int32) ([]byte, error)
+
+	ListUserIdentityProviders(ctx context.Context, userID int32) ([]string, error)
+	EraseUserAlerts(ctx context.Context, userID int32) (int64, error)
+	EraseUserHourlyRecords(ctx context.Context, userID int32) (int64, error)
+	EraseUserProductionRollups(ctx context.Context, userID int32) (int64, error)
+	EraseUserInverterMerges(ctx context.Context, userID int32) (int64, error)
+	DetachUserSolarPanels(ctx context.Context, userID int32) (int64, error)
+	EraseUserInverters(ctx context.Context, userID int32) (int64, error)
+	EraseUserLinkSessions(ctx context.Context, userID int32) (int64, error)
+	EraseUserIdentities(ctx context.Context, userID int32) (int64, error)
+	EraseUserWebhookEvents(ctx context.Context, userID int32) (int64, error)
+	EraseUserOutboxEvents(ctx context.Context, userID int32) (int64, error)
+	ClearUserExportOutputs(ctx context.Context, userID int32) (int64, error)
+	RedactUserAuditLog(ctx context.Context, userID int32) (int64, error)
+
+	InsertOutboxEvent(ctx context.Context, arg db.InsertOutboxEventParams) (db.OutboxEvent, error)
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+	InTx(ctx context.Context, fn func(store PrivacyStore) error) error
+}
+
+// PostgresPrivacyStore is the PrivacyStore backed by sqlc queries on a pgx pool.
+type PostgresPrivacyStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresPrivacyStore(pool *pgxpool.Pool) *PostgresPrivacyStore {
+	return &PostgresPrivacyStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresPrivacyStore) InTx(ctx context.Context, fn func(store PrivacyStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresPrivacyStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
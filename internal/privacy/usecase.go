@@ -0,0 +1,193 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidUserID     = errors.New("invalid user id")
+	ErrInvalidJobID      = errors.New("invalid privacy job id")
+	ErrJobNotFound       = errors.New("privacy job not found")
+	ErrJobInProgress     = errors.New("privacy job already in progress")
+	ErrJobNotCompleted   = errors.New("privacy job not completed")
+	ErrJobNotFailed      = errors.New("privacy job has not failed")
+	ErrExportUnavailable = errors.New("privacy export no longer available")
+)
+
+type PrivacyUseCase struct {
+	store PrivacyStore
+}
+
+func NewPrivacyUseCase(store PrivacyStore) *PrivacyUseCase {
+	return &PrivacyUseCase{store: store}
+}
+
+// RequestExport queues a job that collects everything the adapter holds about userID.
+func (uc *PrivacyUseCase) RequestExport(ctx context.Context, userID string) (*JobResponse, error) {
+	return uc.requestJob(ctx, userID, KindExport, audit.ActionPrivacyExportRequested)
+}
+
+// RequestErasure queues a job that deauthorizes userID at Enode and erases their local data.
+func (uc *PrivacyUseCase) RequestErasure(ctx context.Context, userID string) (*JobResponse, error) {
+	return uc.requestJob(ctx, userID, KindErasure, audit.ActionPrivacyErasureRequested)
+}
+
+func (uc *PrivacyUseCase) requestJob(ctx context.Context, userID string, kind string, action string) (*JobResponse, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response JobResponse
+	err = uc.store.InTx(ctx, func(store PrivacyStore) error {
+		job, err := store.CreatePrivacyJob(ctx, db.CreatePrivacyJobParams{
+			UserID:      id,
+			Kind:        kind,
+			RequestedBy: audit.ActorFromContext(ctx).ID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrJobInProgress
+		}
+		if err != nil {
+			return fmt.Errorf("creating %s job: %w", kind, err)
+		}
+
+		steps := make([]db.PrivacyJobStep, 0, len(stepsFor(kind)))
+		for position, step := range stepsFor(kind) {
+			arg := db.InsertPrivacyJobStepParams{JobID: job.ID, Position: int32(position), Name: step.name}
+			if err := store.InsertPrivacyJobStep(ctx, arg); err != nil {
+				return fmt.Errorf("creating %s job step %s: %w", kind, step.name, err)
+			}
+			steps = append(steps, db.PrivacyJobStep{JobID: job.ID, Position: arg.Position, Name: step.name, Status: StatusPending})
+		}
+
+		response = newJobResponse(job, steps)
+		return audit.Record(ctx, store, audit.Entry{
+			Action:       action,
+			ResourceType: audit.ResourceUser,
+			ResourceID:   userID,
+			UserID:       userID,
+			After:        response,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (uc *PrivacyUseCase) GetJob(ctx context.Context, jobID string) (*JobResponse, error) {
+	job, err := uc.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.jobResponse(ctx, job)
+}
+
+func (uc *PrivacyUseCase) ListUserJobs(ctx context.Context, userID string) (*ListJobsResponse, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := uc.store.ListUserPrivacyJobs(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("listing privacy jobs: %w", err)
+	}
+
+	response := &ListJobsResponse{Data: make([]JobResponse, 0, len(jobs))}
+	for _, job := range jobs {
+		jobResponse, err := uc.jobResponse(ctx, job)
+		if err != nil {
+			return nil, err
+		}
+		response.Data = append(response.Data, *jobResponse)
+	}
+	return response, nil
+}
+
+// RetryJob moves a job that exhausted its attempts back to pending. Completed steps are kept,
+// so the job resumes where it failed.
+func (uc *PrivacyUseCase) RetryJob(ctx context.Context, jobID string) (*JobResponse, error) {
+	job, err := uc.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	retried, err := uc.store.RetryPrivacyJob(ctx, job.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrJobNotFailed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("retrying privacy job %d: %w", job.ID, err)
+	}
+	return uc.jobResponse(ctx, retried)
+}
+
+// ExportDocument assembles the output of a completed export job. Exports of a user erased
+// afterwards are no longer available.
+func (uc *PrivacyUseCase) ExportDocument(ctx context.Context, jobID string) (*ExportDocument, error) {
+	job, err := uc.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Kind != KindExport || job.Status != StatusCompleted {
+		return nil, ErrJobNotCompleted
+	}
+	steps, err := uc.store.ListPrivacyJobSteps(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("listing privacy job steps: %w", err)
+	}
+
+	document := &ExportDocument{
+		JobID:       strconv.FormatInt(job.ID, 10),
+		UserID:      strconv.Itoa(int(job.UserID)),
+		GeneratedAt: job.CompletedAt,
+		Data:        make(map[string]json.RawMessage, len(steps)),
+	}
+	for _, step := range steps {
+		if step.Output == nil {
+			return nil, ErrExportUnavailable
+		}
+		document.Data[step.Name] = step.Output
+	}
+	return document, nil
+}
+
+func (uc *PrivacyUseCase) getJob(ctx context.Context, jobID string) (db.PrivacyJob, error) {
+	id, err := strconv.ParseInt(jobID, 10, 64)
+	if err != nil {
+		return db.PrivacyJob{}, fmt.Errorf("%w: %q", ErrInvalidJobID, jobID)
+	}
+	job, err := uc.store.GetPrivacyJob(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.PrivacyJob{}, ErrJobNotFound
+	}
+	if err != nil {
+		return db.PrivacyJob{}, fmt.Errorf("getting privacy job %d: %w", id, err)
+	}
+	return job, nil
+}
+
+func (uc *PrivacyUseCase) jobResponse(ctx context.Context, job db.PrivacyJob) (*JobResponse, error) {
+	steps, err := uc.store.ListPrivacyJobSteps(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("listing privacy job steps: %w", err)
+	}
+	response := newJobResponse(job, steps)
+	return &response, nil
+}
+
+func parseUserID(userID string) (int32, error) {
+	id, err := strconv.ParseInt(userID, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUserID, userID)
+	}
+	return int32(id), nil
+}
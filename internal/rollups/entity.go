@@ -0,0 +1,28 @@
+package rollups
+
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// ProductionPoint is the energy produced in one day, month or year. Period is formatted as
+// 2006-01-02, 2006-01 or 2006 depending on the requested period.
+type ProductionPoint struct {
+	Period    string  `json:"period"`
+	EnergyKwh float64 `json:"energyKwh"`
+	Source    string  `json:"source,omitempty"`
+	Days      int32   `json:"days,omitempty"`
+	Months    int32   `json:"months,omitempty"`
+	Inverters int32   `json:"inverters,omitempty"`
+}
+
+type ProductionResponse struct {
+	InverterID *int32            `json:"inverterId,omitempty"`
+	UserID     *int32            `json:"userId,omitempty"`
+	Period     string            `json:"period"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	TotalKwh   float64           `json:"totalKwh"`
+	Data       []ProductionPoint `json:"data"`
+}
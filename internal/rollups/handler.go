@@ -0,0 +1,90 @@
+package rollups
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RollupHandler struct {
+	rollupUseCase *RollupUseCase
+}
+
+func NewRollupHandler(rollupUseCase *RollupUseCase) *RollupHandler {
+	return &RollupHandler{
+		rollupUseCase: rollupUseCase,
+	}
+}
+
+func (h *RollupHandler) GetInverterProduction(c echo.Context) error {
+	inverterID := c.Param("inverterID")
+	r, err := rangeParams(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date range")
+	}
+
+	response, err := h.rollupUseCase.GetInverterProduction(c.Request().Context(), inverterID, periodParam(c), r)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get inverter production", "inverterID", inverterID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get inverter production")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *RollupHandler) GetUserProduction(c echo.Context) error {
+	userID := c.Param("userID")
+	r, err := rangeParams(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date range")
+	}
+
+	response, err := h.rollupUseCase.GetUserProduction(c.Request().Context(), userID, periodParam(c), r)
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to get user production", "userID", userID, "error", err)
+		return echo.NewHTTPError(statusFromError(err), "Failed to get user production")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// periodParam reads the period query parameter, defaulting to day.
+func periodParam(c echo.Context) string {
+	if period := c.QueryParam("period"); period != "" {
+		return period
+	}
+	return PeriodDay
+}
+
+// rangeParams reads the optional from and to query parameters as dates, e.g. 2024-06-01.
+func rangeParams(c echo.Context) (Range, error) {
+	var r Range
+	for name, target := range map[string]*time.Time{"from": &r.From, "to": &r.To} {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return Range{}, fmt.Errorf("%w: %s: %v", ErrInvalidRange, name, err)
+		}
+		*target = parsed
+	}
+	return r, nil
+}
+
+// statusFromError maps use case errors to the HTTP status returned to clients.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInverterID), errors.Is(err, ErrInvalidUserID), errors.Is(err, ErrInvalidPeriod), errors.Is(err, ErrInvalidRange):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInverterNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,242 @@
+package rollups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const hourlyRecordsCursor = "hourly_records"
+
+// StatisticsSource provides Enode inverters and their production statistics.
+type StatisticsSource interface {
+	IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error]
+	GetInverterProductionStatistics(ctx context.Context, inverterID string, year int, month int, day int) (*inverters.InverterStatistic, error)
+}
+
+// RefreshResult counts the rollup rows written by one refresh.
+type RefreshResult struct {
+	Daily   int64
+	Monthly int64
+	Yearly  int64
+	Skipped bool
+}
+
+// Refresher keeps the rollup tables in line with hourly records and Enode DAY statistics.
+type Refresher struct {
+	store          RollupStore
+	source         StatisticsSource
+	lateDataLag    time.Duration
+	batchSize      int32
+	interval       time.Duration
+	importInterval time.Duration
+	now            func() time.Time
+}
+
+func NewRefresher(store RollupStore, source StatisticsSource, lateDataLag time.Duration, batchSize int32, interval time.Duration, importInterval time.Duration) *Refresher {
+	return &Refresher{
+		store:          store,
+		source:         source,
+		lateDataLag:    lateDataLag,
+		batchSize:      max(batchSize, 1),
+		interval:       interval,
+		importInterval: importInterval,
+		now:            time.Now,
+	}
+}
+
+// Run refreshes rollups from hourly records every interval and imports Enode statistics every
+// import interval until ctx is cancelled.
+func (r *Refresher) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting production rollup refresher", "interval", r.interval, "importInterval", r.importInterval)
+	refresh := time.NewTicker(r.interval)
+	defer refresh.Stop()
+	enodeImport := time.NewTicker(r.importInterval)
+	defer enodeImport.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping production rollup refresher")
+			return
+		case <-refresh.C:
+			result, err := r.RefreshOnce(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Production rollup refresh failed", "error", err)
+				continue
+			}
+			if !result.Skipped {
+				slog.InfoContext(ctx, "Refreshed production rollups", "daily", result.Daily, "monthly", result.Monthly, "yearly", result.Yearly)
+			}
+		case <-enodeImport.C:
+			imported, err := r.ImportEnodeOnce(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Enode production import failed", "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Imported Enode daily production", "days", imported)
+		}
+	}
+}
+
+// RefreshOnce folds hourly records added since the last refresh into the rollups, in batches of
+// at most batchSize records. Each batch commits with the cursor, so a large backlog such as the
+// first run makes steady progress. Records are folded only once they are lateDataLag old, so rows
+// with lower ids committed by slow transactions are not skipped.
+func (r *Refresher) RefreshOnce(ctx context.Context) (RefreshResult, error) {
+	var total RefreshResult
+	for {
+		result, more, err := r.refreshBatch(ctx)
+		total.Daily += result.Daily
+		total.Monthly += result.Monthly
+		total.Yearly += result.Yearly
+		total.Skipped = result.Skipped
+		if err != nil || !more {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// refreshBatch folds the next batch of settled hourly records and reports whether more are waiting.
+func (r *Refresher) refreshBatch(ctx context.Context) (RefreshResult, bool, error) {
+	var result RefreshResult
+	more := false
+	err := r.store.InTx(ctx, func(store RollupStore) error {
+		locked, err := store.TryLockProductionRollups(ctx)
+		if err != nil {
+			return fmt.Errorf("locking rollups: %w", err)
+		}
+		if !locked {
+			// Another replica is refreshing.
+			result.Skipped = true
+			return nil
+		}
+
+		cursor, err := store.GetRollupCursor(ctx, hourlyRecordsCursor)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("reading cursor: %w", err)
+		}
+		batch, err := store.GetHourlyRecordBatchEnd(ctx, db.GetHourlyRecordBatchEndParams{
+			SettledBefore: r.now().Add(-r.lateDataLag),
+			AfterID:       cursor,
+			BatchSize:     r.batchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("finding next batch of hourly records: %w", err)
+		}
+		if batch.LastID <= cursor {
+			return nil
+		}
+
+		result.Daily, err = store.FoldHourlyDailyProduction(ctx, db.FoldHourlyDailyProductionParams{AfterID: cursor, LastID: batch.LastID})
+		if err != nil {
+			return fmt.Errorf("folding hourly records %d to %d: %w", cursor+1, batch.LastID, err)
+		}
+		if err := refreshAggregates(ctx, store, &result); err != nil {
+			return err
+		}
+		if err := store.SetRollupCursor(ctx, db.SetRollupCursorParams{Name: hourlyRecordsCursor, LastID: batch.LastID}); err != nil {
+			return fmt.Errorf("storing cursor: %w", err)
+		}
+		more = batch.More
+		return nil
+	})
+	return result, more && err == nil, err
+}
+
+// ImportEnodeOnce stores Enode DAY statistics for the current and previous month of every Enode
+// inverter that matches a local inverter (see inverters.MatchEnodeInverter), then refreshes the
+// affected months and years. The previous month is included so corrections Enode publishes late
+// are picked up.
+func (r *Refresher) ImportEnodeOnce(ctx context.Context) (int, error) {
+	now := r.now()
+	months := []time.Time{now.AddDate(0, -1, 0), now}
+
+	imported := 0
+	for inverter, err := range r.source.IterateInverters(ctx, 0) {
+		if err != nil {
+			return imported, fmt.Errorf("listing inverters: %w", err)
+		}
+		local, err := inverters.MatchEnodeInverter(ctx, r.store, inverter)
+		if errors.Is(err, inverters.ErrInverterNotFound) {
+			continue
+		}
+		if errors.Is(err, inverters.ErrAmbiguousInverter) {
+			slog.WarnContext(ctx, "Skipping Enode production of ambiguous inverter", "inverterID", inverter.ID, "error", err)
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		var days []db.UpsertEnodeDailyProductionParams
+		for _, month := range months {
+			stats, err := r.source.GetInverterProductionStatistics(ctx, inverter.ID, month.Year(), int(month.Month()), 0)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to fetch Enode production statistics", "inverterID", inverter.ID, "month", month.Format("2006-01"), "error", err)
+				continue
+			}
+			days = append(days, dailyProduction(local, stats)...)
+		}
+		if len(days) == 0 {
+			continue
+		}
+
+		err = r.store.InTx(ctx, func(store RollupStore) error {
+			for _, day := range days {
+				if err := store.UpsertEnodeDailyProduction(ctx, day); err != nil {
+					return fmt.Errorf("storing Enode production for inverter %d: %w", local.ID, err)
+				}
+			}
+			return refreshAggregates(ctx, store, &RefreshResult{})
+		})
+		if err != nil {
+			return imported, err
+		}
+		imported += len(days)
+	}
+	return imported, nil
+}
+
+// refreshAggregates recomputes months and years containing daily rows written in this transaction.
+func refreshAggregates(ctx context.Context, store RollupStore, result *RefreshResult) error {
+	var err error
+	if result.Monthly, err = store.RefreshMonthlyProduction(ctx); err != nil {
+		return fmt.Errorf("refreshing monthly production: %w", err)
+	}
+	if result.Yearly, err = store.RefreshYearlyProduction(ctx); err != nil {
+		return fmt.Errorf("refreshing yearly production: %w", err)
+	}
+	return nil
+}
+
+// dailyProduction converts the DAY resolution of Enode statistics into rollup rows. Each data
+// point's day is taken in the timezone Enode reported it in.
+func dailyProduction(local db.Inverter, stats *inverters.InverterStatistic) []db.UpsertEnodeDailyProductionParams {
+	resolution, ok := stats.Resolutions["DAY"]
+	if !ok {
+		return nil
+	}
+	days := make([]db.UpsertEnodeDailyProductionParams, 0, len(resolution.Data))
+	for _, point := range resolution.Data {
+		year, month, day := point.Date.Date()
+		days = append(days, db.UpsertEnodeDailyProductionParams{
+			InverterID: local.ID,
+			UserID:     local.UserID,
+			Day:        pgtype.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true},
+			EnergyKwh:  resolution.EnergyKwh(point.Value),
+		})
+	}
+	return days
+}
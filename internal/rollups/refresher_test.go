@@ -0,0 +1,238 @@
+package rollups
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeRollupStore records refresher writes. Read queries are left to the embedded nil interface.
+// Hourly records are given by id and creation time; folding records the id range of each batch.
+type fakeRollupStore struct {
+	RollupStore
+	locked     bool
+	cursor     *int32
+	records    map[int32]time.Time
+	folded     [][2]int32
+	aggregated int
+	enodeDays  []db.UpsertEnodeDailyProductionParams
+	inverters  []db.Inverter
+}
+
+func (f *fakeRollupStore) InTx(ctx context.Context, fn func(store RollupStore) error) error {
+	return fn(f)
+}
+
+func (f *fakeRollupStore) TryLockProductionRollups(ctx context.Context) (bool, error) {
+	return f.locked, nil
+}
+
+func (f *fakeRollupStore) GetRollupCursor(ctx context.Context, name string) (int32, error) {
+	if f.cursor == nil {
+		return 0, pgx.ErrNoRows
+	}
+	return *f.cursor, nil
+}
+
+func (f *fakeRollupStore) SetRollupCursor(ctx context.Context, arg db.SetRollupCursorParams) error {
+	f.cursor = &arg.LastID
+	return nil
+}
+
+func (f *fakeRollupStore) GetHourlyRecordBatchEnd(ctx context.Context, arg db.GetHourlyRecordBatchEndParams) (db.GetHourlyRecordBatchEndRow, error) {
+	ids := slices.Sorted(maps.Keys(f.records))
+	ids = slices.DeleteFunc(ids, func(id int32) bool { return id <= arg.AfterID })
+	ids = ids[:min(len(ids), int(arg.BatchSize))]
+	end := db.GetHourlyRecordBatchEndRow{LastID: arg.AfterID, More: len(ids) == int(arg.BatchSize)}
+	for _, id := range ids {
+		if !f.records[id].Before(arg.SettledBefore) {
+			end.LastID, end.More = id-1, false
+			break
+		}
+		end.LastID = id
+	}
+	return end, nil
+}
+
+func (f *fakeRollupStore) FoldHourlyDailyProduction(ctx context.Context, arg db.FoldHourlyDailyProductionParams) (int64, error) {
+	f.folded = append(f.folded, [2]int32{arg.AfterID, arg.LastID})
+	return int64(arg.LastID - arg.AfterID), nil
+}
+
+func (f *fakeRollupStore) RefreshMonthlyProduction(ctx context.Context) (int64, error) {
+	f.aggregated++
+	return 1, nil
+}
+
+func (f *fakeRollupStore) RefreshYearlyProduction(ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeRollupStore) UpsertEnodeDailyProduction(ctx context.Context, arg db.UpsertEnodeDailyProductionParams) error {
+	f.enodeDays = append(f.enodeDays, arg)
+	return nil
+}
+
+func (f *fakeRollupStore) ListEnodeInverterMatches(ctx context.Context, arg db.ListEnodeInverterMatchesParams) ([]db.Inverter, error) {
+	var matches []db.Inverter
+	for _, inverter := range f.inverters {
+		if inverter.SerialNumber == arg.SerialNumber && inverter.UserID == arg.UserID {
+			matches = append(matches, inverter)
+		}
+	}
+	return matches, nil
+}
+
+type fakeStatisticsSource struct {
+	inverters []inverters.SolarInverter
+	stats     map[string]*inverters.InverterStatistic
+	requested []string
+}
+
+func (f *fakeStatisticsSource) IterateInverters(ctx context.Context, pageSize int) iter.Seq2[inverters.SolarInverter, error] {
+	return func(yield func(inverters.SolarInverter, error) bool) {
+		for _, inverter := range f.inverters {
+			if !yield(inverter, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *fakeStatisticsSource) GetInverterProductionStatistics(ctx context.Context, inverterID string, year int, month int, day int) (*inverters.InverterStatistic, error) {
+	key := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+	f.requested = append(f.requested, key)
+	if stats, ok := f.stats[key]; ok {
+		return stats, nil
+	}
+	return &inverters.InverterStatistic{Resolutions: map[string]inverters.Resolution{}}, nil
+}
+
+func TestRefresher_RefreshOnce(t *testing.T) {
+	now := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-time.Hour)
+	records := map[int32]time.Time{1: old, 2: old, 3: old, 4: old, 5: old, 7: now.Add(-5 * time.Minute), 8: old}
+	cursorAt := func(id int32) *int32 { return &id }
+
+	tests := []struct {
+		name       string
+		cursor     *int32
+		locked     bool
+		wantFolded [][2]int32
+		wantCursor *int32
+		wantDaily  int64
+	}{
+		{
+			name:       "first run folds the backlog in batches",
+			locked:     true,
+			wantFolded: [][2]int32{{0, 2}, {2, 4}, {4, 6}},
+			wantCursor: cursorAt(6),
+			wantDaily:  6,
+		},
+		{
+			name:       "continues from the cursor",
+			cursor:     cursorAt(4),
+			locked:     true,
+			wantFolded: [][2]int32{{4, 6}},
+			wantCursor: cursorAt(6),
+			wantDaily:  2,
+		},
+		{
+			name:       "waits for records within the lag",
+			cursor:     cursorAt(6),
+			locked:     true,
+			wantCursor: cursorAt(6),
+		},
+		{
+			name:   "skips when another replica holds the lock",
+			locked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeRollupStore{locked: tt.locked, cursor: tt.cursor, records: records}
+			refresher := NewRefresher(store, nil, 10*time.Minute, 2, time.Minute, time.Hour)
+			refresher.now = func() time.Time { return now }
+
+			result, err := refresher.RefreshOnce(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(store.folded, tt.wantFolded) {
+				t.Fatalf("folded batches = %v, want %v", store.folded, tt.wantFolded)
+			}
+			if (store.cursor == nil) != (tt.wantCursor == nil) || store.cursor != nil && *store.cursor != *tt.wantCursor {
+				t.Fatalf("cursor = %v, want %v", store.cursor, tt.wantCursor)
+			}
+			if result.Daily != tt.wantDaily || result.Skipped != !tt.locked || store.aggregated != len(tt.wantFolded) {
+				t.Fatalf("result = %+v, aggregated %d times", result, store.aggregated)
+			}
+		})
+	}
+}
+
+func TestRefresher_ImportEnodeOnce(t *testing.T) {
+	serial := "SN-1"
+	unknown := "SN-2"
+	shared := "SN-3"
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeRollupStore{inverters: []db.Inverter{
+		{ID: 7, UserID: 3, SerialNumber: serial},
+		// Another user registered the same serial number.
+		{ID: 8, UserID: 4, SerialNumber: serial},
+		// User 3 has two inverters with this serial number until they are merged.
+		{ID: 9, UserID: 3, SerialNumber: shared},
+		{ID: 10, UserID: 3, SerialNumber: shared},
+	}}
+	source := &fakeStatisticsSource{
+		inverters: []inverters.SolarInverter{
+			{ID: "enode-1", UserID: "3", Information: inverters.Information{SerialNumber: &serial}},
+			{ID: "enode-2", UserID: "3", Information: inverters.Information{SerialNumber: &unknown}},
+			{ID: "enode-3", UserID: "3"},
+			{ID: "enode-4", UserID: "3", Information: inverters.Information{SerialNumber: &shared}},
+		},
+		stats: map[string]*inverters.InverterStatistic{
+			"2024-06": {Resolutions: map[string]inverters.Resolution{
+				"DAY": {Unit: "Wh", Data: []inverters.DataPoint{
+					{Date: time.Date(2024, time.June, 1, 0, 0, 0, 0, paris), Value: 12500},
+					{Date: time.Date(2024, time.June, 2, 0, 0, 0, 0, paris), Value: 8000},
+				}},
+			}},
+		},
+	}
+	refresher := NewRefresher(store, source, 10*time.Minute, 100, time.Minute, time.Hour)
+	refresher.now = func() time.Time { return time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC) }
+
+	imported, err := refresher.ImportEnodeOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 || len(store.enodeDays) != 2 {
+		t.Fatalf("imported = %d, days = %+v", imported, store.enodeDays)
+	}
+	if len(source.requested) != 2 || source.requested[0] != "2024-05" || source.requested[1] != "2024-06" {
+		t.Fatalf("requested months = %v", source.requested)
+	}
+	first := store.enodeDays[0]
+	if first.InverterID != 7 || first.UserID != 3 || first.EnergyKwh != 12.5 {
+		t.Fatalf("first day = %+v", first)
+	}
+	// Midnight in Paris is the previous evening in UTC; the day must stay the one Enode reported.
+	if got := first.Day.Time.Format(time.DateOnly); got != "2024-06-01" {
+		t.Fatalf("day = %s, want 2024-06-01", got)
+	}
+	if store.aggregated != 1 {
+		t.Fatalf("aggregates refreshed %d times, want 1", store.aggregated)
+	}
+}
@@ -0,0 +1,51 @@
+package rollups
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RollupStore reads and refreshes the production rollup tables.
+type RollupStore interface {
+	TryLockProductionRollups(ctx context.Context) (bool, error)
+	GetRollupCursor(ctx context.Context, name string) (int32, error)
+	SetRollupCursor(ctx context.Context, arg db.SetRollupCursorParams) error
+	GetHourlyRecordBatchEnd(ctx context.Context, arg db.GetHourlyRecordBatchEndParams) (db.GetHourlyRecordBatchEndRow, error)
+	FoldHourlyDailyProduction(ctx context.Context, arg db.FoldHourlyDailyProductionParams) (int64, error)
+	UpsertEnodeDailyProduction(ctx context.Context, arg db.UpsertEnodeDailyProductionParams) error
+	RefreshMonthlyProduction(ctx context.Context) (int64, error)
+	RefreshYearlyProduction(ctx context.Context) (int64, error)
+	GetInverterById(ctx context.Context, id int32) (db.Inverter, error)
+	ListEnodeInverterMatches(ctx context.Context, arg db.ListEnodeInverterMatchesParams) ([]db.Inverter, error)
+	ListInverterDailyProduction(ctx context.Context, arg db.ListInverterDailyProductionParams) ([]db.ListInverterDailyProductionRow, error)
+	ListInverterMonthlyProduction(ctx context.Context, arg db.ListInverterMonthlyProductionParams) ([]db.ListInverterMonthlyProductionRow, error)
+	ListInverterYearlyProduction(ctx context.Context, arg db.ListInverterYearlyProductionParams) ([]db.ListInverterYearlyProductionRow, error)
+	ListUserDailyProduction(ctx context.Context, arg db.ListUserDailyProductionParams) ([]db.ListUserDailyProductionRow, error)
+	ListUserMonthlyProduction(ctx context.Context, arg db.ListUserMonthlyProductionParams) ([]db.ListUserMonthlyProductionRow, error)
+	ListUserYearlyProduction(ctx context.Context, arg db.ListUserYearlyProductionParams) ([]db.ListUserYearlyProductionRow, error)
+	// InTx runs fn against a store whose writes commit together. Monthly and yearly refreshes only
+	// see daily rows written earlier in the same transaction.
+	InTx(ctx context.Context, fn func(store RollupStore) error) error
+}
+
+// PostgresRollupStore is the RollupStore backed by sqlc queries on a pgx pool.
+type PostgresRollupStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRollupStore(pool *pgxpool.Pool) *PostgresRollupStore {
+	return &PostgresRollupStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresRollupStore) InTx(ctx context.Context, fn func(store RollupStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresRollupStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
@@ -0,0 +1,214 @@
+package rollups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInverterNotFound  = errors.New("inverter not found")
+	ErrInvalidInverterID = errors.New("invalid inverter id")
+	ErrInvalidUserID     = errors.New("invalid user id")
+	ErrInvalidPeriod     = errors.New("invalid period")
+	ErrInvalidRange      = errors.New("invalid date range")
+)
+
+// maxPoints bounds the number of data points a single request can ask for.
+const maxPoints = 366
+
+// Range is an inclusive span of days. Zero values select the default range for the period.
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+type RollupUseCase struct {
+	store RollupStore
+	now   func() time.Time
+}
+
+func NewRollupUseCase(store RollupStore) *RollupUseCase {
+	return &RollupUseCase{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// GetInverterProduction returns the production of one local inverter per day, month or year.
+func (uc *RollupUseCase) GetInverterProduction(ctx context.Context, inverterID string, period string, r Range) (*ProductionResponse, error) {
+	id, err := strconv.ParseInt(inverterID, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidInverterID
+	}
+	from, to, err := uc.resolveRange(period, r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.store.GetInverterById(ctx, int32(id)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInverterNotFound
+		}
+		return nil, fmt.Errorf("getting inverter: %w", err)
+	}
+
+	var points []ProductionPoint
+	switch period {
+	case PeriodDay:
+		rows, err := uc.store.ListInverterDailyProduction(ctx, db.ListInverterDailyProductionParams{
+			InverterID: int32(id), FromDay: date(from), ToDay: date(to),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing daily production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: row.Day.Time.Format(time.DateOnly), EnergyKwh: row.EnergyKwh, Source: row.Source})
+		}
+	case PeriodMonth:
+		rows, err := uc.store.ListInverterMonthlyProduction(ctx, db.ListInverterMonthlyProductionParams{
+			InverterID: int32(id), FromMonth: date(from), ToMonth: date(to),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing monthly production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: row.Month.Time.Format("2006-01"), EnergyKwh: row.EnergyKwh, Days: row.Days})
+		}
+	case PeriodYear:
+		rows, err := uc.store.ListInverterYearlyProduction(ctx, db.ListInverterYearlyProductionParams{
+			InverterID: int32(id), FromYear: int32(from.Year()), ToYear: int32(to.Year()),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing yearly production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: strconv.Itoa(int(row.Year)), EnergyKwh: row.EnergyKwh, Months: row.Months})
+		}
+	}
+
+	response := newProductionResponse(period, from, to, points)
+	inverter := int32(id)
+	response.InverterID = &inverter
+	return response, nil
+}
+
+// GetUserProduction returns the production of all inverters owned by a local user per day,
+// month or year.
+func (uc *RollupUseCase) GetUserProduction(ctx context.Context, userID string, period string, r Range) (*ProductionResponse, error) {
+	id, err := strconv.ParseInt(userID, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidUserID
+	}
+	from, to, err := uc.resolveRange(period, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []ProductionPoint
+	switch period {
+	case PeriodDay:
+		rows, err := uc.store.ListUserDailyProduction(ctx, db.ListUserDailyProductionParams{
+			UserID: int32(id), FromDay: date(from), ToDay: date(to),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing daily production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: row.Day.Time.Format(time.DateOnly), EnergyKwh: row.EnergyKwh, Inverters: row.Inverters})
+		}
+	case PeriodMonth:
+		rows, err := uc.store.ListUserMonthlyProduction(ctx, db.ListUserMonthlyProductionParams{
+			UserID: int32(id), FromMonth: date(from), ToMonth: date(to),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing monthly production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: row.Month.Time.Format("2006-01"), EnergyKwh: row.EnergyKwh, Inverters: row.Inverters})
+		}
+	case PeriodYear:
+		rows, err := uc.store.ListUserYearlyProduction(ctx, db.ListUserYearlyProductionParams{
+			UserID: int32(id), FromYear: int32(from.Year()), ToYear: int32(to.Year()),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing yearly production: %w", err)
+		}
+		for _, row := range rows {
+			points = append(points, ProductionPoint{Period: strconv.Itoa(int(row.Year)), EnergyKwh: row.EnergyKwh, Inverters: row.Inverters})
+		}
+	}
+
+	response := newProductionResponse(period, from, to, points)
+	user := int32(id)
+	response.UserID = &user
+	return response, nil
+}
+
+// resolveRange aligns r to the start of its periods and fills in defaults: the last 30 days, the
+// last 12 months or the last 5 years, each including the current one.
+func (uc *RollupUseCase) resolveRange(period string, r Range) (time.Time, time.Time, error) {
+	to := r.To
+	if to.IsZero() {
+		to = uc.now().UTC()
+	}
+	from := r.From
+
+	var points int
+	switch period {
+	case PeriodDay:
+		to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+		if from.IsZero() {
+			from = to.AddDate(0, 0, -29)
+		}
+		from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+		points = int(to.Sub(from).Hours()/24) + 1
+	case PeriodMonth:
+		to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if from.IsZero() {
+			from = to.AddDate(0, -11, 0)
+		}
+		from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		points = (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	case PeriodYear:
+		to = time.Date(to.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		if from.IsZero() {
+			from = to.AddDate(-4, 0, 0)
+		}
+		from = time.Date(from.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		points = to.Year() - from.Year() + 1
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, period)
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", ErrInvalidRange)
+	}
+	if points > maxPoints {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d %ss per request", ErrInvalidRange, maxPoints, period)
+	}
+	return from, to, nil
+}
+
+func newProductionResponse(period string, from time.Time, to time.Time, points []ProductionPoint) *ProductionResponse {
+	response := &ProductionResponse{
+		Period: period,
+		From:   from.Format(time.DateOnly),
+		To:     to.Format(time.DateOnly),
+		Data:   make([]ProductionPoint, 0, len(points)),
+	}
+	for _, point := range points {
+		response.TotalKwh += point.EnergyKwh
+		response.Data = append(response.Data, point)
+	}
+	return response
+}
+
+func date(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: t, Valid: true}
+}
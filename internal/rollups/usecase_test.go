@@ -0,0 +1,60 @@
+package rollups
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRollupUseCase_ResolveRange(t *testing.T) {
+	uc := NewRollupUseCase(nil)
+	uc.now = func() time.Time { return time.Date(2024, time.June, 21, 15, 30, 0, 0, time.UTC) }
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		period   string
+		r        Range
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  error
+	}{
+		{name: "default days", period: PeriodDay, wantFrom: day(2024, time.May, 23), wantTo: day(2024, time.June, 21)},
+		{name: "default months", period: PeriodMonth, wantFrom: day(2023, time.July, 1), wantTo: day(2024, time.June, 1)},
+		{name: "default years", period: PeriodYear, wantFrom: day(2020, time.January, 1), wantTo: day(2024, time.January, 1)},
+		{name: "months aligned to start", period: PeriodMonth, r: Range{From: day(2024, time.January, 15), To: day(2024, time.March, 3)}, wantFrom: day(2024, time.January, 1), wantTo: day(2024, time.March, 1)},
+		{name: "from after to", period: PeriodDay, r: Range{From: day(2024, time.June, 2), To: day(2024, time.June, 1)}, wantErr: ErrInvalidRange},
+		{name: "too many days", period: PeriodDay, r: Range{From: day(2022, time.January, 1), To: day(2024, time.January, 1)}, wantErr: ErrInvalidRange},
+		{name: "unknown period", period: "week", wantErr: ErrInvalidPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := uc.resolveRange(tt.period, tt.r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Fatalf("range = %v..%v, want %v..%v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestNewProductionResponse_Total(t *testing.T) {
+	response := newProductionResponse(PeriodDay, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC), []ProductionPoint{
+		{Period: "2024-06-01", EnergyKwh: 12.5},
+		{Period: "2024-06-02", EnergyKwh: 8},
+	})
+	if response.TotalKwh != 20.5 || len(response.Data) != 2 || response.From != "2024-06-01" || response.To != "2024-06-02" {
+		t.Fatalf("response = %+v", response)
+	}
+}
@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
+
+const usage = `usage: evolyte-energy-provider-adapter secrets <command> [flags]
+
+commands:
+  keygen   print a new random key for the keyfile
+  rotate   encrypt plaintext identity tokens and re-encrypt tokens under the active key
+           (-dry-run to only count them, -page-size N)
+`
+
+// NeedsKeyring reports whether the secrets subcommand described by args reads or writes
+// encrypted tokens. Other commands run without a keyring, store or configuration.
+func NeedsKeyring(args []string) bool {
+	return len(args) > 0 && args[0] == "rotate"
+}
+
+// RunCLI executes the secrets subcommand described by args, writing results to out. envelope and
+// store may be nil when NeedsKeyring reports false.
+func RunCLI(ctx context.Context, envelope *Envelope, store IdentityTokenStore, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return fmt.Errorf("missing secrets command")
+	}
+
+	flags := flag.NewFlagSet("secrets "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+
+	switch args[0] {
+	case "keygen":
+		key, err := GenerateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, key)
+		return nil
+	case "rotate":
+		dryRun := flags.Bool("dry-run", false, "count tokens that need re-encryption without writing")
+		pageSize := flags.Int("page-size", defaultRotationPageSize, "identities read per query")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		result, err := ReencryptIdentityTokens(ctx, store, envelope, *pageSize, *dryRun)
+		fmt.Fprintf(out, "scanned %d, encrypted %d, rotated %d, failed %d, skipped %d\n",
+			result.Scanned, result.Encrypted, result.Rotated, result.Failed, result.Skipped)
+		if err == nil && result.Failed > 0 {
+			return fmt.Errorf("%d identity tokens could not be opened", result.Failed)
+		}
+		return err
+	default:
+		fmt.Fprint(out, usage)
+		return fmt.Errorf("unknown secrets command %q", args[0])
+	}
+}
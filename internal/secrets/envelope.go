@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// prefix marks sealed values, so values written before encryption was enabled can be told apart.
+	prefix    = "enc:v1:"
+	separator = ":"
+)
+
+var (
+	ErrMalformed  = errors.New("malformed sealed value")
+	ErrTampered   = errors.New("sealed value failed authentication")
+	ErrUnknownKey = errors.New("unknown encryption key")
+)
+
+// Envelope encrypts secrets with a fresh data key per value and stores the data key wrapped by
+// the provider's active key next to the ciphertext. Sealed values look like
+//
+//	enc:v1:<key id>:<wrapped data key>:<nonce and ciphertext>
+//
+// with both binary parts base64url-encoded. The associated data passed to Seal and Open names
+// where the value is stored, so a value copied to another row or cache key fails to open.
+type Envelope struct {
+	provider KeyProvider
+}
+
+func NewEnvelope(provider KeyProvider) *Envelope {
+	return &Envelope{provider: provider}
+}
+
+func (e *Envelope) Seal(ctx context.Context, plaintext string, associatedData string) (string, error) {
+	keyID := e.provider.ActiveKeyID()
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generating data key: %w", err)
+	}
+	wrapped, err := e.provider.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("wrapping data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext), []byte(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("encrypting value: %w", err)
+	}
+	return prefix + keyID + separator + encode(wrapped) + separator + encode(ciphertext), nil
+}
+
+func (e *Envelope) Open(ctx context.Context, sealed string, associatedData string) (string, error) {
+	keyID, wrapped, ciphertext, err := parse(sealed)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := e.provider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("unwrapping data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	plaintext, err := open(aead, ciphertext, []byte(associatedData))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value is plaintext or sealed under a key other than the active one.
+func (e *Envelope) NeedsRotation(value string) bool {
+	keyID, _, _, err := parse(value)
+	return err != nil || keyID != e.provider.ActiveKeyID()
+}
+
+// IsSealed reports whether value has the sealed format. It does not check that it opens.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func parse(sealed string) (string, []byte, []byte, error) {
+	body, ok := strings.CutPrefix(sealed, prefix)
+	if !ok {
+		return "", nil, nil, ErrMalformed
+	}
+	parts := strings.Split(body, separator)
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
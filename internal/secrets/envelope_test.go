@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, active string, ids ...string) *LocalKeyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, keySize)
+	}
+	keyring, err := NewLocalKeyring(active, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestEnvelope_SealOpen(t *testing.T) {
+	ctx := context.Background()
+	envelope := NewEnvelope(testKeyring(t, "k1", "k1"))
+
+	sealed, err := envelope.Seal(ctx, "live-token", "identities.access_token:enode:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v1:k1:") || strings.Contains(sealed, "live-token") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	other, _ := envelope.Seal(ctx, "live-token", "identities.access_token:enode:42")
+	if other == sealed {
+		t.Fatal("sealing twice produced the same value")
+	}
+
+	opened, err := envelope.Open(ctx, sealed, "identities.access_token:enode:42")
+	if err != nil || opened != "live-token" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+}
+
+func TestEnvelope_OpenRejects(t *testing.T) {
+	ctx := context.Background()
+	envelope := NewEnvelope(testKeyring(t, "k1", "k1"))
+	sealed, err := envelope.Seal(ctx, "live-token", "aad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(sealed, ":")
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		sealed  string
+		aad     string
+		wantErr error
+	}{
+		{name: "plaintext", sealed: "live-token", aad: "aad", wantErr: ErrMalformed},
+		{name: "truncated", sealed: strings.Join(parts[:4], ":"), aad: "aad", wantErr: ErrMalformed},
+		{name: "other location", sealed: sealed, aad: "other", wantErr: ErrTampered},
+		{name: "modified ciphertext", sealed: strings.Join(append(parts[:4:4], encode(ciphertext)), ":"), aad: "aad", wantErr: ErrTampered},
+		{name: "key id swapped", sealed: strings.Replace(sealed, ":k1:", ":k2:", 1), aad: "aad", wantErr: ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := envelope.Open(ctx, tt.sealed, tt.aad); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvelope_Rotation(t *testing.T) {
+	ctx := context.Background()
+	old := NewEnvelope(testKeyring(t, "k1", "k1"))
+	sealed, err := old.Seal(ctx, "live-token", "aad")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := NewEnvelope(testKeyring(t, "k2", "k1", "k2"))
+	if !rotated.NeedsRotation(sealed) || !rotated.NeedsRotation("live-token") {
+		t.Fatal("values under k1 or in plaintext should need rotation")
+	}
+	if opened, err := rotated.Open(ctx, sealed, "aad"); err != nil || opened != "live-token" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+	resealed, err := rotated.Seal(ctx, "live-token", "aad")
+	if err != nil || rotated.NeedsRotation(resealed) {
+		t.Fatalf("resealed = %q, %v", resealed, err)
+	}
+}
+
+func TestLoadLocalKeyring(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid", body: `{"activeKeyId":"2024-06","keys":{"2024-06":"` + key + `"}}`},
+		{name: "missing active key", body: `{"activeKeyId":"2024-07","keys":{"2024-06":"` + key + `"}}`, wantErr: true},
+		{name: "short key", body: `{"activeKeyId":"a","keys":{"a":"c2hvcnQ="}}`, wantErr: true},
+		{name: "separator in id", body: `{"activeKeyId":"a:b","keys":{"a:b":"` + key + `"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			if err := os.WriteFile(path, []byte(tt.body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			keyring, err := LoadLocalKeyring(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadLocalKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && keyring.ActiveKeyID() != "2024-06" {
+				t.Fatalf("active key = %q", keyring.ActiveKeyID())
+			}
+		})
+	}
+	t.Run("no keyfile configured", func(t *testing.T) {
+		if _, err := LoadLocalKeyring(""); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// keySize is the length of key-encryption and data-encryption keys (AES-256).
+const keySize = 32
+
+// KeyProvider wraps and unwraps data keys with named key-encryption keys. The local keyring
+// implements it from a keyfile; a KMS-backed provider maps keyID to a KMS key and calls its
+// encrypt and decrypt operations.
+type KeyProvider interface {
+	// ActiveKeyID names the key new data keys are wrapped with.
+	ActiveKeyID() string
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// keyfile is the JSON layout of a local keyring. Keys are base64-encoded 32-byte values.
+type keyfile struct {
+	ActiveKeyID string            `json:"activeKeyId"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// LocalKeyring is a KeyProvider holding key-encryption keys in memory.
+type LocalKeyring struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+// LoadLocalKeyring reads a keyring from the JSON keyfile at path.
+func LoadLocalKeyring(path string) (*LocalKeyring, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no keyfile configured")
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keyfile: %w", err)
+	}
+	var file keyfile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return nil, fmt.Errorf("decoding keyfile: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewLocalKeyring(file.ActiveKeyID, keys)
+}
+
+func NewLocalKeyring(activeKeyID string, keys map[string][]byte) (*LocalKeyring, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeKeyID)
+	}
+	keyring := &LocalKeyring{activeKeyID: activeKeyID, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, separator) {
+			return nil, fmt.Errorf("key id %q must be non-empty and must not contain %q", id, separator)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q is %d bytes, want %d", id, len(key), keySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		keyring.keys[id] = aead
+	}
+	return keyring, nil
+}
+
+func (k *LocalKeyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// WrapKey encrypts dataKey under keyID, binding the result to the key ID.
+func (k *LocalKeyring) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return seal(aead, dataKey, []byte(keyID))
+}
+
+func (k *LocalKeyring) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
+
+// GenerateKey returns a random base64-encoded key for a keyfile.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which is prepended to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, body := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, body, associatedData)
+	if err != nil {
+		return nil, ErrTampered
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const defaultRotationPageSize = 500
+
+// Identity token columns, used in the associated data tokens are sealed with.
+const (
+	ColumnAccessToken  = "access_token"
+	ColumnRefreshToken = "refresh_token"
+)
+
+// IdentityTokenStore pages through stored identity tokens and swaps them in place.
+type IdentityTokenStore interface {
+	ListIdentityTokens(ctx context.Context, arg db.ListIdentityTokensParams) ([]db.ListIdentityTokensRow, error)
+	ReplaceIdentityAccessToken(ctx context.Context, arg db.ReplaceIdentityAccessTokenParams) (int64, error)
+	ReplaceIdentityRefreshToken(ctx context.Context, arg db.ReplaceIdentityRefreshTokenParams) (int64, error)
+}
+
+// RotationResult counts identity tokens by what re-encryption did with them.
+type RotationResult struct {
+	Scanned int
+	// Encrypted tokens were stored in plaintext before encryption was enabled.
+	Encrypted int
+	// Rotated tokens were sealed under a key other than the active one.
+	Rotated int
+	// Failed tokens could not be opened, either because their key is missing from the keyring
+	// or because they were modified. They are left untouched.
+	Failed int
+	// Skipped tokens changed between being read and being rewritten.
+	Skipped int
+}
+
+// IdentityTokenAAD is the associated data identity tokens are sealed with. It names the column
+// and the identity by its natural key, which is known before the row is inserted.
+func IdentityTokenAAD(column string, provider string, providerUserID string) string {
+	return "identities." + column + ":" + provider + ":" + providerUserID
+}
+
+// ReencryptIdentityTokens seals plaintext identity access and refresh tokens and re-seals tokens
+// under the active key. Each token is replaced only if it still holds the value read, so tokens
+// refreshed concurrently are not overwritten. With dryRun set nothing is written.
+func ReencryptIdentityTokens(ctx context.Context, store IdentityTokenStore, envelope *Envelope, pageSize int, dryRun bool) (RotationResult, error) {
+	if pageSize <= 0 {
+		pageSize = defaultRotationPageSize
+	}
+
+	var result RotationResult
+	afterID := int32(0)
+	for {
+		rows, err := store.ListIdentityTokens(ctx, db.ListIdentityTokensParams{AfterID: afterID, Limit: int32(pageSize)})
+		if err != nil {
+			return result, fmt.Errorf("listing identity tokens: %w", err)
+		}
+		for _, row := range rows {
+			afterID = row.ID
+			for _, token := range []identityToken{
+				{column: ColumnAccessToken, value: row.AccessToken},
+				{column: ColumnRefreshToken, value: row.RefreshToken},
+			} {
+				if !token.value.Valid {
+					continue
+				}
+				result.Scanned++
+				if err := reencryptIdentityToken(ctx, store, envelope, row, token, dryRun, &result); err != nil {
+					return result, err
+				}
+			}
+		}
+		if len(rows) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+type identityToken struct {
+	column string
+	value  pgtype.Text
+}
+
+func reencryptIdentityToken(ctx context.Context, store IdentityTokenStore, envelope *Envelope, row db.ListIdentityTokensRow, token identityToken, dryRun bool, result *RotationResult) error {
+	stored := token.value.String
+	if !envelope.NeedsRotation(stored) {
+		return nil
+	}
+
+	aad := IdentityTokenAAD(token.column, row.Provider, row.ProviderUserID)
+	plaintext, counter := stored, &result.Encrypted
+	if IsSealed(stored) {
+		opened, err := envelope.Open(ctx, stored, aad)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to open identity token for re-encryption", "identityID", row.ID, "column", token.column, "error", err)
+			result.Failed++
+			return nil
+		}
+		plaintext, counter = opened, &result.Rotated
+	}
+	if dryRun {
+		*counter++
+		return nil
+	}
+
+	sealed, err := envelope.Seal(ctx, plaintext, aad)
+	if err != nil {
+		return fmt.Errorf("sealing identity %d %s: %w", row.ID, token.column, err)
+	}
+	replaced, err := replaceIdentityToken(ctx, store, row.ID, token, pgtype.Text{String: sealed, Valid: true})
+	if err != nil {
+		return fmt.Errorf("storing identity %d %s: %w", row.ID, token.column, err)
+	}
+	if replaced == 0 {
+		result.Skipped++
+		return nil
+	}
+	*counter++
+	return nil
+}
+
+func replaceIdentityToken(ctx context.Context, store IdentityTokenStore, id int32, token identityToken, sealed pgtype.Text) (int64, error) {
+	if token.column == ColumnRefreshToken {
+		return store.ReplaceIdentityRefreshToken(ctx, db.ReplaceIdentityRefreshTokenParams{RefreshToken: sealed, ID: id, PreviousRefreshToken: token.value})
+	}
+	return store.ReplaceIdentityAccessToken(ctx, db.ReplaceIdentityAccessTokenParams{AccessToken: sealed, ID: id, PreviousAccessToken: token.value})
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeIdentityTokenStore struct {
+	rows []db.ListIdentityTokensRow
+	// refreshed identities get a new token between being listed and being replaced.
+	refreshed map[int32]bool
+}
+
+func (f *fakeIdentityTokenStore) ListIdentityTokens(ctx context.Context, arg db.ListIdentityTokensParams) ([]db.ListIdentityTokensRow, error) {
+	var page []db.ListIdentityTokensRow
+	for _, row := range f.rows {
+		if row.ID > arg.AfterID && len(page) < int(arg.Limit) {
+			page = append(page, row)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeIdentityTokenStore) ReplaceIdentityAccessToken(ctx context.Context, arg db.ReplaceIdentityAccessTokenParams) (int64, error) {
+	for i, row := range f.rows {
+		if row.ID == arg.ID && row.AccessToken == arg.PreviousAccessToken && !f.refreshed[row.ID] {
+			f.rows[i].AccessToken = arg.AccessToken
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeIdentityTokenStore) ReplaceIdentityRefreshToken(ctx context.Context, arg db.ReplaceIdentityRefreshTokenParams) (int64, error) {
+	for i, row := range f.rows {
+		if row.ID == arg.ID && row.RefreshToken == arg.PreviousRefreshToken && !f.refreshed[row.ID] {
+			f.rows[i].RefreshToken = arg.RefreshToken
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func TestReencryptIdentityTokens(t *testing.T) {
+	ctx := context.Background()
+	old := NewEnvelope(testKeyring(t, "k1", "k1"))
+	current := NewEnvelope(testKeyring(t, "k2", "k1", "k2"))
+
+	seal := func(envelope *Envelope, token string, providerUserID string) pgtype.Text {
+		sealed, err := envelope.Seal(ctx, token, IdentityTokenAAD(ColumnAccessToken, "enode", providerUserID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pgtype.Text{String: sealed, Valid: true}
+	}
+	newStore := func() *fakeIdentityTokenStore {
+		return &fakeIdentityTokenStore{
+			rows: []db.ListIdentityTokensRow{
+				{ID: 1, Provider: "enode", ProviderUserID: "u1", AccessToken: pgtype.Text{String: "plain-1", Valid: true}},
+				{ID: 2, Provider: "enode", ProviderUserID: "u2", AccessToken: seal(old, "token-2", "u2")},
+				{ID: 3, Provider: "enode", ProviderUserID: "u3", AccessToken: seal(current, "token-3", "u3"), RefreshToken: pgtype.Text{String: "refresh-3", Valid: true}},
+				// Sealed for another identity and copied here.
+				{ID: 4, Provider: "enode", ProviderUserID: "u4", AccessToken: seal(old, "token-5", "u5")},
+				{ID: 5, Provider: "enode", ProviderUserID: "u5", AccessToken: pgtype.Text{String: "plain-5", Valid: true}},
+			},
+			refreshed: map[int32]bool{5: true},
+		}
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		store := newStore()
+		before := store.rows[0].AccessToken
+		result, err := ReencryptIdentityTokens(ctx, store, current, 2, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != (RotationResult{Scanned: 6, Encrypted: 3, Rotated: 1, Failed: 1}) {
+			t.Fatalf("result = %+v", result)
+		}
+		if store.rows[0].AccessToken != before {
+			t.Fatal("dry run modified a token")
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		store := newStore()
+		result, err := ReencryptIdentityTokens(ctx, store, current, 2, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != (RotationResult{Scanned: 6, Encrypted: 2, Rotated: 1, Failed: 1, Skipped: 1}) {
+			t.Fatalf("result = %+v", result)
+		}
+		for i, want := range []string{"plain-1", "token-2", "token-3"} {
+			row := store.rows[i]
+			if current.NeedsRotation(row.AccessToken.String) {
+				t.Fatalf("identity %d still needs rotation", row.ID)
+			}
+			if got, err := current.Open(ctx, row.AccessToken.String, IdentityTokenAAD(ColumnAccessToken, "enode", row.ProviderUserID)); err != nil || got != want {
+				t.Fatalf("identity %d token = %q, %v", row.ID, got, err)
+			}
+		}
+		refresh := store.rows[2].RefreshToken.String
+		if got, err := current.Open(ctx, refresh, IdentityTokenAAD(ColumnRefreshToken, "enode", "u3")); err != nil || got != "refresh-3" {
+			t.Fatalf("refresh token = %q, %v", got, err)
+		}
+	})
+}
@@ -9,39 +9,56 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/config"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/logging"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/metrics"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/secrets"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/utils"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoLog "github.com/labstack/gommon/log"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 type echoServer struct {
 	echoApp         *echo.Echo
 	redisClient     *redis.Client
+	dbPool          *pgxpool.Pool
 	inverterQueries *db.Queries
 	conf            *config.Config
 	validator       *utils.CustomValidator
+	envelope        *secrets.Envelope
+	logLevel        *slog.LevelVar
+	// workerCtx is cancelled on shutdown to stop background workers started by MapHandlers.
+	workerCtx context.Context
 }
 
-func NewEchoServer(conf *config.Config, redisClient *redis.Client, inverterQueries *db.Queries, validator *utils.CustomValidator) Server {
+func NewEchoServer(conf *config.Config, redisClient *redis.Client, dbPool *pgxpool.Pool, inverterQueries *db.Queries, validator *utils.CustomValidator, envelope *secrets.Envelope, logLevel *slog.LevelVar) Server {
 	echoApp := echo.New()
 	echoApp.Logger.SetLevel(echoLog.DEBUG)
 
 	return &echoServer{
 		echoApp:         echoApp,
 		redisClient:     redisClient,
+		dbPool:          dbPool,
 		inverterQueries: inverterQueries,
 		conf:            conf,
 		validator:       validator,
+		envelope:        envelope,
+		logLevel:        logLevel,
 	}
 }
 
 func (s *echoServer) Start() error {
 	s.echoApp.Use(middleware.Recover())
-	s.echoApp.Use(middleware.Logger())
+	s.echoApp.Use(otelecho.Middleware(s.conf.Tracing.ServiceName))
+	s.echoApp.Use(logging.Middleware())
+	s.echoApp.Use(metrics.Middleware())
+	s.echoApp.Use(audit.Middleware())
 	s.echoApp.Validator = s.validator
 
 	go func() {
@@ -51,6 +68,10 @@ func (s *echoServer) Start() error {
 		}
 	}()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	s.workerCtx = workerCtx
+
 	if err := MapHandlers(s); err != nil {
 		slog.Error("Failed to map handlers", "error", err)
 	}
@@ -61,6 +82,7 @@ func (s *echoServer) Start() error {
 	<-quit
 
 	slog.Info("Shutting down server gracefully")
+	stopWorkers()
 	ctx, shutdown := context.WithTimeout(context.Background(), 5*time.Second)
 
 	defer shutdown()
@@ -1,42 +1,116 @@
 package server
 
 import (
-	"net/http"
+	"context"
+	"log/slog"
 
+	"github.com/entl/evolyte-energy-provider-adapter/internal/alerts"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/enode"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/export"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/health"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/identities"
 	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/live"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/logging"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/merges"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/performance"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/privacy"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/rollups"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/upstream"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/webhooks"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func MapHandlers(s *echoServer) error {
+	s.echoApp.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	upstreamClients := upstream.NewFactory(upstream.Options{
+		ConnectTimeout:      s.conf.Upstream.ConnectTimeout,
+		ReadTimeout:         s.conf.Upstream.ReadTimeout,
+		IdleConnTimeout:     s.conf.Upstream.IdleConnTimeout,
+		MaxIdleConns:        s.conf.Upstream.MaxIdleConns,
+		MaxIdleConnsPerHost: s.conf.Upstream.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     s.conf.Upstream.MaxConnsPerHost,
+		MaxRetries:          s.conf.Upstream.MaxRetries,
+		RetryBaseDelay:      s.conf.Upstream.RetryBaseDelay,
+		RetryMaxDelay:       s.conf.Upstream.RetryMaxDelay,
+		BreakerFailures:     s.conf.Upstream.BreakerFailures,
+		BreakerCooldown:     s.conf.Upstream.BreakerCooldown,
+	})
+
+	authClient := enode.NewEnodeAuthClient(
+		s.conf.Enode.ClientID,
+		s.conf.Enode.ClientSecret,
+		s.conf.Enode.OAuthBaseURL,
+		s.conf.Enode.ApiURL,
+		s.redisClient,
+		s.envelope,
+		upstreamClients.Client(s.conf.Enode.Timeouts.Token),
+	)
+
+	initializeProbes(s, authClient)
+
 	v1 := s.echoApp.Group("/api/v1")
 	initalizeHealth(v1)
-	initializeInverters(s, v1)
-
+	inverterUseCase := initializeInverters(s, v1, authClient, upstreamClients)
+	initializeLive(s, v1, inverterUseCase)
+	initializeAlerts(s, v1, inverterUseCase)
+	initializePerformance(s, v1)
+	initializeRollups(s, v1, inverterUseCase)
+	initializeExport(s, v1, inverterUseCase)
+	initializeMerges(s, v1, inverterUseCase)
+	initializeAudit(s, v1)
+	initializeLogging(s, v1)
+	identityUseCase, err := initializeIdentities(s, v1, upstreamClients)
+	if err != nil {
+		return err
+	}
+	initializePrivacy(s, v1, inverterUseCase, identityUseCase)
 	return nil
 }
 
+func initializeProbes(s *echoServer, authClient *enode.EnodeAuthClient) {
+	checker := health.NewChecker(
+		s.conf.Health.CacheTTL,
+		s.conf.Health.CheckTimeout,
+		health.Check{Name: "postgres", Fn: s.dbPool.Ping},
+		health.Check{Name: "redis", Fn: func(ctx context.Context) error {
+			return s.redisClient.Ping(ctx).Err()
+		}},
+		health.Check{Name: "enode_token", Fn: func(ctx context.Context) error {
+			_, err := authClient.GetAccessToken(ctx)
+			return err
+		}},
+	)
+	healthHandler := health.NewHandler(checker)
+
+	s.echoApp.GET("/livez", healthHandler.Livez)
+	s.echoApp.GET("/readyz", healthHandler.Readyz)
+}
+
 func initalizeHealth(parentGroup *echo.Group) {
 	parentGroup.GET("/health", func(c echo.Context) error {
 		return c.String(200, "OK")
 	})
 }
 
-func initializeInverters(s *echoServer, parentGroup *echo.Group) {
-	authClient := enode.NewEnodeAuthClient(
-		s.conf.Enode.ClientID,
-		s.conf.Enode.ClientSecret,
-		s.conf.Enode.OAuthBaseURL,
-		s.conf.Enode.ApiURL,
-		s.redisClient,
-	)
+func initializeInverters(s *echoServer, parentGroup *echo.Group, authClient *enode.EnodeAuthClient, upstreamClients *upstream.Factory) *inverters.InverterUseCase {
+	inverterStore := inverters.NewPostgresInverterStore(s.dbPool)
 	inverterClient := inverters.NewEnodeSolarInverterClient(
-		authClient,
 		s.conf.Enode.ApiURL,
-		&http.Client{},
-		s.inverterQueries,
+		// Deadlines come from the per-operation Enode timeouts.
+		upstreamClients.Client(0),
 	)
-	inverterUseCase := inverters.NewInverterUseCase(inverterClient, authClient, s.inverterQueries, s.validator)
+	inverterUseCase := inverters.NewInverterUseCase(inverterClient, authClient, inverterStore, s.validator, inverters.Timeouts{
+		Token:      s.conf.Enode.Timeouts.Token,
+		List:       s.conf.Enode.Timeouts.List,
+		Get:        s.conf.Enode.Timeouts.Get,
+		Statistics: s.conf.Enode.Timeouts.Statistics,
+		Link:       s.conf.Enode.Timeouts.Link,
+	}, s.conf.Outbox.ProductionThresholdKwh)
 
 	inverterHandler := inverters.NewInverterHandler(inverterUseCase)
 
@@ -50,4 +124,151 @@ func initializeInverters(s *echoServer, parentGroup *echo.Group) {
 	invertersGroup.GET("/:inverterID", inverterHandler.GetInverter)
 	invertersGroup.GET("/:inverterID/stats", inverterHandler.GetInverterProductionStatistics)
 	invertersGroup.POST("", inverterHandler.AddInverter)
+	invertersGroup.POST("/:inverterID/sync", inverterHandler.SyncInverter)
+
+	// Local inverters registered via AddInverter are addressed by their numeric database ID under
+	// /inverters; /enode/inverters/:inverterID always takes an Enode ID.
+	parentGroup.PATCH("/inverters/:inverterID", inverterHandler.UpdateInverter)
+	parentGroup.DELETE("/inverters/:inverterID", inverterHandler.DeleteInverter)
+	parentGroup.GET("/inverters/:inverterID/solar-position", inverterHandler.GetSolarPosition)
+
+	importHandler := inverters.NewImportHandler(inverters.NewImporter(inverterStore, s.validator, s.conf.Import.MaxRows))
+	parentGroup.POST("/inverters/import", importHandler.ImportInverters)
+
+	return inverterUseCase
+}
+
+func initializeLive(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase) {
+	broker := live.NewBroker(s.redisClient, s.conf.Live.WatchTTL, s.conf.Live.LastStateTTL)
+	go live.NewPoller(broker, inverterUseCase, s.conf.Live.PollInterval).Run(s.workerCtx)
+
+	liveHandler := live.NewHandler(broker, s.conf.Live.HeartbeatInterval)
+	parentGroup.GET("/inverters/:inverterID/live", liveHandler.Stream)
+
+	if s.conf.Live.TokenSecret == "" {
+		slog.Warn("LIVE_TOKEN_SECRET not set, live WebSocket gateway disabled")
+	} else {
+		gateway := live.NewGateway(broker, inverterUseCase, live.GatewayOptions{
+			Heartbeat:        s.conf.Live.HeartbeatInterval,
+			WriteTimeout:     s.conf.Live.WriteTimeout,
+			MaxSubscriptions: s.conf.Live.MaxSubscriptions,
+			TokenSecret:      []byte(s.conf.Live.TokenSecret),
+		})
+		parentGroup.GET("/live/ws", gateway.Connect)
+	}
+
+	if s.conf.Enode.WebhookSecret == "" {
+		slog.Warn("ENODE_WEBHOOK_SECRET not set, Enode webhook receiver disabled")
+		return
+	}
+	webhookHandler := webhooks.NewEnodeHandler(s.conf.Enode.WebhookSecret, webhooks.NewPostgresEventStore(s.dbPool), broker)
+	parentGroup.POST("/enode/webhooks", webhookHandler.Receive)
+	go webhooks.NewRetrier(webhookHandler, s.conf.Webhooks.RetryAfter, s.conf.Webhooks.RetryBatchSize, s.conf.Webhooks.RetryInterval).Run(s.workerCtx)
+}
+
+func initializeAlerts(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase) {
+	alertStore := alerts.NewPostgresAlertStore(s.dbPool)
+	engine := alerts.NewEngine(alertStore, inverterUseCase, s.redisClient, s.conf.Alerts.EvaluationInterval,
+		alerts.UnreachableRule{After: s.conf.Alerts.UnreachableAfter},
+		alerts.NotProducingInDaylightRule{MinElevation: s.conf.Alerts.DaylightMinElevation},
+	)
+	go engine.Run(s.workerCtx)
+
+	alertHandler := alerts.NewAlertHandler(alerts.NewAlertUseCase(alertStore))
+	alertsGroup := parentGroup.Group("/alerts")
+	alertsGroup.GET("", alertHandler.ListAlerts)
+	alertsGroup.GET("/:alertID", alertHandler.GetAlert)
+	alertsGroup.POST("/:alertID/acknowledge", alertHandler.AcknowledgeAlert)
+	alertsGroup.POST("/:alertID/resolve", alertHandler.ResolveAlert)
+}
+
+func initializePerformance(s *echoServer, parentGroup *echo.Group) {
+	queries := db.New(s.dbPool)
+	backfill := performance.NewClearSkyBackfill(queries, s.conf.Performance.ClearSkyBackfillBatchSize, s.conf.Performance.ClearSkyBackfillInterval)
+	go backfill.Run(s.workerCtx)
+
+	performanceUseCase := performance.NewPerformanceUseCase(queries, s.conf.Performance.Window, performance.Options{
+		Threshold:        s.conf.Performance.RatioThreshold,
+		MinClearSkyIndex: s.conf.Performance.MinClearSkyIndex,
+		MinHours:         s.conf.Performance.MinHours,
+	})
+	performanceHandler := performance.NewPerformanceHandler(performanceUseCase)
+
+	parentGroup.GET("/performance", performanceHandler.ListPerformance)
+	parentGroup.GET("/inverters/:inverterID/performance", performanceHandler.GetInverterPerformance)
+}
+
+func initializeRollups(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase) {
+	rollupStore := rollups.NewPostgresRollupStore(s.dbPool)
+	refresher := rollups.NewRefresher(rollupStore, inverterUseCase, s.conf.Rollups.LateDataLag, s.conf.Rollups.BatchSize, s.conf.Rollups.RefreshInterval, s.conf.Rollups.EnodeImportInterval)
+	go refresher.Run(s.workerCtx)
+
+	rollupHandler := rollups.NewRollupHandler(rollups.NewRollupUseCase(rollupStore))
+	parentGroup.GET("/inverters/:inverterID/production", rollupHandler.GetInverterProduction)
+	parentGroup.GET("/users/:userID/production", rollupHandler.GetUserProduction)
+}
+
+func initializeExport(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase) {
+	exportUseCase := export.NewExportUseCase(db.New(s.dbPool), inverterUseCase, s.conf.Export.PageSize, s.conf.Export.MaxEnodeRequests)
+	exportHandler := export.NewExportHandler(exportUseCase)
+
+	parentGroup.GET("/inverters/:inverterID/export", exportHandler.ExportInverter)
+	parentGroup.GET("/users/:userID/export", exportHandler.ExportUser)
+}
+
+func initializeMerges(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase) {
+	mergeHandler := merges.NewMergeHandler(merges.NewMergeUseCase(merges.NewPostgresMergeStore(s.dbPool), inverterUseCase))
+
+	adminGroup := parentGroup.Group("/admin/inverters")
+	adminGroup.GET("/duplicates", mergeHandler.ListDuplicates)
+	adminGroup.POST("/merge", mergeHandler.MergeInverters)
+	adminGroup.GET("/merges", mergeHandler.ListMerges)
+}
+
+func initializeAudit(s *echoServer, parentGroup *echo.Group) {
+	auditHandler := audit.NewAuditHandler(audit.NewAuditUseCase(db.New(s.dbPool)))
+	parentGroup.GET("/audit", auditHandler.ListEntries)
+}
+
+func initializeLogging(s *echoServer, parentGroup *echo.Group) {
+	levelHandler := logging.NewLevelHandler(s.logLevel, db.New(s.dbPool))
+	parentGroup.GET("/admin/log-level", levelHandler.GetLevel)
+	parentGroup.PUT("/admin/log-level", levelHandler.SetLevel)
+}
+
+func initializePrivacy(s *echoServer, parentGroup *echo.Group, inverterUseCase *inverters.InverterUseCase, identityUseCase *identities.IdentityUseCase) {
+	privacyStore := privacy.NewPostgresPrivacyStore(s.dbPool)
+	runner := privacy.NewRunner(privacyStore, inverterUseCase, identityUseCase, s.conf.Privacy.PollInterval, s.conf.Privacy.Lease, s.conf.Privacy.MaxAttempts)
+	go runner.Run(s.workerCtx)
+
+	privacyHandler := privacy.NewPrivacyHandler(privacy.NewPrivacyUseCase(privacyStore))
+	parentGroup.POST("/users/:userID/privacy/export", privacyHandler.RequestExport)
+	parentGroup.POST("/users/:userID/privacy/erasure", privacyHandler.RequestErasure)
+	parentGroup.GET("/users/:userID/privacy/jobs", privacyHandler.ListUserJobs)
+	parentGroup.GET("/privacy/jobs/:jobID", privacyHandler.GetJob)
+	parentGroup.GET("/privacy/jobs/:jobID/download", privacyHandler.DownloadExport)
+	parentGroup.POST("/privacy/jobs/:jobID/retry", privacyHandler.RetryJob)
+}
+
+func initializeIdentities(s *echoServer, parentGroup *echo.Group, upstreamClients *upstream.Factory) (*identities.IdentityUseCase, error) {
+	providers, err := identities.LoadProviders(s.conf.OAuth.ProvidersFile)
+	if err != nil {
+		return nil, err
+	}
+	identityUseCase := identities.NewIdentityUseCase(
+		identities.NewPostgresIdentityStore(s.dbPool),
+		identities.NewRedisStateStore(s.redisClient),
+		identities.NewOAuthClient(upstreamClients.Client(s.conf.OAuth.RequestTimeout)),
+		providers,
+		s.envelope,
+		s.conf.OAuth.StateTTL,
+	)
+	go identities.NewRefresher(identityUseCase, s.conf.OAuth.RefreshInterval, s.conf.OAuth.RefreshLeeway).Run(s.workerCtx)
+
+	identityHandler := identities.NewIdentityHandler(identityUseCase)
+	parentGroup.GET("/users/:userID/identities/:provider/authorize", identityHandler.Authorize)
+	parentGroup.GET("/users/:userID/identities/:provider", identityHandler.GetIdentity)
+	parentGroup.DELETE("/users/:userID/identities/:provider", identityHandler.Revoke)
+	parentGroup.GET("/oauth/:provider/callback", identityHandler.Callback)
+	return identityUseCase, nil
 }
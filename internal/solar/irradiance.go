@@ -0,0 +1,62 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// SolarConstant is the mean extraterrestrial irradiance in W/m².
+	SolarConstant = 1361.0
+	// StandardIrradiance is the irradiance at standard test conditions in W/m², at which a panel
+	// produces its rated capacity.
+	StandardIrradiance = 1000.0
+
+	groundAlbedo = 0.2
+)
+
+// ExtraterrestrialHorizontal returns the irradiance reaching a horizontal plane at the top of the
+// atmosphere for the sun at elevation on t's day of year.
+func ExtraterrestrialHorizontal(elevation float64, t time.Time) float64 {
+	if elevation <= 0 {
+		return 0
+	}
+	dayAngle := 2 * math.Pi * float64(t.UTC().YearDay()) / daysInYear(t.Year())
+	return SolarConstant * (1 + 0.033*math.Cos(dayAngle)) * math.Sin(radians(elevation))
+}
+
+// PlaneOfArray estimates the irradiance on a panel with the given tilt and azimuth (degrees
+// clockwise from north) from global horizontal irradiance. GHI is split into direct and diffuse
+// parts with the Erbs model and transposed with an isotropic sky.
+func PlaneOfArray(ghi float64, sun Position, tilt float64, azimuth float64, t time.Time) float64 {
+	extraterrestrial := ExtraterrestrialHorizontal(sun.Elevation, t)
+	if ghi <= 0 || extraterrestrial <= 0 {
+		return 0
+	}
+
+	diffuse := ghi * diffuseFraction(min(ghi/extraterrestrial, 1))
+	direct := (ghi - diffuse) / math.Sin(radians(sun.Elevation))
+
+	zenith := radians(90 - sun.Elevation)
+	tiltRad := radians(tilt)
+	cosIncidence := math.Cos(zenith)*math.Cos(tiltRad) +
+		math.Sin(zenith)*math.Sin(tiltRad)*math.Cos(radians(sun.Azimuth-azimuth))
+
+	beam := direct * math.Max(cosIncidence, 0)
+	sky := diffuse * (1 + math.Cos(tiltRad)) / 2
+	ground := ghi * groundAlbedo * (1 - math.Cos(tiltRad)) / 2
+	return beam + sky + ground
+}
+
+// diffuseFraction is the Erbs correlation between the clearness index and the diffuse share of GHI.
+func diffuseFraction(clearness float64) float64 {
+	switch {
+	case clearness <= 0.22:
+		return 1 - 0.09*clearness
+	case clearness <= 0.8:
+		return 0.9511 - 0.1604*clearness + 4.388*math.Pow(clearness, 2) -
+			16.638*math.Pow(clearness, 3) + 12.336*math.Pow(clearness, 4)
+	default:
+		return 0.165
+	}
+}
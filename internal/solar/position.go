@@ -0,0 +1,70 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// Position is the apparent position of the sun in degrees. Elevation is measured above the
+// horizon and Azimuth clockwise from true north.
+type Position struct {
+	Elevation float64 `json:"elevation"`
+	Azimuth   float64 `json:"azimuth"`
+}
+
+// SunPosition computes the sun's position at latitude/longitude at t using the NOAA
+// general solar position equations, which are accurate to within about a degree.
+func SunPosition(latitude float64, longitude float64, t time.Time) Position {
+	t = t.UTC()
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	declination, eqTime := declinationAndEquationOfTime(t)
+
+	trueSolarMinutes := hour*60 + eqTime + 4*longitude
+	hourAngle := radians(trueSolarMinutes/4 - 180)
+
+	lat := radians(latitude)
+	cosZenith := math.Sin(lat)*math.Sin(declination) + math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle)
+	zenith := math.Acos(math.Max(-1, math.Min(1, cosZenith)))
+
+	azimuth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(lat)-math.Tan(declination)*math.Cos(lat))
+
+	return Position{
+		Elevation: 90 - degrees(zenith),
+		Azimuth:   math.Mod(degrees(azimuth)+180, 360),
+	}
+}
+
+// IsDaylight reports whether the upper limb of the sun is above the horizon, allowing for
+// atmospheric refraction.
+func (p Position) IsDaylight() bool {
+	return p.Elevation > horizonElevation
+}
+
+// declinationAndEquationOfTime returns the solar declination in radians and the equation of
+// time in minutes at t.
+func declinationAndEquationOfTime(t time.Time) (float64, float64) {
+	t = t.UTC()
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+
+	// Fractional year in radians.
+	gamma := 2 * math.Pi / daysInYear(t.Year()) * (float64(t.YearDay()-1) + (hour-12)/24)
+
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	declination := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+	return declination, eqTime
+}
+
+func daysInYear(year int) float64 {
+	return float64(time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay())
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
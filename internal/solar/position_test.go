@@ -0,0 +1,79 @@
+package solar
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunPosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		latitude      float64
+		longitude     float64
+		time          time.Time
+		wantElevation float64
+		wantAzimuth   float64
+	}{
+		{
+			// Just before solar noon: elevation is 90 - latitude + declination (23.44).
+			name:          "London summer solstice noon",
+			latitude:      51.5,
+			longitude:     -0.13,
+			time:          time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC),
+			wantElevation: 61.8,
+			wantAzimuth:   178.4,
+		},
+		{
+			// Just after solar midnight the sun is below the northern horizon.
+			name:          "London winter midnight",
+			latitude:      51.5,
+			longitude:     -0.13,
+			time:          time.Date(2024, time.December, 21, 0, 0, 0, 0, time.UTC),
+			wantElevation: -61.9,
+			wantAzimuth:   0.9,
+		},
+		{
+			// A few minutes before solar noon the sun is high in the north-north-east.
+			name:          "Sydney summer noon",
+			latitude:      -33.87,
+			longitude:     151.21,
+			time:          time.Date(2024, time.January, 15, 2, 0, 0, 0, time.UTC),
+			wantElevation: 77.3,
+			wantAzimuth:   3.9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SunPosition(tt.latitude, tt.longitude, tt.time)
+			if math.Abs(got.Elevation-tt.wantElevation) > 1 {
+				t.Errorf("elevation = %.2f, want %.2f", got.Elevation, tt.wantElevation)
+			}
+			if math.Abs(got.Azimuth-tt.wantAzimuth) > 2 {
+				t.Errorf("azimuth = %.2f, want %.2f", got.Azimuth, tt.wantAzimuth)
+			}
+		})
+	}
+}
+
+func TestPlaneOfArray(t *testing.T) {
+	noon := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	sun := SunPosition(51.5, -0.13, noon)
+
+	horizontal := PlaneOfArray(800, sun, 0, 180, noon)
+	if math.Abs(horizontal-800) > 1 {
+		t.Fatalf("horizontal plane = %.1f, want GHI", horizontal)
+	}
+
+	south := PlaneOfArray(800, sun, 35, 180, noon)
+	north := PlaneOfArray(800, sun, 35, 0, noon)
+	if south <= north {
+		t.Fatalf("south-facing %.1f should exceed north-facing %.1f at noon", south, north)
+	}
+
+	night := SunPosition(51.5, -0.13, noon.Add(-12*time.Hour))
+	if got := PlaneOfArray(800, night, 35, 180, noon); got != 0 {
+		t.Fatalf("night irradiance = %.1f, want 0", got)
+	}
+}
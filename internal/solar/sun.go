@@ -0,0 +1,129 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+// horizonElevation is the sun's elevation at sunrise and sunset: the upper limb touches the
+// horizon once refraction is accounted for.
+const horizonElevation = -0.833
+
+// maxDaylightDays bounds how far back DaylightDuration walks.
+const maxDaylightDays = 400
+
+// SunTimes holds the sun events of one day. Sunrise and Sunset are zero when the sun does not
+// cross the horizon that day.
+type SunTimes struct {
+	Sunrise    time.Time
+	SolarNoon  time.Time
+	Sunset     time.Time
+	PolarDay   bool
+	PolarNight bool
+}
+
+// SunTimesOn returns the sun events at latitude/longitude on the calendar day of day in its
+// location. Returned times are in the same location.
+func SunTimesOn(latitude float64, longitude float64, day time.Time) SunTimes {
+	year, month, date := day.Date()
+	localNoon := time.Date(year, month, date, 12, 0, 0, 0, day.Location())
+	declination, eqTime := declinationAndEquationOfTime(localNoon)
+
+	// Solar noon in minutes after midnight UTC, moved to the UTC day closest to local noon.
+	utcMidnight := localNoon.UTC().Truncate(24 * time.Hour)
+	solarNoon := utcMidnight.Add(minutes(720 - 4*longitude - eqTime))
+	for solarNoon.Sub(localNoon) > 12*time.Hour {
+		solarNoon = solarNoon.Add(-24 * time.Hour)
+	}
+	for localNoon.Sub(solarNoon) > 12*time.Hour {
+		solarNoon = solarNoon.Add(24 * time.Hour)
+	}
+	times := SunTimes{SolarNoon: solarNoon.In(day.Location())}
+
+	lat := radians(latitude)
+	cosHourAngle := math.Cos(radians(90-horizonElevation))/(math.Cos(lat)*math.Cos(declination)) -
+		math.Tan(lat)*math.Tan(declination)
+	switch {
+	case cosHourAngle > 1:
+		times.PolarNight = true
+	case cosHourAngle < -1:
+		times.PolarDay = true
+	default:
+		halfDay := minutes(4 * degrees(math.Acos(cosHourAngle)))
+		times.Sunrise = times.SolarNoon.Add(-halfDay)
+		times.Sunset = times.SolarNoon.Add(halfDay)
+	}
+	return times
+}
+
+// DaylightDuration returns how much of the interval [from, to) the sun was up at
+// latitude/longitude.
+func DaylightDuration(latitude float64, longitude float64, from time.Time, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	// Days are walked in local mean solar time so each day holds exactly one daylight period.
+	zone := time.FixedZone("LMT", int(longitude*240))
+	first := from.In(zone).AddDate(0, 0, -1)
+	last := to.In(zone).AddDate(0, 0, 1)
+	if last.Sub(first) > maxDaylightDays*24*time.Hour {
+		first = last.AddDate(0, 0, -maxDaylightDays)
+	}
+
+	var total time.Duration
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		times := SunTimesOn(latitude, longitude, day)
+		var start, end time.Time
+		switch {
+		case times.PolarNight:
+			continue
+		case times.PolarDay:
+			year, month, date := day.Date()
+			start = time.Date(year, month, date, 0, 0, 0, 0, zone)
+			end = start.AddDate(0, 0, 1)
+		default:
+			start, end = times.Sunrise, times.Sunset
+		}
+		total += overlap(start, end, from, to)
+	}
+	return total
+}
+
+// ClearSkyGHI estimates global horizontal irradiance in W/m² under a cloudless sky using the
+// Haurwitz model.
+func ClearSkyGHI(sun Position) float64 {
+	if sun.Elevation <= 0 {
+		return 0
+	}
+	cosZenith := math.Sin(radians(sun.Elevation))
+	return 1098 * cosZenith * math.Exp(-0.059/cosZenith)
+}
+
+// maxClearSkyIndex caps the index when cloud edges reflect extra light or the sun is very low.
+const maxClearSkyIndex = 2.0
+
+// ClearSkyIndex is measured GHI relative to the clear-sky estimate. It is 0 when the sun is down.
+func ClearSkyIndex(ghi float64, sun Position) float64 {
+	clearSky := ClearSkyGHI(sun)
+	if clearSky < 1 {
+		return 0
+	}
+	return math.Max(0, math.Min(ghi/clearSky, maxClearSkyIndex))
+}
+
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
@@ -0,0 +1,98 @@
+package solar
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunTimesOn(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		latitude       float64
+		longitude      float64
+		day            time.Time
+		wantSunrise    string
+		wantSunset     string
+		wantPolarDay   bool
+		wantPolarNight bool
+	}{
+		// Published times for London on the 2024 summer solstice are 04:43 and 21:21 BST.
+		{name: "London solstice", latitude: 51.5, longitude: -0.13, day: time.Date(2024, time.June, 21, 0, 0, 0, 0, london), wantSunrise: "04:43", wantSunset: "21:21"},
+		{name: "Tromsø midsummer", latitude: 69.65, longitude: 18.96, day: time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC), wantPolarDay: true},
+		{name: "Tromsø midwinter", latitude: 69.65, longitude: 18.96, day: time.Date(2024, time.December, 21, 0, 0, 0, 0, time.UTC), wantPolarNight: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SunTimesOn(tt.latitude, tt.longitude, tt.day)
+			if got.PolarDay != tt.wantPolarDay || got.PolarNight != tt.wantPolarNight {
+				t.Fatalf("polar day %v night %v", got.PolarDay, got.PolarNight)
+			}
+			if tt.wantSunrise == "" {
+				return
+			}
+			if diff := clockDiff(got.Sunrise, tt.wantSunrise); diff > 2*time.Minute {
+				t.Errorf("sunrise = %s, want %s", got.Sunrise.Format("15:04"), tt.wantSunrise)
+			}
+			if diff := clockDiff(got.Sunset, tt.wantSunset); diff > 2*time.Minute {
+				t.Errorf("sunset = %s, want %s", got.Sunset.Format("15:04"), tt.wantSunset)
+			}
+		})
+	}
+}
+
+func clockDiff(got time.Time, want string) time.Duration {
+	wantTime, _ := time.Parse("15:04", want)
+	gotMinutes := got.Hour()*60 + got.Minute()
+	wantMinutes := wantTime.Hour()*60 + wantTime.Minute()
+	return time.Duration(math.Abs(float64(gotMinutes-wantMinutes))) * time.Minute
+}
+
+func TestDaylightDuration(t *testing.T) {
+	solstice := time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		from      time.Time
+		to        time.Time
+		want      time.Duration
+		tolerance time.Duration
+	}{
+		{name: "London full day", latitude: 51.5, longitude: -0.13, from: solstice, to: solstice.Add(24 * time.Hour), want: 16*time.Hour + 38*time.Minute, tolerance: 5 * time.Minute},
+		{name: "London overnight", latitude: 51.5, longitude: -0.13, from: solstice.Add(21 * time.Hour), to: solstice.Add(26 * time.Hour), want: 0, tolerance: time.Minute},
+		{name: "equator one week", latitude: 0, longitude: 0, from: solstice, to: solstice.Add(7 * 24 * time.Hour), want: 7 * (12*time.Hour + 7*time.Minute), tolerance: 10 * time.Minute},
+		{name: "polar day", latitude: 69.65, longitude: 18.96, from: solstice, to: solstice.Add(5 * time.Hour), want: 5 * time.Hour, tolerance: 0},
+		{name: "empty interval", latitude: 51.5, longitude: -0.13, from: solstice, to: solstice, want: 0, tolerance: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DaylightDuration(tt.latitude, tt.longitude, tt.from, tt.to)
+			if diff := got - tt.want; diff > tt.tolerance || diff < -tt.tolerance {
+				t.Fatalf("daylight = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearSkyIndex(t *testing.T) {
+	noon := SunPosition(51.5, -0.13, time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC))
+	clearSky := ClearSkyGHI(noon)
+	if clearSky < 800 || clearSky > 1000 {
+		t.Fatalf("clear-sky GHI at midsummer noon = %.0f", clearSky)
+	}
+	if got := ClearSkyIndex(clearSky/2, noon); math.Abs(got-0.5) > 1e-9 {
+		t.Fatalf("index = %v, want 0.5", got)
+	}
+	if got := ClearSkyIndex(100, Position{Elevation: -10}); got != 0 {
+		t.Fatalf("night index = %v, want 0", got)
+	}
+}
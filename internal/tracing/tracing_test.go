@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "disabled", exporter: ExporterNone},
+		{name: "empty defaults to disabled", exporter: ""},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "unknown", exporter: "zipkin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), config.Tracing{Exporter: tt.exporter, ServiceName: "test", SampleRatio: 1})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if err := shutdown(context.Background()); err != nil {
+					t.Fatalf("shutdown: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/metrics"
+)
+
+// ErrCircuitOpen is returned without contacting the upstream while its circuit is open.
+var ErrCircuitOpen = errors.New("upstream circuit open")
+
+type circuitState int
+
+const (
+	stateClosed circuitState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case stateHalfOpen:
+		return "half_open"
+	case stateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker opens after failureThreshold consecutive failures. Once cooldown has passed, a single
+// probe request is let through: success closes the circuit and failure opens it again.
+type breaker struct {
+	host             string
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, b.host)
+		}
+		b.setState(stateHalfOpen)
+		return nil
+	case stateHalfOpen:
+		// A probe is already in flight.
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, b.host)
+	default:
+		return nil
+	}
+}
+
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		if b.state != stateClosed {
+			b.setState(stateClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.failureThreshold {
+		b.openedAt = b.now()
+		if b.state != stateOpen {
+			b.setState(stateOpen)
+		}
+	}
+}
+
+func (b *breaker) setState(state circuitState) {
+	slog.Warn("Upstream circuit changed state", "host", b.host, "from", b.state.String(), "to", state.String(), "failures", b.failures)
+	b.state = state
+	metrics.SetCircuitState(b.host, int(state))
+}
+
+// breakerTransport guards each upstream host with its own breaker. Transport errors and 5xx
+// responses count as failures; requests the caller cancelled do not count either way.
+type breakerTransport struct {
+	next             http.RoundTripper
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breaker(req.URL.Host)
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case errors.Is(err, context.Canceled):
+		// Release a half-open probe without judging the upstream.
+		b.mu.Lock()
+		if b.state == stateHalfOpen {
+			b.openedAt = time.Time{}
+			b.setState(stateOpen)
+		}
+		b.mu.Unlock()
+	case err != nil:
+		b.record(false)
+	default:
+		b.record(resp.StatusCode < http.StatusInternalServerError)
+	}
+	return resp, err
+}
+
+func (t *breakerTransport) breaker(host string) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &breaker{host: host, failureThreshold: t.failureThreshold, cooldown: t.cooldown, now: t.now}
+		t.breakers[host] = b
+	}
+	return b
+}
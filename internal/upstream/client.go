@@ -0,0 +1,90 @@
+// Package upstream builds the HTTP clients used to call vendor APIs such as Enode.
+package upstream
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Options tunes connections, retries and circuit breaking for upstream calls.
+type Options struct {
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for response headers once a request has been written.
+	ReadTimeout         time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// Factory hands out clients that share one connection pool and one circuit breaker per
+// upstream host, so every caller of a degraded host fails fast together.
+type Factory struct {
+	transport http.RoundTripper
+}
+
+func NewFactory(opts Options) *Factory {
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}
+	base := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.ReadTimeout,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+	}
+	breakers := &breakerTransport{
+		next:             base,
+		failureThreshold: max(opts.BreakerFailures, 1),
+		cooldown:         opts.BreakerCooldown,
+		now:              time.Now,
+		breakers:         make(map[string]*breaker),
+	}
+	return &Factory{
+		transport: otelhttp.NewTransport(requestIDTransport{next: &retryTransport{
+			next:       breakers,
+			maxRetries: opts.MaxRetries,
+			baseDelay:  opts.RetryBaseDelay,
+			maxDelay:   opts.RetryMaxDelay,
+		}}),
+	}
+}
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDTransport forwards the ID of the request being served, so upstream logs and support
+// tickets can be matched with ours.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestID := logging.RequestID(req.Context())
+	if requestID == "" || req.Header.Get(requestIDHeader) != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(requestIDHeader, requestID)
+	return t.next.RoundTrip(req)
+}
+
+// Client returns a client whose requests, including retries, are bounded by timeout. Zero
+// leaves the deadline to the request context.
+func (f *Factory) Client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: f.transport}
+}
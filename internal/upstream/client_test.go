@@ -0,0 +1,183 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/logging"
+)
+
+func testOptions() Options {
+	return Options{
+		ConnectTimeout:  time.Second,
+		ReadTimeout:     time.Second,
+		MaxRetries:      2,
+		RetryBaseDelay:  time.Millisecond,
+		RetryMaxDelay:   5 * time.Millisecond,
+		BreakerFailures: 100,
+		BreakerCooldown: time.Minute,
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		header       string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{name: "get recovers", method: http.MethodGet, statuses: []int{503, 502, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "get gives up", method: http.MethodGet, statuses: []int{503, 503, 503, 503}, wantStatus: 503, wantAttempts: 3},
+		{name: "rate limited", method: http.MethodGet, statuses: []int{429, 200}, wantStatus: 200, wantAttempts: 2},
+		{name: "server error not retried", method: http.MethodGet, statuses: []int{500, 200}, wantStatus: 500, wantAttempts: 1},
+		{name: "client error not retried", method: http.MethodGet, statuses: []int{404, 200}, wantStatus: 404, wantAttempts: 1},
+		{name: "post not retried", method: http.MethodPost, body: "x", statuses: []int{503, 200}, wantStatus: 503, wantAttempts: 1},
+		{name: "post with idempotency key", method: http.MethodPost, body: "x", header: "key-1", statuses: []int{503, 200}, wantStatus: 200, wantAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				if body, err := io.ReadAll(r.Body); err != nil || string(body) != tt.body {
+					t.Errorf("attempt %d body = %q, %v", n, body, err)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.header)
+			}
+			resp, err := NewFactory(testOptions()).Client(0).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || attempts.Load() != tt.wantAttempts {
+				t.Fatalf("status = %d after %d attempts, want %d after %d", resp.StatusCode, attempts.Load(), tt.wantStatus, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestClient_RetryStopsWhenContextDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	opts := testOptions()
+	opts.RetryBaseDelay, opts.RetryMaxDelay = time.Hour, time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	start := time.Now()
+	_, err := NewFactory(opts).Client(0).Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("retry ignored the deadline for %v", elapsed)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	transport := &breakerTransport{
+		next:             http.DefaultTransport,
+		failureThreshold: 3,
+		cooldown:         time.Minute,
+		now:              func() time.Time { return now },
+		breakers:         make(map[string]*breaker),
+	}
+	client := &http.Client{Transport: transport}
+	get := func() error {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	failing.Store(true)
+	for range 3 {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want open circuit", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("upstream called %d times while open", calls.Load())
+	}
+
+	// The probe after the cooldown fails and reopens the circuit.
+	now = now.Add(time.Minute)
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want reopened circuit", err)
+	}
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	failing.Store(false)
+	for range 2 {
+		if err := get(); err != nil {
+			t.Fatalf("err = %v, want closed circuit", err)
+		}
+	}
+	if calls.Load() != 6 {
+		t.Fatalf("upstream called %d times, want 6", calls.Load())
+	}
+}
+
+func TestClient_ForwardsRequestID(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestIDHeader)
+	}))
+	defer server.Close()
+
+	ctx := logging.WithRequestFields(context.Background(), logging.RequestFields{RequestID: "req-1"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := NewFactory(testOptions()).Client(0).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got != "req-1" || req.Header.Get(requestIDHeader) != "" {
+		t.Fatalf("upstream saw request ID %q; caller's request was modified: %v", got, req.Header)
+	}
+}
@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/inverters"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/live"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	providerEnode   = "enode"
+	signatureHeader = "X-Enode-Signature"
+
+	eventInverterDiscovered = "user:inverter:discovered"
+	eventInverterUpdated    = "user:inverter:updated"
+	eventInverterDeleted    = "user:inverter:deleted"
+
+	// actorEnode is recorded in the audit log for changes Enode reports on a user's behalf.
+	actorEnode = "enode"
+)
+
+// auditedEvents maps the webhook events that link or unlink a customer's device to audit actions.
+var auditedEvents = map[string]string{
+	eventInverterDiscovered: audit.ActionInverterLinked,
+	eventInverterDeleted:    audit.ActionInverterUnlinked,
+}
+
+// ProductionPublisher forwards production state to live subscribers.
+type ProductionPublisher interface {
+	Publish(ctx context.Context, update live.ProductionUpdate) (bool, error)
+	Last(ctx context.Context, inverterID string) (*live.ProductionUpdate, error)
+}
+
+type enodeEvent struct {
+	Event    string                   `json:"event"`
+	Inverter *inverters.SolarInverter `json:"inverter"`
+}
+
+// storedEvent is a decoded event together with the ID of its webhook_events row.
+type storedEvent struct {
+	id    int64
+	event enodeEvent
+}
+
+type EnodeHandler struct {
+	secret    []byte
+	store     EventStore
+	publisher ProductionPublisher
+}
+
+func NewEnodeHandler(secret string, store EventStore, publisher ProductionPublisher) *EnodeHandler {
+	return &EnodeHandler{secret: []byte(secret), store: store, publisher: publisher}
+}
+
+// Receive accepts a batch of Enode webhook events signed with the shared webhook secret.
+func (h *EnodeHandler) Receive(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if !h.validSignature(body, c.Request().Header.Get(signatureHeader)) {
+		slog.WarnContext(c.Request().Context(), "Rejected Enode webhook with invalid signature")
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid signature")
+	}
+
+	var events []json.RawMessage
+	if err := json.Unmarshal(body, &events); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to decode Enode webhook batch", "error", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook payload")
+	}
+
+	ctx := c.Request().Context()
+	var stored []storedEvent
+	err = h.store.InTx(ctx, func(store EventStore) error {
+		stored = stored[:0]
+		for _, raw := range events {
+			event, ok, err := storeEvent(ctx, store, raw)
+			if err != nil {
+				return err
+			}
+			if ok {
+				stored = append(stored, event)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Enode retries the whole batch on non-2xx. The batch is stored in one transaction, so
+		// none of it was kept and the retry does not store any event twice.
+		slog.ErrorContext(ctx, "Failed to store Enode webhook batch", "events", len(events), "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to store webhook events")
+	}
+
+	// The batch is committed, so failures are logged and leave the event unprocessed in
+	// webhook_events for the Retrier rather than making Enode retry the batch.
+	for _, event := range stored {
+		if err := h.processEvent(ctx, h.store, event); err != nil {
+			slog.ErrorContext(ctx, "Failed to process webhook event", "webhookEventID", event.id, "error", err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// storeEvent records raw and the audit entry it implies. Events that cannot be decoded are
+// skipped, since a retry would not decode them either.
+func storeEvent(ctx context.Context, store EventStore, raw json.RawMessage) (storedEvent, bool, error) {
+	var event enodeEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		slog.WarnContext(ctx, "Skipping undecodable Enode webhook event", "error", err)
+		return storedEvent{}, false, nil
+	}
+
+	row, err := store.InsertWebhookEvent(ctx, db.InsertWebhookEventParams{
+		Provider:  providerEnode,
+		EventType: event.Event,
+		Payload:   raw,
+	})
+	if err != nil {
+		return storedEvent{}, false, err
+	}
+
+	if action, ok := auditedEvents[event.Event]; ok && event.Inverter != nil {
+		entry := audit.Entry{
+			Action:       action,
+			ResourceType: audit.ResourceEnodeInverter,
+			ResourceID:   event.Inverter.ID,
+			UserID:       event.Inverter.UserID,
+			After:        event.Inverter,
+		}
+		if event.Event == eventInverterDeleted {
+			entry.Before, entry.After = event.Inverter, nil
+		}
+		actor := audit.ActorFromContext(ctx)
+		actor.ID = actorEnode
+		if err := audit.Record(audit.WithActor(ctx, actor), store, entry); err != nil {
+			return storedEvent{}, false, err
+		}
+	}
+
+	return storedEvent{id: row.ID, event: event}, true, nil
+}
+
+// processEvent publishes a stored event to live subscribers and marks it processed in store. An
+// update older than the last published state is not published, so a retried event cannot move
+// live state backwards.
+func (h *EnodeHandler) processEvent(ctx context.Context, store EventStore, stored storedEvent) error {
+	switch stored.event.Event {
+	case eventInverterDiscovered, eventInverterUpdated:
+		inverter := stored.event.Inverter
+		if inverter == nil {
+			break
+		}
+		update := live.NewProductionUpdate(*inverter)
+		last, err := h.publisher.Last(ctx, inverter.ID)
+		if err != nil {
+			return fmt.Errorf("reading last production state of inverter %s: %w", inverter.ID, err)
+		}
+		if last == nil || !last.LastUpdated.After(update.LastUpdated) {
+			if _, err := h.publisher.Publish(ctx, update); err != nil {
+				return fmt.Errorf("publishing production update of inverter %s: %w", inverter.ID, err)
+			}
+		}
+	}
+
+	if err := store.MarkWebhookEventProcessed(ctx, stored.id); err != nil {
+		return fmt.Errorf("marking webhook event processed: %w", err)
+	}
+	return nil
+}
+
+// validSignature checks the "sha1=<hex>" HMAC Enode computes over the raw body.
+func (h *EnodeHandler) validSignature(body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha1=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, h.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/audit"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/live"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeEventStore keeps events in memory. Writes made in InTx are discarded when fn fails, and
+// inserting the failOn-th event of a transaction fails. unprocessed holds stored events for the
+// retrier to find.
+type fakeEventStore struct {
+	inserted    []db.InsertWebhookEventParams
+	processed   []int64
+	audits      []db.InsertAuditLogEntryParams
+	unprocessed []db.WebhookEvent
+	locked      bool
+	failOn      int
+	txInserts   int
+}
+
+func (f *fakeEventStore) InTx(ctx context.Context, fn func(store EventStore) error) error {
+	tx := &fakeEventStore{
+		inserted:    slices.Clone(f.inserted),
+		processed:   slices.Clone(f.processed),
+		audits:      slices.Clone(f.audits),
+		unprocessed: f.unprocessed,
+		locked:      f.locked,
+		failOn:      f.failOn,
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	f.inserted, f.processed, f.audits = tx.inserted, tx.processed, tx.audits
+	return nil
+}
+
+func (f *fakeEventStore) InsertWebhookEvent(ctx context.Context, arg db.InsertWebhookEventParams) (db.WebhookEvent, error) {
+	f.txInserts++
+	if f.txInserts == f.failOn {
+		return db.WebhookEvent{}, errors.New("connection reset")
+	}
+	f.inserted = append(f.inserted, arg)
+	return db.WebhookEvent{ID: int64(len(f.inserted))}, nil
+}
+
+func (f *fakeEventStore) MarkWebhookEventProcessed(ctx context.Context, id int64) error {
+	f.processed = append(f.processed, id)
+	return nil
+}
+
+func (f *fakeEventStore) ListUnprocessedWebhookEvents(ctx context.Context, arg db.ListUnprocessedWebhookEventsParams) ([]db.WebhookEvent, error) {
+	var events []db.WebhookEvent
+	for _, event := range f.unprocessed {
+		if !slices.Contains(f.processed, event.ID) && event.ReceivedAt.Before(arg.ReceivedBefore) && len(events) < int(arg.BatchSize) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeEventStore) TryLockWebhookRetry(ctx context.Context) (bool, error) {
+	return f.locked, nil
+}
+
+func (f *fakeEventStore) InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error) {
+	f.audits = append(f.audits, arg)
+	return db.AuditLog{ID: int64(len(f.audits))}, nil
+}
+
+type fakePublisher struct {
+	updates []live.ProductionUpdate
+	last    *live.ProductionUpdate
+	err     error
+}
+
+func (f *fakePublisher) Last(ctx context.Context, inverterID string) (*live.ProductionUpdate, error) {
+	return f.last, nil
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, update live.ProductionUpdate) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	f.updates = append(f.updates, update)
+	return true, nil
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestEnodeHandler_Receive(t *testing.T) {
+	batch := `[{"event":"user:inverter:updated","inverter":{"id":"inv-1","productionState":{"productionRate":4.2,"isProducing":true}}},{"event":"enode:firehose:test"}]`
+	linkBatch := `[{"event":"user:inverter:discovered","inverter":{"id":"inv-2","userId":"42","productionState":{"productionRate":4.2}}},{"event":"user:inverter:deleted","inverter":{"id":"inv-3","userId":"42"}}]`
+	tests := []struct {
+		name          string
+		body          string
+		signature     string
+		failOn        int
+		publishErr    error
+		wantStatus    int
+		wantInserted  int
+		wantProcessed int
+		wantPublished int
+		wantAudited   []string
+	}{
+		{
+			name:          "valid batch",
+			body:          batch,
+			signature:     sign("secret", batch),
+			wantStatus:    http.StatusNoContent,
+			wantInserted:  2,
+			wantProcessed: 2,
+			wantPublished: 1,
+		},
+		{
+			name:       "failure part way stores nothing",
+			body:       linkBatch,
+			signature:  sign("secret", linkBatch),
+			failOn:     2,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:          "publish failure leaves event unprocessed",
+			body:          batch,
+			signature:     sign("secret", batch),
+			publishErr:    errors.New("redis unavailable"),
+			wantStatus:    http.StatusNoContent,
+			wantInserted:  2,
+			wantProcessed: 1,
+		},
+		{
+			name:          "link and unlink are audited",
+			body:          linkBatch,
+			signature:     sign("secret", linkBatch),
+			wantStatus:    http.StatusNoContent,
+			wantInserted:  2,
+			wantProcessed: 2,
+			wantPublished: 1,
+			wantAudited:   []string{audit.ActionInverterLinked, audit.ActionInverterUnlinked},
+		},
+		{
+			name:       "wrong secret",
+			body:       batch,
+			signature:  sign("other", batch),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing signature",
+			body:       batch,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "malformed batch",
+			body:       `{"event":`,
+			signature:  sign("secret", `{"event":`),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeEventStore{failOn: tt.failOn}
+			publisher := &fakePublisher{err: tt.publishErr}
+			e := echo.New()
+			e.POST("/webhooks", NewEnodeHandler("secret", store, publisher).Receive)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if len(store.inserted) != tt.wantInserted || len(store.processed) != tt.wantProcessed {
+				t.Fatalf("inserted %d, processed %d, want %d and %d", len(store.inserted), len(store.processed), tt.wantInserted, tt.wantProcessed)
+			}
+			if len(publisher.updates) != tt.wantPublished {
+				t.Fatalf("published = %d, want %d", len(publisher.updates), tt.wantPublished)
+			}
+			if tt.wantPublished > 0 && publisher.updates[0].ProductionRate != 4.2 {
+				t.Fatalf("update = %+v", publisher.updates[0])
+			}
+			var audited []string
+			for _, entry := range store.audits {
+				if entry.Actor != actorEnode || entry.UserID.String != "42" {
+					t.Fatalf("unexpected audit entry %+v", entry)
+				}
+				audited = append(audited, entry.Action)
+			}
+			if !slices.Equal(audited, tt.wantAudited) {
+				t.Fatalf("audited = %v, want %v", audited, tt.wantAudited)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+)
+
+// Retrier processes stored webhook events that could not be processed when they were received,
+// for example because Redis was unavailable.
+type Retrier struct {
+	handler   *EnodeHandler
+	after     time.Duration
+	batchSize int32
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// NewRetrier returns a Retrier for events left unprocessed for longer than after, which leaves
+// Receive time to process events it has just stored.
+func NewRetrier(handler *EnodeHandler, after time.Duration, batchSize int32, interval time.Duration) *Retrier {
+	return &Retrier{
+		handler:   handler,
+		after:     after,
+		batchSize: max(batchSize, 1),
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Run retries unprocessed events every interval until ctx is cancelled.
+func (r *Retrier) Run(ctx context.Context) {
+	slog.InfoContext(ctx, "Starting webhook event retrier", "interval", r.interval)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping webhook event retrier")
+			return
+		case <-ticker.C:
+			processed, err := r.RetryOnce(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Webhook event retry failed", "error", err)
+				continue
+			}
+			if processed > 0 {
+				slog.InfoContext(ctx, "Processed webhook events on retry", "events", processed)
+			}
+		}
+	}
+}
+
+// RetryOnce processes one batch of unprocessed events in id order and returns how many were
+// processed. Only one replica retries at a time. The batch stops at the first event that fails
+// again; it stays unprocessed for the next run.
+func (r *Retrier) RetryOnce(ctx context.Context) (int, error) {
+	processed := 0
+	err := r.handler.store.InTx(ctx, func(store EventStore) error {
+		locked, err := store.TryLockWebhookRetry(ctx)
+		if err != nil {
+			return fmt.Errorf("locking webhook retry: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		events, err := store.ListUnprocessedWebhookEvents(ctx, db.ListUnprocessedWebhookEventsParams{
+			ReceivedBefore: r.now().Add(-r.after),
+			BatchSize:      r.batchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("listing unprocessed webhook events: %w", err)
+		}
+
+		for _, row := range events {
+			stored := storedEvent{id: row.ID}
+			if err := json.Unmarshal(row.Payload, &stored.event); err != nil {
+				// Receive only stores events it could decode, so this payload will never process.
+				slog.ErrorContext(ctx, "Skipping undecodable stored webhook event", "webhookEventID", row.ID, "error", err)
+				if err := store.MarkWebhookEventProcessed(ctx, row.ID); err != nil {
+					return fmt.Errorf("marking webhook event %d processed: %w", row.ID, err)
+				}
+				continue
+			}
+			if err := r.handler.processEvent(ctx, store, stored); err != nil {
+				slog.ErrorContext(ctx, "Failed to retry webhook event", "webhookEventID", row.ID, "error", err)
+				return nil
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/entl/evolyte-energy-provider-adapter/internal/live"
+)
+
+func TestRetrier_RetryOnce(t *testing.T) {
+	now := time.Date(2024, time.June, 21, 12, 0, 0, 0, time.UTC)
+	stateAt := time.Date(2024, time.June, 21, 11, 0, 0, 0, time.UTC)
+	unprocessed := []db.WebhookEvent{
+		{ID: 1, EventType: eventInverterUpdated, ReceivedAt: now.Add(-time.Hour), Payload: []byte(`{"event":"user:inverter:updated","inverter":{"id":"inv-1","productionState":{"productionRate":4.2,"lastUpdated":"2024-06-21T11:00:00Z"}}}`)},
+		{ID: 2, EventType: eventInverterDeleted, ReceivedAt: now.Add(-time.Hour), Payload: []byte(`{"event":"user:inverter:deleted","inverter":{"id":"inv-2"}}`)},
+		{ID: 3, EventType: eventInverterUpdated, ReceivedAt: now.Add(-10 * time.Second), Payload: []byte(`{"event":"user:inverter:updated","inverter":{"id":"inv-3"}}`)},
+	}
+
+	tests := []struct {
+		name          string
+		locked        bool
+		last          *live.ProductionUpdate
+		publishErr    error
+		wantProcessed []int64
+		wantPublished int
+	}{
+		{
+			name:          "processes events left unprocessed",
+			locked:        true,
+			wantProcessed: []int64{1, 2},
+			wantPublished: 1,
+		},
+		{
+			name:   "skips when another replica holds the lock",
+			locked: false,
+		},
+		{
+			name:       "stops at the first failure",
+			locked:     true,
+			publishErr: errors.New("redis unavailable"),
+		},
+		{
+			name:          "does not move live state backwards",
+			locked:        true,
+			last:          &live.ProductionUpdate{InverterID: "inv-1", LastUpdated: stateAt.Add(time.Minute)},
+			wantProcessed: []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeEventStore{locked: tt.locked, unprocessed: unprocessed}
+			publisher := &fakePublisher{last: tt.last, err: tt.publishErr}
+			retrier := NewRetrier(NewEnodeHandler("secret", store, publisher), time.Minute, 10, time.Minute)
+			retrier.now = func() time.Time { return now }
+
+			processed, err := retrier.RetryOnce(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(store.processed, tt.wantProcessed) || processed != len(tt.wantProcessed) {
+				t.Fatalf("processed = %v (%d), want %v", store.processed, processed, tt.wantProcessed)
+			}
+			if len(publisher.updates) != tt.wantPublished {
+				t.Fatalf("published = %d, want %d", len(publisher.updates), tt.wantPublished)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"context"
+
+	"github.com/entl/evolyte-energy-provider-adapter/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventStore records received webhook events and the audit entries they imply.
+type EventStore interface {
+	InsertWebhookEvent(ctx context.Context, arg db.InsertWebhookEventParams) (db.WebhookEvent, error)
+	MarkWebhookEventProcessed(ctx context.Context, id int64) error
+	ListUnprocessedWebhookEvents(ctx context.Context, arg db.ListUnprocessedWebhookEventsParams) ([]db.WebhookEvent, error)
+	TryLockWebhookRetry(ctx context.Context) (bool, error)
+	InsertAuditLogEntry(ctx context.Context, arg db.InsertAuditLogEntryParams) (db.AuditLog, error)
+	// InTx runs fn against a store whose writes commit together.
+	InTx(ctx context.Context, fn func(store EventStore) error) error
+}
+
+// PostgresEventStore is the EventStore backed by sqlc queries on a pgx pool.
+type PostgresEventStore struct {
+	*db.Queries
+	pool *pgxpool.Pool
+}
+
+func NewPostgresEventStore(pool *pgxpool.Pool) *PostgresEventStore {
+	return &PostgresEventStore{
+		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+func (s *PostgresEventStore) InTx(ctx context.Context, fn func(store EventStore) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&PostgresEventStore{Queries: s.Queries.WithTx(tx), pool: s.pool})
+	})
+}
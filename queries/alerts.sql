@@ -0,0 +1,48 @@
+-- name: OpenAlert :one
+INSERT INTO alerts (
+    inverter_id,
+    user_id,
+    rule,
+    message
+)
+VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (inverter_id, rule) WHERE status <> 'resolved' DO NOTHING
+RETURNING *;
+
+-- name: GetAlert :one
+SELECT * FROM alerts
+WHERE id = $1 LIMIT 1;
+
+-- name: ListActiveAlerts :many
+SELECT * FROM alerts
+WHERE status <> 'resolved'
+ORDER BY id;
+
+-- name: ListAlerts :many
+SELECT * FROM alerts
+WHERE (sqlc.narg('status')::varchar IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('inverter_id')::varchar IS NULL OR inverter_id = sqlc.narg('inverter_id'))
+  AND (sqlc.narg('user_id')::varchar IS NULL OR user_id = sqlc.narg('user_id'))
+ORDER BY opened_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: AcknowledgeAlert :one
+UPDATE alerts
+SET
+    status = 'acknowledged',
+    acknowledged_at = NOW(),
+    acknowledged_by = $2,
+    updated_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: ResolveAlert :one
+UPDATE alerts
+SET
+    status = 'resolved',
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND status <> 'resolved'
+RETURNING *;
@@ -0,0 +1,30 @@
+-- name: InsertAuditLogEntry :one
+INSERT INTO audit_log (
+    actor,
+    action,
+    resource_type,
+    resource_id,
+    user_id,
+    request_id,
+    source_ip,
+    before,
+    after,
+    changes
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: ListAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('actor')::varchar IS NULL OR actor = sqlc.narg('actor'))
+  AND (sqlc.narg('action')::varchar IS NULL OR action = sqlc.narg('action'))
+  AND (sqlc.narg('resource_type')::varchar IS NULL OR resource_type = sqlc.narg('resource_type'))
+  AND (sqlc.narg('resource_id')::varchar IS NULL OR resource_id = sqlc.narg('resource_id'))
+  AND (sqlc.narg('user_id')::varchar IS NULL OR user_id = sqlc.narg('user_id'))
+  AND (sqlc.narg('request_id')::varchar IS NULL OR request_id = sqlc.narg('request_id'))
+  AND (sqlc.narg('from')::timestamptz IS NULL OR occurred_at >= sqlc.narg('from'))
+  AND (sqlc.narg('to')::timestamptz IS NULL OR occurred_at < sqlc.narg('to'))
+ORDER BY occurred_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
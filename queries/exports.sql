@@ -0,0 +1,46 @@
+-- name: ListInvertersWithHourlyRecords :many
+SELECT DISTINCT inverter_id
+FROM solar_panel_hourly_records
+WHERE inverter_id = ANY(sqlc.arg('inverter_ids')::int[])
+  AND timestamp >= sqlc.arg('start_time')
+  AND timestamp < sqlc.arg('end_time');
+
+-- name: ListProductionExportRows :many
+SELECT
+    bucket,
+    inverter_id,
+    energy_kwh,
+    power_output_kw,
+    predicted_power_output_kw,
+    irradiance,
+    poa_irradiance,
+    temperature_celsius,
+    cell_temperature_celsius,
+    cloud_cover_percent,
+    clear_sky_index,
+    readings
+FROM (
+    SELECT
+        timezone(sqlc.arg('time_zone')::text, date_trunc(sqlc.arg('resolution')::text, timezone(sqlc.arg('time_zone')::text, timezone('UTC', r.timestamp))))::timestamptz AS bucket,
+        r.inverter_id,
+        SUM(r.energy_generated_kwh)::float8 AS energy_kwh,
+        AVG(r.power_output_kw)::float8 AS power_output_kw,
+        AVG(r.predicted_power_output_kw) AS predicted_power_output_kw,
+        AVG(r.irradiance) AS irradiance,
+        AVG(r.poa_irradiance) AS poa_irradiance,
+        AVG(r.temperature_celsius) AS temperature_celsius,
+        AVG(r.cell_temperature_celsius) AS cell_temperature_celsius,
+        AVG(r.cloud_cover_percent) AS cloud_cover_percent,
+        AVG(r.clear_sky_index) AS clear_sky_index,
+        COUNT(*)::int AS readings
+    FROM solar_panel_hourly_records r
+    WHERE r.inverter_id = ANY(sqlc.arg('inverter_ids')::int[])
+      AND r.timestamp >= sqlc.arg('start_time')
+      AND r.timestamp < sqlc.arg('end_time')
+      -- Buckets after the cursor only contain records at or after the cursor.
+      AND r.timestamp >= timezone('UTC', sqlc.arg('after_bucket')::timestamptz)
+    GROUP BY 1, 2
+) b
+WHERE (bucket, inverter_id) > (sqlc.arg('after_bucket')::timestamptz, sqlc.arg('after_inverter_id')::int)
+ORDER BY bucket, inverter_id
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,80 @@
+-- name: GetUserIdentity :one
+SELECT * FROM identities
+WHERE user_id = $1 AND provider = $2
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: UpsertIdentityTokens :one
+INSERT INTO identities (user_id, provider, provider_user_id, access_token, refresh_token, scope, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (provider, provider_user_id) DO UPDATE
+SET access_token = EXCLUDED.access_token,
+    refresh_token = EXCLUDED.refresh_token,
+    scope = EXCLUDED.scope,
+    expires_at = EXCLUDED.expires_at,
+    revoked_at = NULL,
+    refresh_failures = 0,
+    refresh_locked_until = NULL,
+    updated_at = NOW()
+WHERE identities.user_id = EXCLUDED.user_id
+RETURNING *;
+
+-- name: ListIdentitiesDueForRefresh :many
+SELECT * FROM identities
+WHERE refresh_token IS NOT NULL
+  AND revoked_at IS NULL
+  AND expires_at < sqlc.arg('expires_before')
+  AND (refresh_locked_until IS NULL OR refresh_locked_until < NOW())
+ORDER BY expires_at
+LIMIT sqlc.arg('limit');
+
+-- name: LockIdentityRefresh :one
+UPDATE identities
+SET refresh_locked_until = sqlc.arg('locked_until')
+WHERE id = sqlc.arg('id')
+  AND revoked_at IS NULL
+  AND (refresh_locked_until IS NULL OR refresh_locked_until < NOW())
+RETURNING *;
+
+-- name: UpdateIdentityTokens :one
+UPDATE identities
+SET access_token = $1,
+    refresh_token = $2,
+    scope = $3,
+    expires_at = $4,
+    refresh_failures = 0,
+    refresh_locked_until = NULL,
+    updated_at = NOW()
+WHERE id = $5
+RETURNING *;
+
+-- name: RecordIdentityRefreshFailure :exec
+UPDATE identities
+SET refresh_failures = refresh_failures + 1, refresh_locked_until = NULL
+WHERE id = $1;
+
+-- name: RevokeIdentity :one
+UPDATE identities
+SET access_token = NULL,
+    refresh_token = NULL,
+    revoked_at = NOW(),
+    refresh_locked_until = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListIdentityTokens :many
+SELECT id, provider, provider_user_id, access_token, refresh_token FROM identities
+WHERE id > sqlc.arg('after_id') AND (access_token IS NOT NULL OR refresh_token IS NOT NULL)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ReplaceIdentityAccessToken :execrows
+UPDATE identities
+SET access_token = sqlc.arg('access_token')
+WHERE id = sqlc.arg('id') AND access_token = sqlc.arg('previous_access_token');
+
+-- name: ReplaceIdentityRefreshToken :execrows
+UPDATE identities
+SET refresh_token = sqlc.arg('refresh_token')
+WHERE id = sqlc.arg('id') AND refresh_token = sqlc.arg('previous_refresh_token');
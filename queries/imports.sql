@@ -0,0 +1,10 @@
+-- name: ListInvertersBySerialNumbers :many
+SELECT * FROM inverters WHERE serial_number = ANY(sqlc.arg('serial_numbers')::text[]);
+
+-- name: ListHourlyRecordTimestamps :many
+SELECT timestamp FROM solar_panel_hourly_records
+WHERE inverter_id = $1 AND timestamp = ANY(sqlc.arg('timestamps')::timestamp[]);
+
+-- name: InsertHourlyRecords :copyfrom
+INSERT INTO solar_panel_hourly_records (inverter_id, timestamp, power_output_kw, energy_generated_kwh)
+VALUES ($1, $2, $3, $4);
@@ -20,6 +20,20 @@ RETURNING
 -- name: GetInverterById :one
 SELECT * FROM inverters WHERE id = $1;
 
+-- name: ListEnodeInverterMatches :many
+SELECT * FROM inverters i
+WHERE i.id IN (SELECT inverter_id FROM inverter_enode_links WHERE enode_inverter_id = sqlc.arg('enode_inverter_id'))
+   OR (
+       i.serial_number = sqlc.arg('serial_number')
+       AND i.user_id = sqlc.arg('user_id')
+       AND NOT EXISTS (
+           SELECT 1 FROM inverter_enode_links l
+           WHERE l.enode_inverter_id = sqlc.arg('enode_inverter_id') OR l.inverter_id = i.id
+       )
+   )
+ORDER BY i.id
+LIMIT 2;
+
 -- name: GetInvertersByUserId :many
 SELECT * FROM inverters WHERE user_id = $1;
 
@@ -0,0 +1,77 @@
+-- name: ListInverterEnodeLinks :many
+SELECT * FROM inverter_enode_links
+WHERE inverter_id = ANY(sqlc.arg('inverter_ids')::int[]);
+
+-- name: LinkInverterToEnode :exec
+INSERT INTO inverter_enode_links (inverter_id, enode_inverter_id)
+VALUES ($1, $2)
+ON CONFLICT (inverter_id) DO UPDATE
+SET enode_inverter_id = EXCLUDED.enode_inverter_id, linked_at = NOW();
+
+-- name: ReassignSolarPanels :execrows
+UPDATE solar_panels
+SET inverter_id = sqlc.arg('to_inverter_id'), updated_at = NOW()
+WHERE inverter_id = sqlc.arg('from_inverter_id');
+
+-- name: DeleteOverlappingHourlyRecords :many
+DELETE FROM solar_panel_hourly_records r
+USING solar_panel_hourly_records k
+WHERE r.inverter_id = sqlc.arg('from_inverter_id')
+  AND k.inverter_id = sqlc.arg('to_inverter_id')
+  AND k.timestamp = r.timestamp
+RETURNING r.id, r.timestamp, r.power_output_kw, r.energy_generated_kwh;
+
+-- name: LockProductionRollups :exec
+SELECT pg_advisory_xact_lock(hashtext('production_rollups'));
+
+-- name: MoveFoldedHourlyProduction :execrows
+INSERT INTO production_daily (inverter_id, user_id, day, source, energy_kwh, readings, refreshed_at)
+SELECT i.id, i.user_id, r.timestamp::date, 'hourly', SUM(r.energy_generated_kwh), COUNT(*), NOW()
+FROM solar_panel_hourly_records r
+JOIN inverters i ON i.id = sqlc.arg('to_inverter_id')
+WHERE r.inverter_id = sqlc.arg('from_inverter_id')
+  AND r.timestamp IS NOT NULL
+  AND r.id <= (SELECT COALESCE(MAX(last_id), 0) FROM production_rollup_cursors WHERE name = 'hourly_records')
+GROUP BY i.id, i.user_id, r.timestamp::date
+ON CONFLICT (inverter_id, day, source) DO UPDATE
+SET
+    energy_kwh = production_daily.energy_kwh + EXCLUDED.energy_kwh,
+    readings = production_daily.readings + EXCLUDED.readings,
+    refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: ReassignHourlyRecords :execrows
+UPDATE solar_panel_hourly_records
+SET inverter_id = sqlc.arg('to_inverter_id'), updated_at = NOW()
+WHERE inverter_id = sqlc.arg('from_inverter_id');
+
+-- name: DeleteInverterProductionRollups :exec
+WITH daily AS (
+    DELETE FROM production_daily WHERE production_daily.inverter_id = $1
+), monthly AS (
+    DELETE FROM production_monthly WHERE production_monthly.inverter_id = $1
+)
+DELETE FROM production_yearly WHERE production_yearly.inverter_id = $1;
+
+-- name: InsertInverterMerge :one
+INSERT INTO inverter_merges (
+    serial_number,
+    enode_inverter_id,
+    kept_inverter_id,
+    merged_inverter_id,
+    merged_inverter,
+    panels_reassigned,
+    records_reassigned,
+    discarded_records,
+    merged_by
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: ListInverterMerges :many
+SELECT * FROM inverter_merges
+WHERE (sqlc.narg('serial_number')::varchar IS NULL OR serial_number = sqlc.narg('serial_number'))
+  AND (sqlc.narg('inverter_id')::int IS NULL OR kept_inverter_id = sqlc.narg('inverter_id') OR merged_inverter_id = sqlc.narg('inverter_id'))
+ORDER BY merged_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
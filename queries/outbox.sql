@@ -0,0 +1,36 @@
+-- name: InsertOutboxEvent :one
+INSERT INTO outbox_events (
+    aggregate_type,
+    aggregate_id,
+    event_type,
+    payload
+)
+VALUES (
+    $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: TryLockOutboxRelay :one
+SELECT pg_try_advisory_xact_lock(hashtext('outbox_relay'));
+
+-- name: ListPendingOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE published_at IS NULL
+  AND attempts < sqlc.arg('max_attempts')
+ORDER BY id
+LIMIT sqlc.arg('batch_size');
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET
+    published_at = NOW(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET
+    attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1;
@@ -0,0 +1,58 @@
+-- name: ListPanelInverterIds :many
+SELECT DISTINCT inverter_id FROM solar_panels
+WHERE inverter_id IS NOT NULL
+ORDER BY inverter_id;
+
+-- name: ListInverterPanels :many
+SELECT
+    id,
+    inverter_id,
+    capacity_kw,
+    tilt,
+    orientation,
+    ST_Y(location::geometry)::float8 AS latitude,
+    ST_X(location::geometry)::float8 AS longitude
+FROM solar_panels
+WHERE inverter_id = ANY(sqlc.arg('inverter_ids')::int[])
+ORDER BY inverter_id, id;
+
+-- name: ListHourlyRecordsInRange :many
+SELECT
+    inverter_id,
+    timestamp,
+    energy_generated_kwh,
+    irradiance,
+    poa_irradiance,
+    clear_sky_index
+FROM solar_panel_hourly_records
+WHERE inverter_id = ANY(sqlc.arg('inverter_ids')::int[])
+  AND timestamp >= sqlc.arg('start_time')
+  AND timestamp < sqlc.arg('end_time')
+ORDER BY inverter_id, timestamp;
+
+-- name: ListHourlyRecordsMissingClearSkyIndex :many
+SELECT
+    r.id,
+    r.timestamp,
+    r.irradiance,
+    ST_Y(p.location::geometry)::float8 AS latitude,
+    ST_X(p.location::geometry)::float8 AS longitude
+FROM solar_panel_hourly_records r
+JOIN LATERAL (
+    SELECT location FROM solar_panels
+    WHERE inverter_id = r.inverter_id AND location IS NOT NULL
+    ORDER BY id
+    LIMIT 1
+) p ON TRUE
+WHERE r.clear_sky_index IS NULL
+  AND r.irradiance IS NOT NULL
+  AND r.timestamp IS NOT NULL
+ORDER BY r.id
+LIMIT $1;
+
+-- name: SetHourlyRecordClearSkyIndex :exec
+UPDATE solar_panel_hourly_records
+SET
+    clear_sky_index = $2,
+    updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,162 @@
+-- name: CreatePrivacyJob :one
+INSERT INTO privacy_jobs (user_id, kind, requested_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, kind) WHERE status IN ('pending', 'running') DO NOTHING
+RETURNING *;
+
+-- name: InsertPrivacyJobStep :exec
+INSERT INTO privacy_job_steps (job_id, position, name)
+VALUES ($1, $2, $3);
+
+-- name: GetPrivacyJob :one
+SELECT * FROM privacy_jobs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListUserPrivacyJobs :many
+SELECT * FROM privacy_jobs
+WHERE user_id = $1
+ORDER BY id DESC;
+
+-- name: ListPrivacyJobSteps :many
+SELECT * FROM privacy_job_steps
+WHERE job_id = $1
+ORDER BY position;
+
+-- name: ClaimPrivacyJob :one
+UPDATE privacy_jobs
+SET status = 'running', attempts = attempts + 1, locked_until = sqlc.arg('locked_until'), updated_at = NOW()
+WHERE id = (
+    SELECT id FROM privacy_jobs
+    WHERE (status = 'pending' AND run_after <= NOW())
+       OR (status = 'running' AND locked_until < NOW())
+    ORDER BY id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompletePrivacyJobStep :exec
+UPDATE privacy_job_steps
+SET status = 'completed', affected_rows = $3, output = $4, completed_at = NOW()
+WHERE job_id = $1 AND name = $2;
+
+-- name: CompletePrivacyJob :exec
+UPDATE privacy_jobs
+SET status = 'completed', last_error = NULL, locked_until = NULL, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailPrivacyJob :exec
+UPDATE privacy_jobs
+SET status = $2, last_error = $3, run_after = $4, locked_until = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryPrivacyJob :one
+UPDATE privacy_jobs
+SET status = 'pending', attempts = 0, run_after = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'failed'
+RETURNING *;
+
+-- name: ExportUserIdentities :one
+SELECT COALESCE(jsonb_agg(to_jsonb(i) - 'access_token' - 'refresh_token' ORDER BY i.id), '[]')::jsonb
+FROM identities i
+WHERE i.user_id = $1;
+
+-- name: ExportUserLinkSessions :one
+SELECT COALESCE(jsonb_agg(to_jsonb(s) - 'link_token' ORDER BY s.id), '[]')::jsonb
+FROM link_sessions s
+WHERE s.user_id = $1;
+
+-- name: ExportUserInverters :one
+SELECT COALESCE(jsonb_agg(to_jsonb(i) || jsonb_build_object('enode_inverter_id', l.enode_inverter_id) ORDER BY i.id), '[]')::jsonb
+FROM inverters i
+LEFT JOIN inverter_enode_links l ON l.inverter_id = i.id
+WHERE i.user_id = $1;
+
+-- name: ExportUserSolarPanels :one
+SELECT COALESCE(jsonb_agg(to_jsonb(p) ORDER BY p.id), '[]')::jsonb
+FROM solar_panels p
+WHERE p.user_id = $1 OR p.inverter_id IN (SELECT id FROM inverters WHERE user_id = $1);
+
+-- name: ExportUserHourlyRecords :one
+SELECT COALESCE(jsonb_agg(to_jsonb(r) ORDER BY r.inverter_id, r.timestamp), '[]')::jsonb
+FROM solar_panel_hourly_records r
+WHERE r.inverter_id IN (SELECT id FROM inverters WHERE user_id = $1);
+
+-- name: ExportUserDailyProduction :one
+SELECT COALESCE(jsonb_agg(to_jsonb(d) ORDER BY d.inverter_id, d.day, d.source), '[]')::jsonb
+FROM production_daily d
+WHERE d.user_id = $1;
+
+-- name: ExportUserAlerts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(a) ORDER BY a.id), '[]')::jsonb
+FROM alerts a
+WHERE a.user_id = sqlc.arg('user_id')::int::text;
+
+-- name: ExportUserWebhookEvents :one
+SELECT COALESCE(jsonb_agg(to_jsonb(w) ORDER BY w.id), '[]')::jsonb
+FROM webhook_events w
+WHERE w.payload->'inverter'->>'userId' = sqlc.arg('user_id')::int::text;
+
+-- name: ExportUserAuditLog :one
+SELECT COALESCE(jsonb_agg(to_jsonb(a) ORDER BY a.id), '[]')::jsonb
+FROM audit_log a
+WHERE a.user_id = sqlc.arg('user_id')::int::text;
+
+-- name: ListUserIdentityProviders :many
+SELECT provider FROM identities
+WHERE user_id = $1
+ORDER BY provider;
+
+-- name: EraseUserAlerts :execrows
+DELETE FROM alerts WHERE user_id = sqlc.arg('user_id')::int::text;
+
+-- name: EraseUserHourlyRecords :execrows
+DELETE FROM solar_panel_hourly_records
+WHERE inverter_id IN (SELECT id FROM inverters WHERE user_id = $1);
+
+-- name: EraseUserProductionRollups :one
+WITH daily AS (
+    DELETE FROM production_daily WHERE production_daily.user_id = $1 RETURNING 1
+), monthly AS (
+    DELETE FROM production_monthly WHERE production_monthly.user_id = $1 RETURNING 1
+), yearly AS (
+    DELETE FROM production_yearly WHERE production_yearly.user_id = $1 RETURNING 1
+)
+SELECT ((SELECT COUNT(*) FROM daily) + (SELECT COUNT(*) FROM monthly) + (SELECT COUNT(*) FROM yearly))::bigint;
+
+-- name: EraseUserInverterMerges :execrows
+DELETE FROM inverter_merges
+WHERE kept_inverter_id IN (SELECT id FROM inverters WHERE user_id = sqlc.arg('user_id'))
+   OR merged_inverter->>'userId' = sqlc.arg('user_id')::int::text;
+
+-- name: DetachUserSolarPanels :execrows
+UPDATE solar_panels
+SET inverter_id = NULL, updated_at = NOW()
+WHERE inverter_id IN (SELECT id FROM inverters WHERE user_id = $1);
+
+-- name: EraseUserInverters :execrows
+DELETE FROM inverters WHERE user_id = $1;
+
+-- name: EraseUserLinkSessions :execrows
+DELETE FROM link_sessions WHERE user_id = $1;
+
+-- name: EraseUserIdentities :execrows
+DELETE FROM identities WHERE user_id = $1;
+
+-- name: EraseUserWebhookEvents :execrows
+DELETE FROM webhook_events
+WHERE payload->'inverter'->>'userId' = sqlc.arg('user_id')::int::text;
+
+-- name: EraseUserOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE published_at IS NOT NULL
+  AND (payload->'inverter'->>'userId' = sqlc.arg('user_id')::int::text OR payload->'alert'->>'userId' = sqlc.arg('user_id')::int::text);
+
+-- name: RedactUserAuditLog :one
+SELECT redact_user_audit_log(sqlc.arg('user_id')::int::text)::bigint;
+
+-- name: ClearUserExportOutputs :execrows
+UPDATE privacy_job_steps
+SET output = NULL
+WHERE output IS NOT NULL
+  AND job_id IN (SELECT id FROM privacy_jobs WHERE user_id = $1 AND kind = 'export');
@@ -0,0 +1,129 @@
+-- name: TryLockProductionRollups :one
+SELECT pg_try_advisory_xact_lock(hashtext('production_rollups'));
+
+-- name: GetRollupCursor :one
+SELECT last_id FROM production_rollup_cursors
+WHERE name = $1;
+
+-- name: SetRollupCursor :exec
+INSERT INTO production_rollup_cursors (name, last_id)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET last_id = EXCLUDED.last_id;
+
+-- name: GetHourlyRecordBatchEnd :one
+SELECT
+    COALESCE(MIN(id) FILTER (WHERE created_at >= sqlc.arg('settled_before')) - 1, MAX(id), sqlc.arg('after_id'))::int AS last_id,
+    (COUNT(*) = sqlc.arg('batch_size')::int AND BOOL_AND(created_at < sqlc.arg('settled_before')))::bool AS more
+FROM (
+    SELECT id, created_at
+    FROM solar_panel_hourly_records
+    WHERE id > sqlc.arg('after_id')
+    ORDER BY id
+    LIMIT sqlc.arg('batch_size')::int
+) batch;
+
+-- name: FoldHourlyDailyProduction :execrows
+INSERT INTO production_daily (inverter_id, user_id, day, source, energy_kwh, readings, refreshed_at)
+SELECT r.inverter_id, i.user_id, r.timestamp::date, 'hourly', SUM(r.energy_generated_kwh), COUNT(*), NOW()
+FROM solar_panel_hourly_records r
+JOIN inverters i ON i.id = r.inverter_id
+WHERE r.id > sqlc.arg('after_id') AND r.id <= sqlc.arg('last_id') AND r.timestamp IS NOT NULL
+GROUP BY r.inverter_id, i.user_id, r.timestamp::date
+ON CONFLICT (inverter_id, day, source) DO UPDATE
+SET
+    user_id = EXCLUDED.user_id,
+    energy_kwh = production_daily.energy_kwh + EXCLUDED.energy_kwh,
+    readings = production_daily.readings + EXCLUDED.readings,
+    refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: UpsertEnodeDailyProduction :exec
+INSERT INTO production_daily (inverter_id, user_id, day, source, energy_kwh, readings, refreshed_at)
+VALUES ($1, $2, $3, 'enode', $4, 1, NOW())
+ON CONFLICT (inverter_id, day, source) DO UPDATE
+SET
+    user_id = EXCLUDED.user_id,
+    energy_kwh = EXCLUDED.energy_kwh,
+    refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: RefreshMonthlyProduction :execrows
+INSERT INTO production_monthly (inverter_id, user_id, month, energy_kwh, days, refreshed_at)
+SELECT d.inverter_id, i.user_id, date_trunc('month', d.day)::date, SUM(d.energy_kwh), COUNT(*), NOW()
+FROM (
+    SELECT DISTINCT ON (inverter_id, day) inverter_id, day, energy_kwh
+    FROM production_daily
+    WHERE (inverter_id, date_trunc('month', day)::date) IN (
+        SELECT inverter_id, date_trunc('month', day)::date
+        FROM production_daily
+        WHERE refreshed_at >= NOW()
+    )
+    ORDER BY inverter_id, day, CASE source WHEN 'hourly' THEN 0 ELSE 1 END
+) d
+JOIN inverters i ON i.id = d.inverter_id
+GROUP BY d.inverter_id, i.user_id, date_trunc('month', d.day)::date
+ON CONFLICT (inverter_id, month) DO UPDATE
+SET
+    user_id = EXCLUDED.user_id,
+    energy_kwh = EXCLUDED.energy_kwh,
+    days = EXCLUDED.days,
+    refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: RefreshYearlyProduction :execrows
+INSERT INTO production_yearly (inverter_id, user_id, year, energy_kwh, months, refreshed_at)
+SELECT m.inverter_id, i.user_id, EXTRACT(YEAR FROM m.month)::int, SUM(m.energy_kwh), COUNT(*), NOW()
+FROM production_monthly m
+JOIN inverters i ON i.id = m.inverter_id
+WHERE (m.inverter_id, EXTRACT(YEAR FROM m.month)::int) IN (
+    SELECT inverter_id, EXTRACT(YEAR FROM month)::int
+    FROM production_monthly
+    WHERE refreshed_at >= NOW()
+)
+GROUP BY m.inverter_id, i.user_id, EXTRACT(YEAR FROM m.month)::int
+ON CONFLICT (inverter_id, year) DO UPDATE
+SET
+    user_id = EXCLUDED.user_id,
+    energy_kwh = EXCLUDED.energy_kwh,
+    months = EXCLUDED.months,
+    refreshed_at = EXCLUDED.refreshed_at;
+
+-- name: ListInverterDailyProduction :many
+SELECT DISTINCT ON (day) day, energy_kwh, source
+FROM production_daily
+WHERE inverter_id = $1 AND day >= sqlc.arg('from_day') AND day <= sqlc.arg('to_day')
+ORDER BY day, CASE source WHEN 'hourly' THEN 0 ELSE 1 END;
+
+-- name: ListInverterMonthlyProduction :many
+SELECT month, energy_kwh, days
+FROM production_monthly
+WHERE inverter_id = $1 AND month >= sqlc.arg('from_month') AND month <= sqlc.arg('to_month')
+ORDER BY month;
+
+-- name: ListInverterYearlyProduction :many
+SELECT year, energy_kwh, months
+FROM production_yearly
+WHERE inverter_id = $1 AND year >= sqlc.arg('from_year') AND year <= sqlc.arg('to_year')
+ORDER BY year;
+
+-- name: ListUserDailyProduction :many
+SELECT d.day, SUM(d.energy_kwh)::float8 AS energy_kwh, COUNT(*)::int AS inverters
+FROM (
+    SELECT DISTINCT ON (inverter_id, day) inverter_id, day, energy_kwh
+    FROM production_daily
+    WHERE user_id = $1 AND day >= sqlc.arg('from_day') AND day <= sqlc.arg('to_day')
+    ORDER BY inverter_id, day, CASE source WHEN 'hourly' THEN 0 ELSE 1 END
+) d
+GROUP BY d.day
+ORDER BY d.day;
+
+-- name: ListUserMonthlyProduction :many
+SELECT month, SUM(energy_kwh)::float8 AS energy_kwh, COUNT(*)::int AS inverters
+FROM production_monthly
+WHERE user_id = $1 AND month >= sqlc.arg('from_month') AND month <= sqlc.arg('to_month')
+GROUP BY month
+ORDER BY month;
+
+-- name: ListUserYearlyProduction :many
+SELECT year, SUM(energy_kwh)::float8 AS energy_kwh, COUNT(*)::int AS inverters
+FROM production_yearly
+WHERE user_id = $1 AND year >= sqlc.arg('from_year') AND year <= sqlc.arg('to_year')
+GROUP BY year
+ORDER BY year;
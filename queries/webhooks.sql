@@ -0,0 +1,23 @@
+-- name: InsertWebhookEvent :one
+INSERT INTO webhook_events (
+    provider,
+    event_type,
+    payload
+)
+VALUES (
+    $1, $2, $3
+)
+RETURNING *;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events SET processed_at = NOW() WHERE id = $1;
+
+-- name: ListUnprocessedWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE processed_at IS NULL
+  AND received_at < sqlc.arg('received_before')
+ORDER BY id
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: TryLockWebhookRetry :one
+SELECT pg_try_advisory_xact_lock(hashtext('webhook_retry'));